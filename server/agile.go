@@ -0,0 +1,366 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+)
+
+const (
+	boardSearchMaxResults = 20
+
+	backlogDefaultSize = 10
+	backlogMaxSize     = 50
+
+	sprintStateActive = "active"
+	sprintStateFuture = "future"
+)
+
+// statusCategoryOrder sorts the Jira status categories from to do to done.
+var statusCategoryOrder = map[string]int{
+	"new":           0,
+	"indeterminate": 1,
+	"done":          2,
+}
+
+// resolveBoard finds a board by ID, or by name among the boards visible to the user. Without a
+// query, the user must see a single board.
+func resolveBoard(client Client, query string) (*Board, error) {
+	query = strings.TrimSpace(strings.Trim(query, `"“”`))
+	if id, err := strconv.Atoi(query); err == nil && id > 0 {
+		return client.GetBoard(id)
+	}
+
+	boards, err := client.GetBoards("", query, boardSearchMaxResults)
+	if err != nil {
+		return nil, err
+	}
+	for i := range boards {
+		if query != "" && strings.EqualFold(boards[i].Name, query) {
+			return &boards[i], nil
+		}
+	}
+
+	switch {
+	case len(boards) == 1:
+		return &boards[0], nil
+	case len(boards) == 0 && query == "":
+		return nil, errors.New("no board is visible to you")
+	case len(boards) == 0:
+		return nil, errors.Errorf("no board matches %q", query)
+	}
+
+	names := []string{}
+	for _, board := range boards {
+		names = append(names, fmt.Sprintf("%s (%d)", board.Name, board.ID))
+	}
+	if query == "" {
+		return nil, errors.Errorf("please specify one of the boards: %s", strings.Join(names, ", "))
+	}
+	return nil, errors.Errorf("several boards match %q, please use one of their IDs: %s", query, strings.Join(names, ", "))
+}
+
+// resolveIssueSprint finds a sprint by ID, or by name among the active and future sprints of the
+// boards of the issue's project. Without a query, the project must have a single active sprint.
+func resolveIssueSprint(client Client, issue *jira.Issue, query string) (*Sprint, error) {
+	query = strings.TrimSpace(strings.Trim(query, `"“”`))
+	if id, err := strconv.Atoi(query); err == nil && id > 0 {
+		return client.GetSprint(id)
+	}
+
+	boards, err := client.GetBoards(issue.Fields.Project.Key, "", 0)
+	if err != nil {
+		return nil, err
+	}
+	sprints := []Sprint{}
+	seen := map[int]bool{}
+	for _, board := range boards {
+		boardSprints, err := client.GetBoardSprints(board.ID, sprintStateActive+","+sprintStateFuture)
+		if err != nil {
+			// Kanban boards have no sprints
+			continue
+		}
+		for _, sprint := range boardSprints {
+			if !seen[sprint.ID] {
+				seen[sprint.ID] = true
+				sprints = append(sprints, sprint)
+			}
+		}
+	}
+
+	found := []Sprint{}
+	for _, sprint := range sprints {
+		switch {
+		case query == "":
+			if sprint.State == sprintStateActive {
+				found = append(found, sprint)
+			}
+		case strings.EqualFold(sprint.Name, query):
+			return &sprint, nil
+		case strings.Contains(strings.ToLower(sprint.Name), strings.ToLower(query)):
+			found = append(found, sprint)
+		}
+	}
+
+	switch {
+	case len(found) == 1:
+		return &found[0], nil
+	case len(found) == 0 && query == "":
+		return nil, errors.Errorf("project %s has no active sprint", issue.Fields.Project.Key)
+	case len(found) == 0:
+		return nil, errors.Errorf("no active or future sprint of project %s matches %q", issue.Fields.Project.Key, query)
+	}
+
+	names := []string{}
+	for _, sprint := range found {
+		names = append(names, fmt.Sprintf("%s (%d)", sprint.Name, sprint.ID))
+	}
+	return nil, errors.Errorf("several sprints match, please use one of their IDs: %s", strings.Join(names, ", "))
+}
+
+func boardURL(instance Instance, board *Board) string {
+	return fmt.Sprintf("%s/secure/RapidBoard.jspa?rapidView=%d", instance.GetJiraBaseURL(), board.ID)
+}
+
+func sprintURL(instance Instance, sprint *Sprint) string {
+	return fmt.Sprintf("%s/issues/?jql=%s", instance.GetJiraBaseURL(), url.QueryEscape(fmt.Sprintf("sprint = %d", sprint.ID)))
+}
+
+// formatSprintDates returns the dates of a sprint, with the days left until its end while it is
+// active.
+func formatSprintDates(sprint *Sprint, now time.Time) string {
+	start, startErr := time.Parse(time.RFC3339, sprint.StartDate)
+	end, endErr := time.Parse(time.RFC3339, sprint.EndDate)
+	if startErr != nil || endErr != nil {
+		return "Not scheduled"
+	}
+
+	dates := fmt.Sprintf("%s - %s", start.Format("Jan 2"), end.Format("Jan 2, 2006"))
+	if sprint.State != sprintStateActive {
+		return dates
+	}
+	daysLeft := int(end.Sub(now).Hours() / 24)
+	switch {
+	case end.Before(now):
+		return dates + " (overdue)"
+	case daysLeft == 1:
+		return dates + " (1 day left)"
+	default:
+		return dates + fmt.Sprintf(" (%d days left)", daysLeft)
+	}
+}
+
+// formatSprintReport renders the goal, dates and issue counts by status category of a sprint.
+func formatSprintReport(instance Instance, board *Board, sprint *Sprint, issues []jira.Issue, now time.Time) string {
+	text := fmt.Sprintf("#### Sprint [%s](%s) of board [%s](%s)\n", sprint.Name, sprintURL(instance, sprint), board.Name, boardURL(instance, board))
+	if sprint.Goal != "" {
+		text += fmt.Sprintf("**Goal:** %s\n", sprint.Goal)
+	}
+	text += fmt.Sprintf("**Dates:** %s\n", formatSprintDates(sprint, now))
+
+	if len(issues) == 0 {
+		return text + "\nThe sprint has no issues."
+	}
+
+	type categoryCount struct {
+		key   string
+		name  string
+		count int
+	}
+	counts := []*categoryCount{}
+	byName := map[string]*categoryCount{}
+	done := 0
+	for _, issue := range issues {
+		category := jira.StatusCategory{Key: "undefined", Name: "No category"}
+		if issue.Fields != nil && issue.Fields.Status != nil {
+			category = issue.Fields.Status.StatusCategory
+		}
+		if category.Key == "done" {
+			done++
+		}
+		if byName[category.Name] == nil {
+			byName[category.Name] = &categoryCount{key: category.Key, name: category.Name}
+			counts = append(counts, byName[category.Name])
+		}
+		byName[category.Name].count++
+	}
+	sort.SliceStable(counts, func(i, j int) bool {
+		rank := func(key string) int {
+			if r, ok := statusCategoryOrder[key]; ok {
+				return r
+			}
+			return len(statusCategoryOrder)
+		}
+		return rank(counts[i].key) < rank(counts[j].key)
+	})
+
+	text += "\n| Status | Issues |\n|:--|--:|\n"
+	for _, c := range counts {
+		text += fmt.Sprintf("|%s|%d|\n", c.name, c.count)
+	}
+	text += fmt.Sprintf("\n%d issues, %d%% done.", len(issues), done*100/len(issues))
+	return text
+}
+
+// parseBacklogArgs parses the board and the --top flag of the backlog command.
+func parseBacklogArgs(args []string) (string, int, error) {
+	top := backlogDefaultSize
+	board := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--top") {
+			board = append(board, arg)
+			continue
+		}
+
+		value := strings.TrimPrefix(arg, "--top")
+		switch {
+		case strings.HasPrefix(value, "="):
+			value = value[1:]
+		case value != "":
+			return "", 0, errors.Errorf("`%s` is not valid", arg)
+		case i+1 < len(args):
+			i++
+			value = args[i]
+		default:
+			return "", 0, errors.New("--top requires a number of issues")
+		}
+
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > backlogMaxSize {
+			return "", 0, errors.Errorf("--top must be a number between 1 and %d", backlogMaxSize)
+		}
+		top = n
+	}
+	return strings.Join(board, " "), top, nil
+}
+
+func formatBacklog(instance Instance, board *Board, issues []jira.Issue) string {
+	text := fmt.Sprintf("#### Backlog of board [%s](%s)\n", board.Name, boardURL(instance, board))
+	if len(issues) == 0 {
+		return text + "The backlog is empty."
+	}
+
+	text += "| # | Issue | Status | Priority | Assignee |\n|--:|:--|:--|:--|:--|\n"
+	for i, issue := range issues {
+		status, priority, assignee := "", "", "Unassigned"
+		if issue.Fields.Status != nil {
+			status = issue.Fields.Status.Name
+		}
+		if issue.Fields.Priority != nil {
+			priority = issue.Fields.Priority.Name
+		}
+		if issue.Fields.Assignee != nil {
+			assignee = issue.Fields.Assignee.DisplayName
+		}
+		text += fmt.Sprintf("|%d|[%s](%s/browse/%s) %s|%s|%s|%s|\n", i+1, issue.Key, instance.GetJiraBaseURL(), issue.Key,
+			strings.ReplaceAll(truncate(issue.Fields.Summary, maxIssueSummaryLength), "|", "\\|"), status, priority, assignee)
+	}
+	return text
+}
+
+func executeSprint(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	user, instance, args, err := p.loadFlagUserInstance(header.UserId, args)
+	if err != nil {
+		return p.responsef(header, "Failed to load your connection to Jira. Error: %v.", err)
+	}
+
+	client, _, _, err := p.getClient(instance.GetID(), user.MattermostUserID)
+	if err != nil {
+		return p.responsef(header, "Your username is not connected to Jira. Please type `jira connect`.")
+	}
+
+	board, err := resolveBoard(client, strings.Join(args, " "))
+	if err != nil {
+		return p.responsef(header, "Failed to find the board. Error: %v.", err)
+	}
+
+	sprints, err := client.GetBoardSprints(board.ID, sprintStateActive)
+	if err != nil {
+		return p.responsef(header, "Failed to get the sprints of board %q. Error: %v.", board.Name, err)
+	}
+	if len(sprints) == 0 {
+		return p.responsef(header, "Board [%s](%s) has no active sprint.", board.Name, boardURL(instance, board))
+	}
+
+	// Boards can run parallel sprints
+	reports := []string{}
+	for i := range sprints {
+		issues, err := client.GetSprintIssues(sprints[i].ID, []string{"status"})
+		if err != nil {
+			return p.responsef(header, "Failed to get the issues of sprint %q. Error: %v.", sprints[i].Name, err)
+		}
+		reports = append(reports, formatSprintReport(instance, board, &sprints[i], issues, time.Now()))
+	}
+	return p.responsef(header, "%s", strings.Join(reports, "\n\n"))
+}
+
+func executeSprintAdd(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	user, instance, args, err := p.loadFlagUserInstance(header.UserId, args)
+	if err != nil {
+		return p.responsef(header, "Failed to load your connection to Jira. Error: %v.", err)
+	}
+	if len(args) == 0 {
+		return p.responsef(header, "Please specify an issue in the form `/jira sprint add <issue-key> [sprint]`.")
+	}
+
+	client, _, _, err := p.getClient(instance.GetID(), user.MattermostUserID)
+	if err != nil {
+		return p.responsef(header, "Your username is not connected to Jira. Please type `jira connect`.")
+	}
+
+	issue, err := client.GetIssue(strings.ToUpper(args[0]), &jira.GetQueryOptions{Fields: "project"})
+	if err != nil {
+		return p.responsef(header, "%v", issueActionError(err, "view the issue"))
+	}
+
+	sprint, err := resolveIssueSprint(client, issue, strings.Join(args[1:], " "))
+	if err != nil {
+		return p.responsef(header, "Failed to find the sprint. Error: %v.", err)
+	}
+
+	if err = client.MoveIssuesToSprint(sprint.ID, []string{issue.Key}); err != nil {
+		return p.responsef(header, "%v", issueActionError(err, "move the issue to the sprint"))
+	}
+	return p.responsef(header, "Moved [%s](%s/browse/%s) to sprint [%s](%s).",
+		issue.Key, instance.GetJiraBaseURL(), issue.Key, sprint.Name, sprintURL(instance, sprint))
+}
+
+func executeBacklog(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	user, instance, args, err := p.loadFlagUserInstance(header.UserId, args)
+	if err != nil {
+		return p.responsef(header, "Failed to load your connection to Jira. Error: %v.", err)
+	}
+	query, top, err := parseBacklogArgs(args)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+
+	client, _, _, err := p.getClient(instance.GetID(), user.MattermostUserID)
+	if err != nil {
+		return p.responsef(header, "Your username is not connected to Jira. Please type `jira connect`.")
+	}
+
+	board, err := resolveBoard(client, query)
+	if err != nil {
+		return p.responsef(header, "Failed to find the board. Error: %v.", err)
+	}
+
+	issues, err := client.GetBoardBacklog(board.ID, []string{"summary", "status", "priority", "assignee"}, top)
+	if err != nil {
+		return p.responsef(header, "Failed to get the backlog of board %q. Error: %v.", board.Name, err)
+	}
+	return p.responsef(header, "%s", formatBacklog(instance, board, issues))
+}
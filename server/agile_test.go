@@ -0,0 +1,181 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testBoards = []Board{
+	{ID: 1, Name: "Web", Type: "scrum"},
+	{ID: 2, Name: "Web support", Type: "kanban"},
+	{ID: 3, Name: "Mobile", Type: "scrum"},
+}
+
+var testSprints = map[int][]Sprint{
+	1: {
+		{ID: 11, Name: "Web 12", State: sprintStateActive},
+		{ID: 12, Name: "Web 13", State: sprintStateFuture},
+	},
+	3: {
+		{ID: 31, Name: "Mobile 4", State: sprintStateActive},
+	},
+}
+
+func (client testClient) GetBoards(projectKeyOrID, name string, maxResults int) ([]Board, error) {
+	found := []Board{}
+	for _, board := range testBoards {
+		if projectKeyOrID == "MOB" && board.ID != 3 {
+			continue
+		}
+		if strings.Contains(strings.ToLower(board.Name), strings.ToLower(name)) {
+			found = append(found, board)
+		}
+	}
+	return found, nil
+}
+
+func (client testClient) GetBoard(boardID int) (*Board, error) {
+	for _, board := range testBoards {
+		if board.ID == boardID {
+			return &board, nil
+		}
+	}
+	return nil, errors.Errorf("board %d not found", boardID)
+}
+
+func (client testClient) GetBoardSprints(boardID int, state string) ([]Sprint, error) {
+	sprints, ok := testSprints[boardID]
+	if !ok {
+		return nil, errors.New("the board does not support sprints")
+	}
+	return sprints, nil
+}
+
+func (client testClient) GetSprint(sprintID int) (*Sprint, error) {
+	return &Sprint{ID: sprintID, Name: "Sprint"}, nil
+}
+
+func TestResolveBoard(t *testing.T) {
+	for name, tc := range map[string]struct {
+		query        string
+		expectedID   int
+		errorMessage string
+	}{
+		"by ID":                {query: "3", expectedID: 3},
+		"unknown ID":           {query: "9", errorMessage: "board 9 not found"},
+		"exact name":           {query: "web", expectedID: 1},
+		"single partial match": {query: "mob", expectedID: 3},
+		"ambiguous": {
+			query:        "we",
+			errorMessage: `several boards match "we", please use one of their IDs: Web (1), Web support (2)`,
+		},
+		"no match": {query: "desktop", errorMessage: `no board matches "desktop"`},
+		"no query": {
+			errorMessage: "please specify one of the boards: Web (1), Web support (2), Mobile (3)",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			board, err := resolveBoard(testClient{}, tc.query)
+			if tc.errorMessage != "" {
+				require.EqualError(t, err, tc.errorMessage)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedID, board.ID)
+		})
+	}
+}
+
+func TestResolveIssueSprint(t *testing.T) {
+	mobileIssue := &jira.Issue{Key: "MOB-1", Fields: &jira.IssueFields{Project: jira.Project{Key: "MOB"}}}
+	webIssue := &jira.Issue{Key: "WEB-1", Fields: &jira.IssueFields{Project: jira.Project{Key: "WEB"}}}
+
+	for name, tc := range map[string]struct {
+		issue        *jira.Issue
+		query        string
+		expectedID   int
+		errorMessage string
+	}{
+		"by ID":                 {issue: webIssue, query: "42", expectedID: 42},
+		"active sprint":         {issue: mobileIssue, expectedID: 31},
+		"exact name":            {issue: webIssue, query: "web 13", expectedID: 12},
+		"several active sprint": {issue: webIssue, errorMessage: "several sprints match, please use one of their IDs: Web 12 (11), Mobile 4 (31)"},
+		"no match":              {issue: mobileIssue, query: "Web", errorMessage: `no active or future sprint of project MOB matches "Web"`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			sprint, err := resolveIssueSprint(testClient{}, tc.issue, tc.query)
+			if tc.errorMessage != "" {
+				require.EqualError(t, err, tc.errorMessage)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedID, sprint.ID)
+		})
+	}
+}
+
+func TestFormatSprintReport(t *testing.T) {
+	now := time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC)
+	sprint := &Sprint{
+		ID:        11,
+		Name:      "Web 12",
+		State:     sprintStateActive,
+		Goal:      "Ship the new login page",
+		StartDate: "2024-03-01T09:00:00.000Z",
+		EndDate:   "2024-03-15T17:00:00.000Z",
+	}
+	issueWithStatus := func(key string) jira.Issue {
+		return jira.Issue{Fields: &jira.IssueFields{Status: &jira.Status{StatusCategory: jira.StatusCategory{Key: key, Name: map[string]string{
+			"new": "To Do", "indeterminate": "In Progress", "done": "Done",
+		}[key]}}}}
+	}
+	issues := []jira.Issue{issueWithStatus("done"), issueWithStatus("new"), issueWithStatus("indeterminate"), issueWithStatus("done")}
+
+	text := formatSprintReport(testInstance1, &testBoards[0], sprint, issues, now)
+	assert.Contains(t, text, "Sprint [Web 12](https://jiraurl1.com/issues/?jql=sprint+%3D+11) of board [Web](https://jiraurl1.com/secure/RapidBoard.jspa?rapidView=1)")
+	assert.Contains(t, text, "**Goal:** Ship the new login page")
+	assert.Contains(t, text, "**Dates:** Mar 1 - Mar 15, 2024 (9 days left)")
+	assert.Contains(t, text, "|To Do|1|\n|In Progress|1|\n|Done|2|\n")
+	assert.Contains(t, text, "4 issues, 50% done.")
+
+	sprint.State = sprintStateFuture
+	sprint.StartDate = ""
+	assert.Contains(t, formatSprintReport(testInstance1, &testBoards[0], sprint, nil, now), "**Dates:** Not scheduled\n\nThe sprint has no issues.")
+}
+
+func TestParseBacklogArgs(t *testing.T) {
+	for name, tc := range map[string]struct {
+		args          []string
+		expectedBoard string
+		expectedTop   int
+		errorMessage  string
+	}{
+		"defaults":     {expectedTop: backlogDefaultSize},
+		"board":        {args: []string{"Web", "support"}, expectedBoard: "Web support", expectedTop: backlogDefaultSize},
+		"top":          {args: []string{"Web", "--top", "5"}, expectedBoard: "Web", expectedTop: 5},
+		"top equals":   {args: []string{"--top=20", "Web"}, expectedBoard: "Web", expectedTop: 20},
+		"top missing":  {args: []string{"Web", "--top"}, errorMessage: "--top requires a number of issues"},
+		"top too high": {args: []string{"--top", "500"}, errorMessage: "--top must be a number between 1 and 50"},
+		"invalid flag": {args: []string{"--topmost"}, errorMessage: "`--topmost` is not valid"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			board, top, err := parseBacklogArgs(tc.args)
+			if tc.errorMessage != "" {
+				require.EqualError(t, err, tc.errorMessage)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedBoard, board)
+			assert.Equal(t, tc.expectedTop, top)
+		})
+	}
+}
@@ -77,7 +77,14 @@ func (p *Plugin) ParseAuthToken(encoded string) (mattermostUserID, tokenSecret s
 
 		jsonBytes, err := decrypt(decoded, encryptSecret)
 		if err != nil {
-			return err
+			// Tokens issued before a key rotation remain valid until they expire
+			previousSecret := p.previousAuthTokenSecret()
+			if previousSecret == nil {
+				return err
+			}
+			if jsonBytes, err = decrypt(decoded, previousSecret); err != nil {
+				return err
+			}
 		}
 
 		err = json.Unmarshal(jsonBytes, &t)
@@ -15,6 +15,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	jira "github.com/andygrunwald/go-jira"
 	"github.com/pkg/errors"
@@ -22,6 +23,7 @@ import (
 	"github.com/mattermost/mattermost/server/public/plugin"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
 
+	"github.com/mattermost/mattermost-plugin-jira/server/utils"
 	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
 )
 
@@ -38,6 +40,7 @@ type Client interface {
 	ProjectService
 	SearchService
 	UserService
+	AgileService
 }
 
 // RESTService is the low-level interface for invoking the upstream service.
@@ -67,11 +70,26 @@ type ProjectService interface {
 // SearchService is the interface for search-related APIs.
 type SearchService interface {
 	SearchIssues(jql string, options *jira.SearchOptions) ([]jira.Issue, error)
+	SearchIssuesPage(jql, pageToken string, options *jira.SearchOptions) ([]jira.Issue, string, error)
 	SearchUsersAssignableToIssue(issueKey, query string, maxResults int) ([]jira.User, error)
 	SearchUsersAssignableInProject(projectKey, query string, maxResults int) ([]jira.User, error)
 	SearchAutoCompleteFields(params map[string]string) (*AutoCompleteResult, error)
 	GetWatchers(instanceID, issueKey string, connection *Connection) (*jira.Watches, error)
 	GetUserVisibilityGroups(params map[string]string) (*CommentVisibilityResult, error)
+	GetFavouriteFilters() ([]*jira.Filter, error)
+	GetFilter(filterID string) (*jira.Filter, error)
+	SearchFilters(name string, maxResults int) ([]*jira.Filter, error)
+}
+
+// AgileService is the interface for the board and sprint APIs of Jira Software.
+type AgileService interface {
+	GetBoards(projectKeyOrID, name string, maxResults int) ([]Board, error)
+	GetBoard(boardID int) (*Board, error)
+	GetBoardSprints(boardID int, state string) ([]Sprint, error)
+	GetBoardBacklog(boardID int, fields []string, maxResults int) ([]jira.Issue, error)
+	GetSprint(sprintID int) (*Sprint, error)
+	GetSprintIssues(sprintID int, fields []string) ([]jira.Issue, error)
+	MoveIssuesToSprint(sprintID int, issueKeys []string) error
 }
 
 // IssueService is the interface for issue-related APIs.
@@ -80,12 +98,20 @@ type IssueService interface {
 	CreateIssue(issue *jira.Issue) (*jira.Issue, error)
 
 	AddAttachment(mmClient pluginapi.Client, issueKey, fileID string, maxSize types.ByteSize) (mattermostName, jiraName, mime string, err error)
+	GetAttachment(attachmentID string) (*jira.Attachment, error)
+	DownloadAttachment(attachment *jira.Attachment, maxSize types.ByteSize) ([]byte, error)
 	AddComment(issueKey string, comment *jira.Comment) (*jira.Comment, error)
 	DoTransition(issueKey, transitionID string) error
 	GetCreateMetaInfo(api plugin.API, options *jira.GetQueryOptions) (*jira.CreateMetaInfo, error)
 	GetTransitions(issueKey string) ([]jira.Transition, error)
 	UpdateAssignee(issueKey string, user *jira.User) error
+	AddWatcher(issueKey string, user *jira.User) error
 	UpdateComment(issueKey string, comment *jira.Comment) (*jira.Comment, error)
+	GetIssueLinkTypes() ([]jira.IssueLinkType, error)
+	LinkIssues(link *jira.IssueLink) error
+	AddRemoteLink(issueKey string, link *jira.RemoteLink) error
+	GetTransitionFields(issueKey, transitionID string) (map[string]TransitionFieldMeta, error)
+	DoTransitionWithFields(issueKey, transitionID string, fields map[string]interface{}, comment string) error
 }
 
 // JiraClient is the common implementation of most Jira APIs, except those that are
@@ -256,6 +282,81 @@ func (client JiraClient) GetWatchers(instanceID, issueKey string, connection *Co
 	return &watchers, nil
 }
 
+// TransitionFieldMeta describes a field of the screen of a transition.
+type TransitionFieldMeta struct {
+	Required        bool   `json:"required"`
+	Name            string `json:"name"`
+	HasDefaultValue bool   `json:"hasDefaultValue"`
+	Schema          struct {
+		Type   string `json:"type"`
+		Items  string `json:"items"`
+		System string `json:"system"`
+		Custom string `json:"custom"`
+	} `json:"schema"`
+	AllowedValues []struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"allowedValues"`
+}
+
+// GetTransitionFields returns the fields of the screen of a transition, with their schema and
+// allowed values.
+func (client JiraClient) GetTransitionFields(issueKey, transitionID string) (map[string]TransitionFieldMeta, error) {
+	result := struct {
+		Transitions []struct {
+			ID     string                         `json:"id"`
+			Fields map[string]TransitionFieldMeta `json:"fields"`
+		} `json:"transitions"`
+	}{}
+	params := map[string]string{
+		"transitionId": transitionID,
+		"expand":       "transitions.fields",
+	}
+	if err := client.RESTGet(fmt.Sprintf("2/issue/%s/transitions", issueKey), params, &result); err != nil {
+		return nil, err
+	}
+	for _, transition := range result.Transitions {
+		if transition.ID == transitionID {
+			return transition.Fields, nil
+		}
+	}
+	return nil, errors.Errorf("transition %s is not available for issue %s", transitionID, issueKey)
+}
+
+// DoTransitionWithFields executes a transition on an issue, setting the fields of the
+// transition screen and adding a comment.
+func (client JiraClient) DoTransitionWithFields(issueKey, transitionID string, fields map[string]interface{}, comment string) error {
+	payload := map[string]interface{}{
+		"transition": jira.TransitionPayload{ID: transitionID},
+	}
+	if len(fields) > 0 {
+		payload["fields"] = fields
+	}
+	if comment != "" {
+		payload["update"] = jira.TransitionPayloadUpdate{
+			Comment: []jira.TransitionPayloadComment{{Add: jira.TransitionPayloadCommentBody{Body: comment}}},
+		}
+	}
+
+	resp, err := client.Jira.Issue.DoTransitionWithPayload(issueKey, payload)
+	if err != nil {
+		return userFriendlyJiraError(resp, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// AddRemoteLink adds a remote link to an issue. Jira updates the link with the same global ID
+// instead of adding another one.
+func (client JiraClient) AddRemoteLink(issueKey string, link *jira.RemoteLink) error {
+	_, resp, err := client.Jira.Issue.AddRemoteLink(issueKey, link)
+	if err != nil {
+		return userFriendlyJiraError(resp, err)
+	}
+	return nil
+}
+
 // GetTransitions returns transitions for an issue with issueKey.
 func (client JiraClient) GetTransitions(issueKey string) ([]jira.Transition, error) {
 	transitions, resp, err := client.Jira.Issue.GetTransitions(issueKey)
@@ -283,6 +384,41 @@ func (client JiraClient) UpdateAssignee(issueKey string, user *jira.User) error
 	return err
 }
 
+// AddWatcher adds a user to the watchers of an issue. Cloud instances identify the user by
+// account ID, server instances by username.
+func (client JiraClient) AddWatcher(issueKey string, user *jira.User) error {
+	watcher := user.AccountID
+	if watcher == "" {
+		watcher = user.Name
+	}
+	resp, err := client.Jira.Issue.AddWatcher(issueKey, watcher)
+	if err != nil {
+		return userFriendlyJiraError(resp, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// GetIssueLinkTypes returns the types of links between issues configured in Jira.
+func (client JiraClient) GetIssueLinkTypes() ([]jira.IssueLinkType, error) {
+	result := struct {
+		IssueLinkTypes []jira.IssueLinkType `json:"issueLinkTypes"`
+	}{}
+	if err := client.RESTGet("2/issueLinkType", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.IssueLinkTypes, nil
+}
+
+// LinkIssues creates a link between two issues.
+func (client JiraClient) LinkIssues(link *jira.IssueLink) error {
+	resp, err := client.Jira.Issue.AddLink(link)
+	if err != nil {
+		return userFriendlyJiraError(resp, err)
+	}
+	return nil
+}
+
 // AddComment adds a comment to an issue.
 func (client JiraClient) AddComment(issueKey string, comment *jira.Comment) (*jira.Comment, error) {
 	added, resp, err := client.Jira.Issue.AddComment(issueKey, comment)
@@ -313,6 +449,234 @@ func (client JiraClient) SearchIssues(jql string, options *jira.SearchOptions) (
 	return found, nil
 }
 
+// SearchIssuesPage returns the page of issues matching the jql that starts at pageToken, and the
+// token of the next page, empty on the last page. The first page has an empty token. On Jira
+// Server the token is the index of the first issue of the page.
+func (client JiraClient) SearchIssuesPage(jql, pageToken string, options *jira.SearchOptions) ([]jira.Issue, string, error) {
+	start := 0
+	if pageToken != "" {
+		var err error
+		start, err = strconv.Atoi(pageToken)
+		if err != nil || start < 0 {
+			return nil, "", errors.Errorf("invalid page token %q", pageToken)
+		}
+	}
+
+	pageOptions := jira.SearchOptions{}
+	if options != nil {
+		pageOptions = *options
+	}
+	pageOptions.StartAt = start
+
+	found, resp, err := client.Jira.Issue.Search(jql, &pageOptions)
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusUnauthorized) {
+			return nil, "", errors.New("not authorized to search issues")
+		}
+		return nil, "", userFriendlyJiraError(resp, err)
+	}
+
+	nextPageToken := ""
+	if len(found) > 0 && start+len(found) < resp.Total {
+		nextPageToken = strconv.Itoa(start + len(found))
+	}
+	return found, nextPageToken, nil
+}
+
+// GetFavouriteFilters returns the saved filters the user marked as favourite.
+func (client JiraClient) GetFavouriteFilters() ([]*jira.Filter, error) {
+	filters := []*jira.Filter{}
+	if err := client.RESTGet("2/filter/favourite", nil, &filters); err != nil {
+		return nil, err
+	}
+	return filters, nil
+}
+
+// GetFilter returns a saved filter, including its JQL.
+func (client JiraClient) GetFilter(filterID string) (*jira.Filter, error) {
+	filter := &jira.Filter{}
+	if err := client.RESTGet("2/filter/"+url.PathEscape(filterID), nil, filter); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// SearchFilters returns the favourite filters with a name containing the query. Jira Server
+// has no API to search all the filters visible to the user.
+func (client JiraClient) SearchFilters(name string, maxResults int) ([]*jira.Filter, error) {
+	filters, err := client.GetFavouriteFilters()
+	if err != nil {
+		return nil, err
+	}
+
+	found := []*jira.Filter{}
+	for _, filter := range filters {
+		if len(found) == maxResults {
+			break
+		}
+		if strings.Contains(strings.ToLower(filter.Name), strings.ToLower(name)) {
+			found = append(found, filter)
+		}
+	}
+	return found, nil
+}
+
+type Sprint struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	State         string `json:"state"`
+	Goal          string `json:"goal,omitempty"`
+	StartDate     string `json:"startDate,omitempty"`
+	EndDate       string `json:"endDate,omitempty"`
+	CompleteDate  string `json:"completeDate,omitempty"`
+	OriginBoardID int    `json:"originBoardId,omitempty"`
+}
+
+type SprintSearchResult struct {
+	MaxResults int      `json:"maxResults"`
+	StartAt    int      `json:"startAt"`
+	IsLast     bool     `json:"isLast"`
+	Values     []Sprint `json:"values"`
+}
+
+type Board struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+}
+
+type BoardSearchResult struct {
+	MaxResults int     `json:"maxResults"`
+	StartAt    int     `json:"startAt"`
+	IsLast     bool    `json:"isLast"`
+	Values     []Board `json:"values"`
+}
+
+type AgileIssueSearchResult struct {
+	MaxResults int          `json:"maxResults"`
+	StartAt    int          `json:"startAt"`
+	Total      int          `json:"total"`
+	Issues     []jira.Issue `json:"issues"`
+}
+
+// GetBoards returns the boards of a project, or of all the projects if projectKeyOrID is empty,
+// with a name containing name. All the pages are returned when maxResults is 0.
+func (client JiraClient) GetBoards(projectKeyOrID, name string, maxResults int) ([]Board, error) {
+	boards := []Board{}
+	for {
+		params := map[string]string{
+			"startAt": strconv.Itoa(len(boards)),
+		}
+		if projectKeyOrID != "" {
+			params["projectKeyOrId"] = projectKeyOrID
+		}
+		if name != "" {
+			params["name"] = name
+		}
+		if maxResults > 0 {
+			params["maxResults"] = strconv.Itoa(maxResults - len(boards))
+		}
+
+		var result BoardSearchResult
+		if err := client.RESTGetRaw("rest/agile/1.0/board", params, &result); err != nil {
+			return nil, err
+		}
+		boards = append(boards, result.Values...)
+		if result.IsLast || len(result.Values) == 0 || (maxResults > 0 && len(boards) >= maxResults) {
+			return boards, nil
+		}
+	}
+}
+
+// GetBoard returns a board by ID.
+func (client JiraClient) GetBoard(boardID int) (*Board, error) {
+	board := &Board{}
+	if err := client.RESTGetRaw(fmt.Sprintf("rest/agile/1.0/board/%d", boardID), nil, board); err != nil {
+		return nil, err
+	}
+	return board, nil
+}
+
+// GetBoardSprints returns the sprints of a board in the comma-separated states, or in all
+// the states if state is empty.
+func (client JiraClient) GetBoardSprints(boardID int, state string) ([]Sprint, error) {
+	sprints := []Sprint{}
+	for {
+		params := map[string]string{
+			"startAt": strconv.Itoa(len(sprints)),
+		}
+		if state != "" {
+			params["state"] = state
+		}
+
+		var result SprintSearchResult
+		if err := client.RESTGetRaw(fmt.Sprintf("rest/agile/1.0/board/%d/sprint", boardID), params, &result); err != nil {
+			return nil, err
+		}
+		sprints = append(sprints, result.Values...)
+		if result.IsLast || len(result.Values) == 0 {
+			return sprints, nil
+		}
+	}
+}
+
+// GetBoardBacklog returns the first issues of the backlog of a board, in their rank order.
+func (client JiraClient) GetBoardBacklog(boardID int, fields []string, maxResults int) ([]jira.Issue, error) {
+	var result AgileIssueSearchResult
+	err := client.RESTGetRaw(fmt.Sprintf("rest/agile/1.0/board/%d/backlog", boardID), map[string]string{
+		"fields":     strings.Join(fields, ","),
+		"maxResults": strconv.Itoa(maxResults),
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result.Issues, nil
+}
+
+// GetSprint returns a sprint by ID.
+func (client JiraClient) GetSprint(sprintID int) (*Sprint, error) {
+	sprint := &Sprint{}
+	if err := client.RESTGetRaw(fmt.Sprintf("rest/agile/1.0/sprint/%d", sprintID), nil, sprint); err != nil {
+		return nil, err
+	}
+	return sprint, nil
+}
+
+// GetSprintIssues returns all the issues of a sprint.
+func (client JiraClient) GetSprintIssues(sprintID int, fields []string) ([]jira.Issue, error) {
+	issues := []jira.Issue{}
+	for {
+		var result AgileIssueSearchResult
+		err := client.RESTGetRaw(fmt.Sprintf("rest/agile/1.0/sprint/%d/issue", sprintID), map[string]string{
+			"fields":  strings.Join(fields, ","),
+			"startAt": strconv.Itoa(len(issues)),
+		}, &result)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, result.Issues...)
+		if len(result.Issues) == 0 || len(issues) >= result.Total {
+			return issues, nil
+		}
+	}
+}
+
+// MoveIssuesToSprint moves issues to a sprint. Jira accepts up to 50 issues at once.
+func (client JiraClient) MoveIssuesToSprint(sprintID int, issueKeys []string) error {
+	req, err := client.Jira.NewRequest(http.MethodPost, fmt.Sprintf("rest/agile/1.0/sprint/%d/issue", sprintID), map[string][]string{
+		"issues": issueKeys,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := client.Jira.Do(req, nil)
+	if err != nil {
+		return userFriendlyJiraError(resp, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
 type Result struct {
 	Value       string `json:"value"`
 	DisplayName string `json:"displayName"`
@@ -390,6 +754,47 @@ func (client JiraClient) AddAttachment(mmClient pluginapi.Client, issueKey, file
 	return fileinfo.Name, attachment.Filename, fileinfo.MimeType, nil
 }
 
+// GetAttachment returns the metadata of an issue attachment.
+func (client JiraClient) GetAttachment(attachmentID string) (*jira.Attachment, error) {
+	attachment := &jira.Attachment{}
+	if err := client.RESTGet("2/attachment/"+url.PathEscape(attachmentID), nil, attachment); err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// DownloadAttachment returns the content of an issue attachment. Attachments larger than
+// maxSize are not downloaded.
+func (client JiraClient) DownloadAttachment(attachment *jira.Attachment, maxSize types.ByteSize) ([]byte, error) {
+	return client.getAttachmentContent(
+		fmt.Sprintf("secure/attachment/%s/%s", url.PathEscape(attachment.ID), url.PathEscape(attachment.Filename)), attachment, maxSize)
+}
+
+func (client JiraClient) getAttachmentContent(contentPath string, attachment *jira.Attachment, maxSize types.ByteSize) ([]byte, error) {
+	if types.ByteSize(attachment.Size) > maxSize {
+		return nil, errors.Errorf("Maximum attachment size %v exceeded, file size %v", maxSize, types.ByteSize(attachment.Size))
+	}
+
+	req, err := client.Jira.NewRequest(http.MethodGet, contentPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Jira.Do(req, nil)
+	if err != nil {
+		return nil, userFriendlyJiraError(resp, err)
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxSize)+1))
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read the attachment")
+	}
+	if types.ByteSize(len(content)) > maxSize {
+		return nil, errors.Errorf("Maximum attachment size %v exceeded", maxSize)
+	}
+	return content, nil
+}
+
 // GetSelf returns a user associated with this Jira client
 func (client JiraClient) GetSelf() (*jira.User, error) {
 	self, resp, err := client.Jira.User.GetSelf()
@@ -414,7 +819,7 @@ func MakeCreateIssueURL(instance Instance, project *jira.Project, issue *jira.Is
 	q.Add("description", issue.Fields.Description)
 
 	// Add reporter for only server instances
-	if instance.Common().Type == ServerInstanceType && issue.Fields.Reporter != nil {
+	if instance.Common().IsServerInstance() && issue.Fields.Reporter != nil {
 		q.Add("reporter", issue.Fields.Reporter.Name)
 	}
 
@@ -583,6 +988,18 @@ func StatusCode(err error) int {
 }
 
 func userFriendlyJiraError(resp *jira.Response, err error) error {
+	// The idempotent requests were already retried, tell the user when to try again
+	if resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+		message := "Jira is busy, please try again later"
+		if resp.StatusCode == http.StatusTooManyRequests {
+			message = "Jira is limiting the rate of requests, please try again later"
+		}
+		if retryAfter, ok := utils.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok && retryAfter >= time.Second {
+			message = strings.TrimSuffix(message, " later") + fmt.Sprintf(" in %v", retryAfter.Round(time.Second))
+		}
+		return RESTError{errors.New(message), resp.StatusCode}
+	}
+
 	jerr, ok := err.(*jira.Error)
 	if !ok {
 		if resp == nil {
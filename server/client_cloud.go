@@ -6,12 +6,15 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strconv"
 	"strings"
 
 	jira "github.com/andygrunwald/go-jira"
 	"github.com/mattermost/mattermost/server/public/plugin"
 	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
 )
 
 type jiraCloudClient struct {
@@ -177,3 +180,64 @@ func (client jiraCloudClient) SearchIssues(jql string, options *jira.SearchOptio
 	}
 	return result.Issues, nil
 }
+
+// SearchIssuesPage overrides the base JiraClient implementation to use the
+// /rest/api/2/search/jql endpoint, which pages with a token and doesn't report a total.
+func (client jiraCloudClient) SearchIssuesPage(jql, pageToken string, options *jira.SearchOptions) ([]jira.Issue, string, error) {
+	type searchResult struct {
+		Issues        []jira.Issue `json:"issues"`
+		NextPageToken string       `json:"nextPageToken"`
+		IsLast        bool         `json:"isLast"`
+	}
+
+	params := map[string]string{
+		"jql": jql,
+	}
+	if pageToken != "" {
+		params["nextPageToken"] = pageToken
+	}
+	if options != nil {
+		if options.MaxResults > 0 {
+			params["maxResults"] = strconv.Itoa(options.MaxResults)
+		}
+		if len(options.Fields) > 0 {
+			params["fields"] = strings.Join(options.Fields, ",")
+		}
+	}
+
+	var result searchResult
+	if err := client.RESTGet("2/search/jql", params, &result); err != nil {
+		return nil, "", err
+	}
+	if result.IsLast {
+		return result.Issues, "", nil
+	}
+	return result.Issues, result.NextPageToken, nil
+}
+
+// SearchFilters returns the filters visible to the user with a name containing the query.
+func (client jiraCloudClient) SearchFilters(name string, maxResults int) ([]*jira.Filter, error) {
+	type searchResult struct {
+		Values []*jira.Filter `json:"values"`
+	}
+
+	params := map[string]string{
+		"filterName": name,
+		"expand":     "jql,favourite,owner,viewUrl",
+	}
+	if maxResults > 0 {
+		params["maxResults"] = strconv.Itoa(maxResults)
+	}
+
+	var result searchResult
+	if err := client.RESTGet("3/filter/search", params, &result); err != nil {
+		return nil, err
+	}
+	return result.Values, nil
+}
+
+// DownloadAttachment uses the attachment content endpoint, since the legacy attachment URLs are
+// not served through the Atlassian API gateway.
+func (client jiraCloudClient) DownloadAttachment(attachment *jira.Attachment, maxSize types.ByteSize) ([]byte, error) {
+	return client.getAttachmentContent("rest/api/2/attachment/content/"+url.PathEscape(attachment.ID), attachment, maxSize)
+}
@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"testing"
 
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 )
 
@@ -41,3 +43,20 @@ func TestEndpointNameFromRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestUserFriendlyJiraErrorRateLimited(t *testing.T) {
+	resp := &jira.Response{Response: &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"42"}},
+	}}
+
+	err := userFriendlyJiraError(resp, errors.New("request failed"))
+	require.Equal(t, http.StatusTooManyRequests, StatusCode(err))
+	require.Equal(t, "Jira is limiting the rate of requests, please try again in 42s", err.Error())
+
+	resp.StatusCode = http.StatusServiceUnavailable
+	resp.Header = http.Header{}
+	err = userFriendlyJiraError(resp, errors.New("request failed"))
+	require.Equal(t, http.StatusServiceUnavailable, StatusCode(err))
+	require.Equal(t, "Jira is busy, please try again later", err.Error())
+}
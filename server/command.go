@@ -27,40 +27,68 @@ const commandTrigger = "jira"
 
 var jiraCommandHandler = CommandHandler{
 	handlers: map[string]CommandHandlerFunc{
-		"assign":                       executeAssign,
-		"connect":                      executeConnect,
-		"disconnect":                   executeDisconnect,
-		"help":                         executeHelp,
-		"me":                           executeMe,
-		"about":                        executeAbout,
-		"install/cloud":                executeInstanceInstallCloud,
-		"install/cloud-oauth":          executeInstanceInstallCloudOAuth,
-		"install/server":               executeInstanceInstallServer,
-		"instance/alias":               executeInstanceAlias,
-		"instance/unalias":             executeInstanceUnalias,
-		"instance/connect":             executeConnect,
-		"instance/disconnect":          executeDisconnect,
-		"instance/install/cloud":       executeInstanceInstallCloud,
-		"instance/install/cloud-oauth": executeInstanceInstallCloudOAuth,
-		"instance/install/server":      executeInstanceInstallServer,
-		"instance/list":                executeInstanceList,
-		"instance/settings":            executeSettings,
-		"instance/uninstall":           executeInstanceUninstall,
-		"instance/v2":                  executeInstanceV2Legacy,
-		"instance/default":             executeDefaultInstance,
-		"issue/assign":                 executeAssign,
-		"issue/transition":             executeTransition,
-		"issue/unassign":               executeUnassign,
-		"issue/view":                   executeView,
-		"settings":                     executeSettings,
-		"subscribe/list":               executeSubscribeList,
-		"transition":                   executeTransition,
-		"unassign":                     executeUnassign,
-		"uninstall":                    executeInstanceUninstall,
-		"view":                         executeView,
-		"v2revert":                     executeV2Revert,
-		"webhook":                      executeWebhookURL,
-		"setup":                        executeSetup,
+		"assign":                        executeAssign,
+		"backlog":                       executeBacklog,
+		"connect":                       executeConnect,
+		"disconnect":                    executeDisconnect,
+		"help":                          executeHelp,
+		"me":                            executeMe,
+		"about":                         executeAbout,
+		"install/cloud":                 executeInstanceInstallCloud,
+		"install/cloud-oauth":           executeInstanceInstallCloudOAuth,
+		"install/server":                executeInstanceInstallServer,
+		"install/server-oauth":          executeInstanceInstallServerOAuth,
+		"instance/alias":                executeInstanceAlias,
+		"instance/auth":                 executeInstanceAuth,
+		"instance/http":                 executeInstanceHTTP,
+		"instance/cache/flush":          executeInstanceCacheFlush,
+		"instance/unalias":              executeInstanceUnalias,
+		"instance/connect":              executeConnect,
+		"instance/disconnect":           executeDisconnect,
+		"instance/install/cloud":        executeInstanceInstallCloud,
+		"instance/install/cloud-oauth":  executeInstanceInstallCloudOAuth,
+		"instance/install/server":       executeInstanceInstallServer,
+		"instance/install/server-oauth": executeInstanceInstallServerOAuth,
+		"instance/list":                 executeInstanceList,
+		"instance/settings":             executeSettings,
+		"instance/status":               executeInstanceStatus,
+		"instance/connections":          executeInstanceConnections,
+		"instance/force-disconnect":     executeInstanceForceDisconnect,
+		"instance/cleanup/status":       executeInstanceCleanupStatus,
+		"instance/cleanup/run":          executeInstanceCleanupRun,
+		"keys/rotate":                   executeKeysRotate,
+		"keys/status":                   executeKeysStatus,
+		"keys/rsa/start":                executeKeysRSAStart,
+		"keys/rsa/complete":             executeKeysRSAComplete,
+		"instance/uninstall":            executeInstanceUninstall,
+		"instance/v2":                   executeInstanceV2Legacy,
+		"instance/default":              executeDefaultInstance,
+		"issue/assign":                  executeAssign,
+		"issue/transition":              executeTransition,
+		"issue/unassign":                executeUnassign,
+		"issue/view":                    executeView,
+		"issue/search":                  executeSearch,
+		"issue/link":                    executeIssueLink,
+		"issue/subtask":                 executeIssueSubtask,
+		"issue/clone":                   executeIssueClone,
+		"search":                        executeSearch,
+		"filter/list":                   executeFilterList,
+		"filter/run":                    executeFilterRun,
+		"filter/subscribe":              executeFilterSubscribe,
+		"settings":                      executeSettings,
+		"sprint":                        executeSprint,
+		"sprint/add":                    executeSprintAdd,
+		"subscribe/list":                executeSubscribeList,
+		"transition":                    executeTransition,
+		"unassign":                      executeUnassign,
+		"uninstall":                     executeInstanceUninstall,
+		"view":                          executeView,
+		"v2revert":                      executeV2Revert,
+		"webhook":                       executeWebhookURL,
+		"webhook/events":                executeWebhookEvents,
+		"webhook/replay":                executeWebhookReplay,
+		"webhook/register":              executeWebhookRegister,
+		"setup":                         executeSetup,
 	},
 	defaultHandler: executeJiraDefault,
 }
@@ -73,8 +101,17 @@ const commonHelpText = "\n" +
 	"* `/jira [issue] assign [issue-key] [assignee]` - Change the assignee of a Jira issue\n" +
 	"* `/jira [issue] create [text]` - Create a new Issue with 'text' inserted into the description field\n" +
 	"* `/jira [issue] transition [issue-key] [state]` - Change the state of a Jira issue\n" +
+	"* `/jira issue link [issue-key] [link-type] [issue-key]` - Link a Jira issue to another one, e.g. `/jira issue link KEY-1 blocks KEY-2`\n" +
+	"* `/jira issue subtask [parent-key] \"[summary]\"` - Create a sub-task of a Jira issue\n" +
+	"* `/jira issue clone [issue-key]` - Create a copy of a Jira issue\n" +
 	"* `/jira [issue] unassign [issue-key]` - Unassign the Jira issue\n" +
 	"* `/jira [issue] view [issue-key]` - View the details of a specific Jira issue\n" +
+	"* `/jira [issue] search \"[JQL or text]\"` - Search the Jira issues matching a JQL query or a text\n" +
+	"* `/jira filter list` - List your favourite Jira filters\n" +
+	"* `/jira filter run [name|id]` - Search the Jira issues matching a saved filter\n" +
+	"* `/jira sprint [board]` - Display the goal, dates and progress of the active sprint of a Jira board\n" +
+	"* `/jira sprint add [issue-key] [sprint]` - Move a Jira issue to a sprint, by default the active sprint of its project\n" +
+	"* `/jira backlog [board] [--top number]` - List the first issues of the backlog of a Jira board\n" +
 	"* `/jira help` - Launch the Jira plugin command line help syntax\n" +
 	"* `/jira me` - Display information about the current user\n" +
 	"* `/jira about` - Display build info\n" +
@@ -83,6 +120,7 @@ const commonHelpText = "\n" +
 	"  * [setting] can be `notifications`\n" +
 	"  * [role] can be `assignee` , `mention` , `reporter` or `watching`\n" +
 	"  * [value] can be `on` or `off`\n" +
+	"* `/jira instance settings subscriptions [list|add|remove]` - Manage your personal subscriptions, delivered by DM\n" +
 	""
 
 const sysAdminHelpText = "\n###### For System Administrators:\n" +
@@ -90,20 +128,38 @@ const sysAdminHelpText = "\n###### For System Administrators:\n" +
 	"* `/jira setup` - Start Jira plugin setup flow\n" +
 	"* `/jira webhook [jiraURL]` - Display the webhook URLs to setup on Jira\n" +
 	"Install Jira instances:\n" +
-	"* `/jira instance install server [jiraURL]` - Connect Mattermost to a Jira Server or Data Center instance located at <jiraURL>\n" +
+	"* `/jira instance install server [jiraURL] [oauth1|pat]` - Connect Mattermost to a Jira Server or Data Center instance located at <jiraURL>, users connect with an Application Link (default) or a Personal Access Token\n" +
+	"* `/jira instance install server-oauth [jiraURL]` - Connect Mattermost to a Jira Data Center instance using OAuth 2.0 located at <jiraURL>, existing server connections keep working\n" +
 	"* `/jira instance install cloud-oauth [jiraURL]` - Connect Mattermost to a Jira Cloud instance using OAuth 2.0 located at <jiraURL>\n" +
 	"Uninstall Jira instances:\n" +
 	"* `/jira instance uninstall server [jiraURL]` - Disconnect Mattermost from a Jira Server or Data Center instance located at <jiraURL>\n" +
+	"* `/jira instance uninstall server-oauth [jiraURL]` - Disconnect Mattermost from a Jira Data Center instance using OAuth 2.0 located at <jiraURL>\n" +
 	"* `/jira instance uninstall cloud-oauth [jiraURL]` - Disconnect Mattermost from a Jira Cloud instance using OAuth 2.0 located at <jiraURL>\n" +
 	"Manage channel subscriptions:\n" +
 	"* `/jira subscribe ` - Configure the Jira notifications sent to this channel\n" +
 	"* `/jira subscribe list` - Display all the the subscription rules setup across all the channels and teams on your Mattermost instance\n" +
+	"* `/jira filter subscribe [name|id]` - Send the notifications of the issues matching a saved filter to this channel, following the edits of the filter in Jira\n" +
 	"Other:\n" +
 	"* `/jira instance alias [URL] [alias-name]` - assign an alias to an instance\n" +
+	"* `/jira instance auth [jiraURL] [oauth1|pat]` - Choose how users connect to a Jira Server or Data Center instance, existing connections keep working\n" +
+	"* `/jira instance http [jiraURL] [proxy|ca|cert|key|timeout|retries|concurrency|qps] [value]` - Show or change the proxy, CA certificates, client certificate, timeout in seconds, retries, concurrent requests and requests per second to a Jira instance, an empty value resets the setting. Settings of a Jira URL not installed yet are used to install it\n" +
+	"* `/jira instance cache flush [jiraURL]` - Flush the cached projects, fields and transitions of a Jira instance, or of all the instances\n" +
 	"* `/jira instance unalias [alias-name]` - remve an alias from an instance\n" +
 	"* `/jira instance v2 <jiraURL>` - Set the Jira instance to process \"v2\" webhooks and subscriptions (not prefixed with the instance ID)\n" +
 	"* `/jira instance default <jiraURL>` - Set a default instance in case of multiple Jira instances\n" +
+	"* `/jira instance status [jiraURL]` - Show the health of the Jira webhook for an instance\n" +
+	"* `/jira instance connections [jiraURL]` - List the connected users with their connection type, last use and token status\n" +
+	"* `/jira instance force-disconnect [jiraURL] [@username]` - Disconnect a user from a Jira instance\n" +
+	"* `/jira instance cleanup status` - Show the connections, subscriptions and issue threads removed by the last daily cleanup\n" +
+	"* `/jira instance cleanup run` - Remove the data left behind by deactivated users, uninstalled instances and deleted channels now\n" +
 	"* `/jira webhook [--instance=<jiraURL>]` -  Show the Mattermost webhook to receive JQL queries\n" +
+	"* `/jira webhook events [event-id] [--instance=<jiraURL>]` - Browse the most recent webhook events received from Jira\n" +
+	"* `/jira webhook replay <event-id> [--instance=<jiraURL>]` - Process a recent webhook event again\n" +
+	"* `/jira webhook register [--instance=<jiraURL>]` - Create or update the subscriptions webhook in Jira using the Admin API Token\n" +
+	"* `/jira keys rotate` - Replace the encryption key and re-encrypt the stored tokens in the background\n" +
+	"* `/jira keys status` - Show the progress of the last encryption key rotation\n" +
+	"* `/jira keys rsa start` - Generate a new RSA key for the Jira Server Application Links, and show the current and new public keys\n" +
+	"* `/jira keys rsa complete` - Sign the requests to Jira Server with the new RSA key, once the Application Links are updated\n" +
 	"* `/jira v2revert ` - Revert to V2 jira plugin data model\n" +
 	""
 
@@ -152,6 +208,10 @@ func (p *Plugin) createJiraCommand(enableAutocomplete, enableOptInstance bool) (
 func addSubCommands(jira *model.AutocompleteData, optInstance bool) {
 	// Top-level common commands
 	jira.AddCommand(createViewCommand(optInstance))
+	jira.AddCommand(createSearchCommand(optInstance))
+	jira.AddCommand(createFilterCommand(optInstance))
+	jira.AddCommand(createSprintCommand(optInstance))
+	jira.AddCommand(createBacklogCommand(optInstance))
 	jira.AddCommand(createTransitionCommand(optInstance))
 	jira.AddCommand(createAssignCommand(optInstance))
 	jira.AddCommand(createUnassignCommand(optInstance))
@@ -166,6 +226,7 @@ func addSubCommands(jira *model.AutocompleteData, optInstance bool) {
 	// Admin commands
 	jira.AddCommand(createSubscribeCommand(optInstance))
 	jira.AddCommand(createWebhookCommand(optInstance))
+	jira.AddCommand(createKeysCommand())
 	jira.AddCommand(createSetupCommand())
 
 	// Help and info
@@ -186,28 +247,102 @@ func createInstanceCommand(optInstance bool) *model.AutocompleteData {
 
 	jiraTypes := []model.AutocompleteListItem{
 		{HelpText: "Jira Server or Datacenter", Item: "server"},
+		{HelpText: "Jira Datacenter OAuth 2.0", Item: "server-oauth"},
 		{HelpText: "Jira Cloud OAuth 2.0 (atlassian.net)", Item: "cloud-oauth"},
 	}
 
 	install := model.NewAutocompleteData(
-		"install", "[server|cloud-oauth] [URL]", "Connect Mattermost to a Jira instance")
-	install.AddStaticListArgument("Jira type: server, cloud or cloud-oauth", true, jiraTypes)
+		"install", "[server|server-oauth|cloud-oauth] [URL]", "Connect Mattermost to a Jira instance")
+	install.AddStaticListArgument("Jira type: server, server-oauth, cloud or cloud-oauth", true, jiraTypes)
 	install.AddTextArgument("Jira URL", "Enter the Jira URL, e.g. https://mattermost.atlassian.net", "")
 	install.RoleID = model.SystemAdminRoleId
 
 	uninstall := model.NewAutocompleteData(
-		"uninstall", "[server|cloud-oauth] [URL]", "Disconnect Mattermost from a Jira instance")
-	uninstall.AddStaticListArgument("Jira type: server, cloud or cloud-oauth", true, jiraTypes)
+		"uninstall", "[server|server-oauth|cloud-oauth] [URL]", "Disconnect Mattermost from a Jira instance")
+	uninstall.AddStaticListArgument("Jira type: server, server-oauth, cloud or cloud-oauth", true, jiraTypes)
 	uninstall.AddDynamicListArgument("Jira instance", makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias), true)
 	uninstall.RoleID = model.SystemAdminRoleId
 
+	authModes := []model.AutocompleteListItem{
+		{HelpText: "Application Link (OAuth 1.0a)", Item: ServerAuthModeOAuth1},
+		{HelpText: "Personal Access Token", Item: ServerAuthModePAT},
+	}
+
+	auth := model.NewAutocompleteData(
+		"auth", "[URL] [oauth1|pat]", "Choose how users connect to a Jira Server or Data Center instance")
+	auth.AddDynamicListArgument("Jira instance", makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias), true)
+	auth.AddStaticListArgument("Authentication mode", false, authModes)
+	auth.RoleID = model.SystemAdminRoleId
+
+	httpSettings := []model.AutocompleteListItem{
+		{HelpText: "Proxy URL", Item: httpSettingProxy},
+		{HelpText: "PEM encoded CA certificates", Item: httpSettingCA},
+		{HelpText: "PEM encoded client certificate, optionally followed by its key", Item: httpSettingCert},
+		{HelpText: "PEM encoded client key", Item: httpSettingKey},
+		{HelpText: "Request timeout in seconds", Item: httpSettingTimeout},
+		{HelpText: "Number of retries of the failed read requests", Item: httpSettingRetries},
+		{HelpText: "Maximum number of concurrent requests", Item: httpSettingConcurrency},
+		{HelpText: "Maximum number of requests per second", Item: httpSettingQPS},
+	}
+
+	httpCommand := model.NewAutocompleteData(
+		"http", "[URL] [setting] [value]", "Show or change the HTTP settings of the requests to a Jira instance")
+	httpCommand.AddDynamicListArgument("Jira instance", makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias), true)
+	httpCommand.AddStaticListArgument("Setting", false, httpSettings)
+	httpCommand.AddTextArgument("Value, leave empty to reset the setting", "[value]", "")
+	httpCommand.RoleID = model.SystemAdminRoleId
+
+	cache := model.NewAutocompleteData(
+		"cache", "", "Manage the cached Jira metadata")
+	cache.RoleID = model.SystemAdminRoleId
+	cacheFlush := model.NewAutocompleteData(
+		"flush", "[Jira URL]", "Flush the cached projects, fields and transitions of a Jira instance")
+	cacheFlush.AddDynamicListArgument("Jira instance", makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias), false)
+	cacheFlush.RoleID = model.SystemAdminRoleId
+	cache.AddCommand(cacheFlush)
+
 	list := model.NewAutocompleteData(
 		"list", "", "List installed Jira instances")
 	list.RoleID = model.SystemAdminRoleId
 
+	status := model.NewAutocompleteData(
+		"status", "[Jira URL]", "Show the health of the Jira webhook for an instance")
+	status.AddDynamicListArgument("Jira instance", makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias), false)
+	status.RoleID = model.SystemAdminRoleId
+
+	connections := model.NewAutocompleteData(
+		"connections", "[Jira URL]", "List the connected users with their connection type, last use and token status")
+	connections.AddDynamicListArgument("Jira instance", makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias), false)
+	connections.RoleID = model.SystemAdminRoleId
+
+	forceDisconnect := model.NewAutocompleteData(
+		"force-disconnect", "[Jira URL] [@username]", "Disconnect a user from a Jira instance")
+	forceDisconnect.AddDynamicListArgument("Jira instance", makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias), true)
+	forceDisconnect.AddTextArgument("Mattermost user", "[@username]", "")
+	forceDisconnect.RoleID = model.SystemAdminRoleId
+
+	cleanup := model.NewAutocompleteData(
+		"cleanup", "", "Remove the data left behind by deactivated users, uninstalled instances and deleted channels")
+	cleanup.RoleID = model.SystemAdminRoleId
+	cleanupStatus := model.NewAutocompleteData(
+		"status", "", "Show the report of the last cleanup")
+	cleanupStatus.RoleID = model.SystemAdminRoleId
+	cleanupRun := model.NewAutocompleteData(
+		"run", "", "Run the cleanup now")
+	cleanupRun.RoleID = model.SystemAdminRoleId
+	cleanup.AddCommand(cleanupStatus)
+	cleanup.AddCommand(cleanupRun)
+
 	instance.AddCommand(createConnectCommand())
 	instance.AddCommand(createDisconnectCommand())
 	instance.AddCommand(list)
+	instance.AddCommand(status)
+	instance.AddCommand(connections)
+	instance.AddCommand(forceDisconnect)
+	instance.AddCommand(cleanup)
+	instance.AddCommand(auth)
+	instance.AddCommand(httpCommand)
+	instance.AddCommand(cache)
 	instance.AddCommand(createSettingsCommand(optInstance))
 	instance.AddCommand(install)
 	instance.AddCommand(uninstall)
@@ -216,15 +351,46 @@ func createInstanceCommand(optInstance bool) *model.AutocompleteData {
 
 func createIssueCommand(optInstance bool) *model.AutocompleteData {
 	issue := model.NewAutocompleteData(
-		"issue", "[view|assign|transition|create]", "View and manage Jira issues")
+		"issue", "[view|search|assign|transition|create|link|subtask|clone]", "View and manage Jira issues")
 	issue.AddCommand(createViewCommand(optInstance))
+	issue.AddCommand(createSearchCommand(optInstance))
 	issue.AddCommand(createTransitionCommand(optInstance))
 	issue.AddCommand(createAssignCommand(optInstance))
 	issue.AddCommand(createUnassignCommand(optInstance))
 	issue.AddCommand(createCreateIssueCommand(optInstance))
+	issue.AddCommand(createLinkCommand(optInstance))
+	issue.AddCommand(createSubtaskCommand(optInstance))
+	issue.AddCommand(createCloneCommand(optInstance))
 	return issue
 }
 
+func createLinkCommand(optInstance bool) *model.AutocompleteData {
+	link := model.NewAutocompleteData(
+		"link", "[issue-key] [link-type] [issue-key]", "Link a Jira issue to another one")
+	withParamIssueKey(link)
+	link.AddDynamicListArgument("Link type", makeAutocompleteRoute(routeAutocompleteIssueLinkTypes), true)
+	withParamIssueKey(link)
+	withFlagInstance(link, optInstance, makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias))
+	return link
+}
+
+func createSubtaskCommand(optInstance bool) *model.AutocompleteData {
+	subtask := model.NewAutocompleteData(
+		"subtask", "[parent-key] [summary]", "Create a sub-task of a Jira issue")
+	withParamIssueKey(subtask)
+	subtask.AddTextArgument("Summary of the sub-task", "[summary]", "")
+	withFlagInstance(subtask, optInstance, makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias))
+	return subtask
+}
+
+func createCloneCommand(optInstance bool) *model.AutocompleteData {
+	clone := model.NewAutocompleteData(
+		"clone", "[issue-key]", "Create a copy of a Jira issue")
+	withParamIssueKey(clone)
+	withFlagInstance(clone, optInstance, makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias))
+	return clone
+}
+
 func createCreateIssueCommand(optInstance bool) *model.AutocompleteData {
 	create := model.NewAutocompleteData(
 		"create", "[description]", "Create a new Jira issue and optionally prefill its description")
@@ -282,7 +448,7 @@ func createDefaultInstanceCommand() *model.AutocompleteData {
 
 func createSettingsCommand(optInstance bool) *model.AutocompleteData {
 	settings := model.NewAutocompleteData(
-		"settings", "[list|notifications]", "View or update your user settings")
+		"settings", "[list|notifications|subscriptions]", "View or update your user settings")
 
 	list := model.NewAutocompleteData(
 		"list", "", "View your current settings")
@@ -318,6 +484,23 @@ func createSettingsCommand(optInstance bool) *model.AutocompleteData {
 
 	settings.AddCommand(notifications)
 
+	subscriptions := model.NewAutocompleteData(
+		"subscriptions", "[list|add|remove]", "Manage your personal subscriptions, delivered by DM")
+	subscriptionsList := model.NewAutocompleteData("list", "", "List your personal subscriptions")
+	withFlagInstance(subscriptionsList, optInstance, makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias))
+	subscriptionsAdd := model.NewAutocompleteData(
+		"add", "[name] project=[key] events=[events] types=[issue types] field=[field:inclusion:values]", "Receive a DM for issues matching the filters")
+	subscriptionsAdd.AddTextArgument("Subscription name and filters", "[name] project=[key] events=[events] types=[issue types]", "")
+	withFlagInstance(subscriptionsAdd, optInstance, makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias))
+	subscriptionsRemove := model.NewAutocompleteData("remove", "[name]", "Remove a personal subscription")
+	subscriptionsRemove.AddTextArgument("Subscription name", "[name]", "")
+	withFlagInstance(subscriptionsRemove, optInstance, makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias))
+	subscriptions.AddCommand(subscriptionsList)
+	subscriptions.AddCommand(subscriptionsAdd)
+	subscriptions.AddCommand(subscriptionsRemove)
+
+	settings.AddCommand(subscriptions)
+
 	return settings
 }
 
@@ -329,6 +512,14 @@ func createViewCommand(optInstance bool) *model.AutocompleteData {
 	return view
 }
 
+func createSearchCommand(optInstance bool) *model.AutocompleteData {
+	search := model.NewAutocompleteData(
+		"search", "[JQL or text]", "Search the Jira issues matching a JQL query or a text")
+	search.AddTextArgument("JQL query or text to search for", "[JQL or text]", "")
+	withFlagInstance(search, optInstance, makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias))
+	return search
+}
+
 func createTransitionCommand(optInstance bool) *model.AutocompleteData {
 	transition := model.NewAutocompleteData(
 		"transition", "[Jira issue] [To state]", "Change the state of a Jira issue")
@@ -370,11 +561,109 @@ func createSubscribeCommand(optInstance bool) *model.AutocompleteData {
 	return subscribe
 }
 
+func createFilterCommand(optInstance bool) *model.AutocompleteData {
+	filter := model.NewAutocompleteData(
+		"filter", "[list|run|subscribe]", "List and run your Jira saved filters, or subscribe this channel to one")
+
+	list := model.NewAutocompleteData(
+		"list", "", "List your favourite Jira filters")
+	withFlagInstance(list, optInstance, makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias))
+	filter.AddCommand(list)
+
+	run := model.NewAutocompleteData(
+		"run", "[name|id]", "Search the Jira issues matching a saved filter")
+	run.AddTextArgument("Name or ID of the saved filter", "[name|id]", "")
+	withFlagInstance(run, optInstance, makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias))
+	filter.AddCommand(run)
+
+	subscribe := model.NewAutocompleteData(
+		"subscribe", "[name|id]", "Send the notifications of the issues matching a saved filter to this channel")
+	subscribe.AddTextArgument("Name or ID of the saved filter", "[name|id]", "")
+	withFlagInstance(subscribe, optInstance, makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias))
+	filter.AddCommand(subscribe)
+	return filter
+}
+
+func createSprintCommand(optInstance bool) *model.AutocompleteData {
+	sprint := model.NewAutocompleteData(
+		"sprint", "[board|add]", "Display the active sprint of a Jira board, or move an issue to a sprint")
+	sprint.AddTextArgument("Name or ID of the board", "[board]", "")
+	withFlagInstance(sprint, optInstance, makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias))
+
+	add := model.NewAutocompleteData(
+		"add", "[issue-key] [sprint]", "Move a Jira issue to a sprint, by default the active sprint of its project")
+	add.AddTextArgument("Issue key, then the name or ID of the sprint", "[issue-key] [sprint]", "")
+	withFlagInstance(add, optInstance, makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias))
+	sprint.AddCommand(add)
+	return sprint
+}
+
+func createBacklogCommand(optInstance bool) *model.AutocompleteData {
+	backlog := model.NewAutocompleteData(
+		"backlog", "[board] [--top number]", "List the first issues of the backlog of a Jira board")
+	backlog.AddTextArgument("Name or ID of the board", "[board]", "")
+	backlog.AddNamedTextArgument("top", "Number of issues to list, up to 50", "[number]", "", false)
+	withFlagInstance(backlog, optInstance, makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias))
+	return backlog
+}
+
+func createKeysCommand() *model.AutocompleteData {
+	keys := model.NewAutocompleteData(
+		"keys", "[rotate|status|rsa]", "Rotate the keys that protect the stored tokens and sign the requests to Jira")
+	keys.RoleID = model.SystemAdminRoleId
+
+	rotate := model.NewAutocompleteData(
+		"rotate", "", "Replace the encryption key and re-encrypt the stored tokens in the background")
+	rotate.RoleID = model.SystemAdminRoleId
+	keys.AddCommand(rotate)
+
+	status := model.NewAutocompleteData(
+		"status", "", "Show the progress of the last encryption key rotation")
+	status.RoleID = model.SystemAdminRoleId
+	keys.AddCommand(status)
+
+	rsa := model.NewAutocompleteData(
+		"rsa", "[start|complete]", "Roll over the RSA key of the Jira Server Application Links")
+	rsa.RoleID = model.SystemAdminRoleId
+	start := model.NewAutocompleteData(
+		"start", "", "Generate a new RSA key, and show the current and new public keys")
+	start.RoleID = model.SystemAdminRoleId
+	rsa.AddCommand(start)
+	complete := model.NewAutocompleteData(
+		"complete", "", "Sign the requests with the new RSA key, once the Application Links are updated")
+	complete.RoleID = model.SystemAdminRoleId
+	rsa.AddCommand(complete)
+	keys.AddCommand(rsa)
+
+	return keys
+}
+
 func createWebhookCommand(optInstance bool) *model.AutocompleteData {
 	webhook := model.NewAutocompleteData(
 		"webhook", "[Jira URL]", "Display the webhook URLs to set up on Jira")
 	webhook.RoleID = model.SystemAdminRoleId
 	withFlagInstance(webhook, optInstance, makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias))
+
+	events := model.NewAutocompleteData(
+		"events", "[event-id]", "Browse the most recent webhook events received from Jira")
+	events.AddTextArgument("Webhook event ID", "[event-id]", "")
+	events.RoleID = model.SystemAdminRoleId
+	withFlagInstance(events, optInstance, makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias))
+	webhook.AddCommand(events)
+
+	replay := model.NewAutocompleteData(
+		"replay", "[event-id]", "Process a recent webhook event again")
+	replay.AddTextArgument("Webhook event ID", "[event-id]", "")
+	replay.RoleID = model.SystemAdminRoleId
+	withFlagInstance(replay, optInstance, makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias))
+	webhook.AddCommand(replay)
+
+	register := model.NewAutocompleteData(
+		"register", "", "Create or update the subscriptions webhook in Jira using the Admin API Token")
+	register.RoleID = model.SystemAdminRoleId
+	withFlagInstance(register, optInstance, makeAutocompleteRoute(routeAutocompleteInstalledInstanceWithAlias))
+	webhook.AddCommand(register)
+
 	return webhook
 }
 
@@ -685,6 +974,8 @@ func executeSettings(p *Plugin, c *plugin.Context, header *model.CommandArgs, ar
 		return p.responsef(header, "Please connect to Jira account using the command `/jira connect`")
 	case "notifications":
 		return p.settingsNotifications(header, instance.GetID(), user.MattermostUserID, conn, args)
+	case "subscriptions":
+		return p.settingsSubscriptions(header, instance, user.MattermostUserID, conn, args)
 	default:
 		return p.responsef(header, "Unknown setting.")
 	}
@@ -927,6 +1218,38 @@ func executeInstanceInstallCloudOAuth(p *Plugin, c *plugin.Context, header *mode
 	return &model.CommandResponse{}
 }
 
+func executeInstanceInstallServerOAuth(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.response(header, err.Error())
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira install` can only be run by a Mattermost system administrator.")
+	}
+	if len(args) != 1 {
+		return p.help(header)
+	}
+
+	jiraURL, _, err := p.installServerOAuthInstance(args[0])
+	if err != nil {
+		return p.response(header, err.Error())
+	}
+
+	if err = p.serverOAuth2Flow.ForUser(header.UserId).Start(p.serverOAuthFlowState(jiraURL)); err != nil {
+		return p.response(header, err.Error())
+	}
+
+	channel, err := p.client.Channel.GetDirect(header.UserId, p.conf.botUserID)
+	if err != nil {
+		return p.response(header, err.Error())
+	}
+	if channel != nil && channel.Id != header.ChannelId {
+		return p.responsef(header, "continue in the direct conversation with @jira bot.")
+	}
+
+	return &model.CommandResponse{}
+}
+
 func executeInstanceInstallServer(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
 	authorized, err := authorizedSysAdmin(p, header.UserId)
 	if err != nil {
@@ -935,13 +1258,22 @@ func executeInstanceInstallServer(p *Plugin, c *plugin.Context, header *model.Co
 	if !authorized {
 		return p.responsef(header, "`/jira install` can only be run by a system administrator.")
 	}
-	if len(args) != 1 {
+	if len(args) != 1 && len(args) != 2 {
 		return p.help(header)
 	}
-	jiraURL, instance, err := p.installServerInstance(args[0])
+	authMode := ServerAuthModeOAuth1
+	if len(args) == 2 {
+		authMode = args[1]
+	}
+	jiraURL, instance, err := p.installServerInstance(args[0], authMode)
 	if err != nil {
 		return p.response(header, err.Error())
 	}
+	if authMode == ServerAuthModePAT {
+		return p.respondCommandTemplate(header, "/command/install_server_pat.md", map[string]string{
+			"JiraURL": jiraURL,
+		})
+	}
 	pkey, err := p.publicKeyString()
 	if err != nil {
 		return p.responsef(header, "Failed to load public key: %v", err)
@@ -955,6 +1287,54 @@ func executeInstanceInstallServer(p *Plugin, c *plugin.Context, header *model.Co
 	})
 }
 
+// executeInstanceAuth shows or changes how users connect to a Jira server instance.
+func executeInstanceAuth(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira instance auth` can only be run by a system administrator.")
+	}
+	if len(args) != 1 && len(args) != 2 {
+		return p.responsef(header, "Please use `/jira instance auth [jiraURL] [oauth1|pat]`.")
+	}
+
+	instances, err := p.instanceStore.LoadInstances()
+	if err != nil {
+		return p.responsef(header, "Failed to load instances. Error: %v.", err)
+	}
+	instanceID := types.ID(args[0])
+	if instanceFound := instances.getByAlias(args[0]); instanceFound != nil {
+		instanceID = instanceFound.InstanceID
+	}
+
+	instance, err := p.instanceStore.LoadInstance(instanceID)
+	if err != nil {
+		return p.responsef(header, "Failed to load instance. Error: %v.", err)
+	}
+	si, ok := instance.(*serverInstance)
+	if !ok {
+		return p.responsef(header, "Instance %s is a %s instance, the authentication mode can only be chosen for Jira Server or Data Center instances.", instanceID, instance.Common().Type)
+	}
+
+	if len(args) == 1 {
+		return p.responsef(header, "Users connect to %s with `%s`.", instanceID, si.GetAuthMode())
+	}
+
+	authMode := args[1]
+	if !isValidServerAuthMode(authMode) {
+		return p.responsef(header, "Unsupported authentication mode `%s`, please use `%s` or `%s`.", authMode, ServerAuthModeOAuth1, ServerAuthModePAT)
+	}
+
+	si.AuthMode = authMode
+	if err = p.instanceStore.StoreInstance(si); err != nil {
+		return p.responsef(header, "Failed to store instance. Error: %v.", err)
+	}
+
+	return p.responsef(header, "Users will now connect to %s with `%s`. Existing connections keep working until the users disconnect.", instanceID, authMode)
+}
+
 // executeUninstall will uninstall the jira instance if the url matches, and then update all connected clients
 // so that their Jira-related menu options are removed.
 func executeInstanceUninstall(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
@@ -1054,12 +1434,18 @@ func executeTransition(p *Plugin, c *plugin.Context, header *model.CommandArgs,
 	msg, err := p.TransitionIssue(&InTransitionIssue{
 		InstanceID:       instanceID,
 		mattermostUserID: mattermostUserID,
+		PostToChannelID:  header.ChannelId,
 		IssueKey:         issueKey,
 		ToState:          toState,
+		TriggerID:        header.TriggerId,
 	})
 	if err != nil {
 		return p.response(header, err.Error())
 	}
+	if msg == "" {
+		// A dialog was opened for the fields required by the transition
+		return &model.CommandResponse{}
+	}
 
 	return p.response(header, msg)
 }
@@ -1088,7 +1474,7 @@ func executeMe(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...
 			return sbullet(id, fmt.Sprintf("Cloud, connected as **%s** (AccountID: `%s`)",
 				connection.User.DisplayName,
 				connection.User.AccountID))
-		case ServerInstanceType:
+		case ServerInstanceType, ServerOAuthInstanceType:
 			return sbullet(id, fmt.Sprintf("Server, connected as **%s** (Name:%s, Key:%s, EmailAddress:%s)",
 				connection.User.DisplayName,
 				connection.User.Name,
@@ -1210,6 +1596,7 @@ func executeWebhookURL(p *Plugin, c *plugin.Context, header *model.CommandArgs,
 	return p.responsef(header,
 		"To set up webhook for instance %s please navigate to [Jira System Settings/Webhooks](%s) where you can add webhooks.\n"+
 			"Use `/jira webhook jiraURL` to specify another Jira instance. Use `/jira instance list` to view the available instances.\n"+
+			"Use `/jira webhook register` to let the plugin create or update the webhook in Jira, this requires the Admin API Token plugin setting. Use `/jira instance status` to check the webhook health.\n"+
 			"##### Subscriptions webhook.\n"+
 			"Subscriptions webhook needs to be set up once, is shared by all channels and subscription filters.\n"+
 			"   - `%s`\n"+
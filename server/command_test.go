@@ -524,6 +524,9 @@ func TestPlugin_ExecuteCommand_Installation(t *testing.T) {
 				actual := strings.TrimSpace(post.Message)
 				assert.True(t, strings.HasPrefix(actual, tt.expectedMsgPrefix), "Expected returned message to start with: \n%s\nActual:\n%s", tt.expectedMsgPrefix, actual)
 			}).Once().Return(&model.Post{})
+			isPendingHTTPConfigKey := mock.MatchedBy(func(key string) bool { return strings.HasPrefix(key, prefixPendingHTTPConfig) })
+			api.On("KVGet", isPendingHTTPConfigKey).Return(nil, nil)
+			api.On("KVSetWithOptions", isPendingHTTPConfigKey, mock.Anything, mock.Anything).Return(true, nil)
 
 			path, err := filepath.Abs("..")
 			require.Nil(t, err)
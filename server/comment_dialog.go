@@ -0,0 +1,240 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+const (
+	commentDialogCallbackID = "comment_issue"
+
+	commentDialogText       = "comment"
+	commentDialogVisibility = "visibility"
+	commentDialogMentions   = "resolve_mentions"
+
+	// commentDialogTextMaxLength is the maximum length of a dialog textarea.
+	commentDialogTextMaxLength = 3000
+)
+
+var reMention = regexp.MustCompile(`(^|[^\w@])@([a-z0-9][a-z0-9._-]*[a-z0-9_-]|[a-z0-9])`)
+
+// commentDialogState is kept in the dialog between opening and submitting it.
+type commentDialogState struct {
+	InstanceID types.ID `json:"instance_id"`
+	IssueKey   string   `json:"issue_key"`
+	ChannelID  string   `json:"channel_id"`
+	RootID     string   `json:"root_id"`
+}
+
+// resolveCommentMentions replaces the @mentions of Mattermost users connected to Jira with
+// mentions of their Jira users.
+func (p *Plugin) resolveCommentMentions(instanceID types.ID, text string) string {
+	mentions := model.UserMentionMap{}
+	for _, m := range reMention.FindAllStringSubmatch(text, -1) {
+		username := m[2]
+		if _, ok := mentions[username]; ok {
+			continue
+		}
+		user, err := p.client.User.GetByUsername(username)
+		if err != nil {
+			continue
+		}
+		mentions[username] = user.Id
+	}
+
+	return reMention.ReplaceAllStringFunc(text, func(match string) string {
+		m := reMention.FindStringSubmatch(match)
+		if _, ok := mentions[m[2]]; !ok {
+			return match
+		}
+		jiraUser, err := p.GetJiraUserFromMentions(instanceID, mentions, m[2])
+		if err != nil {
+			return match
+		}
+		return m[1] + jiraMention(jiraUser)
+	})
+}
+
+func jiraMention(user *jira.User) string {
+	if user.AccountID != "" {
+		return "[~accountid:" + user.AccountID + "]"
+	}
+	return "[~" + user.Name + "]"
+}
+
+func makeCommentDialog(issueKey string, state commentDialogState, groups []*JiraUserGroup) (model.Dialog, error) {
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return model.Dialog{}, err
+	}
+
+	options := []*model.PostActionOptions{}
+	for _, group := range groups {
+		if group.Name == visibleToAllUsers {
+			continue
+		}
+		options = append(options, &model.PostActionOptions{Text: group.Name, Value: group.Name})
+	}
+
+	elements := []model.DialogElement{{
+		DisplayName: "Comment",
+		Name:        commentDialogText,
+		Type:        "textarea",
+		MaxLength:   commentDialogTextMaxLength,
+		HelpText:    "Markdown is converted to Jira formatting.",
+	}}
+	if len(options) > 0 {
+		elements = append(elements, model.DialogElement{
+			DisplayName: "Visible to",
+			Name:        commentDialogVisibility,
+			Type:        "select",
+			Options:     options,
+			Optional:    true,
+			Placeholder: "All users",
+		})
+	}
+	elements = append(elements, model.DialogElement{
+		DisplayName: "Mentions",
+		Name:        commentDialogMentions,
+		Type:        "bool",
+		Default:     "true",
+		Optional:    true,
+		Placeholder: "Mention the Jira users of the @mentioned users",
+	})
+
+	return model.Dialog{
+		CallbackId:  commentDialogCallbackID,
+		Title:       truncate("Comment on "+issueKey, dialogTitleMaxLength),
+		Elements:    elements,
+		SubmitLabel: "Comment",
+		State:       string(encoded),
+	}, nil
+}
+
+func (p *Plugin) httpOpenCommentDialog(w http.ResponseWriter, r *http.Request) (int, error) {
+	authenticatedUserID, requestData, status, err := decodePostActionRequest(r)
+	if err != nil {
+		return respondErr(w, status, err)
+	}
+
+	jiraBotID := p.getUserID()
+	ctx, status, errMsg := p.buildPostActionContext(authenticatedUserID, requestData, false)
+	if errMsg != "" {
+		return p.respondErrWithFeedback(authenticatedUserID, makePost(jiraBotID, requestData.ChannelId, errMsg), w, status)
+	}
+
+	client, instance, connection, err := p.getClient(types.ID(ctx.instanceID), types.ID(ctx.authenticatedUserID))
+	if err != nil {
+		return p.respondErrWithFeedback(ctx.authenticatedUserID, makePost(jiraBotID, ctx.channelID,
+			"Your username is not connected to Jira. Please type `/jira connect`."), w, http.StatusUnauthorized)
+	}
+
+	issue, err := client.GetIssue(ctx.issueKey, &jira.GetQueryOptions{Fields: "key"})
+	if err != nil {
+		return p.respondErrWithFeedback(ctx.authenticatedUserID, makePost(jiraBotID, ctx.channelID,
+			issueActionError(err, "view the issue").Error()), w, http.StatusBadRequest)
+	}
+
+	var groups []*JiraUserGroup
+	result, err := client.GetUserVisibilityGroups(p.commentVisibilityParams(instance, client, connection, ""))
+	if err != nil {
+		p.client.Log.Debug("Failed to get the comment visibility groups", "error", err.Error())
+	} else if result != nil && result.Groups != nil {
+		groups = result.Groups.JiraUserGroups
+	}
+
+	rootID := ""
+	if post, postErr := p.client.Post.GetPost(ctx.postID); postErr == nil {
+		rootID = post.RootId
+		if rootID == "" {
+			rootID = post.Id
+		}
+	}
+
+	dialog, err := makeCommentDialog(issue.Key, commentDialogState{
+		InstanceID: instance.GetID(),
+		IssueKey:   issue.Key,
+		ChannelID:  ctx.channelID,
+		RootID:     rootID,
+	}, groups)
+	if err != nil {
+		return respondErr(w, http.StatusInternalServerError, err)
+	}
+
+	err = p.client.Frontend.OpenInteractiveDialog(model.OpenDialogRequest{
+		TriggerId: requestData.TriggerId,
+		URL:       fmt.Sprintf("/plugins/%s%s%s", manifest.Id, routeAPI, routeIssueCommentDialog),
+		Dialog:    dialog,
+	})
+	if err != nil {
+		return p.respondErrWithFeedback(ctx.authenticatedUserID, makePost(jiraBotID, ctx.channelID,
+			"Failed to open the comment dialog."), w, http.StatusInternalServerError)
+	}
+
+	return respondJSON(w, map[string]string{
+		statusField: "OK",
+	})
+}
+
+func (p *Plugin) httpSubmitCommentDialog(w http.ResponseWriter, r *http.Request) (int, error) {
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+
+	var request model.SubmitDialogRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return respondErr(w, http.StatusBadRequest, errors.WithMessage(err, "failed to decode the dialog submission"))
+	}
+	if request.UserId != mattermostUserID {
+		return respondErr(w, http.StatusUnauthorized, errors.New("not authorized"))
+	}
+	if request.Cancelled {
+		return respondJSON(w, model.SubmitDialogResponse{})
+	}
+
+	var state commentDialogState
+	if err := json.Unmarshal([]byte(request.State), &state); err != nil {
+		return respondErr(w, http.StatusBadRequest, errors.WithMessage(err, "failed to decode the dialog state"))
+	}
+
+	text, _ := request.Submission[commentDialogText].(string)
+	if strings.TrimSpace(text) == "" {
+		return respondJSON(w, model.SubmitDialogResponse{Errors: map[string]string{commentDialogText: "Please enter a comment."}})
+	}
+
+	client, instance, _, err := p.getClient(state.InstanceID, types.ID(mattermostUserID))
+	if err != nil {
+		return respondJSON(w, model.SubmitDialogResponse{Error: "Your username is not connected to Jira. Please type `/jira connect`."})
+	}
+
+	body := markdownToJiraMarkup(text)
+	if resolve, _ := request.Submission[commentDialogMentions].(bool); resolve {
+		body = p.resolveCommentMentions(instance.GetID(), body)
+	}
+	comment := &jira.Comment{Body: body}
+	if group, _ := request.Submission[commentDialogVisibility].(string); group != "" && group != visibleToAllUsers {
+		comment.Visibility = jira.CommentVisibility{Type: CommentVisibilityGroupType, Value: group}
+	}
+
+	if _, err = client.AddComment(state.IssueKey, comment); err != nil {
+		return respondJSON(w, model.SubmitDialogResponse{Error: issueActionError(err, "comment on the issue").Error()})
+	}
+
+	reply := makePost(p.getUserID(), state.ChannelID,
+		fmt.Sprintf("Comment added to [%s](%s/browse/%s).", state.IssueKey, instance.GetJiraBaseURL(), state.IssueKey))
+	reply.RootId = state.RootID
+	p.client.Post.SendEphemeralPost(mattermostUserID, reply)
+
+	return respondJSON(w, model.SubmitDialogResponse{})
+}
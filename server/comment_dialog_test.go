@@ -0,0 +1,38 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMakeCommentDialog(t *testing.T) {
+	state := commentDialogState{InstanceID: testInstance1.GetID(), IssueKey: "TEST-1", ChannelID: "channel_id"}
+
+	t.Run("with groups", func(t *testing.T) {
+		dialog, err := makeCommentDialog("TEST-1", state, []*JiraUserGroup{{visibleToAllUsers}, {"developers"}})
+		require.NoError(t, err)
+		require.Len(t, dialog.Elements, 3)
+		assert.Equal(t, commentDialogText, dialog.Elements[0].Name)
+		assert.Equal(t, commentDialogVisibility, dialog.Elements[1].Name)
+		require.Len(t, dialog.Elements[1].Options, 1)
+		assert.Equal(t, "developers", dialog.Elements[1].Options[0].Value)
+		assert.Equal(t, commentDialogMentions, dialog.Elements[2].Name)
+
+		var decoded commentDialogState
+		require.NoError(t, json.Unmarshal([]byte(dialog.State), &decoded))
+		assert.Equal(t, state, decoded)
+	})
+
+	t.Run("without groups", func(t *testing.T) {
+		dialog, err := makeCommentDialog("TEST-1", state, []*JiraUserGroup{{visibleToAllUsers}})
+		require.NoError(t, err)
+		require.Len(t, dialog.Elements, 2)
+		assert.Equal(t, commentDialogMentions, dialog.Elements[1].Name)
+	})
+}
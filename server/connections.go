@@ -0,0 +1,410 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/kvstore"
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+const (
+	connectionLastUsedKeyPrefix = "last_used_"
+
+	// connectionUsageWriteInterval limits how often the last use of a connection is stored
+	connectionUsageWriteInterval = time.Hour
+)
+
+const (
+	tokenStatusHealthy   = "healthy"
+	tokenStatusUnchecked = "unchecked"
+)
+
+const (
+	routeAPIAdminConnections           = "/admin/connections"
+	routeAPIAdminConnectionsDisconnect = "/admin/connections/disconnect"
+)
+
+// connectedUser describes a user connection for the system administrators.
+type connectedUser struct {
+	MattermostUserID   types.ID `json:"mattermost_user_id"`
+	MattermostUsername string   `json:"mattermost_username"`
+	JiraAccountID      types.ID `json:"jira_account_id"`
+	JiraDisplayName    string   `json:"jira_display_name"`
+	JiraEmailAddress   string   `json:"jira_email_address,omitempty"`
+	ConnectionType     string   `json:"connection_type"`
+	LastUsedAt         int64    `json:"last_used_at,omitempty"`
+	TokenStatus        string   `json:"token_status"`
+	TokenError         string   `json:"token_error,omitempty"`
+}
+
+// connectionUsageTracker remembers when the last use of each connection was stored, so that
+// the KV store is written at most once per connectionUsageWriteInterval.
+type connectionUsageTracker struct {
+	lock    sync.Mutex
+	written map[string]time.Time
+}
+
+func (t *connectionUsageTracker) shouldWrite(key string, now time.Time) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if now.Sub(t.written[key]) < connectionUsageWriteInterval {
+		return false
+	}
+	if t.written == nil {
+		t.written = map[string]time.Time{}
+	}
+	t.written[key] = now
+	return true
+}
+
+func (t *connectionUsageTracker) forget(key string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.written, key)
+}
+
+func connectionLastUsedKey(instanceID, mattermostUserID types.ID) string {
+	return keyWithInstanceID(instanceID, types.ID(connectionLastUsedKeyPrefix+mattermostUserID))
+}
+
+// recordConnectionUse stores the time of a successful request made with a user's connection.
+func (p *Plugin) recordConnectionUse(instanceID, mattermostUserID types.ID, now time.Time) {
+	key := connectionLastUsedKey(instanceID, mattermostUserID)
+	if !p.connectionUsage.shouldWrite(key, now) {
+		return
+	}
+	if _, err := p.client.KV.Set(key, now.UnixMilli()); err != nil {
+		p.connectionUsage.forget(key)
+		p.client.Log.Debug("Failed to store the last use of the connection",
+			"mattermostUserID", mattermostUserID.String(), "instanceID", instanceID.String(), "error", err.Error())
+	}
+}
+
+func (p *Plugin) getConnectionLastUsed(instanceID, mattermostUserID types.ID) (int64, error) {
+	var lastUsed int64
+	if err := p.client.KV.Get(connectionLastUsedKey(instanceID, mattermostUserID), &lastUsed); err != nil {
+		return 0, errors.Wrap(err, "failed to load the last use of the connection")
+	}
+	return lastUsed, nil
+}
+
+func (p *Plugin) deleteConnectionLastUsed(instanceID, mattermostUserID types.ID) {
+	key := connectionLastUsedKey(instanceID, mattermostUserID)
+	p.connectionUsage.forget(key)
+	if err := p.client.KV.Delete(key); err != nil {
+		p.client.Log.Debug("Failed to delete the last use of the connection",
+			"mattermostUserID", mattermostUserID.String(), "instanceID", instanceID.String(), "error", err.Error())
+	}
+}
+
+// connectionUsageTransport records the requests that Jira accepted with a user's credentials.
+type connectionUsageTransport struct {
+	http.RoundTripper
+	record func()
+}
+
+func (t *connectionUsageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.RoundTripper.RoundTrip(req)
+	if err == nil && resp.StatusCode != http.StatusUnauthorized && resp.StatusCode != http.StatusForbidden && resp.StatusCode < 500 {
+		t.record()
+	}
+	return resp, err
+}
+
+// withConnectionUsage returns a copy of the HTTP client that records the last successful use
+// of the connection.
+func (ic *InstanceCommon) withConnectionUsage(httpClient *http.Client, connection *Connection) *http.Client {
+	if ic.Plugin == nil || connection.MattermostUserID == "" {
+		return httpClient
+	}
+
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	client := *httpClient
+	p, instanceID, mattermostUserID := ic.Plugin, ic.InstanceID, connection.MattermostUserID
+	client.Transport = &connectionUsageTransport{
+		RoundTripper: base,
+		record: func() {
+			p.recordConnectionUse(instanceID, mattermostUserID, time.Now())
+		},
+	}
+	return &client
+}
+
+// listConnectedUsers returns the users connected to the instance, sorted by username.
+func (p *Plugin) listConnectedUsers(instance Instance) ([]*connectedUser, error) {
+	instanceID := instance.GetID()
+	report, err := p.getTokenHealthReport(instanceID)
+	if err != nil {
+		report = &tokenHealthReport{}
+	}
+
+	users := []*connectedUser{}
+	err = p.userStore.MapUsers(func(user *User) error {
+		if user.ConnectedInstances == nil || !user.ConnectedInstances.Contains(instanceID) {
+			return nil
+		}
+		connection, err := p.userStore.LoadConnection(instanceID, user.MattermostUserID)
+		if err != nil {
+			p.client.Log.Warn("Failed to load the connection of a connected user",
+				"mattermostUserID", user.MattermostUserID.String(), "instanceID", instanceID.String(), "error", err.Error())
+			return nil
+		}
+
+		cu := &connectedUser{
+			MattermostUserID: user.MattermostUserID,
+			JiraAccountID:    connection.JiraAccountID(),
+			JiraDisplayName:  connection.DisplayName,
+			JiraEmailAddress: connection.EmailAddress,
+			ConnectionType:   connectionType(instance, connection),
+			TokenStatus:      tokenStatusUnchecked,
+		}
+		if mmuser, err := p.client.User.Get(user.MattermostUserID.String()); err == nil {
+			cu.MattermostUsername = mmuser.Username
+		}
+		if cu.LastUsedAt, err = p.getConnectionLastUsed(instanceID, user.MattermostUserID); err != nil {
+			cu.LastUsedAt = 0
+		}
+		switch broken := report.Broken[user.MattermostUserID]; {
+		case broken != nil:
+			cu.TokenStatus = broken.Status
+			cu.TokenError = strings.ReplaceAll(strings.TrimSpace(broken.Error), "\n", " ")
+		case report.Expiring[user.MattermostUserID] != nil:
+			cu.TokenStatus = tokenStatusExpiring
+			cu.TokenError = "expires at " + formatMillis(report.Expiring[user.MattermostUserID].ExpiresAt)
+		case report.CheckedAt != 0:
+			cu.TokenStatus = tokenStatusHealthy
+		}
+		users = append(users, cu)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list the connected users")
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].MattermostUsername != users[j].MattermostUsername {
+			return users[i].MattermostUsername < users[j].MattermostUsername
+		}
+		return users[i].MattermostUserID < users[j].MattermostUserID
+	})
+	return users, nil
+}
+
+func formatMillis(millis int64) string {
+	if millis == 0 {
+		return ""
+	}
+	return time.UnixMilli(millis).UTC().Format(time.RFC3339)
+}
+
+func writeConnectedUsersCSV(w io.Writer, users []*connectedUser) error {
+	cw := csv.NewWriter(w)
+	records := [][]string{{
+		"Mattermost user ID", "Mattermost username", "Jira account ID", "Jira display name", "Jira email address",
+		"Connection type", "Last used at", "Token status", "Token error",
+	}}
+	for _, u := range users {
+		record := []string{
+			u.MattermostUserID.String(), u.MattermostUsername, u.JiraAccountID.String(), u.JiraDisplayName, u.JiraEmailAddress,
+			u.ConnectionType, formatMillis(u.LastUsedAt), u.TokenStatus, u.TokenError,
+		}
+		for i := range record {
+			record[i] = csvSafeCell(record[i])
+		}
+		records = append(records, record)
+	}
+	return cw.WriteAll(records)
+}
+
+// csvSafeCell keeps spreadsheets from evaluating a cell as a formula, by prefixing the values
+// that start like one with a quote. The display names and usernames are chosen by the users.
+func csvSafeCell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func (p *Plugin) formatConnectedUsers(instanceID types.ID, users []*connectedUser) string {
+	if len(users) == 0 {
+		return "No users are connected.\n"
+	}
+
+	text := "\n| User | Jira user | Type | Last used | Token status | Error |\n|---|---|---|---|---|---|\n"
+	for _, u := range users {
+		username := u.MattermostUserID.String()
+		if u.MattermostUsername != "" {
+			username = "@" + u.MattermostUsername
+		}
+		lastUsed := formatMillis(u.LastUsedAt)
+		if lastUsed == "" {
+			lastUsed = "unknown"
+		}
+		text += fmt.Sprintf("| %s | %s | %s | %s | %s | %s |\n",
+			username, u.JiraDisplayName, u.ConnectionType, lastUsed, u.TokenStatus, u.TokenError)
+	}
+	text += fmt.Sprintf("\n[Export as CSV](%s%s%s?instance_id=%s&format=csv)\n",
+		p.GetPluginURL(), routeAPI, routeAPIAdminConnections, url.QueryEscape(instanceID.String()))
+	return text
+}
+
+// resolveInstanceArg returns the ID of the instance with the given alias or URL.
+func (p *Plugin) resolveInstanceArg(arg string) (types.ID, error) {
+	instances, err := p.instanceStore.LoadInstances()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to load instances")
+	}
+	if instanceFound := instances.getByAlias(arg); instanceFound != nil {
+		return instanceFound.InstanceID, nil
+	}
+	return p.ResolveWebhookInstanceURL(arg)
+}
+
+// forceDisconnectUser disconnects a user from the instance on behalf of a system
+// administrator, and lets the user know.
+func (p *Plugin) forceDisconnectUser(instance Instance, mattermostUserID types.ID) error {
+	user, err := p.userStore.LoadUser(mattermostUserID)
+	if err != nil {
+		return err
+	}
+	if _, err = p.disconnectUser(instance, user); err != nil {
+		return err
+	}
+
+	_, err = p.CreateBotDMtoMMUserID(mattermostUserID.String(),
+		"A system administrator disconnected your Jira account from %s. Use `/jira connect %s` to connect it again.",
+		instance.GetJiraBaseURL(), instance.GetID())
+	if err != nil {
+		p.client.Log.Warn("Failed to notify the user about the disconnection",
+			"mattermostUserID", mattermostUserID.String(), "error", err.Error())
+	}
+	return nil
+}
+
+func executeInstanceForceDisconnect(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira instance force-disconnect` can only be run by a system administrator.")
+	}
+	if len(args) != 2 {
+		return p.responsef(header, "Please use `/jira instance force-disconnect [jiraURL] [@username]`.")
+	}
+
+	instanceID, err := p.resolveInstanceArg(args[0])
+	if err != nil {
+		return p.response(header, err.Error())
+	}
+	instance, err := p.instanceStore.LoadInstance(instanceID)
+	if err != nil {
+		return p.responsef(header, "Failed to load the Jira instance %s. Error: %v.", instanceID, err)
+	}
+
+	username := strings.TrimPrefix(args[1], "@")
+	mmuser, err := p.client.User.GetByUsername(username)
+	if err != nil {
+		return p.responsef(header, "User @%s was not found.", username)
+	}
+
+	err = p.forceDisconnectUser(instance, types.ID(mmuser.Id))
+	if errors.Cause(err) == kvstore.ErrNotFound {
+		return p.responsef(header, "User @%s is not connected to %s.", username, instanceID)
+	}
+	if err != nil {
+		return p.responsef(header, "Failed to disconnect @%s from %s. Error: %v.", username, instanceID, err)
+	}
+	return p.responsef(header, "Disconnected @%s from %s.", username, instanceID)
+}
+
+func (p *Plugin) loadAdminRequestInstance(w http.ResponseWriter, r *http.Request, instanceID types.ID) (Instance, int, error) {
+	userID := r.Header.Get("Mattermost-User-Id")
+	authorized, err := authorizedSysAdmin(p, userID)
+	if err != nil {
+		status, err := respondErr(w, http.StatusInternalServerError, err)
+		return nil, status, err
+	}
+	if !authorized {
+		status, err := respondErr(w, http.StatusForbidden, errors.New("only system administrators can manage the Jira connections"))
+		return nil, status, err
+	}
+	if instanceID == "" {
+		status, err := respondErr(w, http.StatusBadRequest, errors.New("instance_id is required"))
+		return nil, status, err
+	}
+
+	instance, err := p.instanceStore.LoadInstance(instanceID)
+	if err != nil {
+		status, err := respondErr(w, http.StatusNotFound, err)
+		return nil, status, err
+	}
+	return instance, http.StatusOK, nil
+}
+
+func (p *Plugin) httpGetAdminConnections(w http.ResponseWriter, r *http.Request) (int, error) {
+	instance, status, err := p.loadAdminRequestInstance(w, r, types.ID(r.URL.Query().Get("instance_id")))
+	if instance == nil {
+		return status, err
+	}
+
+	users, err := p.listConnectedUsers(instance)
+	if err != nil {
+		return respondErr(w, http.StatusInternalServerError, err)
+	}
+
+	if r.URL.Query().Get("format") != "csv" {
+		return respondJSON(w, users)
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="jira-connections.csv"`)
+	if err = writeConnectedUsersCSV(w, users); err != nil {
+		return http.StatusInternalServerError, errors.WithMessage(err, "failed to write response")
+	}
+	return http.StatusOK, nil
+}
+
+func (p *Plugin) httpAdminDisconnectUser(w http.ResponseWriter, r *http.Request) (int, error) {
+	payload := &struct {
+		InstanceID       types.ID `json:"instance_id"`
+		MattermostUserID types.ID `json:"mattermost_user_id"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(payload); err != nil {
+		return respondErr(w, http.StatusBadRequest, errors.WithMessage(err, "failed to decode the disconnect payload"))
+	}
+
+	instance, status, err := p.loadAdminRequestInstance(w, r, payload.InstanceID)
+	if instance == nil {
+		return status, err
+	}
+
+	err = p.forceDisconnectUser(instance, payload.MattermostUserID)
+	if errors.Cause(err) == kvstore.ErrNotFound {
+		return respondErr(w, http.StatusNotFound, err)
+	}
+	if err != nil {
+		return respondErr(w, http.StatusInternalServerError, err)
+	}
+	return respondJSON(w, map[string]bool{"success": true})
+}
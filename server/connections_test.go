@@ -0,0 +1,165 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+type connectedUsersStore struct {
+	mockUserStore
+	users       []*User
+	connections map[types.ID]*Connection
+}
+
+func (store connectedUsersStore) LoadConnection(_, mattermostUserID types.ID) (*Connection, error) {
+	return store.connections[mattermostUserID], nil
+}
+
+func (store connectedUsersStore) MapUsers(f func(*User) error) error {
+	for _, user := range store.users {
+		if err := f(user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func setupConnectionsTestPlugin(t *testing.T) (*Plugin, *plugintest.API) {
+	api := &plugintest.API{}
+	api.On("LogDebug", mockAnythingOfTypeBatch("string", 11)...).Return().Maybe()
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+	makeTestKVStore(api, nil)
+	return p, api
+}
+
+func TestConnectionUsageTransport(t *testing.T) {
+	p, _ := setupConnectionsTestPlugin(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer valid-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+	ic := &InstanceCommon{Plugin: p, InstanceID: types.ID(ts.URL), Type: ServerInstanceType}
+
+	for name, tc := range map[string]struct {
+		token    string
+		expected bool
+	}{
+		"accepted": {token: "valid-token", expected: true},
+		"rejected": {token: "revoked-token"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mattermostUserID := types.ID("user-" + tc.token)
+			client := ic.withConnectionUsage(&http.Client{}, &Connection{MattermostUserID: mattermostUserID})
+			req, err := http.NewRequest(http.MethodGet, ts.URL, nil)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			lastUsed, err := p.getConnectionLastUsed(ic.InstanceID, mattermostUserID)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, lastUsed != 0)
+		})
+	}
+}
+
+func TestConnectionUsageTrackerThrottles(t *testing.T) {
+	tracker := connectionUsageTracker{}
+	now := time.Now()
+	assert.True(t, tracker.shouldWrite("key", now))
+	assert.False(t, tracker.shouldWrite("key", now.Add(time.Minute)))
+	assert.True(t, tracker.shouldWrite("other", now.Add(time.Minute)))
+	assert.True(t, tracker.shouldWrite("key", now.Add(connectionUsageWriteInterval)))
+
+	tracker.forget("key")
+	assert.True(t, tracker.shouldWrite("key", now.Add(connectionUsageWriteInterval+time.Minute)))
+}
+
+func TestListConnectedUsers(t *testing.T) {
+	p, api := setupConnectionsTestPlugin(t)
+	api.On("GetUser", "user1").Return(&model.User{Id: "user1", Username: "zoe"}, nil)
+	api.On("GetUser", "user2").Return(&model.User{Id: "user2", Username: "adam"}, nil)
+
+	instance := &serverInstance{InstanceCommon: &InstanceCommon{Plugin: p, InstanceID: testInstance1.InstanceID, Type: ServerInstanceType}}
+	connected := NewUser("user1")
+	connected.ConnectedInstances.Set(testInstance1.Common())
+	broken := NewUser("user2")
+	broken.ConnectedInstances.Set(testInstance1.Common())
+	p.userStore = connectedUsersStore{
+		users: []*User{connected, broken, NewUser("user3")},
+		connections: map[types.ID]*Connection{
+			"user1": {User: jira.User{Name: "zoe", DisplayName: "Zoe"}, PersonalAccessToken: "encrypted"},
+			"user2": {User: jira.User{Name: "adam", DisplayName: "Adam"}, Oauth1AccessToken: "token"},
+		},
+	}
+
+	now := time.Now()
+	p.recordConnectionUse(instance.GetID(), "user1", now)
+	require.NoError(t, p.storeTokenHealthReport(instance.GetID(), &tokenHealthReport{
+		CheckedAt: now.UnixMilli(),
+		Broken: map[types.ID]*brokenConnection{
+			"user2": {MattermostUserID: "user2", Status: tokenStatusRejected, Error: "401\nUnauthorized"},
+		},
+	}))
+
+	users, err := p.listConnectedUsers(instance)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+
+	assert.Equal(t, "adam", users[0].MattermostUsername)
+	assert.Equal(t, connectionTypeOAuth1, users[0].ConnectionType)
+	assert.Equal(t, tokenStatusRejected, users[0].TokenStatus)
+	assert.Equal(t, "401 Unauthorized", users[0].TokenError)
+	assert.Zero(t, users[0].LastUsedAt)
+
+	assert.Equal(t, "zoe", users[1].MattermostUsername)
+	assert.Equal(t, connectionTypePAT, users[1].ConnectionType)
+	assert.Equal(t, tokenStatusHealthy, users[1].TokenStatus)
+	assert.Equal(t, now.UnixMilli(), users[1].LastUsedAt)
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, writeConnectedUsersCSV(buf, users))
+	records, err := csv.NewReader(buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "Mattermost user ID", records[0][0])
+	assert.Equal(t, []string{"user2", "adam", "adam", "Adam", "", connectionTypeOAuth1, "", tokenStatusRejected, "401 Unauthorized"}, records[1])
+}
+
+func TestWriteConnectedUsersCSVFormulas(t *testing.T) {
+	users := []*connectedUser{{
+		MattermostUserID:   "user1",
+		MattermostUsername: "-adam",
+		JiraDisplayName:    `=HYPERLINK("http://example.com","Adam")`,
+		JiraEmailAddress:   "@adam",
+		TokenError:         "+1",
+	}}
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, writeConnectedUsersCSV(buf, users))
+	records, err := csv.NewReader(buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, []string{"user1", "'-adam", "", `'=HYPERLINK("http://example.com","Adam")`, "'@adam", "", "", "", "'+1"}, records[1])
+}
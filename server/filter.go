@@ -0,0 +1,288 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+const (
+	filterSearchMaxResults = 20
+
+	// savedFilterOwnerMismatch is the filter mismatch of the saved filter subscriptions whose
+	// owner is no longer connected to Jira.
+	savedFilterOwnerMismatch = "saved filter owner not connected"
+
+	savedFilterOwnerNoticeKeyPrefix = "saved_filter_owner_notice_"
+	savedFilterOwnerNoticeInterval  = 24 * time.Hour
+)
+
+var reFilterID = regexp.MustCompile(`^\d+$`)
+
+// SavedFilterSource makes a channel subscription follow the JQL of a Jira saved filter, as it
+// is edited in Jira.
+type SavedFilterSource struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+
+	// OwnerID is the Mattermost user whose Jira connection evaluates the filter, the user who
+	// last saved the subscription.
+	OwnerID types.ID `json:"owner_id"`
+}
+
+func validateSavedFilterSource(source *SavedFilterSource, client Client) error {
+	if !reFilterID.MatchString(source.ID) {
+		return errors.Errorf("invalid saved filter ID %q", source.ID)
+	}
+	if source.OwnerID == "" {
+		return errors.New("saved filter subscriptions need an owner to run the filter")
+	}
+
+	filter, err := client.GetFilter(source.ID)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to get saved filter %q", source.ID)
+	}
+	source.Name = filter.Name
+	return nil
+}
+
+// matchesSavedFilter returns true if the issue matches the saved filter. The filter is run
+// in Jira with the connection of its owner, so that the subscription follows the edits of
+// the filter. Deleted issues can no longer be searched, so they never match. The result is
+// kept with the event, for the other subscriptions following the filter.
+func (p *Plugin) matchesSavedFilter(wh *webhook, instanceID types.ID, source *SavedFilterSource) (bool, error) {
+	key := source.ID + "/" + source.OwnerID.String()
+	if matched, ok := wh.savedFilterMatches[key]; ok {
+		return matched, nil
+	}
+
+	client, _, _, err := p.getClient(instanceID, source.OwnerID)
+	if err != nil {
+		return false, errors.WithMessagef(err, "failed to load the connection of the owner of saved filter %q", source.ID)
+	}
+
+	found, err := client.SearchIssues(savedFilterIssueJQL(source.ID, wh.Issue.Key), &jira.SearchOptions{
+		MaxResults: 1,
+		Fields:     []string{"key"},
+	})
+	if err != nil {
+		p.client.Log.Warn("Failed to run the saved filter", "filter_id", source.ID, "issue_key", wh.Issue.Key, "error", err.Error())
+		found = nil
+	}
+
+	if wh.savedFilterMatches == nil {
+		wh.savedFilterMatches = map[string]bool{}
+	}
+	wh.savedFilterMatches[key] = len(found) > 0
+	return len(found) > 0, nil
+}
+
+// notifySavedFilterOwnerDisconnected tells a channel that one of its saved filter subscriptions
+// can't run, as the connection of its owner is gone. It is told at most once a day.
+func (p *Plugin) notifySavedFilterOwnerDisconnected(sub ChannelSubscription) {
+	set, err := p.client.KV.Set(savedFilterOwnerNoticeKeyPrefix+sub.ID, true,
+		pluginapi.SetAtomic(nil), pluginapi.SetExpiry(savedFilterOwnerNoticeInterval))
+	if err != nil || !set {
+		return
+	}
+
+	err = p.client.Post.CreatePost(&model.Post{
+		UserId:    p.getUserID(),
+		ChannelId: sub.ChannelID,
+		Message: fmt.Sprintf("Jira subscription, \"%v\", can't send notifications: the user who saved it is no longer connected to Jira, so its saved filter %q can't be run. "+
+			"Please edit and save the subscription again to run the filter with your Jira connection.", sub.Name, sub.Filters.SavedFilter.Name),
+	})
+	if err != nil {
+		p.client.Log.Warn("Failed to notify the channel of the disconnected saved filter owner", "subscription_id", sub.ID, "error", err.Error())
+	}
+}
+
+func savedFilterIssueJQL(filterID, issueKey string) string {
+	return fmt.Sprintf(`filter = %s AND issuekey = "%s"`, filterID, issueKey)
+}
+
+// resolveFilter finds a saved filter by ID, or by name among the filters visible to the user.
+func resolveFilter(client Client, query string) (*jira.Filter, error) {
+	query = strings.TrimSpace(strings.Trim(query, `"“”`))
+	if reFilterID.MatchString(query) {
+		return client.GetFilter(query)
+	}
+
+	filters, err := client.SearchFilters(query, filterSearchMaxResults)
+	if err != nil {
+		return nil, err
+	}
+	for _, filter := range filters {
+		if strings.EqualFold(filter.Name, query) {
+			return filter, nil
+		}
+	}
+
+	switch len(filters) {
+	case 0:
+		return nil, errors.Errorf("no saved filter matches %q", query)
+	case 1:
+		return filters[0], nil
+	}
+
+	names := []string{}
+	for _, filter := range filters {
+		names = append(names, fmt.Sprintf("%s (%s)", filter.Name, filter.ID))
+	}
+	return nil, errors.Errorf("several saved filters match %q, please use one of their IDs: %s", query, strings.Join(names, ", "))
+}
+
+func filterURL(instance Instance, filter *jira.Filter) string {
+	if filter.ViewURL != "" {
+		return filter.ViewURL
+	}
+	return fmt.Sprintf("%s/issues/?filter=%s", instance.GetJiraBaseURL(), filter.ID)
+}
+
+func formatFilters(instance Instance, filters []*jira.Filter) string {
+	if len(filters) == 0 {
+		return "You have no favourite filters in Jira. Use `/jira filter run <name|id>` to run any filter visible to you."
+	}
+
+	text := "| Name | ID | Owner | JQL |\n|:--|:--|:--|:--|\n"
+	for _, filter := range filters {
+		text += fmt.Sprintf("|[%s](%s)|%s|%s|`%s`|\n",
+			strings.ReplaceAll(filter.Name, "|", "\\|"), filterURL(instance, filter), filter.ID, filter.Owner.DisplayName,
+			strings.ReplaceAll(truncate(filter.Jql, maxIssueSummaryLength), "|", "\\|"))
+	}
+	return text
+}
+
+func executeFilterList(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	user, instance, args, err := p.loadFlagUserInstance(header.UserId, args)
+	if err != nil {
+		return p.responsef(header, "Failed to load your connection to Jira. Error: %v.", err)
+	}
+	if len(args) != 0 {
+		return p.help(header)
+	}
+
+	client, _, _, err := p.getClient(instance.GetID(), user.MattermostUserID)
+	if err != nil {
+		return p.responsef(header, "Your username is not connected to Jira. Please type `jira connect`.")
+	}
+
+	filters, err := client.GetFavouriteFilters()
+	if err != nil {
+		return p.responsef(header, "Failed to get your favourite filters. Error: %v.", err)
+	}
+	return p.responsef(header, "#### Favourite filters on %s\n%s", instance.GetID(), formatFilters(instance, filters))
+}
+
+func executeFilterRun(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	user, instance, args, err := p.loadFlagUserInstance(header.UserId, args)
+	if err != nil {
+		return p.responsef(header, "Failed to load your connection to Jira. Error: %v.", err)
+	}
+	if len(args) == 0 {
+		return p.responsef(header, "Please specify a filter in the form `/jira filter run <name|id>`.")
+	}
+
+	client, _, _, err := p.getClient(instance.GetID(), user.MattermostUserID)
+	if err != nil {
+		return p.responsef(header, "Your username is not connected to Jira. Please type `jira connect`.")
+	}
+
+	filter, err := resolveFilter(client, strings.Join(args, " "))
+	if err != nil {
+		return p.responsef(header, "Failed to find the filter. Error: %v.", err)
+	}
+
+	post, err := p.makeFilterRunPost(instance, client, filter, header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "Failed to run the filter %q. Error: %v.", filter.Name, err)
+	}
+
+	post.RootId = header.RootId
+	p.client.Post.SendEphemeralPost(header.UserId, post)
+	return &model.CommandResponse{}
+}
+
+// makeFilterRunPost renders the first page of the issues of a saved filter. It pages like
+// `/jira search`, so the following pages are fetched by httpSearchIssuesPage.
+func (p *Plugin) makeFilterRunPost(instance Instance, client Client, filter *jira.Filter, channelID string) (*model.Post, error) {
+	issues, nextPageToken, err := searchIssuesPage(client, filter.Jql, "")
+	if err != nil {
+		return nil, err
+	}
+
+	post := p.makeSearchResultsPost(instance, channelID, filter.Jql, nil, issues, nextPageToken)
+	post.Message = fmt.Sprintf("Filter [%s](%s): %s", filter.Name, filterURL(instance, filter), post.Message)
+	return post, nil
+}
+
+func executeFilterSubscribe(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	user, instance, args, err := p.loadFlagUserInstance(header.UserId, args)
+	if err != nil {
+		return p.responsef(header, "Failed to load your connection to Jira. Error: %v.", err)
+	}
+	if len(args) == 0 {
+		return p.responsef(header, "Please specify a filter in the form `/jira filter subscribe <name|id>`.")
+	}
+
+	channel, err := p.client.Channel.Get(header.ChannelId)
+	if err != nil {
+		return p.responsef(header, "Failed to get the channel. Error: %v.", err)
+	}
+	if channel.Type == model.ChannelTypeDirect || channel.Type == model.ChannelTypeGroup {
+		return p.responsef(header, "Subscriptions are not allowed in direct message or group message channels.")
+	}
+	if err = p.hasPermissionToManageSubscription(instance.GetID(), header.UserId, header.ChannelId); err != nil {
+		return p.responsef(header, "You don't have permission to manage subscriptions. Error: %v.", err)
+	}
+
+	client, _, connection, err := p.getClient(instance.GetID(), user.MattermostUserID)
+	if err != nil {
+		return p.responsef(header, "Your username is not connected to Jira. Please type `jira connect`.")
+	}
+
+	filter, err := resolveFilter(client, strings.Join(args, " "))
+	if err != nil {
+		return p.responsef(header, "Failed to find the filter. Error: %v.", err)
+	}
+
+	subscription := &ChannelSubscription{
+		ChannelID:  header.ChannelId,
+		Name:       truncate(filter.Name, MaxSubscriptionNameLength),
+		InstanceID: instance.GetID(),
+		Filters: SubscriptionFilters{
+			Events: NewStringSet(eventCreated, eventUpdatedAny),
+			SavedFilter: &SavedFilterSource{
+				ID:      filter.ID,
+				OwnerID: user.MattermostUserID,
+			},
+		},
+	}
+	if err = p.addChannelSubscription(instance.GetID(), connection.JiraAccountID(), subscription, client); err != nil {
+		return p.responsef(header, "Failed to subscribe to the filter. Error: %v.", err)
+	}
+
+	err = p.client.Post.CreatePost(&model.Post{
+		UserId:    p.getUserID(),
+		ChannelId: subscription.ChannelID,
+		Message: fmt.Sprintf("Jira subscription, \"%v\", following the saved filter [%s](%s) was added to this channel by %v",
+			subscription.Name, filter.Name, filterURL(instance, filter), connection.DisplayName),
+	})
+	if err != nil {
+		return p.responsef(header, "Subscribed to the filter, but failed to notify the channel. Error: %v.", err)
+	}
+	return &model.CommandResponse{}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+)
+
+const testFilterID = "10100"
+
+var testFilters = []*jira.Filter{
+	{ID: testFilterID, Name: "Open bugs", Jql: "type = Bug AND resolution IS EMPTY"},
+	{ID: "10101", Name: "Open bugs of the web team", Jql: "type = Bug AND component = Web"},
+	{ID: "10102", Name: "Release blockers", Jql: "priority = Blocker"},
+}
+
+func (client testClient) GetFilter(filterID string) (*jira.Filter, error) {
+	for _, filter := range testFilters {
+		if filter.ID == filterID {
+			return filter, nil
+		}
+	}
+	return nil, errors.Errorf("filter %s not found", filterID)
+}
+
+func (client testClient) SearchFilters(name string, maxResults int) ([]*jira.Filter, error) {
+	found := []*jira.Filter{}
+	for _, filter := range testFilters {
+		if strings.Contains(strings.ToLower(filter.Name), strings.ToLower(name)) {
+			found = append(found, filter)
+		}
+	}
+	return found, nil
+}
+
+func TestResolveFilter(t *testing.T) {
+	for name, tc := range map[string]struct {
+		query        string
+		expectedID   string
+		errorMessage string
+	}{
+		"by ID":                {query: "10102", expectedID: "10102"},
+		"unknown ID":           {query: "999", errorMessage: "filter 999 not found"},
+		"exact name":           {query: "open BUGS", expectedID: testFilterID},
+		"quoted name":          {query: `"Open bugs"`, expectedID: testFilterID},
+		"single partial match": {query: "blockers", expectedID: "10102"},
+		"ambiguous": {
+			query:        "open",
+			errorMessage: `several saved filters match "open", please use one of their IDs: Open bugs (10100), Open bugs of the web team (10101)`,
+		},
+		"no match": {query: "sprint", errorMessage: `no saved filter matches "sprint"`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			filter, err := resolveFilter(testClient{}, tc.query)
+			if tc.errorMessage != "" {
+				require.EqualError(t, err, tc.errorMessage)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedID, filter.ID)
+		})
+	}
+}
+
+func TestSavedFilterIssueJQL(t *testing.T) {
+	assert.Equal(t, `filter = 10100 AND issuekey = "TEST-1"`, savedFilterIssueJQL(testFilterID, "TEST-1"))
+}
+
+func TestMatchesSavedFilter(t *testing.T) {
+	source := &SavedFilterSource{ID: testFilterID, Name: "Open bugs", OwnerID: "owner"}
+
+	t.Run("result of the event is reused", func(t *testing.T) {
+		p := &Plugin{instanceStore: mockInstanceStoreForUtils{err: errors.New("must not be loaded")}}
+		wh := &webhook{
+			JiraWebhook:        &JiraWebhook{Issue: jira.Issue{Key: "TEST-1"}},
+			savedFilterMatches: map[string]bool{testFilterID + "/owner": true},
+		}
+
+		matched, err := p.matchesSavedFilter(wh, testInstance1.InstanceID, source)
+		require.NoError(t, err)
+		assert.True(t, matched)
+	})
+
+	t.Run("owner is not connected", func(t *testing.T) {
+		p := &Plugin{instanceStore: mockInstanceStoreForUtils{err: errors.New("instance not found")}}
+		wh := &webhook{JiraWebhook: &JiraWebhook{Issue: jira.Issue{Key: "TEST-1"}}}
+
+		_, err := p.matchesSavedFilter(wh, testInstance1.InstanceID, source)
+		require.EqualError(t, err, `failed to load the connection of the owner of saved filter "10100": instance not found`)
+	})
+}
+
+func TestNotifySavedFilterOwnerDisconnected(t *testing.T) {
+	api := &plugintest.API{}
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+
+	api.On("KVSetWithOptions", savedFilterOwnerNoticeKeyPrefix+"subscriptionid", mock.Anything, mock.Anything).Return(true, nil).Once()
+	api.On("KVSetWithOptions", savedFilterOwnerNoticeKeyPrefix+"subscriptionid", mock.Anything, mock.Anything).Return(false, nil)
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(&model.Post{}, nil)
+
+	sub := ChannelSubscription{
+		ID:        "subscriptionid",
+		ChannelID: "channelid",
+		Name:      "Bugs",
+		Filters:   SubscriptionFilters{SavedFilter: &SavedFilterSource{ID: testFilterID, Name: "Open bugs"}},
+	}
+	p.notifySavedFilterOwnerDisconnected(sub)
+	p.notifySavedFilterOwnerDisconnected(sub)
+
+	api.AssertNumberOfCalls(t, "CreatePost", 1)
+}
+
+func TestMakeFilterRunPostCloud(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/rest/api/2/search/jql", r.URL.Path)
+		assert.Equal(t, testFilters[0].Jql, r.URL.Query().Get("jql"))
+		_, _ = w.Write([]byte(`{"issues": [{"key": "TEST-1", "fields": {"summary": "Crash"}}], "nextPageToken": "page-2", "isLast": false}`))
+	}))
+	defer ts.Close()
+
+	jiraClient, err := jira.NewClient(ts.Client(), ts.URL)
+	require.NoError(t, err)
+
+	p := &Plugin{}
+	post, err := p.makeFilterRunPost(testInstance1, newCloudClient(jiraClient), testFilters[0], "channel_id")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(post.Message, "Filter [Open bugs]("))
+
+	attachments := post.Attachments()
+	require.Len(t, attachments, 2)
+	next := attachments[1].Actions[0]
+	assert.Equal(t, "Next", next.Name)
+	assert.Equal(t, []string{"page-2"}, next.Integration.Context["page_tokens"])
+}
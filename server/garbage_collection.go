@@ -0,0 +1,440 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/kvstore"
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+const (
+	garbageCollectionJobKey      = "garbage_collection"
+	garbageCollectionJobInterval = 24 * time.Hour
+	garbageCollectionReportKey   = "garbage_collection_report"
+
+	// Archived channels can be restored, so their subscriptions are kept for a while
+	archivedChannelSubscriptionRetention = 30 * 24 * time.Hour
+
+	ticketRootPostIDKeyPrefix           = "ticket_post_id_"
+	ticketRootPostIDKeyChannelSeparator = "_channel_id_"
+)
+
+const (
+	orphanReasonUserDeactivated     = "user deactivated"
+	orphanReasonUserDeleted         = "user deleted"
+	orphanReasonInstanceUninstalled = "instance uninstalled"
+	orphanReasonNotConnected        = "not in the user's connected instances"
+	orphanReasonChannelDeleted      = "channel deleted"
+	orphanReasonChannelArchived     = "channel archived"
+)
+
+type orphanedConnection struct {
+	MattermostUserID types.ID
+	InstanceID       types.ID
+	Reason           string
+}
+
+type orphanedSubscription struct {
+	InstanceID     types.ID
+	SubscriptionID string
+	Name           string
+	ChannelID      string
+	Reason         string
+	Removed        bool
+}
+
+// garbageCollectionReport is the result of the last garbage collection.
+type garbageCollectionReport struct {
+	StartedAt         int64
+	FinishedAt        int64
+	Connections       []orphanedConnection
+	Subscriptions     []orphanedSubscription
+	ThreadKeysRemoved int
+	Errors            []string `json:",omitempty"`
+}
+
+func (r *garbageCollectionReport) addError(err error) {
+	r.Errors = append(r.Errors, err.Error())
+}
+
+// UserHasBeenDeactivated disconnects the deactivated users from all the Jira instances, so
+// that they stop receiving notifications and their DM subscriptions are removed.
+func (p *Plugin) UserHasBeenDeactivated(c *plugin.Context, user *model.User) {
+	disconnected, err := p.disconnectDeactivatedUser(types.ID(user.Id))
+	if err != nil {
+		p.client.Log.Warn("Failed to disconnect the deactivated user", "mattermostUserID", user.Id, "error", err.Error())
+		return
+	}
+	if len(disconnected) > 0 {
+		p.client.Log.Info("Disconnected the deactivated user from Jira", "mattermostUserID", user.Id, "instances", disconnected)
+	}
+}
+
+// disconnectDeactivatedUser disconnects the user from all the instances, and returns the IDs of
+// the instances the user was connected to.
+func (p *Plugin) disconnectDeactivatedUser(mattermostUserID types.ID) ([]types.ID, error) {
+	user, err := p.userStore.LoadUser(mattermostUserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.ConnectedInstances == nil {
+		return nil, nil
+	}
+
+	disconnected := []types.ID{}
+	for _, instanceID := range user.ConnectedInstances.IDs() {
+		instance, err := p.instanceStore.LoadInstance(instanceID)
+		if err != nil {
+			if errors.Cause(err) != kvstore.ErrNotFound {
+				return disconnected, err
+			}
+			// The instance was uninstalled without disconnecting the user
+			if err = p.removeOrphanedConnection(user, instanceID); err != nil {
+				return disconnected, err
+			}
+			continue
+		}
+		if _, err = p.disconnectUser(instance, user); err != nil {
+			return disconnected, err
+		}
+		disconnected = append(disconnected, instanceID)
+	}
+	return disconnected, nil
+}
+
+// removeOrphanedConnection removes a connection to an instance that is no longer installed.
+func (p *Plugin) removeOrphanedConnection(user *User, instanceID types.ID) error {
+	err := p.userStore.DeleteConnection(instanceID, user.MattermostUserID)
+	if err != nil && errors.Cause(err) != kvstore.ErrNotFound {
+		return err
+	}
+	p.deleteConnectionLastUsed(instanceID, user.MattermostUserID)
+	if !user.ConnectedInstances.Contains(instanceID) {
+		return nil
+	}
+	user.ConnectedInstances.Delete(instanceID)
+	if user.DefaultInstanceID == instanceID {
+		user.DefaultInstanceID = ""
+	}
+	return p.userStore.StoreUser(user)
+}
+
+// channelStates caches the channels looked up during a garbage collection. A nil channel was
+// not found.
+type channelStates map[string]*model.Channel
+
+func (p *Plugin) lookupChannel(states channelStates, channelID string) (*model.Channel, error) {
+	if channel, ok := states[channelID]; ok {
+		return channel, nil
+	}
+	channel, err := p.client.Channel.Get(channelID)
+	if err != nil {
+		if err != pluginapi.ErrNotFound {
+			return nil, err
+		}
+		channel = nil
+	}
+	states[channelID] = channel
+	return channel, nil
+}
+
+// collectGarbage periodically removes the data left behind by deactivated users, uninstalled
+// instances and deleted channels, and stores a report for the system administrators.
+func (p *Plugin) collectGarbage() {
+	report := &garbageCollectionReport{StartedAt: model.GetMillis()}
+
+	instances, err := p.instanceStore.LoadInstances()
+	if err != nil {
+		p.client.Log.Warn("Failed to load instances to collect garbage", "error", err.Error())
+		return
+	}
+
+	channels := channelStates{}
+	p.collectOrphanedConnections(instances, report)
+	p.collectOrphanedSubscriptions(instances, channels, report)
+	p.collectOrphanedThreadKeys(channels, report)
+
+	report.FinishedAt = model.GetMillis()
+	if _, err = p.client.KV.Set(garbageCollectionReportKey, report); err != nil {
+		p.client.Log.Warn("Failed to store the garbage collection report", "error", err.Error())
+	}
+
+	removedSubscriptions := 0
+	for _, sub := range report.Subscriptions {
+		if sub.Removed {
+			removedSubscriptions++
+		}
+	}
+	p.client.Log.Info("Collected the Jira plugin garbage",
+		"connections", len(report.Connections),
+		"subscriptions", removedSubscriptions,
+		"threadKeys", report.ThreadKeysRemoved,
+		"errors", len(report.Errors))
+}
+
+func (p *Plugin) collectOrphanedConnections(instances *Instances, report *garbageCollectionReport) {
+	// Users are processed after listing them, as deleting keys shifts the pages of keys
+	users := []*User{}
+	err := p.userStore.MapUsers(func(user *User) error {
+		users = append(users, user)
+		return nil
+	})
+	if err != nil {
+		report.addError(errors.WithMessage(err, "failed to list the users"))
+		return
+	}
+
+	for _, user := range users {
+		if user.ConnectedInstances == nil {
+			user.ConnectedInstances = NewInstances()
+		}
+
+		mmuser, err := p.client.User.Get(user.MattermostUserID.String())
+		reason := ""
+		switch {
+		case err == pluginapi.ErrNotFound:
+			reason = orphanReasonUserDeleted
+		case err != nil:
+			report.addError(errors.WithMessagef(err, "failed to load the user %s", user.MattermostUserID))
+			continue
+		case mmuser.DeleteAt > 0:
+			reason = orphanReasonUserDeactivated
+		}
+
+		if reason != "" {
+			connectedInstanceIDs := user.ConnectedInstances.IDs()
+			if _, err = p.disconnectDeactivatedUser(user.MattermostUserID); err != nil {
+				report.addError(errors.WithMessagef(err, "failed to disconnect the user %s", user.MattermostUserID))
+				continue
+			}
+			for _, instanceID := range connectedInstanceIDs {
+				report.Connections = append(report.Connections, orphanedConnection{user.MattermostUserID, instanceID, reason})
+			}
+			continue
+		}
+
+		for _, instanceID := range user.ConnectedInstances.IDs() {
+			if instances.Contains(instanceID) {
+				continue
+			}
+			if err = p.removeOrphanedConnection(user, instanceID); err != nil {
+				report.addError(errors.WithMessagef(err, "failed to remove the connection of %s to %s", user.MattermostUserID, instanceID))
+				continue
+			}
+			report.Connections = append(report.Connections, orphanedConnection{user.MattermostUserID, instanceID, orphanReasonInstanceUninstalled})
+		}
+
+		// A connection that is not listed in the user's connected instances still maps the
+		// Jira user to the Mattermost user
+		for _, instanceID := range instances.IDs() {
+			if user.ConnectedInstances.Contains(instanceID) {
+				continue
+			}
+			if _, err = p.userStore.LoadConnection(instanceID, user.MattermostUserID); err != nil {
+				continue
+			}
+			if err = p.removeOrphanedConnection(user, instanceID); err != nil {
+				report.addError(errors.WithMessagef(err, "failed to remove the connection of %s to %s", user.MattermostUserID, instanceID))
+				continue
+			}
+			report.Connections = append(report.Connections, orphanedConnection{user.MattermostUserID, instanceID, orphanReasonNotConnected})
+		}
+	}
+}
+
+func (p *Plugin) collectOrphanedSubscriptions(instances *Instances, channels channelStates, report *garbageCollectionReport) {
+	now := time.Now()
+	for _, instanceID := range instances.IDs() {
+		subs, err := p.getSubscriptions(instanceID)
+		if err != nil {
+			report.addError(errors.WithMessagef(err, "failed to load the subscriptions of %s", instanceID))
+			continue
+		}
+
+		for channelID, subIDs := range subs.Channel.IDByChannelID {
+			channel, err := p.lookupChannel(channels, channelID)
+			if err != nil {
+				report.addError(errors.WithMessagef(err, "failed to load the channel %s", channelID))
+				continue
+			}
+
+			reason, remove := "", false
+			switch {
+			case channel == nil:
+				reason, remove = orphanReasonChannelDeleted, true
+			case channel.DeleteAt > 0:
+				reason = orphanReasonChannelArchived
+				remove = now.Sub(time.UnixMilli(channel.DeleteAt)) > archivedChannelSubscriptionRetention
+			default:
+				continue
+			}
+
+			if remove {
+				if err = p.removeSubscriptionsForChannel(instanceID, channelID); err != nil {
+					report.addError(errors.WithMessagef(err, "failed to remove the subscriptions of the channel %s", channelID))
+					remove = false
+				}
+			}
+			for _, subID := range subIDs.Elems() {
+				report.Subscriptions = append(report.Subscriptions, orphanedSubscription{
+					InstanceID:     instanceID,
+					SubscriptionID: subID,
+					Name:           subs.Channel.ByID[subID].Name,
+					ChannelID:      channelID,
+					Reason:         reason,
+					Removed:        remove,
+				})
+			}
+		}
+	}
+}
+
+// collectOrphanedThreadKeys removes the root posts of the Jira issues stored for the channels
+// and posts that no longer exist.
+func (p *Plugin) collectOrphanedThreadKeys(channels channelStates, report *garbageCollectionReport) {
+	orphaned := []string{}
+	for i := 0; ; i++ {
+		keys, err := p.client.KV.ListKeys(i, listPerPage)
+		if err != nil {
+			report.addError(errors.WithMessage(err, "failed to list the keys"))
+			return
+		}
+
+		for _, key := range keys {
+			if !strings.HasPrefix(key, ticketRootPostIDKeyPrefix) {
+				continue
+			}
+			index := strings.LastIndex(key, ticketRootPostIDKeyChannelSeparator)
+			if index < 0 {
+				continue
+			}
+			channel, err := p.lookupChannel(channels, key[index+len(ticketRootPostIDKeyChannelSeparator):])
+			if err != nil {
+				report.addError(errors.WithMessagef(err, "failed to load the channel of %s", key))
+				continue
+			}
+			if channel == nil || channel.DeleteAt > 0 {
+				orphaned = append(orphaned, key)
+				continue
+			}
+
+			var rootID string
+			if err = p.client.KV.Get(key, &rootID); err != nil || rootID == "" {
+				continue
+			}
+			post, err := p.client.Post.GetPost(rootID)
+			if err == pluginapi.ErrNotFound || (err == nil && post.DeleteAt > 0) {
+				orphaned = append(orphaned, key)
+			}
+		}
+
+		if len(keys) < listPerPage {
+			break
+		}
+	}
+
+	for _, key := range orphaned {
+		if err := p.client.KV.Delete(key); err != nil {
+			report.addError(errors.WithMessagef(err, "failed to delete %s", key))
+			continue
+		}
+		report.ThreadKeysRemoved++
+	}
+}
+
+func (p *Plugin) formatGarbageCollectionReport(report *garbageCollectionReport) string {
+	text := "#### Cleanup of the orphaned Jira data\n"
+	if report.StartedAt == 0 {
+		return text + "The cleanup has not run yet.\n"
+	}
+
+	removed, kept := 0, 0
+	for _, sub := range report.Subscriptions {
+		if sub.Removed {
+			removed++
+		} else {
+			kept++
+		}
+	}
+	text += fmt.Sprintf("Last run at %s: %v connections removed, %v subscriptions removed, %v subscriptions of archived channels kept, %v issue threads forgotten.\n",
+		formatMillis(report.StartedAt), len(report.Connections), removed, kept, report.ThreadKeysRemoved)
+
+	if len(report.Connections) > 0 {
+		text += "\n| User | Jira instance | Reason |\n|---|---|---|\n"
+		for _, c := range report.Connections {
+			username := c.MattermostUserID.String()
+			if mmuser, err := p.client.User.Get(username); err == nil {
+				username = "@" + mmuser.Username
+			}
+			text += fmt.Sprintf("| %s | %s | %s |\n", username, c.InstanceID, c.Reason)
+		}
+	}
+
+	if len(report.Subscriptions) > 0 {
+		text += "\n| Subscription | Jira instance | Channel | Reason | Removed |\n|---|---|---|---|---|\n"
+		for _, s := range report.Subscriptions {
+			name := s.Name
+			if name == "" {
+				name = s.SubscriptionID
+			}
+			removed := "no"
+			if s.Removed {
+				removed = "yes"
+			}
+			text += fmt.Sprintf("| %s | %s | %s | %s | %s |\n", name, s.InstanceID, s.ChannelID, s.Reason, removed)
+		}
+	}
+
+	if len(report.Errors) > 0 {
+		text += "\nErrors:\n"
+		for _, e := range report.Errors {
+			text += fmt.Sprintf("* %s\n", e)
+		}
+	}
+	return text
+}
+
+func executeInstanceCleanupStatus(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira instance cleanup status` can only be run by a system administrator.")
+	}
+	if len(args) != 0 {
+		return p.responsef(header, "Please use `/jira instance cleanup status`.")
+	}
+
+	report := &garbageCollectionReport{}
+	if err = p.client.KV.Get(garbageCollectionReportKey, report); err != nil {
+		return p.responsef(header, "Failed to load the cleanup report. Error: %v.", err)
+	}
+	return p.response(header, p.formatGarbageCollectionReport(report))
+}
+
+func executeInstanceCleanupRun(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira instance cleanup run` can only be run by a system administrator.")
+	}
+	if len(args) != 0 {
+		return p.responsef(header, "Please use `/jira instance cleanup run`.")
+	}
+
+	go p.collectGarbage()
+	return p.responsef(header, "Started the cleanup of the orphaned Jira data. Use `/jira instance cleanup status` to see the report.")
+}
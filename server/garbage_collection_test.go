@@ -0,0 +1,93 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupGarbageCollectionTestPlugin(t *testing.T) (*Plugin, *plugintest.API, testKVStore) {
+	api := &plugintest.API{}
+	api.On("LogDebug", mockAnythingOfTypeBatch("string", 11)...).Return().Maybe()
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+	store := makeTestKVStore(api, nil)
+
+	notFound := model.NewAppError("GetChannel", "app.channel.get.existing.app_error", nil, "", http.StatusNotFound)
+	now := time.Now()
+	api.On("GetChannel", "deleted").Return(nil, notFound)
+	api.On("GetChannel", "archived-long-ago").Return(&model.Channel{Id: "archived-long-ago", DeleteAt: now.Add(-2 * archivedChannelSubscriptionRetention).UnixMilli()}, nil)
+	api.On("GetChannel", "archived-recently").Return(&model.Channel{Id: "archived-recently", DeleteAt: now.Add(-time.Hour).UnixMilli()}, nil)
+	api.On("GetChannel", "active").Return(&model.Channel{Id: "active"}, nil)
+	return p, api, store
+}
+
+func TestCollectOrphanedSubscriptions(t *testing.T) {
+	p, _, store := setupGarbageCollectionTestPlugin(t)
+
+	subs := withExistingChannelSubscriptions([]ChannelSubscription{
+		{ID: "sub1", ChannelID: "deleted", Name: "Deleted"},
+		{ID: "sub2", ChannelID: "archived-long-ago"},
+		{ID: "sub3", ChannelID: "archived-recently"},
+		{ID: "sub4", ChannelID: "active"},
+	})
+	data, err := json.Marshal(subs)
+	require.NoError(t, err)
+	store[keyWithInstanceID(testInstance1.InstanceID, JiraSubscriptionsKey)] = data
+
+	instances := NewInstances()
+	instances.Set(testInstance1.Common())
+	report := &garbageCollectionReport{}
+	p.collectOrphanedSubscriptions(instances, channelStates{}, report)
+	require.Empty(t, report.Errors)
+
+	byID := map[string]orphanedSubscription{}
+	for _, sub := range report.Subscriptions {
+		byID[sub.SubscriptionID] = sub
+	}
+	require.Len(t, byID, 3)
+	assert.Equal(t, orphanedSubscription{testInstance1.InstanceID, "sub1", "Deleted", "deleted", orphanReasonChannelDeleted, true}, byID["sub1"])
+	assert.True(t, byID["sub2"].Removed)
+	assert.Equal(t, orphanReasonChannelArchived, byID["sub3"].Reason)
+	assert.False(t, byID["sub3"].Removed)
+
+	remaining, err := p.getSubscriptions(testInstance1.InstanceID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"sub3", "sub4"}, remaining.Channel.IDByChannelID["archived-recently"].Add(remaining.Channel.IDByChannelID["active"].Elems()...).Elems())
+}
+
+func TestCollectOrphanedThreadKeys(t *testing.T) {
+	p, api, store := setupGarbageCollectionTestPlugin(t)
+	store["ticket_post_id_1_channel_id_deleted"] = []byte(`"post1"`)
+	store["ticket_post_id_2_channel_id_active"] = []byte(`"post2"`)
+	store["ticket_post_id_3_channel_id_active"] = []byte(`"post3"`)
+	api.On("KVList", 0, listPerPage).Return([]string{
+		"ticket_post_id_1_channel_id_deleted",
+		"ticket_post_id_2_channel_id_active",
+		"ticket_post_id_3_channel_id_active",
+		"token_health",
+	}, nil)
+	api.On("GetPost", "post2").Return(&model.Post{Id: "post2", DeleteAt: 1}, nil)
+	api.On("GetPost", "post3").Return(&model.Post{Id: "post3"}, nil)
+
+	report := &garbageCollectionReport{}
+	p.collectOrphanedThreadKeys(channelStates{}, report)
+	require.Empty(t, report.Errors)
+	assert.Equal(t, 2, report.ThreadKeysRemoved)
+
+	// Deleted keys are set to nil
+	assert.Nil(t, store["ticket_post_id_1_channel_id_deleted"])
+	assert.Nil(t, store["ticket_post_id_2_channel_id_active"])
+	assert.NotNil(t, store["ticket_post_id_3_channel_id_active"])
+}
@@ -35,6 +35,7 @@ const (
 	routeAutocompleteUserInstance               = "/user-instance"
 	routeAutocompleteInstalledInstance          = "/installed-instance"
 	routeAutocompleteInstalledInstanceWithAlias = "/installed-instance-with-alias"
+	routeAutocompleteIssueLinkTypes             = "/issue-link-types"
 	routeAPI                                    = "/api/v2"
 	routeInstancePath                           = "/instance/{id}"
 	routeAPICreateIssue                         = "/create-issue"
@@ -52,6 +53,9 @@ const (
 	routeAPISubscriptionTemplatesWithID         = routeAPISubscriptionTemplates + "/{id:[A-Za-z0-9]+}"
 	routeAPISettingsInfo                        = "/settingsinfo"
 	routeIssueTransition                        = "/transition"
+	routeIssueTransitionDialog                  = "/transition/dialog"
+	routeIssueCommentDialogOpen                 = "/comment-issue"
+	routeIssueCommentDialog                     = "/comment-issue/dialog"
 	routeAPIUserDisconnect                      = "/api/v3/disconnect"
 	routeACInstalled                            = "/ac/installed"
 	routeACJSON                                 = "/ac/atlassian-connect.json"
@@ -62,11 +66,14 @@ const (
 	routeACUserDisconnected                     = "/ac/user_disconnected.html"
 	routeIncomingWebhook                        = "/webhook"
 	routeOAuth1Complete                         = "/oauth1/complete.html"
+	routeUserConnectPAT                         = "/pat/connect.html"
 	routeUserStart                              = "/user/start"
 	routeUserConnect                            = "/user/connect"
 	routeUserDisconnect                         = "/user/disconnect"
 	routeGetIssueByKey                          = "/get-issue-by-key"
 	routeSharePublicly                          = "/share-issue-publicly"
+	routeSearchIssuesPage                       = "/search-issues/page"
+	routeSearchIssueView                        = "/search-issues/view"
 	routeOAuth2Complete                         = "/oauth2/complete.html"
 )
 
@@ -102,6 +109,7 @@ func (p *Plugin) initializeRouter() {
 	autocompleteRouter.HandleFunc(routeAutocompleteUserInstance, p.checkAuth(p.handleResponse(p.httpAutocompleteUserInstance))).Methods(http.MethodGet)
 	autocompleteRouter.HandleFunc(routeAutocompleteInstalledInstance, p.checkAuth(p.handleResponse(p.httpAutocompleteInstalledInstance))).Methods(http.MethodGet)
 	autocompleteRouter.HandleFunc(routeAutocompleteInstalledInstanceWithAlias, p.checkAuth(p.handleResponse(p.httpAutocompleteInstalledInstanceWithAlias))).Methods(http.MethodGet)
+	autocompleteRouter.HandleFunc(routeAutocompleteIssueLinkTypes, p.checkAuth(p.handleResponse(p.httpAutocompleteIssueLinkTypes))).Methods(http.MethodGet)
 
 	apiRouter := p.router.PathPrefix(routeAPI).Subrouter()
 
@@ -118,13 +126,22 @@ func (p *Plugin) initializeRouter() {
 	apiRouter.HandleFunc(routeAPIGetSearchUsers, p.checkAuth(p.handleResponse(p.httpGetSearchUsers))).Methods(http.MethodGet)
 	apiRouter.HandleFunc(routeAPIAttachCommentToIssue, p.checkAuth(p.handleResponse(p.httpAttachCommentToIssue))).Methods(http.MethodPost)
 	apiRouter.HandleFunc(routeIssueTransition, p.handleResponse(p.httpTransitionIssuePostAction)).Methods(http.MethodPost)
+	apiRouter.HandleFunc(routeIssueTransitionDialog, p.checkAuth(p.handleResponse(p.httpSubmitTransitionDialog))).Methods(http.MethodPost)
+	apiRouter.HandleFunc(routeIssueCommentDialogOpen, p.handleResponse(p.httpOpenCommentDialog)).Methods(http.MethodPost)
+	apiRouter.HandleFunc(routeIssueCommentDialog, p.checkAuth(p.handleResponse(p.httpSubmitCommentDialog))).Methods(http.MethodPost)
 	apiRouter.HandleFunc(routeSharePublicly, p.handleResponse(p.httpShareIssuePublicly)).Methods(http.MethodPost)
+	apiRouter.HandleFunc(routeSearchIssuesPage, p.handleResponse(p.httpSearchIssuesPage)).Methods(http.MethodPost)
+	apiRouter.HandleFunc(routeSearchIssueView, p.handleResponse(p.httpSearchIssueView)).Methods(http.MethodPost)
 	apiRouter.HandleFunc(routeGetIssueByKey, p.handleResponse(p.httpGetIssueByKey)).Methods(http.MethodGet)
 
 	// User APIs
 	apiRouter.HandleFunc(routeAPIUserInfo, p.checkAuth(p.handleResponse(p.httpGetUserInfo))).Methods(http.MethodGet)
 	apiRouter.HandleFunc(routeAPISettingsInfo, p.checkAuth(p.handleResponse(p.httpGetSettingsInfo))).Methods(http.MethodGet)
 
+	// Admin APIs
+	apiRouter.HandleFunc(routeAPIAdminConnections, p.checkAuth(p.handleResponse(p.httpGetAdminConnections))).Methods(http.MethodGet)
+	apiRouter.HandleFunc(routeAPIAdminConnectionsDisconnect, p.checkAuth(p.handleResponse(p.httpAdminDisconnectUser))).Methods(http.MethodPost)
+
 	// Atlassian Connect application
 	instanceRouter.HandleFunc(routeACJSON, p.handleResponseWithCallbackInstance(p.httpACJSON)).Methods(http.MethodGet)
 	// Do not use handleResponseWithCallbackInstance: ResolveWebhookInstanceURL errors
@@ -143,6 +160,10 @@ func (p *Plugin) initializeRouter() {
 	instanceRouter.HandleFunc(routeOAuth1Complete, p.checkAuth(p.handleResponseWithCallbackInstance(p.httpOAuth1aComplete))).Methods(http.MethodGet)
 	instanceRouter.HandleFunc(routeUserDisconnect, p.checkAuth(p.handleResponseWithCallbackInstance(p.httpOAuth1aDisconnect))).Methods(http.MethodPost)
 
+	// Personal Access Tokens (Jira Data Center)
+	instanceRouter.HandleFunc(routeUserConnectPAT, p.checkAuth(p.handleResponseWithCallbackInstance(p.httpPATConnectForm))).Methods(http.MethodGet)
+	instanceRouter.HandleFunc(routeUserConnectPAT, p.checkAuth(p.handleResponseWithCallbackInstance(p.httpPATConnect))).Methods(http.MethodPost)
+
 	// OAuth2 (Jira Cloud)
 	instanceRouter.HandleFunc(routeOAuth2Complete, p.handleResponseWithCallbackInstance(p.httpOAuth2Complete)).Methods(http.MethodGet)
 
@@ -12,9 +12,10 @@ import (
 type InstanceType string
 
 const (
-	CloudInstanceType      = InstanceType("cloud")
-	ServerInstanceType     = InstanceType("server")
-	CloudOAuthInstanceType = InstanceType("cloud-oauth")
+	CloudInstanceType       = InstanceType("cloud")
+	ServerInstanceType      = InstanceType("server")
+	CloudOAuthInstanceType  = InstanceType("cloud-oauth")
+	ServerOAuthInstanceType = InstanceType("server-oauth")
 )
 
 type Instance interface {
@@ -42,6 +43,14 @@ type InstanceCommon struct {
 	IsV2Legacy bool
 
 	SetupWizardUserID string
+
+	// HTTP configures the outbound requests to the instance, the default transport is used if nil
+	HTTP *InstanceHTTPConfig `json:",omitempty"`
+
+	// backgroundJob is set on the instances loaded by the background jobs. Their requests
+	// have a lower priority, and the users whose tokens expired are not disconnected, so
+	// that a check does not act on behalf of the users.
+	backgroundJob bool
 }
 
 func newInstanceCommon(p *Plugin, instanceType InstanceType, instanceID types.ID) *InstanceCommon {
@@ -72,3 +81,7 @@ func (ic *InstanceCommon) Common() *InstanceCommon {
 func (ic InstanceCommon) IsCloudInstance() bool {
 	return ic.Type == CloudInstanceType || ic.Type == CloudOAuthInstanceType
 }
+
+func (ic InstanceCommon) IsServerInstance() bool {
+	return ic.Type == ServerInstanceType || ic.Type == ServerOAuthInstanceType
+}
@@ -230,11 +230,11 @@ func (ci *cloudInstance) getClientForConnection(connection *Connection) (*jira.C
 		},
 	}
 
-	conf := ci.getConfig()
-	httpClient := oauth2Conf.Client(context.Background())
-	httpClient = utils.WrapHTTPClient(httpClient,
-		utils.WithRequestSizeLimit(conf.maxAttachmentSize),
-		utils.WithResponseSizeLimit(conf.maxAttachmentSize))
+	ctx, err := ci.httpContext(context.Background())
+	if err != nil {
+		return nil, nil, err
+	}
+	httpClient := ci.withConnectionUsage(ci.wrapHTTPClient(oauth2Conf.Client(ctx)), connection)
 
 	jiraClient, err := jira.NewClient(httpClient, oauth2Conf.BaseURL)
 	return jiraClient, httpClient, err
@@ -242,7 +242,10 @@ func (ci *cloudInstance) getClientForConnection(connection *Connection) (*jira.C
 
 // Creates a "bot" client with a JWT
 func (ci *cloudInstance) getClientForBot() (*jira.Client, error) {
-	conf := ci.getConfig()
+	transport, err := ci.httpTransport()
+	if err != nil {
+		return nil, err
+	}
 	jwtConf := &ajwt.Config{
 		Key:          ci.AtlassianSecurityContext.Key,
 		ClientKey:    ci.AtlassianSecurityContext.ClientKey,
@@ -250,12 +253,11 @@ func (ci *cloudInstance) getClientForBot() (*jira.Client, error) {
 		BaseURL:      ci.AtlassianSecurityContext.BaseURL,
 	}
 
-	httpClient := jwtConf.Client()
-	httpClient = utils.WrapHTTPClient(httpClient,
-		utils.WithRequestSizeLimit(conf.maxAttachmentSize),
-		utils.WithResponseSizeLimit(conf.maxAttachmentSize))
+	httpClient := &http.Client{
+		Transport: &ajwt.Transport{Config: jwtConf, Base: transport},
+	}
 
-	return jira.NewClient(httpClient, jwtConf.BaseURL)
+	return jira.NewClient(ci.wrapHTTPClient(httpClient), jwtConf.BaseURL)
 }
 
 func (ci *cloudInstance) parseHTTPRequestJWT(r *http.Request) (*jwt.Token, string, error) {
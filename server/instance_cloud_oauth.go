@@ -4,7 +4,6 @@
 package main
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -133,7 +132,6 @@ func (ci *cloudOAuthInstance) GetClient(connection *Connection) (Client, error)
 
 func (ci *cloudOAuthInstance) getClientForConnection(connection *Connection) (*jira.Client, *http.Client, error) {
 	oauth2Conf := ci.GetOAuthConfig()
-	ctx := context.Background()
 
 	// Checking if this user's connection is for a JWT instance
 	if connection.OAuth2Token == nil {
@@ -145,41 +143,32 @@ func (ci *cloudOAuthInstance) getClientForConnection(connection *Connection) (*j
 		return nil, nil, errors.New("failed to create client for OAuth instance: no JWT instance found, and connection's OAuth token is missing")
 	}
 
-	tokenSource := oauth2Conf.TokenSource(ctx, connection.OAuth2Token)
-	client := oauth2.NewClient(ctx, tokenSource)
-
-	// Get a new token, if Access Token has expired
-	currentToken := connection.OAuth2Token
-	updatedToken, err := tokenSource.Token()
+	client, err := ci.Plugin.getOAuth2HTTPClient(ci, oauth2Conf, connection)
 	if err != nil {
-		// Disconnect the user and notify them to reconnect their account
-		if strings.Contains(err.Error(), "invalid_grant") {
-			ci.Plugin.disconnectUserDueToExpiredToken(connection.MattermostUserID, ci.GetID())
-			return nil, nil, errors.Wrap(err, "your Jira token has expired, please use `/jira connect` to reconnect your account")
-		}
-		return nil, nil, errors.Wrap(err, "failed to get a new refreshed token for the user")
+		return nil, nil, err
 	}
 
-	if updatedToken.RefreshToken != currentToken.RefreshToken {
-		connection.OAuth2Token = updatedToken
-
-		// Store this new access token & refresh token to get a new access token in future when it has expired
-		if err = ci.Plugin.userStore.StoreConnection(ci.Common().InstanceID, connection.MattermostUserID, connection); err != nil {
+	// The resource ID is looked up once and then persisted with the instance
+	if ci.JiraResourceID == "" {
+		jiraID, err := ci.getJiraCloudResourceID(*client)
+		if err != nil {
+			if errors.Is(err, errTokenExpired) {
+				ci.Plugin.oauth2Clients.invalidate(ci.GetID(), connection.MattermostUserID)
+				if !ci.backgroundJob {
+					ci.Plugin.disconnectUserDueToExpiredToken(connection.MattermostUserID, ci.GetID())
+				}
+				return nil, nil, errors.Wrap(errTokenExpired, "your Jira token has expired, please use `/jira connect` to reconnect your account")
+			}
 			return nil, nil, err
 		}
-	}
 
-	// TODO: Get resource ID if not in the KV Store?
-	jiraID, err := ci.getJiraCloudResourceID(*client)
-	ci.JiraResourceID = jiraID
-	if err != nil {
-		if errors.Is(err, errTokenExpired) {
-			ci.Plugin.disconnectUserDueToExpiredToken(connection.MattermostUserID, ci.GetID())
-			return nil, nil, errors.New("your Jira token has expired, please use `/jira connect` to reconnect your account")
+		ci.JiraResourceID = jiraID
+		if err = ci.Plugin.instanceStore.StoreInstance(ci); err != nil {
+			ci.Plugin.client.Log.Warn("Failed to store the Jira Cloud resource ID", "instanceID", ci.GetID().String(), "error", err.Error())
 		}
-		return nil, nil, err
 	}
 
+	client = ci.withConnectionUsage(ci.withPriority(client), connection)
 	jiraClient, err := jira.NewClient(client, ci.GetURL())
 	return jiraClient, client, err
 }
@@ -221,6 +210,10 @@ func (ci *cloudOAuthInstance) GetOAuthConfig() *oauth2.Config {
 	}
 }
 
+func (ci *cloudOAuthInstance) GetCodeVerifier() string {
+	return ci.CodeVerifier
+}
+
 func (ci *cloudOAuthInstance) GetURL() string {
 	return "https://api.atlassian.com/ex/jira/" + ci.JiraResourceID
 }
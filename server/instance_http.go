@@ -0,0 +1,616 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dghubble/oauth1"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils"
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/kvstore"
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+const (
+	httpSettingProxy       = "proxy"
+	httpSettingCA          = "ca"
+	httpSettingCert        = "cert"
+	httpSettingKey         = "key"
+	httpSettingTimeout     = "timeout"
+	httpSettingRetries     = "retries"
+	httpSettingConcurrency = "concurrency"
+	httpSettingQPS         = "qps"
+
+	maxHTTPTimeoutSeconds = 600
+	maxHTTPRetries        = 5
+
+	// Jira Cloud rate limits bursts of requests, which are retried when idempotent
+	httpRateLimitRetries = 3
+	httpRetryBaseDelay   = 500 * time.Millisecond
+	httpRetryMaxDelay    = 30 * time.Second
+
+	defaultMaxConcurrentRequests = 10
+	defaultRequestsPerSecond     = 20
+
+	// defaultHTTPTimeout is the timeout of the requests when the instance does not configure
+	// one, so that a Jira server that does not respond does not hold the plugin forever.
+	defaultHTTPTimeout = 60 * time.Second
+
+	// httpBudgetMaxWait is how long a request waits for the budget of the instance, before
+	// failing instead of piling up behind the others.
+	httpBudgetMaxWait = 15 * time.Second
+
+	// adminAPITokenRequestTimeout is the timeout of the admin API token requests when the
+	// instance does not configure one.
+	adminAPITokenRequestTimeout = 30 * time.Second
+
+	// pendingHTTPConfigExpiry is how long the HTTP settings of a Jira URL are kept for the
+	// instance to be installed.
+	pendingHTTPConfigExpiry = 24 * time.Hour
+)
+
+var pemBlockRegexp = regexp.MustCompile(`-----BEGIN ([A-Z0-9 ]+)-----([A-Za-z0-9+/=\s]*)-----END ([A-Z0-9 ]+)-----`)
+
+// InstanceHTTPConfig configures the outbound HTTP requests to a Jira instance, for instances
+// only reachable through a proxy, using a private CA, or requiring client certificates. The
+// zero value uses the default transport.
+type InstanceHTTPConfig struct {
+	ProxyURL          string `json:",omitempty"`
+	CACertificates    string `json:",omitempty"`
+	ClientCertificate string `json:",omitempty"`
+	ClientKey         string `json:",omitempty"`
+	TimeoutSeconds    int    `json:",omitempty"`
+	MaxRetries        int    `json:",omitempty"`
+
+	// The budget of requests to the instance, the defaults are used if zero
+	MaxConcurrentRequests int `json:",omitempty"`
+	RequestsPerSecond     int `json:",omitempty"`
+}
+
+func (c *InstanceHTTPConfig) IsEmpty() bool {
+	return c == nil || *c == InstanceHTTPConfig{}
+}
+
+func (c *InstanceHTTPConfig) timeout() time.Duration {
+	if c == nil || c.TimeoutSeconds <= 0 {
+		return defaultHTTPTimeout
+	}
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+func (c InstanceHTTPConfig) maxConcurrentRequests() int {
+	if c.MaxConcurrentRequests > 0 {
+		return c.MaxConcurrentRequests
+	}
+	return defaultMaxConcurrentRequests
+}
+
+func (c InstanceHTTPConfig) requestsPerSecond() int {
+	if c.RequestsPerSecond > 0 {
+		return c.RequestsPerSecond
+	}
+	return defaultRequestsPerSecond
+}
+
+func (c InstanceHTTPConfig) retryPolicy() utils.RetryPolicy {
+	return utils.RetryPolicy{
+		MaxRetries:          c.MaxRetries,
+		MaxRateLimitRetries: httpRateLimitRetries,
+		BaseDelay:           httpRetryBaseDelay,
+		MaxDelay:            httpRetryMaxDelay,
+	}
+}
+
+// Set changes a single setting, an empty value resets it.
+func (c *InstanceHTTPConfig) Set(setting, value string) error {
+	value = strings.TrimSpace(value)
+	switch setting {
+	case httpSettingProxy:
+		c.ProxyURL = value
+	case httpSettingCA:
+		c.CACertificates = normalizePEM(value)
+	case httpSettingCert:
+		// The certificate and its key may be set at once, as they are validated together
+		c.ClientCertificate = ""
+		for _, block := range splitPEM(normalizePEM(value)) {
+			if strings.HasSuffix(block.Type, "PRIVATE KEY") {
+				c.ClientKey = string(pem.EncodeToMemory(block))
+			} else {
+				c.ClientCertificate += string(pem.EncodeToMemory(block))
+			}
+		}
+		if value == "" {
+			c.ClientKey = ""
+		} else if c.ClientCertificate == "" {
+			return errors.New("the client certificate must be PEM encoded")
+		}
+	case httpSettingKey:
+		c.ClientKey = normalizePEM(value)
+	case httpSettingTimeout, httpSettingRetries, httpSettingConcurrency, httpSettingQPS:
+		n := 0
+		if value != "" {
+			var err error
+			if n, err = strconv.Atoi(value); err != nil || n < 0 {
+				return errors.Errorf("%s must be a positive number, got %q", setting, value)
+			}
+		}
+		switch setting {
+		case httpSettingTimeout:
+			c.TimeoutSeconds = n
+		case httpSettingRetries:
+			c.MaxRetries = n
+		case httpSettingConcurrency:
+			c.MaxConcurrentRequests = n
+		case httpSettingQPS:
+			c.RequestsPerSecond = n
+		}
+	default:
+		return errors.Errorf("unknown setting %q, please use one of %s", setting,
+			strings.Join([]string{httpSettingProxy, httpSettingCA, httpSettingCert, httpSettingKey, httpSettingTimeout, httpSettingRetries,
+				httpSettingConcurrency, httpSettingQPS}, ", "))
+	}
+	return nil
+}
+
+// Validate checks that the settings can be used to build a transport.
+func (c *InstanceHTTPConfig) Validate() error {
+	_, err := c.newTransport()
+	return err
+}
+
+func (c *InstanceHTTPConfig) newTransport() (http.RoundTripper, error) {
+	if c.IsEmpty() {
+		return http.DefaultTransport, nil
+	}
+	if c.TimeoutSeconds > maxHTTPTimeoutSeconds {
+		return nil, errors.Errorf("the timeout can not exceed %v seconds", maxHTTPTimeoutSeconds)
+	}
+	if c.MaxRetries > maxHTTPRetries {
+		return nil, errors.Errorf("the number of retries can not exceed %v", maxHTTPRetries)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if c.ProxyURL != "" {
+		proxyURL, err := url.Parse(c.ProxyURL)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid proxy URL")
+		}
+		if proxyURL.Host == "" || (proxyURL.Scheme != "http" && proxyURL.Scheme != "https" && proxyURL.Scheme != "socks5") {
+			return nil, errors.Errorf("invalid proxy URL %q, it must start with http://, https:// or socks5://", c.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if c.CACertificates != "" || c.ClientCertificate != "" || c.ClientKey != "" {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if c.CACertificates != "" {
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM([]byte(c.CACertificates)) {
+				return nil, errors.New("the CA bundle does not contain any PEM encoded certificate")
+			}
+			tlsConfig.RootCAs = pool
+		}
+
+		if (c.ClientCertificate == "") != (c.ClientKey == "") {
+			return nil, errors.New("both the client certificate and the client key must be set")
+		}
+		if c.ClientCertificate != "" {
+			cert, err := tls.X509KeyPair([]byte(c.ClientCertificate), []byte(c.ClientKey))
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid client certificate or key")
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return transport, nil
+}
+
+// Format returns the settings for the system administrators, without the client key.
+func (c *InstanceHTTPConfig) Format() string {
+	if c.IsEmpty() {
+		return "The default HTTP settings are used."
+	}
+
+	describePEM := func(data string) string {
+		if data == "" {
+			return "not set"
+		}
+		return fmt.Sprintf("%v PEM block(s)", len(splitPEM(data)))
+	}
+	orNotSet := func(value string) string {
+		if value == "" {
+			return "not set"
+		}
+		return "`" + value + "`"
+	}
+
+	text := "| Setting | Value |\n|---|---|\n"
+	text += fmt.Sprintf("| Proxy URL | %s |\n", orNotSet(c.ProxyURL))
+	text += fmt.Sprintf("| CA certificates | %s |\n", describePEM(c.CACertificates))
+	text += fmt.Sprintf("| Client certificate | %s |\n", describePEM(c.ClientCertificate))
+	if c.ClientKey != "" {
+		text += "| Client key | set |\n"
+	} else {
+		text += "| Client key | not set |\n"
+	}
+	text += fmt.Sprintf("| Request timeout | %vs |\n", int(c.timeout().Seconds()))
+	text += fmt.Sprintf("| Retries | %v |\n", c.MaxRetries)
+	text += fmt.Sprintf("| Concurrent requests | %v |\n", c.maxConcurrentRequests())
+	text += fmt.Sprintf("| Requests per second | %v |\n", c.requestsPerSecond())
+	return text
+}
+
+// normalizePEM restores the line breaks of PEM blocks, which are lost when they are pasted
+// into a slash command.
+func normalizePEM(value string) string {
+	blocks := pemBlockRegexp.FindAllStringSubmatch(value, -1)
+	if len(blocks) == 0 {
+		return value
+	}
+
+	normalized := ""
+	for _, block := range blocks {
+		body := strings.Join(strings.Fields(block[2]), "")
+		normalized += "-----BEGIN " + block[1] + "-----\n"
+		for len(body) > 64 {
+			normalized += body[:64] + "\n"
+			body = body[64:]
+		}
+		if body != "" {
+			normalized += body + "\n"
+		}
+		normalized += "-----END " + block[3] + "-----\n"
+	}
+	return normalized
+}
+
+func splitPEM(data string) []*pem.Block {
+	blocks := []*pem.Block{}
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			return blocks
+		}
+		blocks = append(blocks, block)
+	}
+}
+
+type httpTransportCacheEntry struct {
+	config    InstanceHTTPConfig
+	transport http.RoundTripper
+	budget    *utils.RequestBudget
+}
+
+// httpTransportCache keeps a transport per instance, so that the connections to Jira are
+// reused across clients, and the requests to an instance share its budget. The zero value is
+// ready to use.
+type httpTransportCache struct {
+	lock    sync.Mutex
+	entries map[types.ID]*httpTransportCacheEntry
+}
+
+func (c *httpTransportCache) get(instanceID types.ID, config *InstanceHTTPConfig) (http.RoundTripper, error) {
+	current := InstanceHTTPConfig{}
+	if config != nil {
+		current = *config
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry := c.entries[instanceID]
+	if entry != nil && entry.config == current {
+		return entry.transport, nil
+	}
+
+	base, err := current.newTransport()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid HTTP settings for %s", instanceID)
+	}
+
+	// Requests in flight keep counting against the budget when other settings change
+	budget := utils.NewRequestBudget(current.maxConcurrentRequests(), current.requestsPerSecond(), httpBudgetMaxWait)
+	if entry != nil && entry.config.maxConcurrentRequests() == current.maxConcurrentRequests() &&
+		entry.config.requestsPerSecond() == current.requestsPerSecond() {
+		budget = entry.budget
+	}
+
+	if c.entries == nil {
+		c.entries = map[types.ID]*httpTransportCacheEntry{}
+	}
+	entry = &httpTransportCacheEntry{
+		config:    current,
+		transport: utils.NewRetryTransport(base, current.retryPolicy(), budget),
+		budget:    budget,
+	}
+	c.entries[instanceID] = entry
+	return entry.transport, nil
+}
+
+// httpTransport returns the transport of the requests to the instance, before authentication.
+func (ic *InstanceCommon) httpTransport() (http.RoundTripper, error) {
+	if ic.Plugin == nil {
+		// The instance is not bound to the plugin, its requests do not share a budget
+		transports := &httpTransportCache{}
+		return transports.get(ic.InstanceID, ic.HTTP)
+	}
+	return ic.Plugin.httpTransports.get(ic.InstanceID, ic.HTTP)
+}
+
+// withPriority sends the requests of the client as background requests when the instance
+// was loaded by a background job. It wraps the clients made for each use, as the
+// authenticated clients may be cached and shared with the requests of the users.
+func (ic *InstanceCommon) withPriority(httpClient *http.Client) *http.Client {
+	if !ic.backgroundJob {
+		return httpClient
+	}
+	client := *httpClient
+	client.Transport = utils.NewBackgroundPriorityTransport(httpClient.Transport)
+	return &client
+}
+
+// httpClient returns an unauthenticated client for the instance. defaultTimeout is used
+// unless the instance configures a timeout, defaultHTTPTimeout if zero.
+func (ic *InstanceCommon) httpClient(defaultTimeout time.Duration) (*http.Client, error) {
+	transport, err := ic.httpTransport()
+	if err != nil {
+		return nil, err
+	}
+	timeout := ic.HTTP.timeout()
+	if defaultTimeout > 0 && (ic.HTTP == nil || ic.HTTP.TimeoutSeconds <= 0) {
+		timeout = defaultTimeout
+	}
+	return ic.withPriority(&http.Client{Transport: transport, Timeout: timeout}), nil
+}
+
+// httpContext returns a context that makes the OAuth 1.0a and OAuth 2.0 libraries use the
+// instance's transport, including for the token requests.
+func (ic *InstanceCommon) httpContext(ctx context.Context) (context.Context, error) {
+	transport, err := ic.httpTransport()
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Transport: transport, Timeout: ic.HTTP.timeout()}
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+	ctx = context.WithValue(ctx, oauth1.HTTPClient, client)
+	return ctx, nil
+}
+
+// wrapHTTPClient applies the instance's timeout and the attachment size limits to an
+// authenticated client.
+func (ic *InstanceCommon) wrapHTTPClient(httpClient *http.Client) *http.Client {
+	conf := ic.getConfig()
+	httpClient = utils.WrapHTTPClient(httpClient,
+		utils.WithRequestSizeLimit(conf.maxAttachmentSize),
+		utils.WithResponseSizeLimit(conf.maxAttachmentSize))
+	httpClient.Timeout = ic.HTTP.timeout()
+	return ic.withPriority(httpClient)
+}
+
+// checkHTTPConfig validates the instance's HTTP settings, and that Jira can be reached with
+// them. Any HTTP response is accepted, as the request is not authenticated.
+func (ic *InstanceCommon) checkHTTPConfig() error {
+	if err := ic.HTTP.Validate(); err != nil {
+		return err
+	}
+
+	client, err := ic.httpClient(adminAPITokenRequestTimeout)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Get(strings.TrimRight(ic.InstanceID.String(), "/") + "/rest/api/2/serverInfo")
+	if err != nil {
+		return errors.Wrapf(err, "failed to reach %s with the configured HTTP settings", ic.InstanceID)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// inheritHTTPConfig keeps the HTTP settings of the instance being reinstalled, or the ones set
+// before installing it, and checks that Jira can be reached with them.
+func (p *Plugin) inheritHTTPConfig(ic *InstanceCommon) error {
+	if ic.HTTP == nil {
+		config, err := p.installHTTPConfig(ic.InstanceID)
+		if err != nil {
+			return err
+		}
+		ic.HTTP = config
+	}
+	if ic.HTTP.IsEmpty() {
+		return nil
+	}
+	return ic.checkHTTPConfig()
+}
+
+// installHTTPConfig returns the HTTP settings to install an instance with: the settings of the
+// instance being reinstalled, or else the settings set for its URL before installing it.
+func (p *Plugin) installHTTPConfig(jiraURL types.ID) (*InstanceHTTPConfig, error) {
+	if existing, err := p.instanceStore.LoadInstance(jiraURL); err == nil {
+		return existing.Common().HTTP, nil
+	}
+	return p.loadPendingHTTPConfig(jiraURL)
+}
+
+// checkInstallJiraURL normalizes the URL of a Jira server being installed, and checks that it
+// is running, reaching it with the HTTP settings it will be installed with.
+func (p *Plugin) checkInstallJiraURL(rawURL string) (string, error) {
+	jiraURL, err := utils.NormalizeJiraURL(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	config, err := p.installHTTPConfig(types.ID(jiraURL))
+	if err != nil {
+		return "", err
+	}
+	ic := &InstanceCommon{InstanceID: types.ID(jiraURL), HTTP: config}
+	client, err := ic.httpClient(adminAPITokenRequestTimeout)
+	if err != nil {
+		return "", err
+	}
+	return utils.CheckJiraURLWithClient(client, p.GetSiteURL(), jiraURL, false)
+}
+
+// loadPendingHTTPConfig returns the HTTP settings set for a Jira URL before it is installed,
+// or nil if there are none.
+func (p *Plugin) loadPendingHTTPConfig(jiraURL types.ID) (*InstanceHTTPConfig, error) {
+	var config *InstanceHTTPConfig
+	err := p.client.KV.Get(hashkey(prefixPendingHTTPConfig, jiraURL.String()), &config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the pending HTTP settings")
+	}
+	if config == nil {
+		return nil, nil
+	}
+
+	config.ClientKey, err = p.openSecret(config.ClientKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt the client key of the pending HTTP settings")
+	}
+	return config, nil
+}
+
+func (p *Plugin) storePendingHTTPConfig(jiraURL types.ID, config *InstanceHTTPConfig) error {
+	key := hashkey(prefixPendingHTTPConfig, jiraURL.String())
+	if config.IsEmpty() {
+		return p.client.KV.Delete(key)
+	}
+
+	sealed := *config
+	var err error
+	sealed.ClientKey, err = p.sealSecret(config.ClientKey)
+	if err != nil {
+		return err
+	}
+	_, err = p.client.KV.Set(key, sealed, pluginapi.SetExpiry(pendingHTTPConfigExpiry))
+	if err != nil {
+		return errors.Wrap(err, "failed to store the pending HTTP settings")
+	}
+	return nil
+}
+
+func (p *Plugin) deletePendingHTTPConfig(jiraURL types.ID) {
+	if err := p.client.KV.Delete(hashkey(prefixPendingHTTPConfig, jiraURL.String())); err != nil {
+		p.client.Log.Warn("Failed to delete the pending HTTP settings", "instance", jiraURL.String(), "error", err.Error())
+	}
+}
+
+// executePendingInstanceHTTP shows or changes the HTTP settings of a Jira URL that is not
+// installed yet. They are used to install it.
+func executePendingInstanceHTTP(p *Plugin, header *model.CommandArgs, rawURL string, args []string) *model.CommandResponse {
+	jiraURL, err := utils.NormalizeJiraURL(rawURL)
+	if err != nil {
+		return p.responsef(header, "No Jira instance is installed at %s. Error: %v.", rawURL, err)
+	}
+	instanceID := types.ID(jiraURL)
+
+	config, err := p.loadPendingHTTPConfig(instanceID)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if len(args) == 0 {
+		return p.responsef(header, "#### HTTP settings to install %s\n%s", instanceID, config.Format())
+	}
+
+	updated := InstanceHTTPConfig{}
+	if config != nil {
+		updated = *config
+	}
+	if err = updated.Set(args[0], strings.Join(args[1:], " ")); err != nil {
+		return p.response(header, err.Error())
+	}
+
+	// Jira is reached when it is installed, its URL may not be reachable without all the settings
+	if err = updated.Validate(); err != nil {
+		return p.responsef(header, "The HTTP settings were not changed. Error: %v.", err)
+	}
+	if err = p.storePendingHTTPConfig(instanceID, &updated); err != nil {
+		return p.responsef(header, "Failed to store the HTTP settings. Error: %v.", err)
+	}
+
+	return p.responsef(header, "The HTTP settings of %s were updated, they will be used to install it within %v.\n%s",
+		instanceID, pendingHTTPConfigExpiry, updated.Format())
+}
+
+// executeInstanceHTTP shows or changes the HTTP settings of an instance.
+func executeInstanceHTTP(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira instance http` can only be run by a system administrator.")
+	}
+	if len(args) < 1 {
+		return p.responsef(header, "Please use `/jira instance http [jiraURL] [setting] [value]`.")
+	}
+
+	instances, err := p.instanceStore.LoadInstances()
+	if err != nil {
+		return p.responsef(header, "Failed to load instances. Error: %v.", err)
+	}
+	instanceID := types.ID(args[0])
+	if instanceFound := instances.getByAlias(args[0]); instanceFound != nil {
+		instanceID = instanceFound.InstanceID
+	}
+
+	instance, err := p.instanceStore.LoadInstance(instanceID)
+	if errors.Is(err, kvstore.ErrNotFound) {
+		return executePendingInstanceHTTP(p, header, args[0], args[1:])
+	}
+	if err != nil {
+		return p.responsef(header, "Failed to load instance. Error: %v.", err)
+	}
+	ic := instance.Common()
+
+	if len(args) == 1 {
+		return p.responsef(header, "#### HTTP settings of %s\n%s", instanceID, ic.HTTP.Format())
+	}
+
+	updated := InstanceHTTPConfig{}
+	if ic.HTTP != nil {
+		updated = *ic.HTTP
+	}
+	if err = updated.Set(args[1], strings.Join(args[2:], " ")); err != nil {
+		return p.response(header, err.Error())
+	}
+
+	previous := ic.HTTP
+	ic.HTTP = &updated
+	if updated.IsEmpty() {
+		ic.HTTP = nil
+	} else if err = ic.checkHTTPConfig(); err != nil {
+		ic.HTTP = previous
+		return p.responsef(header, "The HTTP settings were not changed. Error: %v.", err)
+	}
+
+	if err = p.instanceStore.StoreInstance(instance); err != nil {
+		return p.responsef(header, "Failed to store instance. Error: %v.", err)
+	}
+
+	return p.responsef(header, "The HTTP settings of %s were updated.\n%s", instanceID, ic.HTTP.Format())
+}
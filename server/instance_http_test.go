@@ -0,0 +1,213 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils"
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/kvstore"
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+func TestInstanceHTTPConfigSet(t *testing.T) {
+	for name, tc := range map[string]struct {
+		setting     string
+		value       string
+		expectedErr string
+	}{
+		"proxy":               {setting: httpSettingProxy, value: "http://proxy.example.com:3128"},
+		"proxy without host":  {setting: httpSettingProxy, value: "proxy.example.com", expectedErr: "invalid proxy URL"},
+		"invalid CA":          {setting: httpSettingCA, value: "not a certificate", expectedErr: "the CA bundle does not contain"},
+		"cert without key":    {setting: httpSettingCert, value: "-----BEGIN CERTIFICATE----- AAAA -----END CERTIFICATE-----", expectedErr: "both the client certificate and the client key"},
+		"invalid timeout":     {setting: httpSettingTimeout, value: "ten", expectedErr: "timeout must be a positive number"},
+		"timeout too long":    {setting: httpSettingTimeout, value: "6000", expectedErr: "the timeout can not exceed"},
+		"retries":             {setting: httpSettingRetries, value: "3"},
+		"unknown setting":     {setting: "cookie", value: "1", expectedErr: "unknown setting"},
+		"reset proxy setting": {setting: httpSettingProxy, value: ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			conf := &InstanceHTTPConfig{}
+			err := conf.Set(tc.setting, tc.value)
+			if err == nil {
+				err = conf.Validate()
+			}
+			if tc.expectedErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestNormalizePEM(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}))
+
+	// Slash commands are split into fields, which loses the line breaks
+	flattened := strings.Join(strings.Fields(certPEM), " ")
+	assert.Equal(t, certPEM, normalizePEM(flattened))
+	assert.Equal(t, certPEM, normalizePEM(certPEM))
+	assert.Equal(t, "not a certificate", normalizePEM("not a certificate"))
+}
+
+func TestInstanceHTTPConfigPrivateCA(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/rest/api/2/serverInfo", r.URL.Path)
+	}))
+	defer ts.Close()
+
+	p := &Plugin{}
+	ic := &InstanceCommon{Plugin: p, InstanceID: types.ID(ts.URL), Type: ServerInstanceType}
+
+	// The test server's certificate is not trusted by default
+	ic.HTTP = &InstanceHTTPConfig{TimeoutSeconds: 5}
+	require.Error(t, ic.checkHTTPConfig())
+
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}))
+	require.NoError(t, ic.HTTP.Set(httpSettingCA, strings.Join(strings.Fields(certPEM), " ")))
+	require.NoError(t, ic.checkHTTPConfig())
+
+	client, err := ic.httpClient(adminAPITokenRequestTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, ic.HTTP.timeout(), client.Timeout)
+
+	// The transport is reused until the settings change
+	cached, err := ic.httpTransport()
+	require.NoError(t, err)
+	assert.Same(t, client.Transport, cached)
+	ic.HTTP.MaxRetries = 1
+	changed, err := ic.httpTransport()
+	require.NoError(t, err)
+	assert.NotSame(t, cached, changed)
+}
+
+func TestInstanceHTTPClientTimeout(t *testing.T) {
+	ic := &InstanceCommon{InstanceID: "https://jira.example.com", Type: ServerInstanceType}
+
+	client, err := ic.httpClient(0)
+	require.NoError(t, err)
+	assert.Equal(t, defaultHTTPTimeout, client.Timeout)
+
+	client, err = ic.httpClient(adminAPITokenRequestTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, adminAPITokenRequestTimeout, client.Timeout)
+
+	ic.HTTP = &InstanceHTTPConfig{TimeoutSeconds: 90}
+	client, err = ic.httpClient(adminAPITokenRequestTimeout)
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, client.Timeout)
+}
+
+type recordPriorityTransport struct {
+	background []bool
+}
+
+func (t *recordPriorityTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.background = append(t.background, utils.IsBackgroundPriority(req.Context()))
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+}
+
+func TestInstanceHTTPClientBackgroundPriority(t *testing.T) {
+	recorder := &recordPriorityTransport{}
+	p := &Plugin{}
+	p.httpTransports.entries = map[types.ID]*httpTransportCacheEntry{
+		"https://jira.example.com": {transport: recorder},
+	}
+	ic := &InstanceCommon{Plugin: p, InstanceID: "https://jira.example.com", Type: ServerInstanceType}
+
+	send := func(client *http.Client) {
+		resp, err := client.Get("https://jira.example.com/rest/api/2/myself")
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	client, err := ic.httpClient(0)
+	require.NoError(t, err)
+	send(client)
+	send(ic.wrapHTTPClient(&http.Client{Transport: recorder}))
+
+	// The instances loaded by the background jobs send background requests
+	ic.backgroundJob = true
+	client, err = ic.httpClient(0)
+	require.NoError(t, err)
+	send(client)
+	send(ic.wrapHTTPClient(&http.Client{Transport: recorder}))
+
+	assert.Equal(t, []bool{false, false, true, true}, recorder.background)
+}
+
+func TestCheckInstallJiraURLPendingHTTPConfig(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/status", r.URL.Path)
+		_, _ = w.Write([]byte(`{"state":"RUNNING"}`))
+	}))
+	defer ts.Close()
+
+	api := &plugintest.API{}
+	p := &Plugin{instanceStore: mockInstanceStoreForUtils{err: kvstore.ErrNotFound}}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+	makeTestKVStore(api, nil)
+	p.updateConfig(func(conf *config) {
+		conf.EncryptionKey = testPreviousEncryptionKey
+	})
+
+	// The test server's certificate is not trusted by default
+	_, err := p.checkInstallJiraURL(ts.URL)
+	require.Error(t, err)
+
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}))
+	require.NoError(t, p.storePendingHTTPConfig(types.ID(ts.URL), &InstanceHTTPConfig{CACertificates: certPEM}))
+
+	jiraURL, err := p.checkInstallJiraURL(ts.URL)
+	require.NoError(t, err)
+	assert.Equal(t, ts.URL, jiraURL)
+}
+
+func TestPendingHTTPConfig(t *testing.T) {
+	api := &plugintest.API{}
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+	kv := makeTestKVStore(api, nil)
+	p.updateConfig(func(conf *config) {
+		conf.EncryptionKey = testPreviousEncryptionKey
+	})
+
+	instanceID := types.ID("https://jira.example.com")
+	loaded, err := p.loadPendingHTTPConfig(instanceID)
+	require.NoError(t, err)
+	assert.Nil(t, loaded)
+
+	config := &InstanceHTTPConfig{ClientCertificate: "certificate", ClientKey: "client-key"}
+	require.NoError(t, p.storePendingHTTPConfig(instanceID, config))
+	assert.Equal(t, "client-key", config.ClientKey)
+	for _, data := range kv {
+		assert.NotContains(t, string(data), "client-key")
+	}
+
+	loaded, err = p.loadPendingHTTPConfig(instanceID)
+	require.NoError(t, err)
+	assert.Equal(t, config, loaded)
+
+	require.NoError(t, p.storePendingHTTPConfig(instanceID, &InstanceHTTPConfig{}))
+	loaded, err = p.loadPendingHTTPConfig(instanceID)
+	require.NoError(t, err)
+	assert.Nil(t, loaded)
+}
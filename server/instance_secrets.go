@@ -0,0 +1,158 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// encryptedSecretPrefix marks the instance secrets encrypted with the EncryptionKey setting.
+// Secrets stored before they were encrypted have no prefix, and are read as is.
+const encryptedSecretPrefix = "encrypted:"
+
+// instanceSecretsRecord is the part of a stored instance record holding its secrets.
+type instanceSecretsRecord struct {
+	Type             InstanceType
+	JiraClientSecret string
+	HTTP             *httpSecretsRecord
+	LegacyInstance   *struct {
+		HTTP *httpSecretsRecord
+	}
+}
+
+type httpSecretsRecord struct {
+	ClientKey string
+}
+
+func (r instanceSecretsRecord) values() []string {
+	values := []string{}
+	if r.Type != CloudOAuthInstanceType {
+		values = append(values, r.JiraClientSecret)
+	}
+	if r.HTTP != nil {
+		values = append(values, r.HTTP.ClientKey)
+	}
+	if r.LegacyInstance != nil && r.LegacyInstance.HTTP != nil {
+		values = append(values, r.LegacyInstance.HTTP.ClientKey)
+	}
+	return values
+}
+
+// instanceSecrets returns the fields of an instance that are encrypted in its stored record.
+// The client secret of the Jira Cloud OAuth instances predates the encryption and stays in
+// plain text, so that the instances keep working if the plugin is downgraded. Only the
+// secrets introduced along with the encryption are encrypted.
+func instanceSecrets(instance Instance) []*string {
+	secrets := []*string{}
+	var legacy *serverInstance
+	switch instance := instance.(type) {
+	case *serverOAuthInstance:
+		secrets = append(secrets, &instance.JiraClientSecret)
+		legacy = instance.LegacyInstance
+	}
+
+	// The legacy instance of a reinstalled instance may share its HTTP settings
+	var http *InstanceHTTPConfig
+	if ic := instance.Common(); ic != nil && ic.HTTP != nil {
+		http = ic.HTTP
+		secrets = append(secrets, &http.ClientKey)
+	}
+	if legacy != nil && legacy.InstanceCommon != nil && legacy.HTTP != nil && legacy.HTTP != http {
+		secrets = append(secrets, &legacy.HTTP.ClientKey)
+	}
+	return secrets
+}
+
+// activeEncryptionKey returns the key new secrets are encrypted with. While a rotation is in
+// progress, it is the new key, even before the configuration change reaches this server.
+func (p *Plugin) activeEncryptionKey() string {
+	if rotation, err := p.getKeyRotation(); err == nil && rotation.inProgress() && rotation.EncryptionKey != "" {
+		return rotation.EncryptionKey
+	}
+	return p.getConfig().EncryptionKey
+}
+
+func (p *Plugin) sealSecret(plain string) (string, error) {
+	if plain == "" {
+		return "", nil
+	}
+	key := p.activeEncryptionKey()
+	if key == "" {
+		return "", errors.New("encryption key is not configured")
+	}
+	encrypted, err := encrypt([]byte(plain), []byte(key))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encrypt the secret")
+	}
+	return encryptedSecretPrefix + encode(encrypted), nil
+}
+
+func (p *Plugin) openSecret(stored string) (string, error) {
+	if !strings.HasPrefix(stored, encryptedSecretPrefix) {
+		return stored, nil
+	}
+	encrypted, err := decode(strings.TrimPrefix(stored, encryptedSecretPrefix))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decode the secret")
+	}
+	plain, err := p.decryptWithEncryptionKey(encrypted)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decrypt the secret")
+	}
+	return string(plain), nil
+}
+
+// sealInstanceSecrets encrypts the secrets of an instance before it is stored. The returned
+// function restores the plain secrets.
+func (p *Plugin) sealInstanceSecrets(instance Instance) (func(), error) {
+	secrets := instanceSecrets(instance)
+	plain := make([]string, len(secrets))
+	restore := func() {
+		for i, secret := range secrets {
+			*secret = plain[i]
+		}
+	}
+
+	for i, secret := range secrets {
+		plain[i] = *secret
+		sealed, err := p.sealSecret(*secret)
+		if err != nil {
+			restore()
+			return nil, err
+		}
+		*secret = sealed
+	}
+	return restore, nil
+}
+
+// openInstanceSecrets decrypts the secrets of a loaded instance. The secrets that can't be
+// decrypted are cleared, the instance needs to be configured again.
+func (p *Plugin) openInstanceSecrets(instance Instance) {
+	for _, secret := range instanceSecrets(instance) {
+		plain, err := p.openSecret(*secret)
+		if err != nil {
+			p.client.Log.Warn("Failed to decrypt a secret of the Jira instance, it needs to be configured again",
+				"instance", instance.GetID().String(), "error", err.Error())
+		}
+		*secret = plain
+	}
+}
+
+// isStaleSecret returns true if a stored secret is not encrypted with the key.
+func isStaleSecret(stored, key string) bool {
+	if stored == "" {
+		return false
+	}
+	if !strings.HasPrefix(stored, encryptedSecretPrefix) {
+		return true
+	}
+	encrypted, err := decode(strings.TrimPrefix(stored, encryptedSecretPrefix))
+	if err != nil {
+		return true
+	}
+	_, err = decrypt(encrypted, []byte(key))
+	return err != nil
+}
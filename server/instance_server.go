@@ -15,19 +15,31 @@ import (
 	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
 )
 
+const (
+	ServerAuthModeOAuth1 = "oauth1"
+	ServerAuthModePAT    = "pat"
+)
+
 type serverInstance struct {
 	*InstanceCommon
 
 	// The SiteURL may change as we go, so we store the PluginKey when as it was installed
 	MattermostKey string
 
+	// AuthMode is how users connect their accounts, ServerAuthModeOAuth1 if empty
+	AuthMode string `json:",omitempty"`
+
 	DeprecatedJIRAServerURL string `json:"JIRAServerURL"`
 }
 
 var _ Instance = (*serverInstance)(nil)
 
-func (p *Plugin) installServerInstance(rawURL string) (string, *serverInstance, error) {
-	jiraURL, err := utils.CheckJiraURL(p.GetSiteURL(), rawURL, false)
+func isValidServerAuthMode(mode string) bool {
+	return mode == ServerAuthModeOAuth1 || mode == ServerAuthModePAT
+}
+
+func (p *Plugin) installServerInstance(rawURL, authMode string) (string, *serverInstance, error) {
+	jiraURL, err := p.checkInstallJiraURL(rawURL)
 	if err != nil {
 		return "", nil, err
 	}
@@ -35,15 +47,28 @@ func (p *Plugin) installServerInstance(rawURL string) (string, *serverInstance,
 		return "", nil, errors.Errorf("`%s` is not a Jira server URL, it refers to Jira Cloud", jiraURL)
 	}
 
+	if authMode == "" {
+		authMode = ServerAuthModeOAuth1
+	}
+	if !isValidServerAuthMode(authMode) {
+		return "", nil, errors.Errorf("unsupported authentication mode %q, please use %q or %q", authMode, ServerAuthModeOAuth1, ServerAuthModePAT)
+	}
+
 	instance := &serverInstance{
 		InstanceCommon: newInstanceCommon(p, ServerInstanceType, types.ID(jiraURL)),
 		MattermostKey:  p.GetPluginKey(),
+		AuthMode:       authMode,
+	}
+
+	if err = p.inheritHTTPConfig(instance.InstanceCommon); err != nil {
+		return "", nil, err
 	}
 
 	err = p.InstallInstance(instance)
 	if err != nil {
 		return "", nil, err
 	}
+	p.deletePendingHTTPConfig(instance.InstanceID)
 
 	return jiraURL, instance, err
 }
@@ -68,7 +93,20 @@ func (si *serverInstance) GetMattermostKey() string {
 	return si.MattermostKey
 }
 
+func (si *serverInstance) GetAuthMode() string {
+	if si.AuthMode == "" {
+		return ServerAuthModeOAuth1
+	}
+	return si.AuthMode
+}
+
 func (si *serverInstance) GetDisplayDetails() map[string]string {
+	if si.GetAuthMode() == ServerAuthModePAT {
+		return map[string]string{
+			"Jira Server Authentication": "Personal Access Token",
+		}
+	}
+
 	return map[string]string{
 		"Jira Server Mattermost Key": si.MattermostKey,
 	}
@@ -82,6 +120,10 @@ func (si *serverInstance) GetUserConnectURL(mattermostUserID string) (returnURL
 		returnErr = errors.WithMessage(returnErr, "failed to get a connect link")
 	}()
 
+	if si.GetAuthMode() == ServerAuthModePAT {
+		return si.Plugin.GetPluginURL() + instancePath(routeUserConnectPAT, si.InstanceID), nil, nil
+	}
+
 	oauth1Config := si.getOAuth1Config()
 	token, secret, err := oauth1Config.RequestToken()
 	if err != nil {
@@ -110,19 +152,34 @@ func (si *serverInstance) GetClient(connection *Connection) (client Client, retu
 		returnErr = errors.WithMessage(returnErr, fmt.Sprintf("failed to get a Jira client for %s", connection.Name))
 	}()
 
-	if connection.Oauth1AccessToken == "" || connection.Oauth1AccessSecret == "" {
-		return nil, errors.New("no access token, please use /jira connect")
+	transport, err := si.httpTransport()
+	if err != nil {
+		return nil, err
 	}
 
-	token := oauth1.NewToken(connection.Oauth1AccessToken, connection.Oauth1AccessSecret)
-	conf := si.getConfig()
+	// Existing connections keep working when the sysadmin changes the authentication mode
+	var httpClient *http.Client
+	switch {
+	case connection.PersonalAccessToken != "":
+		pat, err := si.Plugin.decryptPersonalAccessToken(connection.PersonalAccessToken)
+		if err != nil {
+			return nil, err
+		}
+		httpClient = (&jira.BearerAuthTransport{Token: pat, Transport: transport}).Client()
+
+	case connection.Oauth1AccessToken != "" && connection.Oauth1AccessSecret != "":
+		ctx, err := si.httpContext(oauth1.NoContext)
+		if err != nil {
+			return nil, err
+		}
+		token := oauth1.NewToken(connection.Oauth1AccessToken, connection.Oauth1AccessSecret)
+		httpClient = si.getOAuth1Config().Client(ctx, token)
 
-	httpClient := si.getOAuth1Config().Client(oauth1.NoContext, token)
-	httpClient = utils.WrapHTTPClient(httpClient,
-		utils.WithRequestSizeLimit(conf.maxAttachmentSize),
-		utils.WithResponseSizeLimit(conf.maxAttachmentSize))
+	default:
+		return nil, errors.New("no access token, please use /jira connect")
+	}
 
-	jiraClient, err := jira.NewClient(httpClient, si.GetURL())
+	jiraClient, err := jira.NewClient(si.withConnectionUsage(si.wrapHTTPClient(httpClient), connection), si.GetURL())
 	if err != nil {
 		return nil, err
 	}
@@ -142,7 +199,7 @@ func (si *serverInstance) getOAuth1Config() *oauth1.Config {
 			AccessTokenURL:  si.GetURL() + "/plugins/servlet/oauth/access-token",
 		},
 		Signer: &oauth1.RSASigner{
-			PrivateKey: p.getConfig().rsaKey,
+			PrivateKey: p.getRSAKey(),
 		},
 	}
 }
@@ -0,0 +1,186 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils"
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/kvstore"
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+// serverOAuthInstance is a Jira Data Center instance (8.22+) that users connect to through
+// an OAuth 2.0 incoming application link.
+type serverOAuthInstance struct {
+	*InstanceCommon
+
+	// The SiteURL may change as we go, so we store the PluginKey when it was installed
+	MattermostKey string
+
+	JiraClientID     string
+	JiraClientSecret string
+	CodeVerifier     string
+	CodeChallenge    string
+
+	// LegacyInstance is the server instance this instance was installed over, if any. It
+	// keeps the OAuth 1.0a and Personal Access Token connections working until the users
+	// reconnect.
+	LegacyInstance *serverInstance `json:",omitempty"`
+}
+
+var _ Instance = (*serverOAuthInstance)(nil)
+var _ oauth2Instance = (*serverOAuthInstance)(nil)
+
+const ServerOAuthScope = "WRITE"
+
+func (p *Plugin) installServerOAuthInstance(rawURL string) (string, *serverOAuthInstance, error) {
+	jiraURL, err := p.checkInstallJiraURL(rawURL)
+	if err != nil {
+		return "", nil, err
+	}
+	if utils.IsJiraCloudURL(jiraURL) {
+		return "", nil, errors.Errorf("`%s` is not a Jira server URL, it refers to Jira Cloud", jiraURL)
+	}
+
+	params, err := getS256PKCEParams()
+	if err != nil {
+		return "", nil, err
+	}
+
+	newInstance := &serverOAuthInstance{
+		InstanceCommon: newInstanceCommon(p, ServerOAuthInstanceType, types.ID(jiraURL)),
+		MattermostKey:  p.GetPluginKey(),
+		CodeVerifier:   params.CodeVerifier,
+		CodeChallenge:  params.CodeChallenge,
+	}
+
+	existingInstance, err := p.instanceStore.LoadInstance(types.ID(jiraURL))
+	if err != nil && !errors.Is(err, kvstore.ErrNotFound) {
+		return "", nil, errors.Wrapf(err, "failed to load existing jira instance. ID: %s", jiraURL)
+	}
+
+	// Migrating from an OAuth 1.0a server instance, existing connections keep working
+	switch existing := existingInstance.(type) {
+	case *serverInstance:
+		newInstance.LegacyInstance = existing
+		newInstance.Alias = existing.Alias
+		newInstance.IsV2Legacy = existing.IsV2Legacy
+		p.API.LogDebug("Installing server-oauth over existing server instance. Carrying over existing saved server instance.")
+	case *serverOAuthInstance:
+		newInstance.LegacyInstance = existing.LegacyInstance
+		newInstance.Alias = existing.Alias
+		newInstance.IsV2Legacy = existing.IsV2Legacy
+	}
+
+	if err = p.inheritHTTPConfig(newInstance.InstanceCommon); err != nil {
+		return "", nil, err
+	}
+
+	if err = p.InstallInstance(newInstance); err != nil {
+		return "", nil, errors.Wrapf(err, "failed to install server-oauth instance. ID: %s", jiraURL)
+	}
+	p.deletePendingHTTPConfig(newInstance.InstanceID)
+
+	return jiraURL, newInstance, nil
+}
+
+func (si *serverOAuthInstance) GetClient(connection *Connection) (client Client, returnErr error) {
+	defer func() {
+		if returnErr == nil {
+			return
+		}
+		returnErr = errors.WithMessage(returnErr, fmt.Sprintf("failed to get a Jira client for %s", connection.Name))
+	}()
+
+	if connection.OAuth2Token == nil {
+		if si.LegacyInstance != nil {
+			return si.LegacyInstance.GetClient(connection)
+		}
+		return nil, errors.New("no access token, please use /jira connect")
+	}
+
+	httpClient, err := si.Plugin.getOAuth2HTTPClient(si, si.GetOAuthConfig(), connection)
+	if err != nil {
+		return nil, err
+	}
+
+	jiraClient, err := jira.NewClient(si.withConnectionUsage(si.wrapHTTPClient(httpClient), connection), si.GetURL())
+	if err != nil {
+		return nil, err
+	}
+
+	return newServerClient(jiraClient), nil
+}
+
+func (si *serverOAuthInstance) GetDisplayDetails() map[string]string {
+	details := map[string]string{
+		"Jira Server Authentication": "OAuth 2.0",
+	}
+	if si.LegacyInstance != nil {
+		details["Migrated From"] = string(ServerInstanceType)
+	}
+	return details
+}
+
+func (si *serverOAuthInstance) GetUserConnectURL(mattermostUserID string) (string, *http.Cookie, error) {
+	if si.JiraClientID == "" || si.JiraClientSecret == "" {
+		return "", nil, errors.New("the OAuth 2.0 application link is not configured yet, please contact the system administrator")
+	}
+
+	state := fmt.Sprintf("%s_%s", model.NewId()[0:15], mattermostUserID)
+	url := si.GetOAuthConfig().AuthCodeURL(
+		state,
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+		oauth2.SetAuthURLParam("code_challenge", si.CodeChallenge),
+	)
+	if err := si.Plugin.otsStore.StoreOneTimeSecret(mattermostUserID, state); err != nil {
+		return "", nil, err
+	}
+	return url, nil, nil
+}
+
+func (si *serverOAuthInstance) GetOAuthConfig() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     si.JiraClientID,
+		ClientSecret: si.JiraClientSecret,
+		Scopes:       []string{ServerOAuthScope},
+		RedirectURL:  fmt.Sprintf("%s%s", si.Plugin.GetPluginURL(), instancePath(routeOAuth2Complete, si.InstanceID)),
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   si.GetURL() + "/rest/oauth2/latest/authorize",
+			TokenURL:  si.GetURL() + "/rest/oauth2/latest/token",
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}
+}
+
+func (si *serverOAuthInstance) GetCodeVerifier() string {
+	return si.CodeVerifier
+}
+
+func (si *serverOAuthInstance) GetURL() string {
+	return si.InstanceID.String()
+}
+
+func (si *serverOAuthInstance) GetJiraBaseURL() string {
+	return si.GetURL()
+}
+
+func (si *serverOAuthInstance) GetManageAppsURL() string {
+	return fmt.Sprintf("%s/plugins/servlet/applinks/listApplicationLinks", si.GetURL())
+}
+
+func (si *serverOAuthInstance) GetManageWebhooksURL() string {
+	return fmt.Sprintf("%s/plugins/servlet/webhooks", si.GetURL())
+}
+
+func (si *serverOAuthInstance) GetMattermostKey() string {
+	return si.MattermostKey
+}
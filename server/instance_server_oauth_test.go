@@ -0,0 +1,72 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+func TestServerOAuthInstance(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer pat-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"jdoe","displayName":"John Doe"}`))
+	}))
+	defer ts.Close()
+
+	p := &Plugin{}
+	p.SetAPI(&plugintest.API{})
+	p.updateConfig(func(conf *config) {
+		conf.EncryptionKey = "0123456789abcdef0123456789abcdef"
+		conf.maxAttachmentSize = defaultMaxAttachmentSize
+		conf.mattermostSiteURL = "https://mm.example.com"
+	})
+
+	si := &serverOAuthInstance{
+		InstanceCommon:   &InstanceCommon{Plugin: p, InstanceID: types.ID(ts.URL), Type: ServerOAuthInstanceType},
+		JiraClientID:     "client-id",
+		JiraClientSecret: "client-secret",
+	}
+
+	t.Run("OAuth config uses the Data Center endpoints", func(t *testing.T) {
+		conf := si.GetOAuthConfig()
+		assert.Equal(t, ts.URL+"/rest/oauth2/latest/authorize", conf.Endpoint.AuthURL)
+		assert.Equal(t, ts.URL+"/rest/oauth2/latest/token", conf.Endpoint.TokenURL)
+		assert.Equal(t, []string{ServerOAuthScope}, conf.Scopes)
+		assert.Contains(t, conf.RedirectURL, routeOAuth2Complete)
+	})
+
+	encrypted, err := p.encryptPersonalAccessToken("pat-token")
+	require.NoError(t, err)
+	legacyConnection := &Connection{PersonalAccessToken: encrypted}
+
+	t.Run("no OAuth 2.0 token and no legacy instance", func(t *testing.T) {
+		_, err := si.GetClient(legacyConnection)
+		require.Error(t, err)
+	})
+
+	t.Run("legacy connections keep working after migration", func(t *testing.T) {
+		si.LegacyInstance = &serverInstance{
+			InstanceCommon: &InstanceCommon{Plugin: p, InstanceID: types.ID(ts.URL), Type: ServerInstanceType},
+			AuthMode:       ServerAuthModePAT,
+		}
+		defer func() { si.LegacyInstance = nil }()
+
+		client, err := si.GetClient(legacyConnection)
+		require.NoError(t, err)
+		user, err := client.GetSelf()
+		require.NoError(t, err)
+		assert.Equal(t, "jdoe", user.Name)
+	})
+}
@@ -80,6 +80,7 @@ const (
 	descriptionField         = "description"
 	resolutionField          = "resolution"
 	securityLevelField       = "security"
+	dueDateField             = "duedate"
 	createdCommentEvent      = "event_created_comment"
 	notificationTypeReporter = "reporter"
 	notificationTypeWatching = "watching"
@@ -243,7 +244,10 @@ func (p *Plugin) httpShareIssuePublicly(w http.ResponseWriter, r *http.Request)
 			errors.WithMessage(err, "failed to create notification post"))
 	}
 
-	p.client.Post.DeleteEphemeralPost(ctx.authenticatedUserID, ctx.postID)
+	// The search results stay in place after sharing one of the issues
+	if keepPost, _ := requestData.Context["keep_post"].(bool); !keepPost {
+		p.client.Post.DeleteEphemeralPost(ctx.authenticatedUserID, ctx.postID)
+	}
 
 	return respondJSON(w, map[string]string{
 		statusField: "OK",
@@ -272,11 +276,13 @@ func (p *Plugin) httpTransitionIssuePostAction(w http.ResponseWriter, r *http.Re
 	_, err = p.TransitionIssue(&InTransitionIssue{
 		mattermostUserID: types.ID(ctx.authenticatedUserID),
 		InstanceID:       types.ID(ctx.instanceID),
+		PostToChannelID:  ctx.channelID,
 		IssueKey:         ctx.issueKey,
 		ToState:          toState,
+		TriggerID:        requestData.TriggerId,
 	})
 	if err != nil {
-		p.client.Post.SendEphemeralPost(ctx.authenticatedUserID, makePost(jiraBotID, ctx.channelID, "Failed to transition this issue."))
+		p.client.Post.SendEphemeralPost(ctx.authenticatedUserID, makePost(jiraBotID, ctx.channelID, "Failed to transition this issue. Error: "+err.Error()))
 		return respondErr(w, http.StatusInternalServerError, err)
 	}
 
@@ -298,6 +304,9 @@ type InCreateIssue struct {
 	CurrentTeam              string           `json:"current_team"`
 	ChannelID                string           `json:"channel_id"`
 	Fields                   jira.IssueFields `json:"fields"`
+
+	// IncludeThread compiles the whole thread of the post into the issue, with its files.
+	IncludeThread bool `json:"include_thread"`
 }
 
 func (p *Plugin) httpCreateIssue(w http.ResponseWriter, r *http.Request) (int, error) {
@@ -324,6 +333,7 @@ func (p *Plugin) CreateIssue(in *InCreateIssue) (*jira.Issue, int, error) {
 	}
 
 	var post *model.Post
+	var threadTexts, fileIDs []string
 
 	// If this issue is attached to a post, lets add a permalink to the post in the Jira Description
 	if in.PostID != "" {
@@ -341,9 +351,17 @@ func (p *Plugin) CreateIssue(in *InCreateIssue) (*jira.Issue, int, error) {
 			return nil, http.StatusForbidden, errors.New("User does not have access to this post")
 		}
 
-		if len(in.Fields.Description) > 0 {
+		fileIDs = post.FileIds
+		switch {
+		case in.IncludeThread:
+			threadTexts, fileIDs, err = p.compileThread(instance, post, in.CurrentTeam, in.Fields.Description)
+			if err != nil {
+				return nil, http.StatusInternalServerError, err
+			}
+			in.Fields.Description = threadTexts[0]
+		case len(in.Fields.Description) > 0:
 			in.Fields.Description += fmt.Sprintf("\n\n_Issue created from a [message in Mattermost|%v]_.", permalink)
-		} else {
+		default:
 			in.Fields.Description = fmt.Sprintf("_Issue created from a [message in Mattermost|%v]_.", permalink)
 		}
 	}
@@ -375,7 +393,7 @@ func (p *Plugin) CreateIssue(in *InCreateIssue) (*jira.Issue, int, error) {
 			}
 			in.RequiredFieldsNotCovered = requiredFieldsNotCovered
 
-			if instance.Common().Type == ServerInstanceType {
+			if instance.Common().IsServerInstance() {
 				issue.Fields.Reporter = &connection.User
 			}
 			break
@@ -450,6 +468,16 @@ func (p *Plugin) CreateIssue(in *InCreateIssue) (*jira.Issue, int, error) {
 	reply.AddProp("attachments", attachment)
 	p.client.Post.SendEphemeralPost(in.mattermostUserID.String(), reply)
 
+	if post != nil {
+		linkedPostID := post.Id
+		if in.IncludeThread {
+			linkedPostID = rootID
+		}
+		if err = p.addPostRemoteLink(client, instance, created.Key, linkedPostID, post.ChannelId, in.CurrentTeam); err != nil {
+			p.client.Log.Warn("Failed to link the issue to the post", "issue", created.Key, "post_id", linkedPostID, "error", err.Error())
+		}
+	}
+
 	// Fetching issue details as Jira only returns the issue id and issue key at the time of
 	// issue creation. We will not have issue summary in the creation response.
 	createdIssue, err := client.GetIssue(created.Key, nil)
@@ -468,15 +496,23 @@ func (p *Plugin) CreateIssue(in *InCreateIssue) (*jira.Issue, int, error) {
 		RootId:    rootID,
 		UserId:    in.mattermostUserID.String(),
 	}
+	p.addIssuePostProps(publicReply, instance.GetID(), createdIssue.Key)
 	err = p.client.Post.CreatePost(publicReply)
 	if err != nil {
 		return nil, http.StatusInternalServerError, errors.WithMessage(err, "failed to create notification post "+in.PostID)
 	}
 
-	if post != nil && len(post.FileIds) > 0 {
+	if len(threadTexts) > 1 || len(fileIDs) > 0 {
 		go func() {
+			// The rest of a thread too long for the description goes into the first comments
+			for i := 1; i < len(threadTexts); i++ {
+				if _, err := client.AddComment(created.Key, &jira.Comment{Body: threadTexts[i]}); err != nil {
+					notifyOnFailedAttachment(instance, in.mattermostUserID.String(), created.Key, err, "thread comment %d of %d", i, len(threadTexts)-1)
+				}
+			}
+
 			conf := instance.Common().getConfig()
-			for _, fileID := range post.FileIds {
+			for _, fileID := range fileIDs {
 				mattermostName, _, _, err := client.AddAttachment(*p.client, created.ID, fileID, conf.maxAttachmentSize)
 				if err != nil {
 					notifyOnFailedAttachment(instance, in.mattermostUserID.String(), created.Key, err, "file: %s", mattermostName)
@@ -535,33 +571,40 @@ func (p *Plugin) httpGetCreateIssueMetadataForProjects(w http.ResponseWriter, r
 }
 
 func (p *Plugin) GetCreateIssueMetadataForProjects(instanceID, mattermostUserID types.ID, projectKeys string) (*CreateMetaInfo, error) {
-	client, _, _, err := p.getClient(instanceID, mattermostUserID)
+	client, _, connection, err := p.getClient(instanceID, mattermostUserID)
 	if err != nil {
 		return nil, err
 	}
 
-	projectStatuses, err := client.ListProjectStatuses(projectKeys)
-	if err != nil {
-		return nil, err
-	}
+	key := metadataCacheKey{instanceID, connection.JiraAccountID(), metadataKindCreateMeta, projectKeys}
+	cimd, err := p.metadataCache.get(key, metadataCacheTTL, func() (interface{}, error) {
+		projectStatuses, err := client.ListProjectStatuses(projectKeys)
+		if err != nil {
+			return nil, err
+		}
+
+		metaInfo, err := client.GetCreateMetaInfo(p.API, &jira.GetQueryOptions{
+			Expand:      "projects.issuetypes.fields",
+			ProjectKeys: projectKeys,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		teamFieldKeys := injectTeamAllowedValues(metaInfo, p.getConfig().TeamIDList)
+		if len(teamFieldKeys) > 0 {
+			p.cacheTeamFieldKeys(instanceID, teamFieldKeys)
+		}
 
-	metaInfo, err := client.GetCreateMetaInfo(p.API, &jira.GetQueryOptions{
-		Expand:      "projects.issuetypes.fields",
-		ProjectKeys: projectKeys,
+		return &CreateMetaInfo{
+			metaInfo,
+			projectStatuses,
+		}, nil
 	})
 	if err != nil {
 		return nil, err
 	}
-
-	teamFieldKeys := injectTeamAllowedValues(metaInfo, p.conf.TeamIDList)
-	if len(teamFieldKeys) > 0 {
-		p.cacheTeamFieldKeys(instanceID, teamFieldKeys)
-	}
-
-	return &CreateMetaInfo{
-		metaInfo,
-		projectStatuses,
-	}, nil
+	return cimd.(*CreateMetaInfo), nil
 }
 
 // isTeamFieldSchema returns true if the schema custom type is a known team field
@@ -692,31 +735,6 @@ func (p *Plugin) httpGetTeamFields(w http.ResponseWriter, r *http.Request) (int,
 	return respondJSON(w, teamList)
 }
 
-type Sprint struct {
-	ID    int    `json:"id"`
-	Name  string `json:"name"`
-	State string `json:"state"`
-}
-
-type SprintSearchResult struct {
-	MaxResults int      `json:"maxResults"`
-	StartAt    int      `json:"startAt"`
-	IsLast     bool     `json:"isLast"`
-	Values     []Sprint `json:"values"`
-}
-
-type Board struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
-}
-
-type BoardSearchResult struct {
-	MaxResults int     `json:"maxResults"`
-	StartAt    int     `json:"startAt"`
-	IsLast     bool    `json:"isLast"`
-	Values     []Board `json:"values"`
-}
-
 func (p *Plugin) httpGetSprints(w http.ResponseWriter, r *http.Request) (int, error) {
 	if r.Method != http.MethodGet {
 		return respondErr(w, http.StatusMethodNotAllowed, fmt.Errorf("request: %s is not allowed, must be GET", r.Method))
@@ -741,53 +759,26 @@ func (p *Plugin) httpGetSprints(w http.ResponseWriter, r *http.Request) (int, er
 		return respondErr(w, http.StatusInternalServerError, err)
 	}
 
-	var allBoards []Board
-	boardStartAt := 0
-	for {
-		boardParams := map[string]string{
-			"projectKeyOrId": projectKey,
-			"startAt":        strconv.Itoa(boardStartAt),
-		}
-		var boardResult BoardSearchResult
-		if err := client.RESTGetRaw("rest/agile/1.0/board", boardParams, &boardResult); err != nil {
-			p.client.Log.Warn("Failed to get boards for project, returning empty sprint list", "project", projectKey, "error", err.Error())
-			break
-		}
-		allBoards = append(allBoards, boardResult.Values...)
-		if boardResult.IsLast || len(boardResult.Values) == 0 {
-			break
-		}
-		boardStartAt += len(boardResult.Values)
+	boards, err := client.GetBoards(projectKey, "", 0)
+	if err != nil {
+		p.client.Log.Warn("Failed to get boards for project, returning empty sprint list", "project", projectKey, "error", err.Error())
 	}
 
 	allSprints := make([]Sprint, 0)
 	seenSprints := make(map[int]bool)
 
-	for _, board := range allBoards {
-		endpoint := fmt.Sprintf("rest/agile/1.0/board/%d/sprint", board.ID)
-		sprintStartAt := 0
-		for {
-			sprintParams := map[string]string{
-				"state":   "active,future",
-				"startAt": strconv.Itoa(sprintStartAt),
-			}
-			var sprintResult SprintSearchResult
-			if err := client.RESTGetRaw(endpoint, sprintParams, &sprintResult); err != nil {
-				p.client.Log.Debug("Failed to get sprints for board", "board", board.ID, "error", err.Error())
-				break
-			}
-
-			for _, sprint := range sprintResult.Values {
-				if !seenSprints[sprint.ID] {
-					seenSprints[sprint.ID] = true
-					allSprints = append(allSprints, sprint)
-				}
-			}
+	for _, board := range boards {
+		sprints, err := client.GetBoardSprints(board.ID, "active,future")
+		if err != nil {
+			p.client.Log.Debug("Failed to get sprints for board", "board", board.ID, "error", err.Error())
+			continue
+		}
 
-			if sprintResult.IsLast || len(sprintResult.Values) == 0 {
-				break
+		for _, sprint := range sprints {
+			if !seenSprints[sprint.ID] {
+				seenSprints[sprint.ID] = true
+				allSprints = append(allSprints, sprint)
 			}
-			sprintStartAt += len(sprintResult.Values)
 		}
 	}
 
@@ -820,7 +811,8 @@ func (p *Plugin) httpGetSprintByID(w http.ResponseWriter, r *http.Request) (int,
 	if sprintID == "" {
 		return respondErr(w, http.StatusBadRequest, errors.New("sprint_id is required"))
 	}
-	if _, err := strconv.Atoi(sprintID); err != nil {
+	id, err := strconv.Atoi(sprintID)
+	if err != nil {
 		return respondErr(w, http.StatusBadRequest, errors.New("sprint_id must be numeric"))
 	}
 
@@ -829,9 +821,8 @@ func (p *Plugin) httpGetSprintByID(w http.ResponseWriter, r *http.Request) (int,
 		return respondErr(w, http.StatusInternalServerError, err)
 	}
 
-	endpoint := fmt.Sprintf("rest/agile/1.0/sprint/%s", sprintID)
-	var sprint Sprint
-	if err := client.RESTGetRaw(endpoint, nil, &sprint); err != nil {
+	sprint, err := client.GetSprint(id)
+	if err != nil {
 		return respondErr(w, http.StatusInternalServerError, errors.WithMessage(err, "failed to get sprint"))
 	}
 
@@ -846,6 +837,30 @@ func (p *Plugin) httpGetSprintByID(w http.ResponseWriter, r *http.Request) (int,
 	return http.StatusOK, nil
 }
 
+// commentVisibilityParams returns the parameters to search the groups a comment of the user
+// can be restricted to.
+func (p *Plugin) commentVisibilityParams(instance Instance, client Client, connection *Connection, fieldValue string) map[string]string {
+	params := map[string]string{
+		"fieldValue": fieldValue,
+		"expand":     expandValueGroups,
+		"accountId":  connection.AccountID,
+	}
+
+	switch instance.Common().Type {
+	case CloudOAuthInstanceType:
+		params["accountId"] = connection.AccountID
+	case ServerInstanceType, ServerOAuthInstanceType:
+		user, err := client.GetSelf()
+		if err != nil {
+			p.client.Log.Error("Error getting self user from client", "error", err)
+			break
+		}
+
+		params["key"] = user.Key
+	}
+	return params
+}
+
 func (p *Plugin) httpGetCommentVisibilityFields(w http.ResponseWriter, r *http.Request) (int, error) {
 	if r.Method != http.MethodGet {
 		return http.StatusMethodNotAllowed, fmt.Errorf("Request: %s is not allowed, must be GET", r.Method)
@@ -862,26 +877,7 @@ func (p *Plugin) httpGetCommentVisibilityFields(w http.ResponseWriter, r *http.R
 		return http.StatusInternalServerError, err
 	}
 
-	params := map[string]string{
-		"fieldValue": r.FormValue(fieldValueQueryParam),
-		"expand":     expandValueGroups,
-		"accountId":  connection.AccountID,
-	}
-
-	switch instance.Common().Type {
-	case CloudOAuthInstanceType:
-		params["accountId"] = connection.AccountID
-	case ServerInstanceType:
-		var user *jira.User
-		user, err = client.GetSelf()
-		if err != nil {
-			p.client.Log.Error("Error getting self user from client", "error", err)
-		}
-
-		params["key"] = user.Key
-	}
-
-	response, err := client.GetUserVisibilityGroups(params)
+	response, err := client.GetUserVisibilityGroups(p.commentVisibilityParams(instance, client, connection, r.FormValue(fieldValueQueryParam)))
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
@@ -916,6 +912,13 @@ func (p *Plugin) httpGetSearchIssues(w http.ResponseWriter, r *http.Request) (in
 	return respondJSON(w, result)
 }
 
+// textSearchJQL returns the JQL matching the issues that contain the text.
+func textSearchJQL(q string) string {
+	escaped := strings.ReplaceAll(q, `\`, `\\`)
+	escaped = strings.ReplaceAll(escaped, `"`, `\"`)
+	return fmt.Sprintf(`text ~ "%s" OR text ~ "%s*"`, escaped, escaped)
+}
+
 func (p *Plugin) GetSearchIssues(instanceID, mattermostUserID types.ID, q, jqlString, fieldsStr, limitStr string) ([]jira.Issue, error) {
 	client, _, _, err := p.getClient(instanceID, mattermostUserID)
 	if err != nil {
@@ -930,9 +933,7 @@ func (p *Plugin) GetSearchIssues(instanceID, mattermostUserID types.ID, q, jqlSt
 		if len(q) == 0 {
 			jqlString = "updated >= -4w ORDER BY updated DESC"
 		} else {
-			escaped := strings.ReplaceAll(q, `\`, `\\`)
-			escaped = strings.ReplaceAll(escaped, `"`, `\"`)
-			jqlString = fmt.Sprintf(`text ~ "%s" OR text ~ "%s*"`, escaped, escaped)
+			jqlString = textSearchJQL(q)
 		}
 	}
 
@@ -1057,13 +1058,26 @@ func (p *Plugin) ListJiraProjects(instanceID, mattermostUserID types.ID, expandI
 	if err != nil {
 		return nil, nil, err
 	}
-	plist, err := client.ListProjects("", -1, expandIssueTypes)
+
+	plist, err := p.listProjectsCached(instanceID, client, connection, expandIssueTypes)
 	if err != nil {
 		return nil, nil, err
 	}
 	return plist, connection, nil
 }
 
+// listProjectsCached returns the projects the user has access to, from the metadata cache.
+func (p *Plugin) listProjectsCached(instanceID types.ID, client Client, connection *Connection, expandIssueTypes bool) (jira.ProjectList, error) {
+	key := metadataCacheKey{instanceID, connection.JiraAccountID(), metadataKindProjects, strconv.FormatBool(expandIssueTypes)}
+	plist, err := p.metadataCache.get(key, metadataCacheTTL, func() (interface{}, error) {
+		return client.ListProjects("", -1, expandIssueTypes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return plist.(jira.ProjectList), nil
+}
+
 func (p *Plugin) GetIssueTypes(instanceID, mattermostUserID types.ID, projectID string) ([]jira.IssueType, error) {
 	client, _, _, err := p.getClient(instanceID, mattermostUserID)
 	if err != nil {
@@ -1181,6 +1195,10 @@ func (p *Plugin) AttachCommentToIssue(in *InAttachCommentToIssue) (*jira.Comment
 		rootID = post.RootId
 	}
 
+	if err = p.addPostRemoteLink(client, instance, in.IssueKey, in.PostID, post.ChannelId, in.CurrentTeam); err != nil {
+		p.client.Log.Warn("Failed to link the issue to the post", "issue", in.IssueKey, "post_id", in.PostID, "error", err.Error())
+	}
+
 	p.UpdateUserDefaults(in.mattermostUserID, in.InstanceID, nil)
 
 	msg := fmt.Sprintf("Message attached to [%s](%s/browse/%s)", in.IssueKey, instance.GetJiraBaseURL(), in.IssueKey)
@@ -1225,11 +1243,14 @@ func getIssueCustomFieldValue(issue *jira.Issue, key string) StringSet {
 	switch value := m.(type) {
 	case string:
 		return NewStringSet(value)
+	case float64:
+		// number field
+		return NewStringSet(strconv.FormatFloat(value, 'f', -1, 64))
 	case []string:
 		return NewStringSet(value...)
 	case []interface{}:
 		// multi-select value
-		// Checkboxes, multi-select dropdown
+		// Checkboxes, multi-select dropdown, multi-user picker
 		result := NewStringSet()
 		for _, v := range value {
 			s, ok := v.(string)
@@ -1242,7 +1263,7 @@ func getIssueCustomFieldValue(issue *jira.Issue, key string) StringSet {
 			if !ok {
 				return nil
 			}
-			id, ok := obj["id"].(string)
+			id, ok := getCustomFieldObjectID(obj)
 			if !ok {
 				return nil
 			}
@@ -1251,17 +1272,36 @@ func getIssueCustomFieldValue(issue *jira.Issue, key string) StringSet {
 		return result
 	case map[string]interface{}:
 		// single-select value
-		// Radio buttons, single-select dropdown
-		id, ok := value["id"].(string)
+		// Radio buttons, single-select dropdown, user picker
+		id, ok := getCustomFieldObjectID(value)
 		if !ok {
 			return nil
 		}
-		return NewStringSet(id)
+		result := NewStringSet(id)
+
+		// cascading select, the selected child option is matched along with its parent
+		if child, ok := value["child"].(map[string]interface{}); ok {
+			if childID, ok := getCustomFieldObjectID(child); ok {
+				result = result.Add(childID)
+			}
+		}
+		return result
 	}
 
 	return nil
 }
 
+// getCustomFieldObjectID returns the identifier of an option or user object. Options are
+// identified by "id", Jira Cloud users by "accountId" and Jira Server users by "name".
+func getCustomFieldObjectID(obj map[string]interface{}) (string, bool) {
+	for _, key := range []string{"id", "accountId", "name"} {
+		if id, ok := obj[key].(string); ok && id != "" {
+			return id, true
+		}
+	}
+	return "", false
+}
+
 func (p *Plugin) getIssueDataForCloudWebhook(instance Instance, issueKey string) (*jira.Issue, error) {
 	ci, ok := instance.(*cloudInstance)
 	if !ok {
@@ -1332,6 +1372,11 @@ func getIssueFieldValue(issue *jira.Issue, key string) StringSet {
 			}
 		}
 		return result
+	case dueDateField:
+		dueDate := time.Time(issue.Fields.Duedate)
+		if !dueDate.IsZero() {
+			return NewStringSet(dueDate.Format("2006-01-02"))
+		}
 	case "components":
 		result := NewStringSet()
 		if issue.Fields.Components != nil {
@@ -1372,7 +1417,7 @@ func (p *Plugin) getIssueAsSlackAttachment(instance Instance, connection *Connec
 		}
 	}
 
-	return asSlackAttachment(instance, client, issue, showActions)
+	return asSlackAttachment(instance, connection.JiraAccountID(), client, issue, showActions)
 }
 
 func (p *Plugin) UnassignIssue(instance Instance, mattermostUserID types.ID, issueKey string) (string, error) {
@@ -1494,10 +1539,13 @@ type InTransitionIssue struct {
 	PostToChannelID  string   `json:"channel_id"`
 	IssueKey         string   `json:"issue_key"`
 	ToState          string   `json:"to_state"`
+
+	// TriggerID opens a dialog for the fields required by the transition screen.
+	TriggerID string `json:"-"`
 }
 
 func (p *Plugin) TransitionIssue(in *InTransitionIssue) (string, error) {
-	client, instance, _, err := p.getClient(in.InstanceID, in.mattermostUserID)
+	client, instance, connection, err := p.getClient(in.InstanceID, in.mattermostUserID)
 	if err != nil {
 		return "", err
 	}
@@ -1537,13 +1585,36 @@ func (p *Plugin) TransitionIssue(in *InTransitionIssue) (string, error) {
 			in.ToState, strings.Join(matchingStates, ", "))
 	}
 
+	if transitionHasRequiredFields(transition) {
+		var fields map[string]TransitionFieldMeta
+		fields, err = client.GetTransitionFields(in.IssueKey, transition.ID)
+		if err != nil {
+			return "", errors.WithMessage(err, "failed to get the fields of the transition")
+		}
+		if len(transitionDialogFieldKeys(fields)) > 0 {
+			if in.TriggerID == "" {
+				return "", errors.Errorf("the transition to %q requires the fields: %s. Please use `/jira transition` or the \"Transition issue\" menu of the issue",
+					transition.To.Name, strings.Join(transitionFieldNames(fields), ", "))
+			}
+			if err = p.openTransitionDialog(in, instance, transition, fields); err != nil {
+				return "", err
+			}
+			return "", nil
+		}
+	}
+
 	err = client.DoTransition(in.IssueKey, transition.ID)
 	if err != nil {
 		return "", err
 	}
 
+	return p.sendTransitionedIssue(in, client, instance, connection, transition.To.Name)
+}
+
+// sendTransitionedIssue tells the user that the issue was transitioned, showing the updated issue.
+func (p *Plugin) sendTransitionedIssue(in *InTransitionIssue, client Client, instance Instance, connection *Connection, toState string) (string, error) {
 	msg := fmt.Sprintf("[%s](%v/browse/%v) transitioned to `%s`",
-		in.IssueKey, instance.GetJiraBaseURL(), in.IssueKey, transition.To.Name)
+		in.IssueKey, instance.GetJiraBaseURL(), in.IssueKey, toState)
 
 	issue, err := client.GetIssue(in.IssueKey, nil)
 	if err != nil {
@@ -1559,7 +1630,7 @@ func (p *Plugin) TransitionIssue(in *InTransitionIssue) (string, error) {
 		}
 	}
 
-	attachments, err := asSlackAttachment(instance, client, issue, true)
+	attachments, err := asSlackAttachment(instance, connection.JiraAccountID(), client, issue, true)
 	if err != nil {
 		return "", err
 	}
@@ -1626,8 +1697,12 @@ func (p *Plugin) GetIssueByKey(instanceID, mattermostUserID types.ID, issueKey s
 	return issue, nil
 }
 
-func (p *Plugin) GetIssueDataWithAPIToken(issueID, instanceID string) (*jira.Issue, error) {
-	client := &http.Client{}
+func (p *Plugin) GetIssueDataWithAPIToken(issueID string, instance Instance) (*jira.Issue, error) {
+	instanceID := instance.GetID().String()
+	client, err := instance.Common().httpClient(0)
+	if err != nil {
+		return nil, err
+	}
 	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/rest/api/2/issue/%s", instanceID, issueID), nil)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create http request for fetching issue data. IssueID: %s", issueID)
@@ -1679,7 +1754,10 @@ func (p *Plugin) GetWatchersWithAPIToken(issueKeyOrID string, instance Instance)
 		apiVersion = "3"
 	}
 
-	httpClient := &http.Client{Timeout: 30 * time.Second}
+	httpClient, err := instance.Common().httpClient(adminAPITokenRequestTimeout)
+	if err != nil {
+		return nil, err
+	}
 	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/rest/api/%s/issue/%s/watchers", instance.GetURL(), apiVersion, issueKeyOrID), nil)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create HTTP request for fetching watchers. Issue: %s", issueKeyOrID)
@@ -1960,8 +2038,12 @@ type ProjectSearchResponse struct {
 	Values     jira.ProjectList `json:"values"`
 }
 
-func (p *Plugin) GetProjectListWithAPIToken(instanceID string) (*jira.ProjectList, error) {
-	client := &http.Client{}
+func (p *Plugin) GetProjectListWithAPIToken(instance Instance) (*jira.ProjectList, error) {
+	instanceID := instance.GetID().String()
+	client, err := instance.Common().httpClient(0)
+	if err != nil {
+		return nil, err
+	}
 	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/rest/api/3/project/search", instanceID), nil)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create HTTP request for fetching project list data. InstanceID: %s", instanceID)
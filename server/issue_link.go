@@ -0,0 +1,374 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+const (
+	cloneSummaryPrefix  = "CLONE - "
+	clonersLinkTypeName = "Cloners"
+	sprintFieldCustom   = "com.pyxis.greenhopper.jira:gh-sprint"
+)
+
+// cloneSkippedFields are not copied to a clone: they are set explicitly, are not part of the
+// issue itself, or can't be set back with the value read from the original issue.
+var cloneSkippedFields = NewStringSet("project", "issuetype", "summary", "parent", "attachment", "issuelinks",
+	"comment", "worklog", "timetracking")
+
+// issueActionError turns the errors of Jira into a message telling the user whether they are
+// missing a permission.
+func issueActionError(err error, action string) error {
+	switch StatusCode(err) {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return errors.Errorf("you do not have the appropriate permissions to %s. Please contact your Jira administrator", action)
+	case http.StatusNotFound:
+		return errors.New("we couldn't find the issue key, or you do not have the appropriate permissions to view the issue. Please try again or contact your Jira administrator")
+	default:
+		return errors.WithMessagef(err, "failed to %s", action)
+	}
+}
+
+func (p *Plugin) getIssueLinkTypes(instanceID, scope types.ID, client Client) ([]jira.IssueLinkType, error) {
+	key := metadataCacheKey{instanceID, scope, metadataKindLinkTypes, ""}
+	linkTypes, err := p.metadataCache.get(key, metadataCacheTTL, func() (interface{}, error) {
+		return client.GetIssueLinkTypes()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return linkTypes.([]jira.IssueLinkType), nil
+}
+
+func normalizeLinkDescription(description string) string {
+	return strings.ToLower(strings.Join(strings.Fields(strings.Trim(description, `"“”`)), " "))
+}
+
+// findIssueLinkType returns the link type with a name, outward or inward description matching
+// the description, and whether the description is the inward one, like "is blocked by".
+func findIssueLinkType(linkTypes []jira.IssueLinkType, description string) (*jira.IssueLinkType, bool, error) {
+	normalized := normalizeLinkDescription(description)
+	for i := range linkTypes {
+		if normalized == normalizeLinkDescription(linkTypes[i].Outward) || normalized == normalizeLinkDescription(linkTypes[i].Name) {
+			return &linkTypes[i], false, nil
+		}
+	}
+	for i := range linkTypes {
+		if normalized == normalizeLinkDescription(linkTypes[i].Inward) {
+			return &linkTypes[i], true, nil
+		}
+	}
+
+	available := []string{}
+	for _, linkType := range linkTypes {
+		available = append(available, linkType.Outward)
+		if linkType.Inward != linkType.Outward {
+			available = append(available, linkType.Inward)
+		}
+	}
+	return nil, false, errors.Errorf("%q is not a valid link type. Please use one of: %q", description, strings.Join(available, ", "))
+}
+
+// makeIssueLink links the issues so that Jira shows "from <outward description> to". The issue
+// the link starts from is the inward issue of the API.
+func makeIssueLink(linkType *jira.IssueLinkType, fromKey, toKey string) *jira.IssueLink {
+	return &jira.IssueLink{
+		Type:         jira.IssueLinkType{Name: linkType.Name},
+		InwardIssue:  &jira.Issue{Key: fromKey},
+		OutwardIssue: &jira.Issue{Key: toKey},
+	}
+}
+
+func issueLink(instance Instance, issueKey string) string {
+	return fmt.Sprintf("[%s](%s/browse/%s)", issueKey, instance.GetJiraBaseURL(), issueKey)
+}
+
+func (p *Plugin) LinkIssues(instanceID, mattermostUserID types.ID, fromKey, description, toKey string) (string, error) {
+	client, instance, connection, err := p.getClient(instanceID, mattermostUserID)
+	if err != nil {
+		return "", err
+	}
+
+	linkTypes, err := p.getIssueLinkTypes(instanceID, connection.JiraAccountID(), client)
+	if err != nil {
+		return "", issueActionError(err, "get the link types")
+	}
+	linkType, inward, err := findIssueLinkType(linkTypes, description)
+	if err != nil {
+		return "", err
+	}
+
+	link := makeIssueLink(linkType, fromKey, toKey)
+	shown := linkType.Outward
+	if inward {
+		link = makeIssueLink(linkType, toKey, fromKey)
+		shown = linkType.Inward
+	}
+	if err = client.LinkIssues(link); err != nil {
+		return "", issueActionError(err, "link these issues")
+	}
+
+	return fmt.Sprintf("%s %s %s", issueLink(instance, fromKey), shown, issueLink(instance, toKey)), nil
+}
+
+func (p *Plugin) CreateSubtask(instanceID, mattermostUserID types.ID, parentKey, summary string) (string, error) {
+	client, instance, _, err := p.getClient(instanceID, mattermostUserID)
+	if err != nil {
+		return "", err
+	}
+
+	parent, err := client.GetIssue(parentKey, &jira.GetQueryOptions{Fields: "project,issuetype"})
+	if err != nil {
+		return "", issueActionError(err, "view the parent issue")
+	}
+	if parent.Fields.Type.Subtask {
+		return "", errors.Errorf("%s is a sub-task, sub-tasks can't have sub-tasks", parentKey)
+	}
+
+	projectKey := parent.Fields.Project.Key
+	createMeta, err := client.GetCreateMetaInfo(p.API, &jira.GetQueryOptions{
+		Expand:      "projects.issuetypes",
+		ProjectKeys: projectKey,
+	})
+	if err != nil {
+		return "", issueActionError(err, "create issues in project "+projectKey)
+	}
+
+	var subtaskType *jira.MetaIssueType
+	for _, project := range createMeta.Projects {
+		for _, issueType := range project.IssueTypes {
+			if issueType.Subtasks && subtaskType == nil {
+				subtaskType = issueType
+			}
+		}
+	}
+	if subtaskType == nil {
+		return "", errors.Errorf("project %s has no sub-task issue type you can create", projectKey)
+	}
+
+	created, err := client.CreateIssue(&jira.Issue{
+		Fields: &jira.IssueFields{
+			Project: jira.Project{Key: projectKey},
+			Type:    jira.IssueType{ID: subtaskType.Id},
+			Parent:  &jira.Parent{Key: parentKey},
+			Summary: summary,
+		},
+	})
+	if err != nil {
+		return "", issueActionError(err, "create the sub-task")
+	}
+
+	return fmt.Sprintf("Created the %s %s of %s", subtaskType.Name, issueLink(instance, created.Key), issueLink(instance, parentKey)), nil
+}
+
+// cloneIssueFields copies the fields of the issue that can be set when creating an issue of
+// the same type, according to the create metadata.
+func cloneIssueFields(original *jira.Issue, issueType *jira.MetaIssueType) (*jira.IssueFields, error) {
+	data, err := json.Marshal(original.Fields)
+	if err != nil {
+		return nil, err
+	}
+	all := map[string]interface{}{}
+	if err = json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+
+	copied := map[string]interface{}{}
+	for key := range issueType.Fields {
+		if cloneSkippedFields.ContainsAny(key) {
+			continue
+		}
+		if custom, _ := issueType.Fields.String(key + "/schema/custom"); custom == sprintFieldCustom {
+			continue
+		}
+		if value, ok := all[key]; ok && value != nil {
+			copied[key] = value
+		}
+	}
+	copied["project"] = map[string]string{"key": original.Fields.Project.Key}
+	copied["issuetype"] = map[string]string{"id": original.Fields.Type.ID}
+	copied["summary"] = cloneSummaryPrefix + original.Fields.Summary
+	if original.Fields.Parent != nil {
+		copied["parent"] = map[string]string{"key": original.Fields.Parent.Key}
+	}
+
+	data, err = json.Marshal(copied)
+	if err != nil {
+		return nil, err
+	}
+	fields := &jira.IssueFields{}
+	if err = json.Unmarshal(data, fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func (p *Plugin) CloneIssue(instanceID, mattermostUserID types.ID, issueKey string) (string, error) {
+	client, instance, connection, err := p.getClient(instanceID, mattermostUserID)
+	if err != nil {
+		return "", err
+	}
+
+	original, err := client.GetIssue(issueKey, nil)
+	if err != nil {
+		return "", issueActionError(err, "view the issue")
+	}
+
+	projectKey := original.Fields.Project.Key
+	createMeta, err := client.GetCreateMetaInfo(p.API, &jira.GetQueryOptions{
+		Expand:      "projects.issuetypes.fields",
+		ProjectKeys: projectKey,
+	})
+	if err != nil {
+		return "", issueActionError(err, "create issues in project "+projectKey)
+	}
+
+	var issueType *jira.MetaIssueType
+	for _, project := range createMeta.Projects {
+		for _, t := range project.IssueTypes {
+			if t.Id == original.Fields.Type.ID {
+				issueType = t
+			}
+		}
+	}
+	if issueType == nil {
+		return "", errors.Errorf("you can't create %s issues in project %s", original.Fields.Type.Name, projectKey)
+	}
+
+	fields, err := cloneIssueFields(original, issueType)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to copy the fields of the issue")
+	}
+	created, err := client.CreateIssue(&jira.Issue{Fields: fields})
+	if err != nil {
+		return "", issueActionError(err, "create the clone")
+	}
+
+	msg := fmt.Sprintf("Cloned %s to %s", issueLink(instance, issueKey), issueLink(instance, created.Key))
+
+	// The clone is usable even if Jira has no link type for clones
+	linkTypes, err := p.getIssueLinkTypes(instanceID, connection.JiraAccountID(), client)
+	if err != nil {
+		p.client.Log.Debug("Failed to get the link types to link the clone", "error", err.Error())
+		return msg, nil
+	}
+	for i := range linkTypes {
+		if strings.EqualFold(linkTypes[i].Name, clonersLinkTypeName) {
+			if err = client.LinkIssues(makeIssueLink(&linkTypes[i], created.Key, issueKey)); err != nil {
+				p.client.Log.Debug("Failed to link the clone to the original issue", "issue_key", issueKey, "error", err.Error())
+			}
+			break
+		}
+	}
+	return msg, nil
+}
+
+func (p *Plugin) httpAutocompleteIssueLinkTypes(w http.ResponseWriter, r *http.Request) (int, error) {
+	mattermostUserID := types.ID(r.Header.Get("Mattermost-User-Id"))
+	out := []model.AutocompleteListItem{}
+
+	_, instance, err := p.LoadUserInstance(mattermostUserID, "")
+	if err != nil {
+		return respondJSON(w, out)
+	}
+	client, _, connection, err := p.getClient(instance.GetID(), mattermostUserID)
+	if err != nil {
+		return respondJSON(w, out)
+	}
+	linkTypes, err := p.getIssueLinkTypes(instance.GetID(), connection.JiraAccountID(), client)
+	if err != nil {
+		return respondErr(w, http.StatusInternalServerError, err)
+	}
+
+	for _, linkType := range linkTypes {
+		out = append(out, model.AutocompleteListItem{
+			Item:     fmt.Sprintf("%q", linkType.Outward),
+			HelpText: linkType.Name,
+		})
+		if linkType.Inward != linkType.Outward {
+			out = append(out, model.AutocompleteListItem{
+				Item:     fmt.Sprintf("%q", linkType.Inward),
+				HelpText: linkType.Name,
+			})
+		}
+	}
+	return respondJSON(w, out)
+}
+
+func loadIssueCommandInstance(p *Plugin, header *model.CommandArgs, args []string) (types.ID, []string, *model.CommandResponse) {
+	instanceURL, args, err := p.parseCommandFlagInstanceURL(args)
+	if err != nil {
+		return "", nil, p.responsef(header, "Failed to load your connection to Jira. Error: %v.", err)
+	}
+	_, instanceID, err := p.ResolveUserInstanceURL(types.ID(header.UserId), instanceURL)
+	if err != nil {
+		return "", nil, p.responsef(header, "Failed to identify Jira instance %s. Error: %v.", instanceURL, err)
+	}
+	return instanceID, args, nil
+}
+
+func executeIssueLink(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	instanceID, args, resp := loadIssueCommandInstance(p, header, args)
+	if resp != nil {
+		return resp
+	}
+	if len(args) < 3 {
+		return p.responsef(header, "Please specify the issues and the link type in the form `/jira issue link <issue-key> <link-type> <issue-key>`.")
+	}
+
+	msg, err := p.LinkIssues(instanceID, types.ID(header.UserId), strings.ToUpper(args[0]),
+		strings.Join(args[1:len(args)-1], " "), strings.ToUpper(args[len(args)-1]))
+	if err != nil {
+		return p.response(header, err.Error())
+	}
+	return p.response(header, msg)
+}
+
+func executeIssueSubtask(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	instanceID, args, resp := loadIssueCommandInstance(p, header, args)
+	if resp != nil {
+		return resp
+	}
+	summary := ""
+	if len(args) > 1 {
+		summary = strings.TrimSpace(strings.Trim(strings.Join(args[1:], " "), `"“”`))
+	}
+	if summary == "" {
+		return p.responsef(header, "Please specify the parent issue and the summary in the form `/jira issue subtask <parent-key> \"summary\"`.")
+	}
+
+	msg, err := p.CreateSubtask(instanceID, types.ID(header.UserId), strings.ToUpper(args[0]), summary)
+	if err != nil {
+		return p.response(header, err.Error())
+	}
+	return p.response(header, msg)
+}
+
+func executeIssueClone(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	instanceID, args, resp := loadIssueCommandInstance(p, header, args)
+	if resp != nil {
+		return resp
+	}
+	if len(args) != 1 {
+		return p.responsef(header, "Please specify an issue key in the form `/jira issue clone <issue-key>`.")
+	}
+
+	msg, err := p.CloneIssue(instanceID, types.ID(header.UserId), strings.ToUpper(args[0]))
+	if err != nil {
+		return p.response(header, err.Error())
+	}
+	return p.response(header, msg)
+}
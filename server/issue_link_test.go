@@ -0,0 +1,109 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/trivago/tgo/tcontainer"
+)
+
+var testLinkTypes = []jira.IssueLinkType{
+	{ID: "1", Name: "Blocks", Inward: "is blocked by", Outward: "blocks"},
+	{ID: "2", Name: "Relates", Inward: "relates to", Outward: "relates to"},
+	{ID: "3", Name: "Cloners", Inward: "is cloned by", Outward: "clones"},
+}
+
+func TestFindIssueLinkType(t *testing.T) {
+	for name, tc := range map[string]struct {
+		description  string
+		expectedName string
+		inward       bool
+		errorMessage string
+	}{
+		"outward description":   {description: "blocks", expectedName: "Blocks"},
+		"inward description":    {description: "is  Blocked by", expectedName: "Blocks", inward: true},
+		"quoted description":    {description: `"is blocked by"`, expectedName: "Blocks", inward: true},
+		"name":                  {description: "cloners", expectedName: "Cloners"},
+		"symmetric description": {description: "relates to", expectedName: "Relates"},
+		"unknown": {
+			description:  "duplicates",
+			errorMessage: `"duplicates" is not a valid link type. Please use one of: "blocks, is blocked by, relates to, clones, is cloned by"`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			linkType, inward, err := findIssueLinkType(testLinkTypes, tc.description)
+			if tc.errorMessage != "" {
+				require.EqualError(t, err, tc.errorMessage)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedName, linkType.Name)
+			assert.Equal(t, tc.inward, inward)
+		})
+	}
+}
+
+func TestCloneIssueFields(t *testing.T) {
+	original := &jira.Issue{
+		Key: "TEST-1",
+		Fields: &jira.IssueFields{
+			Project:     jira.Project{Key: "TEST"},
+			Type:        jira.IssueType{ID: "10001", Name: "Bug"},
+			Summary:     "Login fails",
+			Description: "Steps to reproduce",
+			Priority:    &jira.Priority{ID: "2", Name: "High"},
+			Labels:      []string{"login"},
+			Reporter:    &jira.User{Name: "reporter"},
+			Unknowns: tcontainer.MarshalMap{
+				"customfield_10010": map[string]interface{}{"value": "Web"},
+				"customfield_10020": []interface{}{map[string]interface{}{"id": 5, "name": "Sprint 5"}},
+			},
+		},
+	}
+	issueType := &jira.MetaIssueType{
+		Id: "10001",
+		Fields: tcontainer.MarshalMap{
+			"project":           map[string]interface{}{},
+			"issuetype":         map[string]interface{}{},
+			"summary":           map[string]interface{}{},
+			"description":       map[string]interface{}{},
+			"priority":          map[string]interface{}{},
+			"labels":            map[string]interface{}{},
+			"attachment":        map[string]interface{}{},
+			"customfield_10010": map[string]interface{}{"schema": map[string]interface{}{"custom": "com.atlassian.jira.plugin.system.customfieldtypes:select"}},
+			"customfield_10020": map[string]interface{}{"schema": map[string]interface{}{"custom": sprintFieldCustom}},
+		},
+	}
+
+	fields, err := cloneIssueFields(original, issueType)
+	require.NoError(t, err)
+
+	assert.Equal(t, "TEST", fields.Project.Key)
+	assert.Equal(t, "10001", fields.Type.ID)
+	assert.Equal(t, "CLONE - Login fails", fields.Summary)
+	assert.Equal(t, "Steps to reproduce", fields.Description)
+	assert.Equal(t, "2", fields.Priority.ID)
+	assert.Equal(t, []string{"login"}, fields.Labels)
+	assert.Equal(t, map[string]interface{}{"value": "Web"}, fields.Unknowns["customfield_10010"])
+
+	// Not settable according to createmeta, or skipped
+	assert.Nil(t, fields.Reporter)
+	assert.NotContains(t, fields.Unknowns, "customfield_10020")
+	assert.Nil(t, fields.Parent)
+}
+
+func TestIssueActionError(t *testing.T) {
+	assert.EqualError(t, issueActionError(RESTError{errors.New("forbidden"), http.StatusForbidden}, "link these issues"),
+		"you do not have the appropriate permissions to link these issues. Please contact your Jira administrator")
+	assert.Contains(t, issueActionError(RESTError{errors.New("not found"), http.StatusNotFound}, "view the issue").Error(),
+		"we couldn't find the issue key")
+	assert.EqualError(t, issueActionError(RESTError{errors.New(" - summary: required\n"), http.StatusBadRequest}, "create the clone"),
+		"failed to create the clone:  - summary: required\n")
+}
@@ -32,7 +32,7 @@ func reporterSummary(reporter *jira.User) string {
 	return reporterSummary
 }
 
-func getActions(p *Plugin, instanceID types.ID, client Client, issue *jira.Issue) ([]*model.PostAction, error) {
+func getActions(p *Plugin, instanceID, scope types.ID, client Client, issue *jira.Issue) ([]*model.PostAction, error) {
 	var actions []*model.PostAction
 
 	ctx := map[string]interface{}{
@@ -53,7 +53,7 @@ func getActions(p *Plugin, instanceID types.ID, client Client, issue *jira.Issue
 
 	var options []*model.PostActionOptions
 
-	transitions, err := client.GetTransitions(issue.Key)
+	transitions, err := p.getIssueTransitions(instanceID, scope, client, issue)
 	if err != nil {
 		return actions, err
 	}
@@ -75,6 +75,15 @@ func getActions(p *Plugin, instanceID types.ID, client Client, issue *jira.Issue
 		Integration: integration,
 	})
 
+	actions = append(actions, &model.PostAction{
+		Name: "Add comment",
+		Type: "button",
+		Integration: &model.PostActionIntegration{
+			URL:     fmt.Sprintf("/plugins/%s%s%s", manifest.Id, routeAPI, routeIssueCommentDialogOpen),
+			Context: ctx,
+		},
+	})
+
 	actions = append(actions, &model.PostAction{
 		Name: "Share publicly",
 		Type: "button",
@@ -87,7 +96,7 @@ func getActions(p *Plugin, instanceID types.ID, client Client, issue *jira.Issue
 	return actions, nil
 }
 
-func asSlackAttachment(instance Instance, client Client, issue *jira.Issue, showActions bool) ([]*model.SlackAttachment, error) {
+func asSlackAttachment(instance Instance, scope types.ID, client Client, issue *jira.Issue, showActions bool) ([]*model.SlackAttachment, error) {
 	text := mdKeySummaryLink(issue, instance)
 	desc := truncate(issue.Fields.Description, 3000)
 	desc = parseJiraLinksToMarkdown(desc)
@@ -122,7 +131,7 @@ func asSlackAttachment(instance Instance, client Client, issue *jira.Issue, show
 	var actions []*model.PostAction
 	var err error
 	if showActions {
-		actions, err = getActions(instance.Common().Plugin, instance.GetID(), client, issue)
+		actions, err = getActions(instance.Common().Plugin, instance.GetID(), scope, client, issue)
 		if err != nil {
 			return []*model.SlackAttachment{}, err
 		}
@@ -144,6 +144,18 @@ func TestAsSlackAttachment(t *testing.T) {
 							},
 						},
 					},
+					{
+						Type: "button",
+						Name: "Add comment",
+						Integration: &model.PostActionIntegration{
+							URL: fmt.Sprintf("/plugins/%s/api/v2/comment-issue", manifest.Id),
+							Context: map[string]any{
+								"issue_key":        "some ID",
+								"instance_id":      testInstance2.GetID().String(),
+								"action_signature": testPlugin.generatePostActionSignature("some ID", testInstance2.GetID().String()),
+							},
+						},
+					},
 					{
 						Type: "button",
 						Name: "Share publicly",
@@ -161,7 +173,7 @@ func TestAsSlackAttachment(t *testing.T) {
 		},
 	} {
 		t.Run(name, func(t *testing.T) {
-			attachments, err := asSlackAttachment(instance, client, tc.issue, tc.showActions)
+			attachments, err := asSlackAttachment(instance, "", client, tc.issue, tc.showActions)
 			assert.NoError(t, err)
 			require.Len(t, attachments, 1)
 			assert.Equal(t, tc.expectedAttachment, attachments[0])
@@ -42,6 +42,7 @@ type testClient struct {
 	ProjectService
 	SearchService
 	IssueService
+	AgileService
 }
 
 func (client testClient) GetProject(key string) (*jira.Project, error) {
@@ -217,6 +218,21 @@ func (client testClient) GetCreateMetaInfo(api plugin.API, options *jira.GetQuer
 									},
 								},
 							},
+							"customfield_10020": tcontainer.MarshalMap{
+								"schema": tcontainer.MarshalMap{
+									"type": "number",
+								},
+							},
+							"duedate": tcontainer.MarshalMap{
+								"schema": tcontainer.MarshalMap{
+									"type": "date",
+								},
+							},
+							"customfield_10030": tcontainer.MarshalMap{
+								"schema": tcontainer.MarshalMap{
+									"type": "user",
+								},
+							},
 						},
 					},
 				},
@@ -931,6 +947,7 @@ func TestRouteAttachCommentToIssue(t *testing.T) {
 		api.On("GetUser", "1").Return(&model.User{Username: "username"}, (*model.AppError)(nil)).Once()
 		api.On("GetChannelMember", mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(&model.ChannelMember{}, nil).Once()
 		api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(&model.Post{}, (*model.AppError)(nil)).Once()
+		api.On("GetChannel", "test_channel").Return(&model.Channel{Id: "test_channel", Name: "test-channel"}, (*model.AppError)(nil)).Once()
 	}
 
 	baseMocks := func(api *plugintest.API) {
@@ -1161,6 +1178,8 @@ func TestCreateIssue(t *testing.T) {
 		Message: "User does not have access to this channel",
 	})
 
+	api.On("GetChannel", "channel_id_1").Return(&model.Channel{Id: "channel_id_1", Name: "channel-1"}, (*model.AppError)(nil))
+
 	// Mock successful issue creation
 	api.On("SendEphemeralPost", mock.AnythingOfType("string"), mock.AnythingOfType("*model.Post")).Return(&model.Post{})
 	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Return(&model.Post{}, (*model.AppError)(nil))
@@ -0,0 +1,129 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+const (
+	// jiraTextFieldMaxLength is the maximum length of the description and of the comments of
+	// an issue.
+	jiraTextFieldMaxLength = 32767
+
+	threadPostSeparator = "\n\n----\n"
+	threadTimeLayout    = "2006-01-02 15:04 MST"
+)
+
+// compileThread renders the thread of a post in Jira markup, after the description. The
+// first text is the description of the issue, and the others, if the thread is too long for
+// the description, are its first comments. The IDs of the files of the whole thread are
+// returned along.
+func (p *Plugin) compileThread(instance Instance, post *model.Post, currentTeam, description string) ([]string, []string, error) {
+	rootID := post.Id
+	if post.RootId != "" {
+		rootID = post.RootId
+	}
+
+	posts, err := p.getThreadPosts(rootID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := threadMarkupHeader(description, getPermaLink(instance, rootID, currentTeam), jiraTextFieldMaxLength)
+
+	usernames := map[string]string{}
+	entries := []string{}
+	fileIDs := []string{}
+	for _, threadPost := range posts {
+		username, ok := usernames[threadPost.UserId]
+		if !ok {
+			username = threadPost.UserId
+			if user, userErr := p.client.User.Get(threadPost.UserId); userErr == nil {
+				username = user.Username
+			}
+			usernames[threadPost.UserId] = username
+		}
+		entries = append(entries, formatThreadPost(username, threadPost))
+		fileIDs = append(fileIDs, threadPost.FileIds...)
+	}
+
+	return splitThreadMarkup(header, entries, jiraTextFieldMaxLength), fileIDs, nil
+}
+
+// threadMarkupHeader returns the description followed by the link to the thread, truncating
+// the description so that both fit in maxLength characters.
+func threadMarkupHeader(description, permalink string, maxLength int) string {
+	header := fmt.Sprintf("_Issue created from a [thread in Mattermost|%v]_.", permalink)
+	if description == "" {
+		return header
+	}
+	const separator = "\n\n"
+	description = truncate(description, maxLength-utf8.RuneCountInString(header)-utf8.RuneCountInString(separator))
+	return description + separator + header
+}
+
+// getThreadPosts returns the posts of a thread, oldest first, without the system messages.
+func (p *Plugin) getThreadPosts(rootID string) ([]*model.Post, error) {
+	list, err := p.client.Post.GetPostThread(rootID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to load thread "+rootID)
+	}
+
+	posts := []*model.Post{}
+	for _, post := range list.Posts {
+		if post.IsSystemMessage() || post.DeleteAt != 0 {
+			continue
+		}
+		posts = append(posts, post)
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return posts[i].CreateAt < posts[j].CreateAt
+	})
+	return posts, nil
+}
+
+func formatThreadPost(username string, post *model.Post) string {
+	created := time.UnixMilli(post.CreateAt).UTC().Format(threadTimeLayout)
+	entry := fmt.Sprintf("*@%s* _%s_\n%s", username, created, markdownToJiraMarkup(post.Message))
+	switch len(post.FileIds) {
+	case 0:
+	case 1:
+		entry += "\n_(1 attached file)_"
+	default:
+		entry += fmt.Sprintf("\n_(%d attached files)_", len(post.FileIds))
+	}
+	return entry
+}
+
+// splitThreadMarkup packs the header and the entries of a thread into texts of at most
+// maxLength characters, truncating the entries that don't fit in a text of their own.
+func splitThreadMarkup(header string, entries []string, maxLength int) []string {
+	texts := []string{}
+	current := header
+	for _, entry := range entries {
+		entry = truncate(entry, maxLength)
+		if current == "" {
+			current = entry
+			continue
+		}
+		if utf8.RuneCountInString(current)+utf8.RuneCountInString(threadPostSeparator)+utf8.RuneCountInString(entry) > maxLength {
+			texts = append(texts, current)
+			current = entry
+			continue
+		}
+		current += threadPostSeparator + entry
+	}
+	if current != "" {
+		texts = append(texts, current)
+	}
+	return texts
+}
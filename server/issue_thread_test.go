@@ -0,0 +1,94 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatThreadPost(t *testing.T) {
+	createAt := time.Date(2026, 10, 18, 14, 3, 0, 0, time.UTC).UnixMilli()
+
+	assert.Equal(t, "*@alice* _2026-10-18 14:03 UTC_\nThe build is broken",
+		formatThreadPost("alice", &model.Post{CreateAt: createAt, Message: "The build is broken"}))
+	assert.Equal(t, "*@bob* _2026-10-18 14:03 UTC_\nLogs attached\n_(2 attached files)_",
+		formatThreadPost("bob", &model.Post{CreateAt: createAt, Message: "Logs attached", FileIds: []string{"file1", "file2"}}))
+	assert.Equal(t, "*@carol* _2026-10-18 14:03 UTC_\nSee *this* and {{make all}}",
+		formatThreadPost("carol", &model.Post{CreateAt: createAt, Message: "See **this** and `make all`"}))
+}
+
+func TestThreadMarkupHeader(t *testing.T) {
+	link := "_Issue created from a [thread in Mattermost|https://mm.example.com/pl/post1]_."
+	assert.Equal(t, link, threadMarkupHeader("", "https://mm.example.com/pl/post1", 200))
+	assert.Equal(t, "Steps\n\n"+link, threadMarkupHeader("Steps", "https://mm.example.com/pl/post1", 200))
+
+	header := threadMarkupHeader(strings.Repeat("a", 300), "https://mm.example.com/pl/post1", 200)
+	assert.Len(t, header, 200)
+	assert.True(t, strings.HasSuffix(header, "...\n\n"+link))
+}
+
+func TestSplitThreadMarkup(t *testing.T) {
+	for name, tc := range map[string]struct {
+		header    string
+		entries   []string
+		maxLength int
+		expected  []string
+	}{
+		"fits in the description": {
+			header:    "header",
+			entries:   []string{"one", "two"},
+			maxLength: 100,
+			expected:  []string{"header" + threadPostSeparator + "one" + threadPostSeparator + "two"},
+		},
+		"overflows into comments": {
+			header:    "header",
+			entries:   []string{"one", "two", "three"},
+			maxLength: 20,
+			expected:  []string{"header" + threadPostSeparator + "one", "two" + threadPostSeparator + "three"},
+		},
+		"truncates long entries": {
+			header:    "header",
+			entries:   []string{strings.Repeat("a", 30)},
+			maxLength: 20,
+			expected:  []string{"header", strings.Repeat("a", 17) + "..."},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, splitThreadMarkup(tc.header, tc.entries, tc.maxLength))
+		})
+	}
+}
+
+func TestGetThreadPosts(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("GetPostThread", "root").Return(&model.PostList{
+		Posts: map[string]*model.Post{
+			"root":   {Id: "root", CreateAt: 1, Message: "root"},
+			"reply2": {Id: "reply2", RootId: "root", CreateAt: 3, Message: "second reply"},
+			"reply1": {Id: "reply1", RootId: "root", CreateAt: 2, Message: "first reply"},
+			"joined": {Id: "joined", RootId: "root", CreateAt: 4, Type: model.PostTypeJoinChannel},
+		},
+	}, nil)
+
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(api, p.Driver)
+
+	posts, err := p.getThreadPosts("root")
+	require.NoError(t, err)
+
+	ids := []string{}
+	for _, post := range posts {
+		ids = append(ids, post.Id)
+	}
+	assert.Equal(t, []string{"root", "reply1", "reply2"}, ids)
+}
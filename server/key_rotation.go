@@ -0,0 +1,629 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/kvstore"
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+const (
+	keyRotationKey         = "key_rotation"
+	keyRotationMutexKey    = "key_rotation_mutex"
+	keyRotationLockTimeout = 5 * time.Second
+	maxKeyRotationFailures = 100
+	maxKeyRotationPasses   = 10
+
+	rsaKeyBits           = 2048
+	rsaKeyReloadInterval = time.Minute
+)
+
+// keyRotation is the state of the last encryption key rotation. The rotation re-encrypts the
+// stored secrets in passes, until a pass finds no secret encrypted with the previous key. It
+// is resumed from the start of a pass when the plugin is activated.
+type keyRotation struct {
+	// EncryptionKey is the new key, PreviousEncryptionKey is accepted until the rotation completes
+	EncryptionKey         string
+	PreviousEncryptionKey string `json:",omitempty"`
+
+	// PreviousTokenSecret is accepted for the auth tokens issued before the rotation
+	PreviousTokenSecret []byte `json:",omitempty"`
+
+	StartedBy   string
+	StartedAt   int64
+	CompletedAt int64 `json:",omitempty"`
+
+	// Passes is the number of completed passes, Processed and Failures are those of the last one
+	Passes      int
+	Processed   int
+	Reencrypted int
+	Failures    []keyRotationFailure `json:",omitempty"`
+}
+
+// keyRotationFailure is a connection, or an instance when MattermostUserID is empty, whose
+// secrets could not be re-encrypted.
+type keyRotationFailure struct {
+	MattermostUserID types.ID `json:",omitempty"`
+	InstanceID       types.ID
+	Error            string
+}
+
+func (r *keyRotation) addFailure(instanceID, mattermostUserID types.ID, err error) {
+	if len(r.Failures) < maxKeyRotationFailures {
+		r.Failures = append(r.Failures, keyRotationFailure{
+			MattermostUserID: mattermostUserID,
+			InstanceID:       instanceID,
+			Error:            err.Error(),
+		})
+	}
+}
+
+func (r *keyRotation) inProgress() bool {
+	return r != nil && r.StartedAt != 0 && r.CompletedAt == 0
+}
+
+func (p *Plugin) getKeyRotation() (*keyRotation, error) {
+	var rotation *keyRotation
+	if err := p.client.KV.Get(keyRotationKey, &rotation); err != nil {
+		return nil, errors.Wrap(err, "failed to load the key rotation state")
+	}
+	return rotation, nil
+}
+
+func (p *Plugin) storeKeyRotation(rotation *keyRotation) error {
+	if _, err := p.client.KV.Set(keyRotationKey, rotation); err != nil {
+		return errors.Wrap(err, "failed to store the key rotation state")
+	}
+	return nil
+}
+
+// decryptWithEncryptionKey decrypts data encrypted with the EncryptionKey setting. While a
+// rotation is in progress, data encrypted with the previous key is accepted as well.
+func (p *Plugin) decryptWithEncryptionKey(encrypted []byte) ([]byte, error) {
+	plain, err := decrypt(encrypted, []byte(p.getConfig().EncryptionKey))
+	if err == nil {
+		return plain, nil
+	}
+
+	rotation, rotationErr := p.getKeyRotation()
+	if rotationErr != nil || rotation == nil {
+		return nil, err
+	}
+	for _, key := range []string{rotation.EncryptionKey, rotation.PreviousEncryptionKey} {
+		if key == "" {
+			continue
+		}
+		if plain, keyErr := decrypt(encrypted, []byte(key)); keyErr == nil {
+			return plain, nil
+		}
+	}
+	return nil, err
+}
+
+// previousAuthTokenSecret returns the auth token secret replaced by the last rotation, as long
+// as the tokens issued with it may still be valid.
+func (p *Plugin) previousAuthTokenSecret() []byte {
+	rotation, err := p.getKeyRotation()
+	if err != nil || rotation == nil || len(rotation.PreviousTokenSecret) == 0 {
+		return nil
+	}
+	if time.Since(time.UnixMilli(rotation.StartedAt)) > authTokenTTL {
+		return nil
+	}
+	return rotation.PreviousTokenSecret
+}
+
+// reencryptWithKey re-encrypts an encoded value with the new key. It returns false if the
+// value is already encrypted with the new key.
+func reencryptWithKey(encoded, newKey, previousKey string) (string, bool, error) {
+	encrypted, err := base64.URLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", false, errors.Wrap(err, "failed to decode")
+	}
+	if _, err = decrypt(encrypted, []byte(newKey)); err == nil {
+		return encoded, false, nil
+	}
+
+	plain, err := decrypt(encrypted, []byte(previousKey))
+	if err != nil {
+		return "", false, errors.Wrap(err, "failed to decrypt with the previous key")
+	}
+	encrypted, err = encrypt(plain, []byte(newKey))
+	if err != nil {
+		return "", false, errors.Wrap(err, "failed to encrypt with the new key")
+	}
+	return encode(encrypted), true, nil
+}
+
+// startKeyRotation replaces the encryption key and the auth token secret, re-encrypts the
+// admin API token in the plugin configuration, and starts re-encrypting the connections.
+func (p *Plugin) startKeyRotation(mattermostUserID string) (*keyRotation, error) {
+	rotation, err := p.getKeyRotation()
+	if err != nil {
+		return nil, err
+	}
+	if rotation.inProgress() {
+		return nil, errors.New("a key rotation is already in progress, use `/jira keys status` to follow it")
+	}
+
+	ec := externalConfig{}
+	if err = p.client.Configuration.LoadPluginConfiguration(&ec); err != nil {
+		return nil, errors.Wrap(err, "failed to load the plugin configuration")
+	}
+	newKey, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+	previousTokenSecret, err := p.secretsStore.EnsureAuthTokenEncryptSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	rotation = &keyRotation{
+		EncryptionKey:         newKey,
+		PreviousEncryptionKey: ec.EncryptionKey,
+		PreviousTokenSecret:   previousTokenSecret,
+		StartedBy:             mattermostUserID,
+		StartedAt:             model.GetMillis(),
+	}
+
+	if ec.AdminAPIToken != "" {
+		plain, decErr := decrypt([]byte(ec.AdminAPIToken), []byte(ec.EncryptionKey))
+		if decErr != nil {
+			return nil, errors.Wrap(decErr, "failed to decrypt the admin API token, re-save it in the System Console first")
+		}
+		encrypted, encErr := encrypt(plain, []byte(newKey))
+		if encErr != nil {
+			return nil, errors.Wrap(encErr, "failed to encrypt the admin API token")
+		}
+		ec.AdminAPIToken = string(encrypted)
+	}
+	ec.EncryptionKey = newKey
+
+	newTokenSecret := make([]byte, 32)
+	if _, err = rand.Read(newTokenSecret); err != nil {
+		return nil, err
+	}
+
+	// The state is stored first, so that the previous key is accepted as soon as the new one is in use
+	if err = p.storeKeyRotation(rotation); err != nil {
+		return nil, err
+	}
+	if err = p.storeConfig(ec); err != nil {
+		return nil, errors.Wrap(err, "failed to store the new encryption key")
+	}
+	if _, err = p.client.KV.Set(keyTokenSecret, newTokenSecret); err != nil {
+		return nil, errors.Wrap(err, "failed to store the new auth token secret")
+	}
+
+	go p.runKeyRotation()
+
+	return rotation, nil
+}
+
+// runKeyRotation re-encrypts the stored secrets. It is started by the rotation command, and
+// resumed when the plugin is activated. The previous key is accepted until a pass over all the
+// secrets finds none encrypted with it, since the KV keys listed in a pass may shift while
+// other keys are added or deleted.
+func (p *Plugin) runKeyRotation() {
+	mutex, err := cluster.NewMutex(p.API, keyRotationMutexKey)
+	if err != nil {
+		p.client.Log.Warn("Failed to create the key rotation mutex", "error", err.Error())
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), keyRotationLockTimeout)
+	defer cancel()
+	if err = mutex.LockWithContext(ctx); err != nil {
+		// Another server is already running the rotation
+		return
+	}
+	defer mutex.Unlock()
+
+	for {
+		rotation, err := p.getKeyRotation()
+		if err != nil {
+			p.client.Log.Warn("Failed to load the key rotation state", "error", err.Error())
+			return
+		}
+		if !rotation.inProgress() {
+			return
+		}
+		if rotation.Passes >= maxKeyRotationPasses {
+			p.client.Log.Warn("The key rotation keeps finding secrets encrypted with the previous key, both keys remain accepted")
+			return
+		}
+
+		found, err := p.runKeyRotationPass(rotation)
+		if err != nil {
+			p.client.Log.Warn("Failed to re-encrypt the stored secrets, the rotation will resume when the plugin is restarted", "error", err.Error())
+			return
+		}
+		rotation.Passes++
+		if found == 0 {
+			rotation.CompletedAt = model.GetMillis()
+			rotation.EncryptionKey = ""
+			rotation.PreviousEncryptionKey = ""
+		}
+		if err = p.storeKeyRotation(rotation); err != nil {
+			p.client.Log.Warn("Failed to store the key rotation progress", "error", err.Error())
+			return
+		}
+
+		if rotation.CompletedAt != 0 {
+			p.notifyKeyRotationComplete(rotation)
+			return
+		}
+	}
+}
+
+// runKeyRotationPass re-encrypts the instance secrets and the connections, and returns the
+// number of secrets that were still encrypted with the previous key.
+func (p *Plugin) runKeyRotationPass(rotation *keyRotation) (int, error) {
+	rotation.Processed = 0
+	rotation.Failures = nil
+	found := 0
+
+	instances, err := p.instanceStore.LoadInstances()
+	if err != nil {
+		return 0, err
+	}
+	for _, instanceID := range instances.IDs() {
+		reencrypted, err := p.reencryptInstanceSecrets(instanceID, rotation)
+		if err != nil {
+			rotation.addFailure(instanceID, "", err)
+			continue
+		}
+		if reencrypted {
+			found++
+		}
+	}
+
+	// The user keys are listed before they are processed, to list them as fast as possible
+	keys, err := p.listUserKeys()
+	if err != nil {
+		return 0, err
+	}
+	for _, key := range keys {
+		found += p.reencryptUserConnections(key, rotation)
+	}
+
+	rotation.Reencrypted += found
+	return found, nil
+}
+
+func (p *Plugin) listUserKeys() ([]string, error) {
+	userKeys := []string{}
+	for i := 0; ; i++ {
+		keys, err := p.client.KV.ListKeys(i, listPerPage)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if strings.HasPrefix(key, prefixUser) {
+				userKeys = append(userKeys, key)
+			}
+		}
+		if len(keys) < listPerPage {
+			return userKeys, nil
+		}
+	}
+}
+
+func (p *Plugin) reencryptInstanceSecrets(instanceID types.ID, rotation *keyRotation) (bool, error) {
+	var record instanceSecretsRecord
+	kv := kvstore.NewStore(kvstore.NewPluginStore(p.client))
+	if err := kv.Entity(prefixInstance).Load(instanceID, &record); err != nil {
+		return false, err
+	}
+
+	stale := false
+	for _, value := range record.values() {
+		if !isStaleSecret(value, rotation.EncryptionKey) {
+			continue
+		}
+		if strings.HasPrefix(value, encryptedSecretPrefix) && isStaleSecret(value, rotation.PreviousEncryptionKey) {
+			return false, errors.New("a secret is encrypted with an unknown key, the instance needs to be configured again")
+		}
+		stale = true
+	}
+	if !stale {
+		return false, nil
+	}
+
+	// The instance is stored with its secrets encrypted with the new key
+	instance, err := p.instanceStore.LoadInstance(instanceID)
+	if err != nil {
+		return false, err
+	}
+	if err = p.instanceStore.StoreInstance(instance); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (p *Plugin) reencryptUserConnections(key string, rotation *keyRotation) int {
+	user := NewUser("")
+	if err := p.client.KV.Get(key, user); err != nil || user.ConnectedInstances == nil {
+		return 0
+	}
+
+	found := 0
+	for _, instanceID := range user.ConnectedInstances.IDs() {
+		rotation.Processed++
+		reencrypted, err := p.reencryptConnection(instanceID, user.MattermostUserID, rotation)
+		if err != nil {
+			rotation.addFailure(instanceID, user.MattermostUserID, err)
+			continue
+		}
+		if reencrypted {
+			found++
+		}
+	}
+	return found
+}
+
+func (p *Plugin) reencryptConnection(instanceID, mattermostUserID types.ID, rotation *keyRotation) (bool, error) {
+	// The connection is read as stored, LoadConnection would re-encrypt it without counting it
+	connection := &Connection{}
+	if err := p.client.KV.Get(keyWithInstanceID(instanceID, mattermostUserID), connection); err != nil {
+		return false, errors.Wrap(err, "failed to load the connection")
+	}
+	if connection.PersonalAccessToken == "" {
+		return false, nil
+	}
+
+	pat, changed, err := reencryptWithKey(connection.PersonalAccessToken, rotation.EncryptionKey, rotation.PreviousEncryptionKey)
+	if err != nil || !changed {
+		return false, err
+	}
+
+	connection.PersonalAccessToken = pat
+	if err = p.userStore.StoreConnection(instanceID, mattermostUserID, connection); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// reencryptPersonalAccessToken re-encrypts a token encrypted with the previous key while a
+// rotation is in progress.
+func (p *Plugin) reencryptPersonalAccessToken(encoded string) (string, bool) {
+	if encoded == "" {
+		return encoded, false
+	}
+	rotation, err := p.getKeyRotation()
+	if err != nil || !rotation.inProgress() {
+		return encoded, false
+	}
+	reencoded, changed, err := reencryptWithKey(encoded, rotation.EncryptionKey, rotation.PreviousEncryptionKey)
+	if err != nil {
+		return encoded, false
+	}
+	return reencoded, changed
+}
+
+func (p *Plugin) notifyKeyRotationComplete(rotation *keyRotation) {
+	if rotation.StartedBy == "" {
+		return
+	}
+	if _, err := p.CreateBotDMtoMMUserID(rotation.StartedBy, "%s", formatKeyRotation(rotation)); err != nil {
+		p.client.Log.Warn("Failed to notify the key rotation completion", "error", err.Error())
+	}
+}
+
+func formatKeyRotation(rotation *keyRotation) string {
+	if rotation == nil || rotation.StartedAt == 0 {
+		return "The encryption key has never been rotated."
+	}
+
+	text := ""
+	if rotation.inProgress() {
+		text = fmt.Sprintf("#### Encryption key rotation in progress\nStarted at %s.\n",
+			time.UnixMilli(rotation.StartedAt).UTC().Format(time.RFC3339))
+	} else {
+		text = fmt.Sprintf("#### Encryption key rotation complete\nStarted at %s, completed at %s.\n",
+			time.UnixMilli(rotation.StartedAt).UTC().Format(time.RFC3339),
+			time.UnixMilli(rotation.CompletedAt).UTC().Format(time.RFC3339))
+	}
+	text += fmt.Sprintf("Passes: %v, connections processed: %v, secrets re-encrypted: %v, failed: %v.\n",
+		rotation.Passes, rotation.Processed, rotation.Reencrypted, len(rotation.Failures))
+
+	if len(rotation.Failures) > 0 {
+		text += "\nThe following users need to reconnect their Jira account, and instances to be configured again:\n"
+		for _, f := range rotation.Failures {
+			if f.MattermostUserID == "" {
+				text += fmt.Sprintf("* Instance %s: %s\n", f.InstanceID, f.Error)
+				continue
+			}
+			text += fmt.Sprintf("* `%s` on %s: %s\n", f.MattermostUserID, f.InstanceID, f.Error)
+		}
+	}
+	return text
+}
+
+// getRSAKey returns the key that signs the OAuth 1.0a requests to Jira server. It is reloaded
+// periodically, so that a rollover completed on another server is picked up.
+func (p *Plugin) getRSAKey() *rsa.PrivateKey {
+	conf := p.getConfig()
+	if conf.rsaKeyLoadedAt.IsZero() || time.Since(conf.rsaKeyLoadedAt) < rsaKeyReloadInterval {
+		return conf.rsaKey
+	}
+
+	rsaKey, err := p.secretsStore.EnsureRSAKey()
+	if err != nil {
+		p.client.Log.Warn("Failed to reload the RSA key", "error", err.Error())
+		return conf.rsaKey
+	}
+	p.updateConfig(func(conf *config) {
+		conf.rsaKey = rsaKey
+		conf.rsaKeyLoadedAt = time.Now()
+	})
+	return rsaKey
+}
+
+func publicKeyPEM(rsaKey *rsa.PrivateKey) (string, error) {
+	b, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to encode public key")
+	}
+	pkey := pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: b,
+	})
+	return strings.TrimSpace(string(pkey)), nil
+}
+
+func (p *Plugin) loadNextRSAKey() (*rsa.PrivateKey, error) {
+	var rsaKey *rsa.PrivateKey
+	if err := p.client.KV.Get(keyRSAKeyNext, &rsaKey); err != nil {
+		return nil, errors.Wrap(err, "failed to load the new RSA key")
+	}
+	return rsaKey, nil
+}
+
+// startRSAKeyRollover generates the new RSA key. The current key keeps signing the requests
+// until the application links in Jira are updated, and the rollover is completed.
+func (p *Plugin) startRSAKeyRollover() (*rsa.PrivateKey, error) {
+	rsaKey, err := p.loadNextRSAKey()
+	if err != nil || rsaKey != nil {
+		return rsaKey, err
+	}
+
+	rsaKey, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate the new RSA key")
+	}
+	if _, err = p.client.KV.Set(keyRSAKeyNext, rsaKey); err != nil {
+		return nil, errors.Wrap(err, "failed to store the new RSA key")
+	}
+	return rsaKey, nil
+}
+
+func (p *Plugin) completeRSAKeyRollover() error {
+	rsaKey, err := p.loadNextRSAKey()
+	if err != nil {
+		return err
+	}
+	if rsaKey == nil {
+		return errors.New("no RSA key rollover in progress, use `/jira keys rsa start` first")
+	}
+
+	if _, err = p.client.KV.Set(keyRSAKey, rsaKey); err != nil {
+		return errors.Wrap(err, "failed to store the new RSA key")
+	}
+	if err = p.client.KV.Delete(keyRSAKeyNext); err != nil {
+		return errors.Wrap(err, "failed to delete the pending RSA key")
+	}
+
+	p.updateConfig(func(conf *config) {
+		conf.rsaKey = rsaKey
+		conf.rsaKeyLoadedAt = time.Now()
+	})
+	return nil
+}
+
+func executeKeysRotate(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira keys rotate` can only be run by a system administrator.")
+	}
+	if len(args) != 0 {
+		return p.responsef(header, "Please use `/jira keys rotate`.")
+	}
+
+	if _, err = p.startKeyRotation(header.UserId); err != nil {
+		return p.responsef(header, "Failed to rotate the encryption key. Error: %v.", err)
+	}
+
+	return p.responsef(header, "The encryption key has been replaced. The stored tokens are being re-encrypted in the background, "+
+		"both keys are accepted until it completes. Use `/jira keys status` to follow the progress.")
+}
+
+func executeKeysStatus(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira keys status` can only be run by a system administrator.")
+	}
+
+	rotation, err := p.getKeyRotation()
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	text := formatKeyRotation(rotation)
+
+	nextRSAKey, err := p.loadNextRSAKey()
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if nextRSAKey != nil {
+		text += "\nAn RSA key rollover is in progress, use `/jira keys rsa start` to see the public keys.\n"
+	}
+
+	return p.response(header, text)
+}
+
+func executeKeysRSAStart(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira keys rsa start` can only be run by a system administrator.")
+	}
+
+	nextRSAKey, err := p.startRSAKeyRollover()
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	currentPublicKey, err := publicKeyPEM(p.getRSAKey())
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	nextPublicKey, err := publicKeyPEM(nextRSAKey)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+
+	return p.respondCommandTemplate(header, "/command/rsa_rollover.md", map[string]string{
+		"CurrentPublicKey": currentPublicKey,
+		"NextPublicKey":    nextPublicKey,
+	})
+}
+
+func executeKeysRSAComplete(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira keys rsa complete` can only be run by a system administrator.")
+	}
+
+	if err = p.completeRSAKeyRollover(); err != nil {
+		return p.responsef(header, "%v", err)
+	}
+
+	return p.responsef(header, "The new RSA key now signs the requests to the Jira Server and Data Center instances. "+
+		"Users connected with an Application Link keep their connections.")
+}
@@ -0,0 +1,258 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+const (
+	testPreviousEncryptionKey = "0123456789abcdef0123456789abcdef"
+	testNewEncryptionKey      = "fedcba9876543210fedcba9876543210"
+)
+
+func TestReencryptWithKey(t *testing.T) {
+	encrypted, err := encrypt([]byte("secret"), []byte(testPreviousEncryptionKey))
+	require.NoError(t, err)
+	encoded := encode(encrypted)
+
+	reencoded, changed, err := reencryptWithKey(encoded, testNewEncryptionKey, testPreviousEncryptionKey)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	reencrypted, err := decode(reencoded)
+	require.NoError(t, err)
+	plain, err := decrypt(reencrypted, []byte(testNewEncryptionKey))
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(plain))
+
+	again, changed, err := reencryptWithKey(reencoded, testNewEncryptionKey, testPreviousEncryptionKey)
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, reencoded, again)
+
+	_, _, err = reencryptWithKey(encoded, testNewEncryptionKey, "00000000000000000000000000000000")
+	require.Error(t, err)
+}
+
+func TestKeyRotationAcceptsBothKeys(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("LogDebug", mockAnythingOfTypeBatch("string", 11)...).Return()
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+	p.userStore = NewStore(p)
+	makeTestKVStore(api, nil)
+	p.updateConfig(func(conf *config) {
+		conf.EncryptionKey = testPreviousEncryptionKey
+	})
+
+	pat, err := p.encryptPersonalAccessToken("pat-token")
+	require.NoError(t, err)
+	require.NoError(t, p.userStore.StoreConnection("instance", "user1", &Connection{PersonalAccessToken: pat}))
+
+	rotation := &keyRotation{
+		EncryptionKey:         testNewEncryptionKey,
+		PreviousEncryptionKey: testPreviousEncryptionKey,
+		StartedAt:             1,
+	}
+	require.NoError(t, p.storeKeyRotation(rotation))
+	p.updateConfig(func(conf *config) {
+		conf.EncryptionKey = testNewEncryptionKey
+	})
+
+	decrypted, err := p.decryptPersonalAccessToken(pat)
+	require.NoError(t, err)
+	assert.Equal(t, "pat-token", decrypted)
+
+	reencrypted, err := p.reencryptConnection("instance", "user1", rotation)
+	require.NoError(t, err)
+	assert.True(t, reencrypted)
+
+	connection, err := p.userStore.LoadConnection("instance", "user1")
+	require.NoError(t, err)
+	plain, err := decode(connection.PersonalAccessToken)
+	require.NoError(t, err)
+	_, err = decrypt(plain, []byte(testNewEncryptionKey))
+	require.NoError(t, err)
+
+	// Once the rotation completes, the previous key is no longer accepted
+	rotation.CompletedAt = 2
+	rotation.EncryptionKey = ""
+	rotation.PreviousEncryptionKey = ""
+	require.NoError(t, p.storeKeyRotation(rotation))
+
+	_, err = p.decryptPersonalAccessToken(pat)
+	require.Error(t, err)
+}
+
+func TestKeyRotationPasses(t *testing.T) {
+	api := &plugintest.API{}
+	api.On("LogDebug", mockAnythingOfTypeBatch("string", 11)...).Maybe().Return()
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+	s := NewStore(p)
+	p.userStore = s
+	p.instanceStore = s
+	kv := makeTestKVStore(api, nil)
+	api.On("KVList", mock.Anything, mock.Anything).Return(func(page, perPage int) []string {
+		keys := []string{}
+		for key := range kv {
+			keys = append(keys, key)
+		}
+		if page > 0 {
+			return nil
+		}
+		return keys
+	}, nil)
+	p.updateConfig(func(conf *config) {
+		conf.EncryptionKey = testPreviousEncryptionKey
+	})
+
+	instanceID := types.ID("https://jira.example.com")
+	instance := &serverOAuthInstance{
+		InstanceCommon:   &InstanceCommon{Plugin: p, InstanceID: instanceID, Type: ServerOAuthInstanceType},
+		JiraClientSecret: "client-secret",
+	}
+	require.NoError(t, p.instanceStore.StoreInstance(instance))
+	assert.Equal(t, "client-secret", instance.JiraClientSecret)
+	require.NoError(t, p.instanceStore.StoreInstances(NewInstances(instance.Common())))
+
+	user := NewUser("user1")
+	user.ConnectedInstances.Set(instance.Common())
+	require.NoError(t, p.userStore.StoreUser(user))
+	pat, err := p.encryptPersonalAccessToken("pat-token")
+	require.NoError(t, err)
+	require.NoError(t, p.userStore.StoreConnection(instanceID, "user1", &Connection{PersonalAccessToken: pat}))
+
+	rotation := &keyRotation{
+		EncryptionKey:         testNewEncryptionKey,
+		PreviousEncryptionKey: testPreviousEncryptionKey,
+		StartedAt:             1,
+	}
+	require.NoError(t, p.storeKeyRotation(rotation))
+	p.updateConfig(func(conf *config) {
+		conf.EncryptionKey = testNewEncryptionKey
+	})
+
+	found, err := p.runKeyRotationPass(rotation)
+	require.NoError(t, err)
+	assert.Equal(t, 2, found)
+	assert.Empty(t, rotation.Failures)
+
+	// A connection loaded before the rotation is stored with the new key
+	require.NoError(t, p.userStore.StoreConnection(instanceID, "user1", &Connection{PersonalAccessToken: pat}))
+
+	found, err = p.runKeyRotationPass(rotation)
+	require.NoError(t, err)
+	assert.Equal(t, 0, found)
+	assert.Equal(t, 2, rotation.Reencrypted)
+
+	// Once the rotation completes, the secrets are readable with the new key only
+	rotation.CompletedAt = 2
+	rotation.EncryptionKey = ""
+	rotation.PreviousEncryptionKey = ""
+	require.NoError(t, p.storeKeyRotation(rotation))
+
+	loaded, err := p.instanceStore.LoadInstance(instanceID)
+	require.NoError(t, err)
+	assert.Equal(t, "client-secret", loaded.(*serverOAuthInstance).JiraClientSecret)
+
+	connection, err := p.userStore.LoadConnection(instanceID, "user1")
+	require.NoError(t, err)
+	decrypted, err := p.decryptPersonalAccessToken(connection.PersonalAccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "pat-token", decrypted)
+}
+
+func TestOpenInstanceSecrets(t *testing.T) {
+	api := &plugintest.API{}
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+	makeTestKVStore(api, nil)
+	p.updateConfig(func(conf *config) {
+		conf.EncryptionKey = testPreviousEncryptionKey
+	})
+
+	sealed, err := p.sealSecret("client-secret")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sealed, encryptedSecretPrefix))
+
+	instance := &serverOAuthInstance{InstanceCommon: &InstanceCommon{InstanceID: "https://jira.example.com"}, JiraClientSecret: sealed}
+	p.openInstanceSecrets(instance)
+	assert.Equal(t, "client-secret", instance.JiraClientSecret)
+
+	// Secrets stored before they were encrypted are read as is
+	instance.JiraClientSecret = "legacy-secret"
+	p.openInstanceSecrets(instance)
+	assert.Equal(t, "legacy-secret", instance.JiraClientSecret)
+}
+
+func TestSealInstanceSecretsCloudOAuth(t *testing.T) {
+	api := &plugintest.API{}
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+	makeTestKVStore(api, nil)
+	p.updateConfig(func(conf *config) {
+		conf.EncryptionKey = testPreviousEncryptionKey
+	})
+
+	instance := &cloudOAuthInstance{
+		InstanceCommon:   &InstanceCommon{InstanceID: "https://example.atlassian.net", Type: CloudOAuthInstanceType},
+		JiraClientSecret: "client-secret",
+	}
+	_, err := p.sealInstanceSecrets(instance)
+	require.NoError(t, err)
+
+	// Earlier versions of the plugin read the client secret as is
+	assert.Equal(t, "client-secret", instance.JiraClientSecret)
+
+	// and it is not reported as stale by the key rotation
+	record := instanceSecretsRecord{Type: CloudOAuthInstanceType, JiraClientSecret: "client-secret"}
+	assert.Empty(t, record.values())
+}
+
+func TestSealInstanceSecretsHTTPClientKey(t *testing.T) {
+	api := &plugintest.API{}
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+	makeTestKVStore(api, nil)
+	p.updateConfig(func(conf *config) {
+		conf.EncryptionKey = testPreviousEncryptionKey
+	})
+
+	http := &InstanceHTTPConfig{ClientKey: "client-key"}
+	instance := &serverOAuthInstance{
+		InstanceCommon:   &InstanceCommon{InstanceID: "https://jira.example.com", HTTP: http},
+		JiraClientSecret: "client-secret",
+		LegacyInstance:   &serverInstance{InstanceCommon: &InstanceCommon{InstanceID: "https://jira.example.com", HTTP: http}},
+	}
+
+	restore, err := p.sealInstanceSecrets(instance)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(http.ClientKey, encryptedSecretPrefix))
+	sealed := http.ClientKey
+
+	// The HTTP settings shared with the legacy instance are encrypted once
+	p.openInstanceSecrets(instance)
+	assert.Equal(t, "client-key", http.ClientKey)
+	assert.Equal(t, "client-secret", instance.JiraClientSecret)
+
+	http.ClientKey = sealed
+	restore()
+	assert.Equal(t, "client-key", http.ClientKey)
+}
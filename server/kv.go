@@ -27,9 +27,11 @@ const (
 
 	keyInstances            = "instances/v3"
 	keyRSAKey               = "rsa_key"
+	keyRSAKeyNext           = "rsa_key_next"
 	keyTokenSecret          = "token_secret"
 	prefixInstance          = "jira_instance_"
 	prefixPendingCloudRoute = "jira_pcsetup_" // opaque routing id to jira URL during Connect install window
+	prefixPendingHTTPConfig = "jira_phttp_"   // HTTP settings of a Jira URL, until the instance is installed
 	prefixOneTimeSecret     = "ots_"          // + unique key that will be deleted after the first verification
 	prefixUser              = "user_"
 )
@@ -145,6 +147,12 @@ func (store store) StoreConnection(instanceID, mattermostUserID types.ID, connec
 	connection.PluginVersion = manifest.Version
 	connection.MattermostUserID = mattermostUserID
 
+	// A connection loaded before a key rotation must not bring back a token encrypted with
+	// the previous key
+	if pat, changed := store.plugin.reencryptPersonalAccessToken(connection.PersonalAccessToken); changed {
+		connection.PersonalAccessToken = pat
+	}
+
 	err := store.set(keyWithInstanceID(instanceID, mattermostUserID), connection)
 	if err != nil {
 		return err
@@ -177,6 +185,14 @@ func (store store) LoadConnection(instanceID, mattermostUserID types.ID) (*Conne
 			"failed to load connection for Mattermost user ID:%q, Jira:%q", mattermostUserID, instanceID)
 	}
 	c.PluginVersion = manifest.Version
+
+	// The connections missed by a key rotation are re-encrypted when they are used
+	if pat, changed := store.plugin.reencryptPersonalAccessToken(c.PersonalAccessToken); changed {
+		c.PersonalAccessToken = pat
+		if err = store.set(keyWithInstanceID(instanceID, mattermostUserID), c); err != nil {
+			store.plugin.client.Log.Warn("Failed to store the re-encrypted connection", "error", err.Error())
+		}
+	}
 	return c, nil
 }
 
@@ -535,6 +551,16 @@ func (store *store) LoadInstance(instanceID types.ID) (Instance, error) {
 }
 
 func (store *store) LoadInstanceFullKey(fullkey string) (Instance, error) {
+	instance, err := store.loadInstanceRecord(fullkey)
+	if err != nil {
+		return nil, err
+	}
+	store.plugin.openInstanceSecrets(instance)
+	return instance, nil
+}
+
+// loadInstanceRecord loads an instance, with its secrets still encrypted.
+func (store *store) loadInstanceRecord(fullkey string) (Instance, error) {
 	var data []byte
 	if err := store.plugin.client.KV.Get(fullkey, &data); err != nil {
 		return nil, err
@@ -579,12 +605,29 @@ func (store *store) LoadInstanceFullKey(fullkey string) (Instance, error) {
 	case ServerInstanceType:
 		si.Plugin = store.plugin
 		return &si, nil
+
+	case ServerOAuthInstanceType:
+		soi := serverOAuthInstance{}
+		if err := json.Unmarshal(data, &soi); err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("failed to unmarshal stored instance %s", fullkey))
+		}
+		if soi.LegacyInstance != nil {
+			soi.LegacyInstance.Common().Plugin = store.plugin
+		}
+		soi.Plugin = store.plugin
+		return &soi, nil
 	}
 
 	return nil, errors.Errorf("Jira instance %s has unsupported type %s", fullkey, si.Type)
 }
 
 func (store *store) StoreInstance(instance Instance) error {
+	restore, err := store.plugin.sealInstanceSecrets(instance)
+	if err != nil {
+		return errors.WithMessage(err, "failed to encrypt the secrets of instance "+instance.GetID().String())
+	}
+	defer restore()
+
 	kv := kvstore.NewStore(kvstore.NewPluginStore(store.plugin.client))
 	instance.Common().PluginVersion = manifest.Version
 	return kv.Entity(prefixInstance).Store(instance.GetID(), instance)
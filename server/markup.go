@@ -0,0 +1,85 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	reMarkdownHeading  = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	reMarkdownBullet   = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	reMarkdownNumbered = regexp.MustCompile(`^(\s*)\d+[.)]\s+(.*)$`)
+	reMarkdownQuote    = regexp.MustCompile(`^>\s?(.*)$`)
+	reMarkdownLink     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	reMarkdownItalic   = regexp.MustCompile(`(^|[^*\w])\*([^*\s](?:[^*]*[^*\s])?)\*`)
+	reMarkdownBold     = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*`)
+	reMarkdownStrike   = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+)
+
+// markdownToJiraMarkup converts the Markdown of Mattermost messages to Jira wiki markup.
+func markdownToJiraMarkup(text string) string {
+	lines := strings.Split(text, "\n")
+	inCode := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			if inCode {
+				lines[i] = "{code}"
+			} else if lang := strings.TrimSpace(strings.TrimPrefix(trimmed, "```")); lang != "" {
+				lines[i] = "{code:" + lang + "}"
+			} else {
+				lines[i] = "{code}"
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+
+		switch {
+		case reMarkdownHeading.MatchString(line):
+			m := reMarkdownHeading.FindStringSubmatch(line)
+			line = fmt.Sprintf("h%d. %s", len(m[1]), m[2])
+		case reMarkdownBullet.MatchString(line):
+			m := reMarkdownBullet.FindStringSubmatch(line)
+			line = strings.Repeat("*", len(m[1])/2+1) + " " + m[2]
+		case reMarkdownNumbered.MatchString(line):
+			m := reMarkdownNumbered.FindStringSubmatch(line)
+			line = strings.Repeat("#", len(m[1])/2+1) + " " + m[2]
+		case reMarkdownQuote.MatchString(line):
+			line = "bq. " + reMarkdownQuote.FindStringSubmatch(line)[1]
+		}
+		lines[i] = markdownInlineToJiraMarkup(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// markdownInlineToJiraMarkup converts the inline formatting of a line, leaving code spans as is.
+func markdownInlineToJiraMarkup(line string) string {
+	parts := strings.Split(line, "`")
+	if len(parts)%2 == 0 {
+		// Unbalanced backticks are not code spans
+		return markdownFormattingToJiraMarkup(line)
+	}
+	for i := range parts {
+		if i%2 == 1 {
+			parts[i] = "{{" + parts[i] + "}}"
+			continue
+		}
+		parts[i] = markdownFormattingToJiraMarkup(parts[i])
+	}
+	return strings.Join(parts, "")
+}
+
+func markdownFormattingToJiraMarkup(text string) string {
+	text = reMarkdownLink.ReplaceAllString(text, "[$1|$2]")
+	text = reMarkdownItalic.ReplaceAllString(text, "${1}_${2}_")
+	text = reMarkdownBold.ReplaceAllString(text, "*$1*")
+	text = reMarkdownStrike.ReplaceAllString(text, "-$1-")
+	return text
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdownToJiraMarkup(t *testing.T) {
+	for name, tc := range map[string]struct {
+		markdown string
+		expected string
+	}{
+		"plain text":    {"Login fails", "Login fails"},
+		"bold":          {"this is **important**", "this is *important*"},
+		"italic":        {"*really* and _really_", "_really_ and _really_"},
+		"strikethrough": {"~~gone~~", "-gone-"},
+		"link":          {"see [the docs](https://example.com/docs)", "see [the docs|https://example.com/docs]"},
+		"inline code":   {"run `make **all**` first", "run {{make **all**}} first"},
+		"heading":       {"## Steps", "h2. Steps"},
+		"bullets":       {"- one\n  - nested\n* two", "* one\n** nested\n* two"},
+		"numbered":      {"1. one\n2. two", "# one\n# two"},
+		"quote":         {"> it broke", "bq. it broke"},
+		"code block": {
+			"```go\nx := **y**\n```\nafter **bold**",
+			"{code:go}\nx := **y**\n{code}\nafter *bold*",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, markdownToJiraMarkup(tc.markdown))
+		})
+	}
+}
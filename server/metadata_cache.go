@@ -0,0 +1,269 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+const (
+	// metadataCacheTTL is how long the cached metadata is fresh. Stale metadata is still
+	// served for metadataCacheStaleTTL while it is refreshed in the background.
+	metadataCacheTTL      = 10 * time.Minute
+	metadataCacheStaleTTL = time.Hour
+
+	// Jira sends no webhook events when a workflow changes, so transitions expire sooner
+	transitionsCacheTTL = time.Minute
+
+	metadataCacheMaxEntries = 10000
+
+	metadataCacheClusterEventID = "metadata_cache_invalidate"
+)
+
+const (
+	metadataKindProjects       = "projects"
+	metadataKindCreateMeta     = "createmeta"
+	metadataKindSecurityLevels = "security_levels"
+	metadataKindTransitions    = "transitions"
+	metadataKindLinkTypes      = "link_types"
+)
+
+// metadataCacheKey identifies cached metadata. Jira only returns the projects, fields and
+// transitions the user has access to, so the metadata is cached per Jira account.
+type metadataCacheKey struct {
+	instanceID types.ID
+	scope      types.ID
+	kind       string
+	args       string
+}
+
+type metadataCacheEntry struct {
+	value      interface{}
+	fetchedAt  time.Time
+	refreshing bool
+}
+
+// metadataCache keeps the metadata fetched from Jira in memory, on each server of the
+// cluster. The cached values are shared and must not be modified. The zero value is ready to
+// use.
+type metadataCache struct {
+	lock    sync.Mutex
+	entries map[metadataCacheKey]*metadataCacheEntry
+
+	// generation changes on every invalidation, so that fetches started before an
+	// invalidation are not cached
+	generation uint64
+}
+
+// get returns the cached value, calling fetch if it is not cached. Stale values are returned
+// right away and refreshed in the background.
+func (c *metadataCache) get(key metadataCacheKey, ttl time.Duration, fetch func() (interface{}, error)) (interface{}, error) {
+	now := time.Now()
+
+	c.lock.Lock()
+	generation := c.generation
+	if entry := c.entries[key]; entry != nil {
+		age := now.Sub(entry.fetchedAt)
+		if age < ttl {
+			c.lock.Unlock()
+			return entry.value, nil
+		}
+		if age < ttl+metadataCacheStaleTTL {
+			if !entry.refreshing {
+				entry.refreshing = true
+				go c.refresh(key, generation, fetch)
+			}
+			c.lock.Unlock()
+			return entry.value, nil
+		}
+	}
+	c.lock.Unlock()
+
+	value, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	c.set(key, generation, value, time.Now())
+	return value, nil
+}
+
+func (c *metadataCache) refresh(key metadataCacheKey, generation uint64, fetch func() (interface{}, error)) {
+	value, err := fetch()
+	if err != nil {
+		// The stale value is served until it expires, the next request retries
+		c.lock.Lock()
+		if entry := c.entries[key]; entry != nil {
+			entry.refreshing = false
+		}
+		c.lock.Unlock()
+		return
+	}
+	c.set(key, generation, value, time.Now())
+}
+
+func (c *metadataCache) set(key metadataCacheKey, generation uint64, value interface{}, now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	if c.entries == nil {
+		c.entries = map[metadataCacheKey]*metadataCacheEntry{}
+	}
+	if len(c.entries) >= metadataCacheMaxEntries {
+		c.evictLocked(now)
+	}
+	c.entries[key] = &metadataCacheEntry{
+		value:     value,
+		fetchedAt: now,
+	}
+}
+
+// evictLocked removes the expired entries, or the oldest entries if none have expired.
+func (c *metadataCache) evictLocked(now time.Time) {
+	var oldestKey metadataCacheKey
+	var oldest *metadataCacheEntry
+	for key, entry := range c.entries {
+		if now.Sub(entry.fetchedAt) > metadataCacheTTL+metadataCacheStaleTTL {
+			delete(c.entries, key)
+			continue
+		}
+		if oldest == nil || entry.fetchedAt.Before(oldest.fetchedAt) {
+			oldestKey, oldest = key, entry
+		}
+	}
+	if len(c.entries) >= metadataCacheMaxEntries && oldest != nil {
+		delete(c.entries, oldestKey)
+	}
+}
+
+// invalidate removes the cached metadata of an instance, of all the instances if instanceID is
+// empty. kinds limits the invalidation to some kinds of metadata.
+func (c *metadataCache) invalidate(instanceID types.ID, kinds ...string) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.generation++
+	kindSet := NewStringSet(kinds...)
+	removed := 0
+	for key := range c.entries {
+		if instanceID != "" && key.instanceID != instanceID {
+			continue
+		}
+		if len(kinds) > 0 && !kindSet.ContainsAny(key.kind) {
+			continue
+		}
+		delete(c.entries, key)
+		removed++
+	}
+	return removed
+}
+
+type metadataCacheInvalidation struct {
+	InstanceID types.ID `json:"instance_id,omitempty"`
+	Kinds      []string `json:"kinds,omitempty"`
+}
+
+// invalidateMetadata invalidates the cached metadata on all the servers of the cluster.
+func (p *Plugin) invalidateMetadata(instanceID types.ID, kinds ...string) int {
+	removed := p.metadataCache.invalidate(instanceID, kinds...)
+
+	data, err := json.Marshal(metadataCacheInvalidation{InstanceID: instanceID, Kinds: kinds})
+	if err != nil {
+		p.client.Log.Warn("Failed to marshal the metadata cache invalidation", "error", err.Error())
+		return removed
+	}
+	err = p.API.PublishPluginClusterEvent(
+		model.PluginClusterEvent{Id: metadataCacheClusterEventID, Data: data},
+		model.PluginClusterEventSendOptions{SendType: model.PluginClusterEventSendTypeReliable},
+	)
+	if err != nil {
+		p.client.Log.Warn("Failed to invalidate the metadata cache on the other servers", "error", err.Error())
+	}
+	return removed
+}
+
+// OnPluginClusterEvent receives the events published by the other servers of the cluster.
+func (p *Plugin) OnPluginClusterEvent(c *plugin.Context, ev model.PluginClusterEvent) {
+	if ev.Id != metadataCacheClusterEventID {
+		return
+	}
+
+	invalidation := metadataCacheInvalidation{}
+	if err := json.Unmarshal(ev.Data, &invalidation); err != nil {
+		p.client.Log.Warn("Failed to unmarshal the metadata cache invalidation", "error", err.Error())
+		return
+	}
+	p.metadataCache.invalidate(invalidation.InstanceID, invalidation.Kinds...)
+}
+
+// invalidateMetadataForWebhookEvent invalidates the metadata changed by a Jira event.
+func (p *Plugin) invalidateMetadataForWebhookEvent(instanceID types.ID, webhookEvent string) {
+	if strings.HasPrefix(webhookEvent, "project_") {
+		p.invalidateMetadata(instanceID)
+	}
+}
+
+// getIssueTransitions returns the transitions available from the current status of the issue.
+func (p *Plugin) getIssueTransitions(instanceID, scope types.ID, client Client, issue *jira.Issue) ([]jira.Transition, error) {
+	if p == nil {
+		return client.GetTransitions(issue.Key)
+	}
+
+	status := ""
+	if issue.Fields != nil && issue.Fields.Status != nil {
+		status = issue.Fields.Status.ID
+	}
+	key := metadataCacheKey{instanceID, scope, metadataKindTransitions, issue.Key + "/" + status}
+	transitions, err := p.metadataCache.get(key, transitionsCacheTTL, func() (interface{}, error) {
+		return client.GetTransitions(issue.Key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transitions.([]jira.Transition), nil
+}
+
+func executeInstanceCacheFlush(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira instance cache flush` can only be run by a system administrator.")
+	}
+	if len(args) > 1 {
+		return p.responsef(header, "Please use `/jira instance cache flush [jiraURL]`.")
+	}
+
+	instanceID := types.ID("")
+	if len(args) == 1 {
+		instances, err := p.instanceStore.LoadInstances()
+		if err != nil {
+			return p.responsef(header, "Failed to load instances. Error: %v.", err)
+		}
+		instanceID = types.ID(args[0])
+		if instanceFound := instances.getByAlias(args[0]); instanceFound != nil {
+			instanceID = instanceFound.InstanceID
+		} else if !instances.Contains(instanceID) {
+			return p.responsef(header, "Jira instance %s is not installed.", instanceID)
+		}
+	}
+
+	removed := p.invalidateMetadata(instanceID)
+	if instanceID == "" {
+		return p.responsef(header, "Flushed the cached Jira metadata of all the instances (%v entries on this server).", removed)
+	}
+	return p.responsef(header, "Flushed the cached Jira metadata of %s (%v entries on this server).", instanceID, removed)
+}
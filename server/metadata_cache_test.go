@@ -0,0 +1,91 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataCacheGet(t *testing.T) {
+	c := &metadataCache{}
+	key := metadataCacheKey{"jira1", "user1", metadataKindProjects, "false"}
+
+	fetches := 0
+	fetch := func() (interface{}, error) {
+		fetches++
+		return fetches, nil
+	}
+
+	value, err := c.get(key, time.Minute, fetch)
+	require.NoError(t, err)
+	assert.Equal(t, 1, value)
+
+	value, err = c.get(key, time.Minute, fetch)
+	require.NoError(t, err)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, 1, fetches)
+
+	// Other users don't share the cached value
+	value, err = c.get(metadataCacheKey{"jira1", "user2", metadataKindProjects, "false"}, time.Minute, fetch)
+	require.NoError(t, err)
+	assert.Equal(t, 2, value)
+}
+
+func TestMetadataCacheStale(t *testing.T) {
+	c := &metadataCache{}
+	key := metadataCacheKey{"jira1", "user1", metadataKindCreateMeta, "TEST"}
+	c.set(key, 0, "stale", time.Now().Add(-2*time.Minute))
+
+	refreshed := make(chan struct{})
+	value, err := c.get(key, time.Minute, func() (interface{}, error) {
+		defer close(refreshed)
+		return "fresh", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "stale", value)
+
+	<-refreshed
+	require.Eventually(t, func() bool {
+		value, _ := c.get(key, time.Minute, func() (interface{}, error) {
+			return "unexpected", nil
+		})
+		return value == "fresh"
+	}, time.Second, 10*time.Millisecond)
+
+	// Expired values are fetched again
+	c.set(key, c.generation, "expired", time.Now().Add(-2*metadataCacheStaleTTL))
+	value, err = c.get(key, time.Minute, func() (interface{}, error) {
+		return "fetched", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "fetched", value)
+}
+
+func TestMetadataCacheInvalidate(t *testing.T) {
+	c := &metadataCache{}
+	now := time.Now()
+	c.set(metadataCacheKey{"jira1", "user1", metadataKindProjects, ""}, 0, 1, now)
+	c.set(metadataCacheKey{"jira1", "user1", metadataKindCreateMeta, ""}, 0, 2, now)
+	c.set(metadataCacheKey{"jira2", "user1", metadataKindProjects, ""}, 0, 3, now)
+
+	assert.Equal(t, 1, c.invalidate("jira1", metadataKindCreateMeta))
+	assert.Equal(t, 1, c.invalidate("jira1"))
+	assert.Len(t, c.entries, 1)
+	assert.Equal(t, 1, c.invalidate(""))
+	assert.Empty(t, c.entries)
+
+	// Values fetched before an invalidation are not cached
+	key := metadataCacheKey{"jira1", "user1", metadataKindProjects, ""}
+	value, err := c.get(key, time.Minute, func() (interface{}, error) {
+		c.invalidate("jira1")
+		return "outdated", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "outdated", value)
+	assert.Empty(t, c.entries)
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+const (
+	oauth2ClientCacheSize = 1000
+	oauth2ClientCacheTTL  = 15 * time.Minute
+)
+
+type oauth2ClientCacheEntry struct {
+	client      *http.Client
+	accessToken string
+	expiresAt   time.Time
+}
+
+// oauth2ClientCache keeps the authenticated HTTP clients of OAuth 2.0 connections, keyed by
+// instance and Mattermost user, so that webhook fan-out does not rebuild a token source for
+// every notified user. The zero value is ready to use.
+type oauth2ClientCache struct {
+	lock    sync.Mutex
+	entries map[string]*oauth2ClientCacheEntry
+}
+
+func oauth2ClientCacheKey(instanceID, mattermostUserID types.ID) string {
+	return instanceID.String() + "/" + mattermostUserID.String()
+}
+
+// get returns the cached client, as long as it was created for the same access token. The
+// token stored in the connection changes when the user reconnects.
+func (c *oauth2ClientCache) get(instanceID types.ID, connection *Connection, now time.Time) *http.Client {
+	if connection.OAuth2Token == nil {
+		return nil
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := oauth2ClientCacheKey(instanceID, connection.MattermostUserID)
+	entry := c.entries[key]
+	if entry == nil {
+		return nil
+	}
+	if now.After(entry.expiresAt) || entry.accessToken != connection.OAuth2Token.AccessToken {
+		delete(c.entries, key)
+		return nil
+	}
+	return entry.client
+}
+
+// set caches the client until the TTL elapses, or until shortly before the access token
+// expires. Refreshing the token always goes through getOAuth2HTTPClient so that the rotated
+// refresh token gets stored.
+func (c *oauth2ClientCache) set(instanceID types.ID, connection *Connection, client *http.Client, now time.Time) {
+	token := connection.OAuth2Token
+	if token == nil || connection.MattermostUserID == "" {
+		return
+	}
+
+	expiresAt := now.Add(oauth2ClientCacheTTL)
+	if !token.Expiry.IsZero() {
+		tokenExpiresAt := token.Expiry.Add(-TokenExpiryTimeBufferInMinutes * time.Minute)
+		if tokenExpiresAt.Before(expiresAt) {
+			expiresAt = tokenExpiresAt
+		}
+	}
+	if !expiresAt.After(now) {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]*oauth2ClientCacheEntry)
+	}
+	key := oauth2ClientCacheKey(instanceID, connection.MattermostUserID)
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= oauth2ClientCacheSize {
+		c.evict(now)
+	}
+
+	c.entries[key] = &oauth2ClientCacheEntry{
+		client:      client,
+		accessToken: token.AccessToken,
+		expiresAt:   expiresAt,
+	}
+}
+
+// evict drops the expired entries, or the one closest to expiry if none has expired. It
+// must be called with the lock held.
+func (c *oauth2ClientCache) evict(now time.Time) {
+	oldestKey := ""
+	var oldest time.Time
+	for key, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, key)
+			continue
+		}
+		if oldestKey == "" || entry.expiresAt.Before(oldest) {
+			oldestKey, oldest = key, entry.expiresAt
+		}
+	}
+	if len(c.entries) >= oauth2ClientCacheSize {
+		delete(c.entries, oldestKey)
+	}
+}
+
+func (c *oauth2ClientCache) invalidate(instanceID, mattermostUserID types.ID) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.entries, oauth2ClientCacheKey(instanceID, mattermostUserID))
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+func TestOAuth2ClientCache(t *testing.T) {
+	now := time.Now()
+	instanceID := types.ID("https://jira.example.com")
+	newConnection := func(userID, accessToken string, expiry time.Time) *Connection {
+		return &Connection{
+			MattermostUserID: types.ID(userID),
+			OAuth2Token:      &oauth2.Token{AccessToken: accessToken, Expiry: expiry},
+		}
+	}
+
+	t.Run("hit for the same access token", func(t *testing.T) {
+		c := oauth2ClientCache{}
+		client := &http.Client{}
+		c.set(instanceID, newConnection("user1", "token1", now.Add(time.Hour)), client, now)
+
+		assert.Same(t, client, c.get(instanceID, newConnection("user1", "token1", now.Add(time.Hour)), now))
+		assert.Nil(t, c.get(instanceID, newConnection("user2", "token1", now.Add(time.Hour)), now))
+		assert.Nil(t, c.get("https://other.example.com", newConnection("user1", "token1", now.Add(time.Hour)), now))
+	})
+
+	t.Run("miss after reconnecting", func(t *testing.T) {
+		c := oauth2ClientCache{}
+		c.set(instanceID, newConnection("user1", "token1", now.Add(time.Hour)), &http.Client{}, now)
+
+		assert.Nil(t, c.get(instanceID, newConnection("user1", "token2", now.Add(time.Hour)), now))
+		assert.Empty(t, c.entries)
+	})
+
+	t.Run("expires with the TTL or before the token", func(t *testing.T) {
+		c := oauth2ClientCache{}
+		c.set(instanceID, newConnection("user1", "token1", now.Add(time.Hour)), &http.Client{}, now)
+		assert.Nil(t, c.get(instanceID, newConnection("user1", "token1", now.Add(time.Hour)), now.Add(oauth2ClientCacheTTL+time.Second)))
+
+		c.set(instanceID, newConnection("user1", "token1", now.Add(10*time.Minute)), &http.Client{}, now)
+		assert.Nil(t, c.get(instanceID, newConnection("user1", "token1", now.Add(10*time.Minute)), now.Add(6*time.Minute)))
+
+		c.set(instanceID, newConnection("user1", "token1", now.Add(time.Minute)), &http.Client{}, now)
+		assert.Empty(t, c.entries)
+	})
+
+	t.Run("invalidate", func(t *testing.T) {
+		c := oauth2ClientCache{}
+		c.set(instanceID, newConnection("user1", "token1", now.Add(time.Hour)), &http.Client{}, now)
+		c.invalidate(instanceID, "user1")
+
+		assert.Nil(t, c.get(instanceID, newConnection("user1", "token1", now.Add(time.Hour)), now))
+	})
+
+	t.Run("bounded size", func(t *testing.T) {
+		c := oauth2ClientCache{}
+		for i := 0; i < oauth2ClientCacheSize+10; i++ {
+			c.set(instanceID, newConnection(fmt.Sprintf("user%d", i), "token", now.Add(time.Hour)), &http.Client{}, now.Add(time.Duration(i)*time.Millisecond))
+		}
+
+		assert.Len(t, c.entries, oauth2ClientCacheSize)
+		assert.Nil(t, c.get(instanceID, newConnection("user0", "token", now.Add(time.Hour)), now))
+	})
+}
+
+func TestCloudOAuthInstancePersistsResourceID(t *testing.T) {
+	resourceRequests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resourceRequests++
+		_, _ = w.Write([]byte(`[{"id":"resource-id"}]`))
+	}))
+	defer ts.Close()
+
+	defaultResourcesURL := jiraOAuthAccessibleResourcesURL
+	jiraOAuthAccessibleResourcesURL = ts.URL
+	defer func() { jiraOAuthAccessibleResourcesURL = defaultResourcesURL }()
+
+	api := &plugintest.API{}
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+	p.instanceStore = NewStore(p)
+	p.userStore = NewStore(p)
+	makeTestKVStore(api, nil)
+
+	ci := &cloudOAuthInstance{
+		InstanceCommon: &InstanceCommon{Plugin: p, InstanceID: "https://example.atlassian.net", Type: CloudOAuthInstanceType},
+	}
+	connection := &Connection{
+		MattermostUserID: "user1",
+		OAuth2Token:      &oauth2.Token{AccessToken: "token", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)},
+	}
+
+	_, _, err := ci.getClientForConnection(connection)
+	require.NoError(t, err)
+	_, _, err = ci.getClientForConnection(connection)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, resourceRequests)
+	assert.Equal(t, "https://api.atlassian.com/ex/jira/resource-id", ci.GetURL())
+
+	stored, err := p.instanceStore.LoadInstance(ci.GetID())
+	require.NoError(t, err)
+	assert.Equal(t, "resource-id", stored.(*cloudOAuthInstance).JiraResourceID)
+}
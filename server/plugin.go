@@ -18,6 +18,7 @@ import (
 	"strings"
 	"sync"
 	textTemplate "text/template"
+	"time"
 
 	"github.com/andygrunwald/go-jira"
 	"github.com/gorilla/mux"
@@ -26,6 +27,7 @@ import (
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
 	"github.com/mattermost/mattermost/server/public/pluginapi/experimental/flow"
 
 	"github.com/mattermost-community/mattermost-plugin-autolink/server/autolink"
@@ -81,9 +83,21 @@ type externalConfig struct {
 	// Enable Webhook Event Logging
 	EnableWebhookEventLogging bool
 
+	// Number of recent webhook events kept per instance for `/jira webhook events`, disabled if 0 or empty
+	WebhookEventInspectorSize string
+
+	// Number of hours without webhook events after which sysadmins are notified, 0 disables it
+	WebhookQuietThresholdHours string
+
 	// Display subscription name in notifications
 	DisplaySubscriptionNameInNotifications bool
 
+	// Add remote links to the Jira issues mentioned in posts
+	EnableRemoteLinksForMentions bool
+
+	// Comma separated list of emoji=action reaction shortcuts on the posts of issues
+	ReactionShortcuts string
+
 	// The encryption key used to encrypt stored api tokens
 	EncryptionKey string
 
@@ -119,8 +133,12 @@ type config struct {
 	// Maximum attachment size allowed to be uploaded to Jira
 	maxAttachmentSize types.ByteSize
 
+	// reactionShortcuts is the parsed ReactionShortcuts setting, by emoji name
+	reactionShortcuts map[string]reactionShortcut
+
 	mattermostSiteURL string
 	rsaKey            *rsa.PrivateKey
+	rsaKeyLoadedAt    time.Time
 }
 
 type Plugin struct {
@@ -139,6 +157,8 @@ type Plugin struct {
 	setupFlow  *flow.Flow
 	oauth2Flow *flow.Flow
 
+	serverOAuth2Flow *flow.Flow
+
 	router *mux.Router
 
 	// Generated once, then cached in the database, and here deserialized
@@ -166,6 +186,31 @@ type Plugin struct {
 
 	teamFieldCache     map[types.ID]map[string]struct{}
 	teamFieldCacheLock sync.RWMutex
+
+	// last time the received webhook event time was stored, per instance
+	webhookLastEventWrites sync.Map
+
+	webhookHealthJob     *cluster.Job
+	tokenHealthJob       *cluster.Job
+	garbageCollectionJob *cluster.Job
+
+	// authenticated HTTP clients of the OAuth 2.0 connections
+	oauth2Clients oauth2ClientCache
+
+	// httpTransports keeps the transports of the instances with custom HTTP settings
+	httpTransports httpTransportCache
+
+	// metadataCache keeps the projects, fields and transitions fetched from Jira
+	metadataCache metadataCache
+
+	// projectVisibility caches the projects whose issues the personal subscribers can see
+	projectVisibility projectVisibilityCache
+
+	// connectionUsage throttles the writes of the last use of the connections
+	connectionUsage connectionUsageTracker
+
+	// webhookEvents allocates the slots of the recorded webhook events
+	webhookEvents webhookEventInspector
 }
 
 func (p *Plugin) getConfig() config {
@@ -257,6 +302,11 @@ func (p *Plugin) OnConfigurationChange() error {
 		return errors.New("comment post reply duration cannot be negative")
 	}
 
+	reactionShortcuts, err := parseReactionShortcuts(ec.ReactionShortcuts)
+	if err != nil {
+		return errors.WithMessage(err, "failed to load plugin configuration")
+	}
+
 	if ec.TeamIDs != "" {
 		teamListData := strings.Split(ec.TeamIDs, ",")
 		re := regexp.MustCompile(`^\[(.*?)\]\((.*?)\)$`)
@@ -314,6 +364,7 @@ func (p *Plugin) OnConfigurationChange() error {
 	p.updateConfig(func(conf *config) {
 		conf.externalConfig = ec
 		conf.maxAttachmentSize = maxAttachmentSize
+		conf.reactionShortcuts = reactionShortcuts
 	})
 
 	// OnConfigurationChanged is first called before the plugin is activated,
@@ -330,6 +381,14 @@ func (p *Plugin) OnConfigurationChange() error {
 		}
 	}
 
+	// The create issue metadata includes the team field values from the configuration
+	p.metadataCache.invalidate("", metadataKindCreateMeta)
+
+	// Webhooks registered by the plugin need to be updated with the new secret
+	if prev.Secret != "" && prev.Secret != ec.Secret && p.instanceStore != nil && p.canRegisterWebhooks() {
+		go p.refreshRegisteredWebhooks()
+	}
+
 	// create new tracker on each configuration change
 	if p.tracker != nil {
 		p.tracker.ReloadConfig(telemetry.NewTrackerConfig(p.API.GetConfig()))
@@ -339,6 +398,22 @@ func (p *Plugin) OnConfigurationChange() error {
 }
 
 func (p *Plugin) OnDeactivate() error {
+	if p.webhookHealthJob != nil {
+		if err := p.webhookHealthJob.Close(); err != nil {
+			p.client.Log.Warn("Failed to close the webhook health job", "error", err.Error())
+		}
+	}
+	if p.tokenHealthJob != nil {
+		if err := p.tokenHealthJob.Close(); err != nil {
+			p.client.Log.Warn("Failed to close the token health job", "error", err.Error())
+		}
+	}
+	if p.garbageCollectionJob != nil {
+		if err := p.garbageCollectionJob.Close(); err != nil {
+			p.client.Log.Warn("Failed to close the garbage collection job", "error", err.Error())
+		}
+	}
+
 	// close the tracker on plugin deactivation
 	if p.telemetryClient != nil {
 		err := p.telemetryClient.Close()
@@ -397,6 +472,7 @@ func (p *Plugin) OnActivate() error {
 		conf.botUserID = botUserID
 		conf.mattermostSiteURL = mattermostSiteURL
 		conf.rsaKey = rsaKey
+		conf.rsaKeyLoadedAt = time.Now()
 	})
 
 	instances, err := MigrateV2Instances(p)
@@ -423,6 +499,12 @@ func (p *Plugin) OnActivate() error {
 	}
 	p.oauth2Flow = oauth2Flow
 
+	serverOAuth2Flow, err := p.NewServerOAuth2Flow()
+	if err != nil {
+		return err
+	}
+	p.serverOAuth2Flow = serverOAuth2Flow
+
 	// Register /jira command and stash the loaded list of known instances for
 	// later (autolink registration).
 	err = p.registerJiraCommand(p.getConfig().EnableAutocomplete, instances.Len() > 1)
@@ -440,6 +522,26 @@ func (p *Plugin) OnActivate() error {
 
 	p.enterpriseChecker = enterprise.NewEnterpriseChecker(p.API)
 
+	p.webhookHealthJob, err = cluster.Schedule(p.API, webhookHealthJobKey, cluster.MakeWaitForRoundedInterval(webhookHealthJobInterval), p.checkWebhookHealth)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule the webhook health job")
+	}
+
+	p.tokenHealthJob, err = cluster.Schedule(p.API, tokenHealthJobKey, cluster.MakeWaitForRoundedInterval(tokenHealthJobInterval), p.checkTokenHealth)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule the token health job")
+	}
+
+	p.garbageCollectionJob, err = cluster.Schedule(p.API, garbageCollectionJobKey, cluster.MakeWaitForRoundedInterval(garbageCollectionJobInterval), p.collectGarbage)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule the garbage collection job")
+	}
+
+	// Resume an interrupted key rotation
+	if rotation, rotationErr := p.getKeyRotation(); rotationErr == nil && rotation.inProgress() {
+		go p.runKeyRotation()
+	}
+
 	go func() {
 		p.SetupAutolink(instances)
 	}()
@@ -513,7 +615,7 @@ func (p *Plugin) AddAutolinksForCloudInstance(ci *cloudInstance) error {
 }
 
 func (p *Plugin) AddAutolinksForCloudOAuthInstance(coi *cloudOAuthInstance) error {
-	plist, err := p.GetProjectListWithAPIToken(string(coi.InstanceID))
+	plist, err := p.GetProjectListWithAPIToken(coi)
 	if err != nil {
 		return fmt.Errorf("error getting project list: %w", err)
 	}
@@ -0,0 +1,183 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+const (
+	propIssueKey       = "jira_issue_key"
+	propInstanceID     = "jira_instance_id"
+	propIssueSignature = "jira_issue_signature"
+
+	reactionActionTransition = "transition"
+	reactionActionAssign     = "assign"
+	reactionActionWatch      = "watch"
+)
+
+type reactionShortcut struct {
+	action  string
+	toState string
+}
+
+// parseReactionShortcuts parses the "emoji=action" pairs of the ReactionShortcuts setting. It is
+// called when the configuration changes, so that an invalid setting is rejected right away.
+func parseReactionShortcuts(value string) (map[string]reactionShortcut, error) {
+	shortcuts := map[string]reactionShortcut{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		emoji, action, ok := strings.Cut(pair, "=")
+		emoji = strings.Trim(strings.TrimSpace(emoji), ":")
+		action = strings.TrimSpace(action)
+		if !ok || emoji == "" {
+			return nil, errors.Errorf("invalid reaction shortcut %q, expected emoji=action", pair)
+		}
+
+		shortcut := reactionShortcut{action: strings.ToLower(action)}
+		if name, toState, found := strings.Cut(action, ":"); found {
+			shortcut = reactionShortcut{action: strings.ToLower(strings.TrimSpace(name)), toState: strings.TrimSpace(toState)}
+		}
+		switch {
+		case shortcut.action == reactionActionTransition && shortcut.toState != "":
+		case shortcut.action == reactionActionAssign && shortcut.toState == "":
+		case shortcut.action == reactionActionWatch && shortcut.toState == "":
+		default:
+			return nil, errors.Errorf("invalid reaction shortcut action %q, expected transition:<state>, assign or watch", action)
+		}
+		shortcuts[emoji] = shortcut
+	}
+	return shortcuts, nil
+}
+
+// addIssuePostProps marks a post as referencing an issue, for the reaction shortcuts.
+func (p *Plugin) addIssuePostProps(post *model.Post, instanceID types.ID, issueKey string) {
+	sig := p.generatePostActionSignature(issueKey, instanceID.String())
+	if sig == "" {
+		return
+	}
+	post.AddProp(propIssueKey, issueKey)
+	post.AddProp(propInstanceID, instanceID.String())
+	post.AddProp(propIssueSignature, sig)
+}
+
+// postIssueReference returns the issue a post references, from its props or from the actions
+// of its issue card. The reference must be signed by the plugin.
+func (p *Plugin) postIssueReference(post *model.Post) (issueKey string, instanceID types.ID, ok bool) {
+	contexts := []map[string]interface{}{{
+		"issue_key":        post.GetProp(propIssueKey),
+		"instance_id":      post.GetProp(propInstanceID),
+		"action_signature": post.GetProp(propIssueSignature),
+	}}
+	for _, attachment := range post.Attachments() {
+		for _, action := range attachment.Actions {
+			if action.Integration != nil {
+				contexts = append(contexts, action.Integration.Context)
+			}
+		}
+	}
+
+	for _, ctx := range contexts {
+		key, _ := ctx["issue_key"].(string)
+		id, _ := ctx["instance_id"].(string)
+		signature, _ := ctx["action_signature"].(string)
+		if p.verifyPostActionSignature(key, id, signature) {
+			return key, types.ID(id), true
+		}
+	}
+	return "", "", false
+}
+
+// ReactionHasBeenAdded runs the reaction shortcut configured for the emoji on the issue the post
+// references, with the Jira connection of the user who reacted.
+func (p *Plugin) ReactionHasBeenAdded(c *plugin.Context, reaction *model.Reaction) {
+	if reaction.UserId == p.getUserID() {
+		return
+	}
+	shortcut, ok := p.getConfig().reactionShortcuts[reaction.EmojiName]
+	if !ok {
+		return
+	}
+
+	post, err := p.client.Post.GetPost(reaction.PostId)
+	if err != nil {
+		return
+	}
+	issueKey, instanceID, ok := p.postIssueReference(post)
+	if !ok {
+		return
+	}
+
+	rootID := post.RootId
+	if rootID == "" {
+		rootID = post.Id
+	}
+	msg, err := p.runReactionShortcut(shortcut, types.ID(reaction.UserId), instanceID, issueKey, post.ChannelId)
+	if err != nil {
+		msg = fmt.Sprintf("Failed to run the `:%s:` shortcut. Error: %v.", reaction.EmojiName, err)
+	}
+	if msg == "" {
+		return
+	}
+
+	reply := makePost(p.getUserID(), post.ChannelId, msg)
+	reply.RootId = rootID
+	p.client.Post.SendEphemeralPost(reaction.UserId, reply)
+}
+
+func (p *Plugin) runReactionShortcut(shortcut reactionShortcut, mattermostUserID, instanceID types.ID, issueKeyOrID, channelID string) (string, error) {
+	client, instance, connection, err := p.getClient(instanceID, mattermostUserID)
+	if err != nil {
+		return "", errors.New("your account is not connected to Jira. Please use `/jira connect`")
+	}
+
+	issue, err := client.GetIssue(issueKeyOrID, &jira.GetQueryOptions{Fields: "key"})
+	if err != nil {
+		return "", issueActionError(err, "view the issue")
+	}
+	permalink := fmt.Sprintf("[%s](%s/browse/%s)", issue.Key, instance.GetJiraBaseURL(), issue.Key)
+
+	switch shortcut.action {
+	case reactionActionTransition:
+		// The transitioned issue is sent to the user
+		_, err = p.TransitionIssue(&InTransitionIssue{
+			mattermostUserID: mattermostUserID,
+			InstanceID:       instanceID,
+			PostToChannelID:  channelID,
+			IssueKey:         issue.Key,
+			ToState:          shortcut.toState,
+		})
+		return "", err
+
+	case reactionActionAssign:
+		user := connection.User
+		// Jira only accepts one of the account ID and the username
+		if user.AccountID != "" {
+			user.Name = ""
+		}
+		if err = client.UpdateAssignee(issue.Key, &user); err != nil {
+			return "", issueActionError(err, "assign the issue")
+		}
+		return fmt.Sprintf("Assigned %s to you.", permalink), nil
+
+	case reactionActionWatch:
+		if err = client.AddWatcher(issue.Key, &connection.User); err != nil {
+			return "", issueActionError(err, "watch the issue")
+		}
+		return fmt.Sprintf("You are now watching %s.", permalink), nil
+	}
+	return "", errors.Errorf("unsupported action %q", shortcut.action)
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReactionShortcuts(t *testing.T) {
+	shortcuts, err := parseReactionShortcuts(" white_check_mark=transition:Done, :raising_hand:=assign,eyes=Watch,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]reactionShortcut{
+		"white_check_mark": {action: reactionActionTransition, toState: "Done"},
+		"raising_hand":     {action: reactionActionAssign},
+		"eyes":             {action: reactionActionWatch},
+	}, shortcuts)
+
+	for _, value := range []string{"eyes", "=watch", "eyes=delete", "tada=transition", "eyes=watch:me"} {
+		_, err = parseReactionShortcuts(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestOnConfigurationChangeReactionShortcuts(t *testing.T) {
+	for name, tc := range map[string]struct {
+		value        string
+		expected     map[string]reactionShortcut
+		errorMessage string
+	}{
+		"valid setting": {
+			value:    "eyes=watch",
+			expected: map[string]reactionShortcut{"eyes": {action: reactionActionWatch}},
+		},
+		"invalid setting": {
+			value:        "eyes=delete",
+			errorMessage: "failed to load plugin configuration: invalid reaction shortcut action \"delete\", expected transition:<state>, assign or watch",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			api := &plugintest.API{}
+			api.On("LoadPluginConfiguration", mock.AnythingOfType("*main.externalConfig")).Return(nil).Run(func(args mock.Arguments) {
+				args.Get(0).(*externalConfig).ReactionShortcuts = tc.value
+			})
+			api.On("GetConfig").Return(&model.Config{})
+			p := &Plugin{}
+			p.SetAPI(api)
+
+			err := p.OnConfigurationChange()
+			if tc.errorMessage != "" {
+				require.EqualError(t, err, tc.errorMessage)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, p.getConfig().reactionShortcuts)
+		})
+	}
+}
+
+func TestPostIssueReference(t *testing.T) {
+	p := &Plugin{}
+	p.updateConfig(func(conf *config) {
+		conf.Secret = someSecret
+	})
+
+	t.Run("post props", func(t *testing.T) {
+		post := &model.Post{}
+		p.addIssuePostProps(post, testInstance1.GetID(), "TEST-1")
+
+		issueKey, instanceID, ok := p.postIssueReference(post)
+		require.True(t, ok)
+		assert.Equal(t, "TEST-1", issueKey)
+		assert.Equal(t, testInstance1.GetID(), instanceID)
+	})
+
+	t.Run("issue card actions", func(t *testing.T) {
+		post := &model.Post{}
+		model.ParseSlackAttachment(post, []*model.SlackAttachment{{
+			Actions: []*model.PostAction{{
+				Integration: &model.PostActionIntegration{
+					Context: map[string]interface{}{
+						"issue_key":        "10001",
+						"instance_id":      testInstance1.GetID().String(),
+						"action_signature": p.generatePostActionSignature("10001", testInstance1.GetID().String()),
+					},
+				},
+			}},
+		}})
+
+		issueKey, _, ok := p.postIssueReference(post)
+		require.True(t, ok)
+		assert.Equal(t, "10001", issueKey)
+	})
+
+	t.Run("forged props", func(t *testing.T) {
+		post := &model.Post{}
+		post.AddProp(propIssueKey, "TEST-2")
+		post.AddProp(propInstanceID, testInstance1.GetID().String())
+		post.AddProp(propIssueSignature, p.generatePostActionSignature("TEST-1", testInstance1.GetID().String()))
+
+		_, _, ok := p.postIssueReference(post)
+		assert.False(t, ok)
+	})
+}
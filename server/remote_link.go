@@ -0,0 +1,142 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+const (
+	// remoteLinkGlobalIDPrefix makes Jira keep a single remote link per post and issue.
+	remoteLinkGlobalIDPrefix = "mattermost-post="
+
+	// remoteLinkIconFile is the icon of the links, served from the plugin's public assets
+	remoteLinkIconFile = "icon.svg"
+
+	// maxMentionedIssuesLinked is the number of issues mentioned in a post that are linked to it.
+	maxMentionedIssuesLinked = 5
+)
+
+var reMentionedIssueKey = regexp.MustCompile(`\b[A-Z][A-Z0-9_]+-\d+\b`)
+
+// makePostRemoteLink returns the remote link to a post. The name of the channel is only shown
+// for public channels, as anyone with access to the issue sees the link.
+func makePostRemoteLink(siteURL, permalink, postID string, channel *model.Channel) *jira.RemoteLink {
+	title := "Discussed in Mattermost"
+	if channel.Type == model.ChannelTypeOpen {
+		title += ": ~" + channel.Name
+	}
+
+	return &jira.RemoteLink{
+		GlobalID: remoteLinkGlobalIDPrefix + postID,
+		Application: &jira.RemoteLinkApplication{
+			Type: "com.mattermost",
+			Name: "Mattermost",
+		},
+		Relationship: "mentioned in",
+		Object: &jira.RemoteLinkObject{
+			URL:   permalink,
+			Title: title,
+			Icon: &jira.RemoteLinkIcon{
+				Url16x16: fmt.Sprintf("%s/plugins/%s/public/%s", siteURL, manifest.Id, remoteLinkIconFile),
+				Title:    "Mattermost",
+			},
+		},
+	}
+}
+
+// addPostRemoteLink adds a remote link to a post in the links of an issue.
+func (p *Plugin) addPostRemoteLink(client Client, instance Instance, issueKey, postID, channelID, teamName string) error {
+	channel, err := p.client.Channel.Get(channelID)
+	if err != nil {
+		return errors.WithMessage(err, "failed to get channel "+channelID)
+	}
+
+	link := makePostRemoteLink(p.GetSiteURL(), getPermaLink(instance, postID, teamName), postID, channel)
+	return client.AddRemoteLink(issueKey, link)
+}
+
+// mentionedIssueKeys returns the distinct issue keys mentioned in a message, in order. Words
+// like UTF-8 or SHA-256 look like issue keys, so only the keys of the projects are returned.
+func mentionedIssueKeys(message string, projectKeys StringSet, limit int) []string {
+	keys := []string{}
+	seen := map[string]bool{}
+	for _, key := range reMentionedIssueKey.FindAllString(message, -1) {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if projectKey, _, _ := strings.Cut(key, "-"); !projectKeys.ContainsAny(projectKey) {
+			continue
+		}
+		keys = append(keys, key)
+		if len(keys) == limit {
+			break
+		}
+	}
+	return keys
+}
+
+// MessageHasBeenPosted links the Jira issues mentioned in a post to it, when enabled.
+func (p *Plugin) MessageHasBeenPosted(c *plugin.Context, post *model.Post) {
+	if !p.getConfig().EnableRemoteLinksForMentions {
+		return
+	}
+	if post.IsSystemMessage() || post.UserId == p.getUserID() || post.GetProp("from_webhook") == "true" || post.GetProp("from_bot") == "true" {
+		return
+	}
+
+	if !reMentionedIssueKey.MatchString(post.Message) {
+		return
+	}
+
+	// Only the posts of public channels are linked, the users who see the issue may not be
+	// members of the others
+	channel, err := p.client.Channel.Get(post.ChannelId)
+	if err != nil || channel.Type != model.ChannelTypeOpen {
+		return
+	}
+	team, err := p.client.Team.Get(channel.TeamId)
+	if err != nil {
+		return
+	}
+
+	// The issues are linked with the author's connection to their default instance
+	_, instanceID, err := p.ResolveUserInstanceURL(types.ID(post.UserId), "")
+	if err != nil {
+		return
+	}
+	client, instance, connection, err := p.getClient(instanceID, types.ID(post.UserId))
+	if err != nil {
+		return
+	}
+
+	projects, err := p.listProjectsCached(instanceID, client, connection, false)
+	if err != nil {
+		p.client.Log.Debug("Failed to list the projects to link the mentioned issues", "post_id", post.Id, "error", err.Error())
+		return
+	}
+	projectKeys := NewStringSet()
+	for _, project := range projects {
+		projectKeys = projectKeys.Add(project.Key)
+	}
+	issueKeys := mentionedIssueKeys(post.Message, projectKeys, maxMentionedIssuesLinked)
+
+	for _, issueKey := range issueKeys {
+		err = client.AddRemoteLink(issueKey, makePostRemoteLink(p.GetSiteURL(), getPermaLink(instance, post.Id, team.Name), post.Id, channel))
+		if err != nil {
+			p.client.Log.Debug("Failed to link the mentioned issue to the post", "issue", issueKey, "post_id", post.Id, "error", err.Error())
+		}
+	}
+}
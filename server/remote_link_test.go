@@ -0,0 +1,42 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mattermost/mattermost/server/public/model"
+)
+
+func (client testClient) AddRemoteLink(issueKey string, link *jira.RemoteLink) error {
+	return nil
+}
+
+func TestMakePostRemoteLink(t *testing.T) {
+	channel := &model.Channel{Name: "town-square", Type: model.ChannelTypeOpen}
+	link := makePostRemoteLink("https://mattermost.example.com", "https://mattermost.example.com/team/pl/post1", "post1", channel)
+
+	assert.Equal(t, "mattermost-post=post1", link.GlobalID)
+	assert.Equal(t, "Discussed in Mattermost: ~town-square", link.Object.Title)
+	assert.Equal(t, "https://mattermost.example.com/team/pl/post1", link.Object.URL)
+	assert.Equal(t, "https://mattermost.example.com/plugins/jira/public/icon.svg", link.Object.Icon.Url16x16)
+
+	// The names of the private channels are not disclosed
+	channel = &model.Channel{Name: "incident-42", Type: model.ChannelTypePrivate}
+	link = makePostRemoteLink("https://mattermost.example.com", "https://mattermost.example.com/team/pl/post1", "post1", channel)
+	assert.Equal(t, "Discussed in Mattermost", link.Object.Title)
+}
+
+func TestMentionedIssueKeys(t *testing.T) {
+	projectKeys := NewStringSet("TEST", "WEB")
+	assert.Equal(t, []string{"TEST-1", "WEB-22"}, mentionedIssueKeys("TEST-1 blocks WEB-22, see TEST-1 again", projectKeys, 5))
+	assert.Equal(t, []string{"TEST-1"}, mentionedIssueKeys("TEST-1 and TEST-2", projectKeys, 1))
+	assert.Empty(t, mentionedIssueKeys("utf-8, test-1 and A-1 are not issue keys", projectKeys, 5))
+
+	// Words that look like issue keys are skipped, and don't count toward the limit
+	assert.Equal(t, []string{"TEST-3"}, mentionedIssueKeys("UTF-8, SHA-256, ISO-8601 and COVID-19 in TEST-3", projectKeys, 1))
+}
@@ -0,0 +1,287 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+const searchPageSize = 10
+
+var searchFields = []string{"key", "summary", "status", "assignee"}
+
+var reJQLOperator = regexp.MustCompile(`(?i)(!=|!~|>=|<=|[=~<>]|\s(not\s+)?in\s*\(|\sis\s+(not\s+)?(empty|null)\b|(^|\s)order\s+by\s)`)
+
+// searchQueryJQL turns the query of `/jira search` into JQL. The query is used as is when it
+// looks like JQL, and as an issue key or a text search otherwise.
+func searchQueryJQL(query string) string {
+	query = strings.TrimSpace(query)
+	// Strip the quotes wrapping the whole query, but not the ones of a quoted value within it
+	for _, quotes := range [][2]string{{`"`, `"`}, {"“", "”"}} {
+		inner := strings.TrimSuffix(strings.TrimPrefix(query, quotes[0]), quotes[1])
+		if len(inner) == len(query)-len(quotes[0])-len(quotes[1]) &&
+			!strings.Contains(inner, quotes[0]) && !strings.Contains(inner, quotes[1]) {
+			query = strings.TrimSpace(inner)
+		}
+	}
+
+	switch {
+	case issueKeyRegexp.MatchString(strings.ToUpper(query)):
+		return fmt.Sprintf("issuekey = %s", strings.ToUpper(query))
+	case reJQLOperator.MatchString(query):
+		return query
+	default:
+		return textSearchJQL(query)
+	}
+}
+
+// searchIssuesPage returns the page of the issues matching the JQL that starts at pageToken, and
+// the token of the next page, empty on the last page.
+func searchIssuesPage(client Client, jql, pageToken string) ([]jira.Issue, string, error) {
+	found, nextPageToken, err := client.SearchIssuesPage(jql, pageToken, &jira.SearchOptions{
+		MaxResults: searchPageSize,
+		Fields:     searchFields,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if len(found) > searchPageSize {
+		found = found[:searchPageSize]
+	}
+	if len(found) == 0 {
+		nextPageToken = ""
+	}
+	return found, nextPageToken, nil
+}
+
+// currentPageToken returns the token of the page reached through pageTokens, the tokens of the
+// pages after the first one, in order. The first page has an empty token.
+func currentPageToken(pageTokens []string) string {
+	if len(pageTokens) == 0 {
+		return ""
+	}
+	return pageTokens[len(pageTokens)-1]
+}
+
+func searchActionURL(route string) string {
+	return fmt.Sprintf("/plugins/%s%s%s", manifest.Id, routeAPI, route)
+}
+
+// makeSearchResultsPost renders a page of search results, with actions to view or share each
+// issue and to navigate between the pages. Jira Cloud pages with tokens that can only be followed
+// forward, so the navigation actions carry the tokens of all the pages up to the one they lead to.
+func (p *Plugin) makeSearchResultsPost(instance Instance, channelID, jql string, pageTokens []string, issues []jira.Issue, nextPageToken string) *model.Post {
+	post := &model.Post{
+		UserId:    p.getUserID(),
+		ChannelId: channelID,
+	}
+	if len(issues) == 0 {
+		post.Message = fmt.Sprintf("No Jira issues match `%s`.", jql)
+		return post
+	}
+
+	page := len(pageTokens)
+	post.Message = fmt.Sprintf("Jira issues matching `%s`, page %v:", jql, page+1)
+
+	attachments := []*model.SlackAttachment{}
+	for i := range issues {
+		issue := &issues[i]
+		ctx := map[string]interface{}{
+			"issue_key":   issue.Key,
+			"instance_id": instance.GetID().String(),
+		}
+		if sig := p.generatePostActionSignature(issue.Key, instance.GetID().String()); sig != "" {
+			ctx["action_signature"] = sig
+		}
+		shareCtx := map[string]interface{}{"keep_post": true}
+		for k, v := range ctx {
+			shareCtx[k] = v
+		}
+
+		assignee := "Unassigned"
+		if issue.Fields.Assignee != nil {
+			assignee = issue.Fields.Assignee.DisplayName
+		}
+		status := ""
+		if issue.Fields.Status != nil {
+			status = issue.Fields.Status.Name
+		}
+
+		attachments = append(attachments, &model.SlackAttachment{
+			Color: "#95b7d0",
+			Text:  fmt.Sprintf("[%s](%s/browse/%s) %s", issue.Key, instance.GetJiraBaseURL(), issue.Key, truncate(issue.Fields.Summary, maxIssueSummaryLength)),
+			Fields: []*model.SlackAttachmentField{
+				{Title: "Status", Value: status, Short: true},
+				{Title: "Assignee", Value: assignee, Short: true},
+			},
+			Actions: []*model.PostAction{
+				{
+					Name:        "View",
+					Type:        model.PostActionTypeButton,
+					Integration: &model.PostActionIntegration{URL: searchActionURL(routeSearchIssueView), Context: ctx},
+				},
+				{
+					Name:        "Share",
+					Type:        model.PostActionTypeButton,
+					Integration: &model.PostActionIntegration{URL: searchActionURL(routeSharePublicly), Context: shareCtx},
+				},
+			},
+		})
+	}
+
+	navigation := []*model.PostAction{}
+	for _, nav := range []struct {
+		name       string
+		pageTokens []string
+		enabled    bool
+	}{
+		{"Prev", pageTokens[:max(page-1, 0)], page > 0},
+		{"Next", append(pageTokens[:page:page], nextPageToken), nextPageToken != ""},
+	} {
+		if !nav.enabled {
+			continue
+		}
+		navigation = append(navigation, &model.PostAction{
+			Name: nav.name,
+			Type: model.PostActionTypeButton,
+			Integration: &model.PostActionIntegration{
+				URL: searchActionURL(routeSearchIssuesPage),
+				Context: map[string]interface{}{
+					"instance_id": instance.GetID().String(),
+					"jql":         jql,
+					"page_tokens": nav.pageTokens,
+				},
+			},
+		})
+	}
+	if len(navigation) > 0 {
+		attachments = append(attachments, &model.SlackAttachment{Actions: navigation})
+	}
+
+	model.ParseSlackAttachment(post, attachments)
+	return post
+}
+
+func executeSearch(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	user, instance, args, err := p.loadFlagUserInstance(header.UserId, args)
+	if err != nil {
+		return p.responsef(header, "Failed to load your connection to Jira. Error: %v.", err)
+	}
+	if len(args) == 0 {
+		return p.responsef(header, "Please specify a JQL query or a text in the form `/jira search \"<JQL or text>\"`.")
+	}
+
+	client, _, _, err := p.getClient(instance.GetID(), user.MattermostUserID)
+	if err != nil {
+		return p.responsef(header, "Your username is not connected to Jira. Please type `jira connect`.")
+	}
+
+	jql := searchQueryJQL(strings.Join(args, " "))
+	issues, nextPageToken, err := searchIssuesPage(client, jql, "")
+	if err != nil {
+		return p.responsef(header, "Failed to search the Jira issues. Error: %v.", err)
+	}
+
+	post := p.makeSearchResultsPost(instance, header.ChannelId, jql, nil, issues, nextPageToken)
+	post.RootId = header.RootId
+	p.client.Post.SendEphemeralPost(header.UserId, post)
+	return &model.CommandResponse{}
+}
+
+// httpSearchIssuesPage replaces the search results with another page of results.
+func (p *Plugin) httpSearchIssuesPage(w http.ResponseWriter, r *http.Request) (int, error) {
+	authenticatedUserID, requestData, status, err := decodePostActionRequest(r)
+	if err != nil {
+		return respondErr(w, status, err)
+	}
+
+	instanceID, _ := requestData.Context["instance_id"].(string)
+	jql, _ := requestData.Context["jql"].(string)
+	pageTokens, ok := contextPageTokens(requestData.Context["page_tokens"])
+	if instanceID == "" || jql == "" || !ok || requestData.PostId == "" {
+		return respondErr(w, http.StatusBadRequest, errors.New("invalid search context"))
+	}
+
+	jiraBotID := p.getUserID()
+	client, instance, _, err := p.getClient(types.ID(instanceID), types.ID(authenticatedUserID))
+	if err != nil {
+		return p.respondErrWithFeedback(authenticatedUserID, makePost(jiraBotID, requestData.ChannelId,
+			"Your username is not connected to Jira. Please type `jira connect`."), w, http.StatusUnauthorized)
+	}
+
+	issues, nextPageToken, err := searchIssuesPage(client, jql, currentPageToken(pageTokens))
+	if err != nil {
+		return p.respondErrWithFeedback(authenticatedUserID, makePost(jiraBotID, requestData.ChannelId,
+			fmt.Sprintf("Failed to search the Jira issues. Error: %v.", err)), w, http.StatusInternalServerError)
+	}
+
+	post := p.makeSearchResultsPost(instance, requestData.ChannelId, jql, pageTokens, issues, nextPageToken)
+	post.Id = requestData.PostId
+	p.client.Post.UpdateEphemeralPost(authenticatedUserID, post)
+
+	return respondJSON(w, &model.PostActionIntegrationResponse{})
+}
+
+// contextPageTokens reads the page tokens of a navigation action, decoded from JSON.
+func contextPageTokens(value interface{}) ([]string, bool) {
+	if value == nil {
+		return nil, true
+	}
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	pageTokens := []string{}
+	for _, v := range values {
+		token, ok := v.(string)
+		if !ok || token == "" {
+			return nil, false
+		}
+		pageTokens = append(pageTokens, token)
+	}
+	return pageTokens, true
+}
+
+// httpSearchIssueView shows the details of an issue of the search results.
+func (p *Plugin) httpSearchIssueView(w http.ResponseWriter, r *http.Request) (int, error) {
+	authenticatedUserID, requestData, status, err := decodePostActionRequest(r)
+	if err != nil {
+		return respondErr(w, status, err)
+	}
+
+	jiraBotID := p.getUserID()
+	ctx, status, errMsg := p.buildPostActionContext(authenticatedUserID, requestData, false)
+	if errMsg != "" {
+		return p.respondErrWithFeedback(authenticatedUserID, makePost(jiraBotID, requestData.ChannelId, errMsg), w, status)
+	}
+
+	_, instance, connection, err := p.getClient(types.ID(ctx.instanceID), types.ID(ctx.authenticatedUserID))
+	if err != nil {
+		return p.respondErrWithFeedback(ctx.authenticatedUserID, makePost(jiraBotID, ctx.channelID,
+			"No connection could be loaded with given params"), w, http.StatusInternalServerError)
+	}
+
+	attachment, err := p.getIssueAsSlackAttachment(instance, connection, ctx.issueKey, true)
+	if err != nil {
+		return p.respondErrWithFeedback(ctx.authenticatedUserID, makePost(jiraBotID, ctx.channelID, err.Error()), w, http.StatusInternalServerError)
+	}
+
+	post := makePost(jiraBotID, ctx.channelID, "")
+	post.AddProp("attachments", attachment)
+	p.client.Post.SendEphemeralPost(ctx.authenticatedUserID, post)
+
+	return respondJSON(w, &model.PostActionIntegrationResponse{})
+}
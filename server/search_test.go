@@ -0,0 +1,161 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type searchTestClient struct {
+	testClient
+	total int
+}
+
+// SearchIssuesPage pages like Jira Server, with the index of the first issue as the token.
+func (client searchTestClient) SearchIssuesPage(_, pageToken string, options *jira.SearchOptions) ([]jira.Issue, string, error) {
+	start := 0
+	if pageToken != "" {
+		start, _ = strconv.Atoi(pageToken)
+	}
+	found := []jira.Issue{}
+	for i := start; i < client.total && i < start+options.MaxResults; i++ {
+		found = append(found, jira.Issue{Key: fmt.Sprintf("TEST-%v", i+1)})
+	}
+	if start+len(found) >= client.total {
+		return found, "", nil
+	}
+	return found, strconv.Itoa(start + len(found)), nil
+}
+
+func TestSearchQueryJQL(t *testing.T) {
+	for query, expected := range map[string]string{
+		`project = TEST AND status = "In Progress"`:   `project = TEST AND status = "In Progress"`,
+		`"assignee = currentUser() order by updated"`: `assignee = currentUser() order by updated`,
+		`status in (Open, Reopened)`:                  `status in (Open, Reopened)`,
+		`"login page"`:                                `text ~ "login page" OR text ~ "login page*"`,
+		`“login page”`:                                `text ~ "login page" OR text ~ "login page*"`,
+		`the "login" page`:                            `text ~ "the \"login\" page" OR text ~ "the \"login\" page*"`,
+		`test-12`:                                     `issuekey = TEST-12`,
+	} {
+		t.Run(query, func(t *testing.T) {
+			assert.Equal(t, expected, searchQueryJQL(query))
+		})
+	}
+}
+
+func TestSearchIssuesPage(t *testing.T) {
+	for name, tc := range map[string]struct {
+		total         int
+		pageToken     string
+		expectedFirst string
+		expectedLen   int
+		expectedNext  string
+	}{
+		"no results":          {total: 0},
+		"single page":         {total: 7, expectedFirst: "TEST-1", expectedLen: 7},
+		"exactly one page":    {total: 10, expectedFirst: "TEST-1", expectedLen: 10},
+		"first of two pages":  {total: 15, expectedFirst: "TEST-1", expectedLen: 10, expectedNext: "10"},
+		"second of two pages": {total: 15, pageToken: "10", expectedFirst: "TEST-11", expectedLen: 5},
+		"middle page":         {total: 100, pageToken: "40", expectedFirst: "TEST-41", expectedLen: 10, expectedNext: "50"},
+		"past the 50th issue": {total: 75, pageToken: "70", expectedFirst: "TEST-71", expectedLen: 5},
+		"beyond the results":  {total: 15, pageToken: "30"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			issues, nextPageToken, err := searchIssuesPage(searchTestClient{total: tc.total}, "project = TEST", tc.pageToken)
+			require.NoError(t, err)
+			require.Len(t, issues, tc.expectedLen)
+			assert.Equal(t, tc.expectedNext, nextPageToken)
+			if tc.expectedLen > 0 {
+				assert.Equal(t, tc.expectedFirst, issues[0].Key)
+			}
+		})
+	}
+}
+
+func TestCloudSearchIssuesPage(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/rest/api/2/search/jql", r.URL.Path)
+		assert.Equal(t, "project = TEST", r.URL.Query().Get("jql"))
+		assert.Equal(t, "10", r.URL.Query().Get("maxResults"))
+		assert.Equal(t, "key,summary,status,assignee", r.URL.Query().Get("fields"))
+
+		switch r.URL.Query().Get("nextPageToken") {
+		case "":
+			_, _ = w.Write([]byte(`{"issues": [{"key": "TEST-1"}, {"key": "TEST-2"}], "nextPageToken": "page-2", "isLast": false}`))
+		case "page-2":
+			_, _ = w.Write([]byte(`{"issues": [{"key": "TEST-3"}], "nextPageToken": "page-3", "isLast": true}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+
+	jiraClient, err := jira.NewClient(ts.Client(), ts.URL)
+	require.NoError(t, err)
+	client := newCloudClient(jiraClient)
+
+	issues, nextPageToken, err := searchIssuesPage(client, "project = TEST", "")
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+	assert.Equal(t, "TEST-1", issues[0].Key)
+	assert.Equal(t, "page-2", nextPageToken)
+
+	// The last page has no next page, even if Jira sends a token
+	issues, nextPageToken, err = searchIssuesPage(client, "project = TEST", "page-2")
+	require.NoError(t, err)
+	require.Len(t, issues, 1)
+	assert.Equal(t, "TEST-3", issues[0].Key)
+	assert.Empty(t, nextPageToken)
+}
+
+func TestSearchResultsNavigation(t *testing.T) {
+	p := &Plugin{}
+	issues := []jira.Issue{{Key: "TEST-1", Fields: &jira.IssueFields{}}}
+
+	navigation := func(post *model.Post) map[string][]string {
+		attachments := post.Attachments()
+		actions := attachments[len(attachments)-1].Actions
+		result := map[string][]string{}
+		for _, action := range actions {
+			result[action.Name] = action.Integration.Context["page_tokens"].([]string)
+		}
+		return result
+	}
+
+	post := p.makeSearchResultsPost(testInstance1, "channel_id", "project = TEST", nil, issues, "page-2")
+	assert.Contains(t, post.Message, "page 1:")
+	assert.Equal(t, map[string][]string{"Next": {"page-2"}}, navigation(post))
+
+	post = p.makeSearchResultsPost(testInstance1, "channel_id", "project = TEST", []string{"page-2", "page-3"}, issues, "page-4")
+	assert.Contains(t, post.Message, "page 3:")
+	assert.Equal(t, map[string][]string{
+		"Prev": {"page-2"},
+		"Next": {"page-2", "page-3", "page-4"},
+	}, navigation(post))
+}
+
+func TestContextPageTokens(t *testing.T) {
+	pageTokens, ok := contextPageTokens(nil)
+	assert.True(t, ok)
+	assert.Empty(t, pageTokens)
+
+	pageTokens, ok = contextPageTokens([]interface{}{"page-2", "page-3"})
+	assert.True(t, ok)
+	assert.Equal(t, []string{"page-2", "page-3"}, pageTokens)
+	assert.Equal(t, "page-3", currentPageToken(pageTokens))
+
+	_, ok = contextPageTokens([]interface{}{"page-2", 3.0})
+	assert.False(t, ok)
+	_, ok = contextPageTokens(2.0)
+	assert.False(t, ok)
+}
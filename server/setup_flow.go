@@ -23,6 +23,7 @@ const (
 	stepDelegated                flow.Name = "delegated"
 	stepChooseEdition            flow.Name = "choose-edition"
 	stepCloudOAuthConfigure      flow.Name = "cloud-oauth-configure"
+	stepServerOAuthConfigure     flow.Name = "server-oauth-configure"
 	stepInstalledJiraApp         flow.Name = "installed-app"
 	stepServerAddAppLink         flow.Name = "server-add-link"
 	stepServerConfirmAppLink     flow.Name = "server-confirm-link"
@@ -83,6 +84,9 @@ func (p *Plugin) NewSetupFlow() (*flow.Flow, error) {
 			// Jira Cloud OAuth steps
 			p.stepCloudOAuthConfigure(),
 
+			// Jira Data Center OAuth steps
+			p.stepServerOAuthConfigure(),
+
 			// Jira server steps
 			p.stepServerAddAppLink(),
 			p.stepServerConfirmAppLink(),
@@ -126,6 +130,29 @@ func (p *Plugin) NewOAuth2Flow() (*flow.Flow, error) {
 		InitHTTP(p.router), nil
 }
 
+func (p *Plugin) NewServerOAuth2Flow() (*flow.Flow, error) {
+	conf := p.getConfig()
+
+	f, err := flow.NewFlow("setup-server-oauth2", p.client, manifest.Id, conf.botUserID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create %q flow", "setup-server-oauth2")
+	}
+	return f.
+		WithSteps(
+			p.stepServerOAuthConfigure(),
+			p.stepInstalledJiraApp(),
+			p.stepWebhook(),
+			p.stepWebhookDone(),
+			p.stepConnect(),
+			p.stepConnected(),
+			p.stepAnnouncementQuestion(),
+			p.stepAnnouncementConfirmation(),
+			p.stepCancel(),
+			p.stepDone(),
+		).
+		InitHTTP(p.router), nil
+}
+
 var cancelButton = flow.Button{
 	Name:    "Cancel setup",
 	Color:   flow.ColorDanger,
@@ -254,6 +281,24 @@ func (p *Plugin) stepChooseEdition() flow.Step {
 			},
 			OnDialogSubmit: p.submitCreateServerInstance,
 		}).
+		WithButton(flow.Button{
+			Name:  "Jira Data Center (OAuth 2.0)",
+			Color: flow.ColorDefault,
+			Dialog: &model.Dialog{
+				Title:            "Enter Jira Data Center URL",
+				IntroductionText: "OAuth 2.0 requires Jira Data Center 8.22 or later.",
+				SubmitLabel:      "Continue",
+				Elements: []model.DialogElement{
+					{
+						DisplayName: "Jira Data Center URL",
+						Name:        "url",
+						Type:        "text",
+						SubType:     "url",
+					},
+				},
+			},
+			OnDialogSubmit: p.initCreateServerOAuthInstance,
+		}).
 		WithButton(cancelButton)
 }
 
@@ -366,6 +411,48 @@ func (p *Plugin) stepCloudOAuthConfigure() flow.Step {
 		WithButton(cancelButton)
 }
 
+func (p *Plugin) stepServerOAuthConfigure() flow.Step {
+	return flow.NewStep(stepServerOAuthConfigure).
+		WithPretext("##### :white_check_mark: Step 2: Create an OAuth 2.0 Application Link in Jira").
+		WithText("Complete the following steps, then come back here to select **Configure**.\n\n" +
+			"1. Navigate to [**Administration > Applications > Application links**]({{.JiraURL}}/plugins/servlet/applinks/listApplicationLinks).\n" +
+			"2. Select **Create link**, choose **External application** and **Incoming**, then select **Continue**.\n" +
+			"3. Enter the following values:\n" +
+			"  - **Name**: `Mattermost`\n" +
+			"  - **Redirect URL**: `{{.OAuthCompleteURL}}`\n" +
+			"  - **Permission**: **Write**\n" +
+			"4. Select **Save**, then copy the **Client ID** and **Client secret** and keep them handy.\n" +
+			"5. Click on the **Configure** button below, enter these details and then **Continue**.\n\n" +
+			"Users connected with the previous Application Link keep working until they run `/jira connect` again.").
+		WithButton(flow.Button{
+			Name:  "Configure",
+			Color: flow.ColorPrimary,
+			Dialog: &model.Dialog{
+				Title:       "Configure your Jira Data Center OAuth 2.0",
+				SubmitLabel: "Continue",
+				Elements: []model.DialogElement{
+					{
+						DisplayName: "Jira OAuth Client ID",
+						Name:        NameClientID,
+						Type:        "text",
+						SubType:     "text",
+						HelpText:    "The client ID of the incoming application link",
+					},
+					{
+						DisplayName: "Jira OAuth Client Secret",
+						Name:        NameCLientSecret,
+						Type:        "text",
+						SubType:     "text",
+						HelpText:    "The client secret of the incoming application link",
+					},
+				},
+			},
+			OnDialogSubmit: p.submitCreateServerOAuthInstance,
+		}).
+		OnRender(p.trackSetupWizard("setup_wizard_server_oauth2_configure", nil)).
+		WithButton(cancelButton)
+}
+
 func (p *Plugin) stepInstalledJiraApp() flow.Step {
 	next := func(to flow.Name) func(*flow.Flow) (flow.Name, flow.State, error) {
 		return func(f *flow.Flow) (flow.Name, flow.State, error) {
@@ -410,7 +497,9 @@ func (p *Plugin) stepWebhook() flow.Step {
 			"and **Issue** events. Leave **Entity property**, **Worklog**, and **Issue " +
 			"link** events unchecked, they are not yet supported.\n" +
 			"6. Leave all other checkboxes blank.\n" +
-			"7. Select **View Webhook URL** to see the secret **URL** to enter in Jira, and continue.\n").
+			"7. Select **View Webhook URL** to see the secret **URL** to enter in Jira, and continue.\n\n" +
+			"If the **Admin API Token** and **Admin Email** plugin settings are configured with a Jira " +
+			"administrator account, select **Register automatically** to let the plugin create the webhook instead.\n").
 		WithImage("public/configure-webhook.png").
 		OnRender(p.trackSetupWizard("setup_wizard_webhook_start", nil)).
 		WithButton(flow.Button{
@@ -423,9 +512,30 @@ func (p *Plugin) stepWebhook() flow.Step {
 			},
 			OnDialogSubmit: flow.DialogGoto(stepWebhookDone),
 		}).
+		WithButton(flow.Button{
+			Name:    "Register automatically",
+			Color:   flow.ColorDefault,
+			OnClick: p.registerSetupWebhook,
+		}).
 		WithButton(cancelButton)
 }
 
+// registerSetupWebhook creates the subscriptions webhook in Jira instead of asking the admin
+// to do it. It requires the Admin API Token plugin setting.
+func (p *Plugin) registerSetupWebhook(f *flow.Flow) (flow.Name, flow.State, error) {
+	jiraURL := f.GetState().GetString(keyJiraURL)
+	instance, err := p.instanceStore.LoadInstance(types.ID(jiraURL))
+	if err != nil {
+		return "", nil, errors.Wrap(err, "failed to load the Jira instance")
+	}
+
+	if _, err = p.registerSubscriptionsWebhook(instance); err != nil {
+		return "", nil, errors.WithMessage(err, "failed to register the webhook, please set it up manually")
+	}
+
+	return stepWebhookDone, nil, nil
+}
+
 func (p *Plugin) stepWebhookDone() flow.Step {
 	return flow.NewStep(stepWebhookDone).
 		WithTitle("Success! Webhook setup is complete. :tada:").
@@ -683,7 +793,7 @@ func (p *Plugin) submitCreateServerInstance(f *flow.Flow, submission map[string]
 	}
 	jiraURL = strings.TrimSpace(jiraURL)
 
-	jiraURL, si, err := p.installServerInstance(jiraURL)
+	jiraURL, si, err := p.installServerInstance(jiraURL, ServerAuthModeOAuth1)
 	if err != nil {
 		return "", nil, nil, err
 	}
@@ -704,6 +814,62 @@ func (p *Plugin) submitCreateServerInstance(f *flow.Flow, submission map[string]
 	}, nil, nil
 }
 
+func (p *Plugin) serverOAuthFlowState(jiraURL string) flow.State {
+	return flow.State{
+		keyEdition:          string(ServerOAuthInstanceType),
+		keyJiraURL:          jiraURL,
+		keyOAuthCompleteURL: p.GetPluginURL() + instancePath(routeOAuth2Complete, types.ID(jiraURL)),
+		keyConnectURL:       p.GetPluginURL() + instancePath(routeUserConnect, types.ID(jiraURL)),
+	}
+}
+
+func (p *Plugin) initCreateServerOAuthInstance(f *flow.Flow, submission map[string]interface{}) (flow.Name, flow.State, map[string]string, error) {
+	jiraURL, _ := submission["url"].(string)
+	if jiraURL == "" {
+		return "", nil, nil, errors.New("no Jira Data Center URL in the request")
+	}
+
+	jiraURL, _, err := p.installServerOAuthInstance(strings.TrimSpace(jiraURL))
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return stepServerOAuthConfigure, p.serverOAuthFlowState(jiraURL), nil, nil
+}
+
+func (p *Plugin) submitCreateServerOAuthInstance(f *flow.Flow, submission map[string]interface{}) (flow.Name, flow.State, map[string]string, error) {
+	jiraURL := f.GetState().GetString(keyJiraURL)
+	if jiraURL == "" {
+		return "", nil, nil, errors.New("no Jira Data Center URL, please restart the setup")
+	}
+
+	clientID, _ := submission[NameClientID].(string)
+	if clientID == "" {
+		return "", nil, nil, errors.New("no Jira OAuth Client ID is present in the request")
+	}
+	clientSecret, _ := submission[NameCLientSecret].(string)
+	if clientSecret == "" {
+		return "", nil, nil, errors.New("no Jira OAuth Client Secret is present in the request")
+	}
+
+	existingInstance, err := p.instanceStore.LoadInstance(types.ID(jiraURL))
+	if err != nil {
+		return "", nil, nil, errors.Wrap(err, "failed to load existing server-oauth instance")
+	}
+	instance, ok := existingInstance.(*serverOAuthInstance)
+	if !ok {
+		return "", nil, nil, errors.Errorf("existing instance is not a server-oauth instance. ID: %s", jiraURL)
+	}
+
+	instance.JiraClientID = strings.TrimSpace(clientID)
+	instance.JiraClientSecret = strings.TrimSpace(clientSecret)
+	if err = p.instanceStore.StoreInstance(instance); err != nil {
+		return "", nil, nil, errors.Wrap(err, "failed to store server-oauth instance")
+	}
+
+	return stepInstalledJiraApp, p.serverOAuthFlowState(jiraURL), nil, nil
+}
+
 func (p *Plugin) trackSetupWizard(event string, args map[string]interface{}) func(f *flow.Flow) {
 	return func(f *flow.Flow) {
 		p.TrackUserEvent(event, f.UserID, args)
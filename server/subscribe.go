@@ -9,7 +9,9 @@ import (
 	"io"
 	"net/http"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	jira "github.com/andygrunwald/go-jira"
 	"github.com/gorilla/mux"
@@ -33,6 +35,19 @@ const (
 	FilterEmpty          = "empty"
 	FilterIncludeOrEmpty = "include_or_empty"
 
+	FilterGreaterThan        = "greater_than"
+	FilterGreaterThanOrEqual = "greater_than_or_equal"
+	FilterLessThan           = "less_than"
+	FilterLessThanOrEqual    = "less_than_or_equal"
+	FilterBetween            = "between"
+	FilterWithinNextDays     = "within_next_days"
+	FilterWithinLastDays     = "within_last_days"
+
+	FieldSchemaNumber   = "number"
+	FieldSchemaDate     = "date"
+	FieldSchemaDateTime = "datetime"
+	FieldSchemaUser     = "user"
+
 	MaxSubscriptionTemplateNameLength = 100
 
 	QueryParamProjectKey       = "project_key"
@@ -46,6 +61,11 @@ type FieldFilter struct {
 	Key       string    `json:"key"`
 	Inclusion string    `json:"inclusion"`
 	Values    StringSet `json:"values"`
+
+	// Schema is the Jira schema type of the field, as reported by createmeta. It is
+	// populated when the subscription is validated and drives the comparison semantics
+	// of the range and date operators.
+	Schema string `json:"schema,omitempty"`
 }
 
 type SubscriptionFilters struct {
@@ -53,14 +73,33 @@ type SubscriptionFilters struct {
 	Projects   StringSet     `json:"projects"`
 	IssueTypes StringSet     `json:"issue_types"`
 	Fields     []FieldFilter `json:"fields"`
+
+	// SavedFilter restricts the subscription to the issues matching a Jira saved filter.
+	// Projects and issue types are optional when it is set.
+	SavedFilter *SavedFilterSource `json:"saved_filter,omitempty"`
+}
+
+// SubscriptionMentions configures who gets @-mentioned in the posts of a channel subscription.
+type SubscriptionMentions struct {
+	// Fields contains "assignee", "reporter" or the keys of user-picker custom fields. The
+	// Mattermost users mapped to the Jira users in these fields are mentioned.
+	Fields StringSet `json:"fields,omitempty"`
+
+	// Group is the name of a Mattermost user group to mention, without the leading "@".
+	Group string `json:"group,omitempty"`
 }
 
 type ChannelSubscription struct {
-	ID         string              `json:"id"`
-	ChannelID  string              `json:"channel_id"`
-	Filters    SubscriptionFilters `json:"filters"`
-	Name       string              `json:"name"`
-	InstanceID types.ID            `json:"instance_id"`
+	ID         string               `json:"id"`
+	ChannelID  string               `json:"channel_id"`
+	Filters    SubscriptionFilters  `json:"filters"`
+	Name       string               `json:"name"`
+	InstanceID types.ID             `json:"instance_id"`
+	Mentions   SubscriptionMentions `json:"mentions"`
+
+	// PostAttachments uploads the attachments added to the issues in Jira as files in the
+	// thread of the issue, so that images show inline.
+	PostAttachments bool `json:"post_attachments,omitempty"`
 }
 
 type SubscriptionTemplate struct {
@@ -181,6 +220,12 @@ func (p *Plugin) getUserID() string {
 }
 
 func (p *Plugin) matchesSubscriptionFilters(wh *webhook, instanceID types.ID, filters SubscriptionFilters) bool {
+	return p.getSubscriptionFilterMismatch(wh, instanceID, filters) == ""
+}
+
+// getSubscriptionFilterMismatch returns a short description of the first filter rejecting
+// the webhook event, or an empty string if the event matches all filters.
+func (p *Plugin) getSubscriptionFilterMismatch(wh *webhook, instanceID types.ID, filters SubscriptionFilters) string {
 	webhookEvents := wh.Events()
 	foundEvent := false
 	eventTypes := filters.Events
@@ -195,18 +240,18 @@ func (p *Plugin) matchesSubscriptionFilters(wh *webhook, instanceID types.ID, fi
 	}
 
 	if !foundEvent {
-		return false
+		return "events"
 	}
 
 	issue := &wh.JiraWebhook.Issue
 	teamFieldKeys := p.getTeamFieldKeys(instanceID)
 
 	if filters.IssueTypes.Len() != 0 && !filters.IssueTypes.ContainsAny(issue.Fields.Type.ID) {
-		return false
+		return "issue types"
 	}
 
 	if filters.Projects.Len() != 0 && !filters.Projects.ContainsAny(issue.Fields.Project.Key) {
-		return false
+		return "projects"
 	}
 
 	containsSecurityLevelFilter := false
@@ -216,7 +261,7 @@ func (p *Plugin) matchesSubscriptionFilters(wh *webhook, instanceID types.ID, fi
 
 		// Broken filter, values must be provided
 		if inclusion == "" || (field.Values.Len() == 0 && inclusion != FilterEmpty) {
-			return false
+			return fmt.Sprintf("field %s (invalid filter)", field.Key)
 		}
 
 		if field.Key == securityLevelField {
@@ -240,18 +285,30 @@ func (p *Plugin) matchesSubscriptionFilters(wh *webhook, instanceID types.ID, fi
 		}
 
 		if !isValidFieldInclusion(field, value, inclusion) {
-			return false
+			return fmt.Sprintf("field %s (%s)", field.Key, inclusion)
 		}
 	}
 
 	if !containsSecurityLevelFilter && useEmptySecurityLevel {
 		securityLevel := getIssueFieldValue(issue, securityLevelField)
 		if securityLevel.Len() > 0 {
-			return false
+			return "security level"
 		}
 	}
 
-	return true
+	// Checked last, as it is the only filter querying Jira
+	if filters.SavedFilter != nil {
+		matched, err := p.matchesSavedFilter(wh, instanceID, filters.SavedFilter)
+		if err != nil {
+			p.client.Log.Warn("Failed to run the saved filter of a subscription", "error", err.Error())
+			return savedFilterOwnerMismatch
+		}
+		if !matched {
+			return "saved filter"
+		}
+	}
+
+	return ""
 }
 
 func updateCommentVisibilityValue(value StringSet, wh *webhook) StringSet {
@@ -311,10 +368,48 @@ func getTeamFieldValue(issue *jira.Issue, key string) StringSet {
 }
 
 func shouldAddVisibleToAllUsersToFieldValues(wh *webhook, field FieldFilter) bool {
-	return !(wh.eventTypes[commentCreated] || wh.eventTypes[commentUpdated]) && field.Inclusion != FilterIncludeAll && field.Inclusion != FilterExcludeAny
+	return !(wh.eventTypes[commentCreated] || wh.eventTypes[commentUpdated]) &&
+		field.Inclusion != FilterIncludeAll &&
+		field.Inclusion != FilterExcludeAny &&
+		!isRangeInclusion(field.Inclusion)
+}
+
+func isRangeInclusion(inclusion string) bool {
+	switch inclusion {
+	case FilterGreaterThan, FilterGreaterThanOrEqual, FilterLessThan, FilterLessThanOrEqual,
+		FilterBetween, FilterWithinNextDays, FilterWithinLastDays:
+		return true
+	}
+	return false
+}
+
+// validateFieldFilterInclusion checks that the inclusion of a filter is known and that it
+// has the values it needs. The values of the range inclusions are checked against the field
+// schema by validateTypedFieldFilters.
+func validateFieldFilterInclusion(field FieldFilter) error {
+	switch field.Inclusion {
+	case FilterIncludeAny, FilterIncludeAll, FilterExcludeAny, FilterIncludeOrEmpty:
+		if field.Values.Len() == 0 {
+			return errors.New("at least one value must be provided")
+		}
+	case FilterEmpty:
+		if field.Values.Len() != 0 {
+			return errors.Errorf("operator %q does not take values", field.Inclusion)
+		}
+	default:
+		if !isRangeInclusion(field.Inclusion) {
+			return errors.Errorf("unknown operator %q", field.Inclusion)
+		}
+	}
+
+	return nil
 }
 
 func isValidFieldInclusion(field FieldFilter, value StringSet, inclusion string) bool {
+	if isRangeInclusion(inclusion) {
+		return isValidRangeInclusion(field, value, inclusion, time.Now())
+	}
+
 	containsAny := value.ContainsAny(field.Values.Elems()...)
 	containsAll := value.ContainsAll(field.Values.Elems()...)
 
@@ -329,25 +424,225 @@ func isValidFieldInclusion(field FieldFilter, value StringSet, inclusion string)
 	return true
 }
 
+// isValidRangeInclusion compares the issue's field value against the bounds of the filter,
+// using numeric or chronological ordering depending on the field schema. An issue without
+// a value for the field never matches a range filter.
+func isValidRangeInclusion(field FieldFilter, value StringSet, inclusion string, now time.Time) bool {
+	for _, v := range value.Elems() {
+		switch field.Schema {
+		case FieldSchemaDate, FieldSchemaDateTime:
+			t, err := parseFilterTime(v)
+			if err != nil {
+				continue
+			}
+			if matchesTimeRange(field, t, inclusion, now) {
+				return true
+			}
+		default:
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			bounds, err := parseNumberBounds(field.Values)
+			if err != nil {
+				return false
+			}
+			if matchesOrderedRange(compareFloats(n, bounds[0]), compareFloats(n, bounds[len(bounds)-1]), inclusion) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func matchesTimeRange(field FieldFilter, t time.Time, inclusion string, now time.Time) bool {
+	if inclusion == FilterWithinNextDays || inclusion == FilterWithinLastDays {
+		days, err := parseFilterDays(field.Values)
+		if err != nil {
+			return false
+		}
+
+		if field.Schema == FieldSchemaDate {
+			now = now.UTC().Truncate(24 * time.Hour)
+		}
+
+		period := time.Duration(days) * 24 * time.Hour
+		if inclusion == FilterWithinNextDays {
+			return !t.Before(now) && !t.After(now.Add(period))
+		}
+		return !t.Before(now.Add(-period)) && !t.After(now)
+	}
+
+	bounds, err := parseTimeBounds(field.Values)
+	if err != nil {
+		return false
+	}
+
+	return matchesOrderedRange(t.Compare(bounds[0]), t.Compare(bounds[len(bounds)-1]), inclusion)
+}
+
+// matchesOrderedRange applies an ordering operator given the comparison of the value
+// against the lower and upper bounds of the filter. For single-valued operators both
+// bounds are the same.
+func matchesOrderedRange(cmpLower, cmpUpper int, inclusion string) bool {
+	switch inclusion {
+	case FilterGreaterThan:
+		return cmpLower > 0
+	case FilterGreaterThanOrEqual:
+		return cmpLower >= 0
+	case FilterLessThan:
+		return cmpUpper < 0
+	case FilterLessThanOrEqual:
+		return cmpUpper <= 0
+	case FilterBetween:
+		return cmpLower >= 0 && cmpUpper <= 0
+	}
+	return false
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+var filterTimeLayouts = []string{
+	"2006-01-02T15:04:05.000-0700",
+	time.RFC3339,
+	"2006-01-02",
+}
+
+func parseFilterTime(value string) (time.Time, error) {
+	for _, layout := range filterTimeLayouts {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("%q is not a valid date", value)
+}
+
+func parseNumberBounds(values StringSet) ([]float64, error) {
+	if values.Len() == 0 {
+		return nil, errors.New("a value must be provided")
+	}
+
+	bounds := []float64{}
+	for _, v := range values.Elems() {
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, errors.Errorf("%q is not a valid number", v)
+		}
+		bounds = append(bounds, n)
+	}
+	sort.Float64s(bounds)
+
+	return bounds, nil
+}
+
+func parseTimeBounds(values StringSet) ([]time.Time, error) {
+	if values.Len() == 0 {
+		return nil, errors.New("a value must be provided")
+	}
+
+	bounds := []time.Time{}
+	for _, v := range values.Elems() {
+		t, err := parseFilterTime(v)
+		if err != nil {
+			return nil, err
+		}
+		bounds = append(bounds, t)
+	}
+	sort.Slice(bounds, func(i, j int) bool {
+		return bounds[i].Before(bounds[j])
+	})
+
+	return bounds, nil
+}
+
+func parseFilterDays(values StringSet) (int, error) {
+	if values.Len() != 1 {
+		return 0, errors.New("exactly one number of days must be provided")
+	}
+
+	days, err := strconv.Atoi(values.Elems()[0])
+	if err != nil || days <= 0 {
+		return 0, errors.Errorf("%q is not a valid number of days", values.Elems()[0])
+	}
+
+	return days, nil
+}
+
+func validateRangeFilterValues(inclusion, schema string, values StringSet) error {
+	switch schema {
+	case FieldSchemaNumber:
+		if inclusion == FilterWithinNextDays || inclusion == FilterWithinLastDays {
+			return errors.Errorf("operator %q is only supported for date fields", inclusion)
+		}
+	case FieldSchemaDate, FieldSchemaDateTime:
+		if inclusion == FilterWithinNextDays || inclusion == FilterWithinLastDays {
+			_, err := parseFilterDays(values)
+			return err
+		}
+	default:
+		return errors.Errorf("operator %q is not supported for fields of type %q", inclusion, schema)
+	}
+
+	if inclusion == FilterBetween && values.Len() != 2 {
+		return errors.New("exactly two values must be provided")
+	}
+	if inclusion != FilterBetween && values.Len() != 1 {
+		return errors.New("exactly one value must be provided")
+	}
+
+	var err error
+	if schema == FieldSchemaNumber {
+		_, err = parseNumberBounds(values)
+	} else {
+		_, err = parseTimeBounds(values)
+	}
+
+	return err
+}
+
 func (p *Plugin) getChannelsSubscribed(wh *webhook, instanceID types.ID) ([]ChannelSubscription, error) {
+	channelSubscriptions, _, err := p.getChannelsSubscribedWithRejections(wh, instanceID)
+	return channelSubscriptions, err
+}
+
+// getChannelsSubscribedWithRejections is getChannelsSubscribed that also returns, by
+// subscription ID, the filter that rejected the event for each non-matching subscription.
+func (p *Plugin) getChannelsSubscribedWithRejections(wh *webhook, instanceID types.ID) ([]ChannelSubscription, map[string]string, error) {
 	subs, err := p.getSubscriptions(instanceID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var channelSubscriptions []ChannelSubscription
+	rejected := map[string]string{}
 	subscriptionMap := make(map[string]bool)
 	subIds := subs.Channel.ByID
 	for _, sub := range subIds {
-		if p.matchesSubscriptionFilters(wh, instanceID, sub.Filters) {
-			if !subscriptionMap[sub.ChannelID] {
-				subscriptionMap[sub.ChannelID] = true
-				channelSubscriptions = append(channelSubscriptions, sub)
+		mismatch := p.getSubscriptionFilterMismatch(wh, instanceID, sub.Filters)
+		if mismatch != "" {
+			rejected[sub.ID] = mismatch
+			if mismatch == savedFilterOwnerMismatch {
+				p.notifySavedFilterOwnerDisconnected(sub)
 			}
+			continue
+		}
+		if !subscriptionMap[sub.ChannelID] {
+			subscriptionMap[sub.ChannelID] = true
+			channelSubscriptions = append(channelSubscriptions, sub)
 		}
 	}
 
-	return channelSubscriptions, nil
+	return channelSubscriptions, rejected, nil
 }
 
 func (p *Plugin) getSubscriptions(instanceID types.ID) (*Subscriptions, error) {
@@ -496,7 +791,7 @@ func (p *Plugin) cleanupDMSubscriptionsOnDisconnect(instanceID types.ID, matterm
 	}
 }
 
-func (p *Plugin) addChannelSubscription(instanceID types.ID, newSubscription *ChannelSubscription, client Client) error {
+func (p *Plugin) addChannelSubscription(instanceID, scope types.ID, newSubscription *ChannelSubscription, client Client) error {
 	subKey := keyWithInstanceID(instanceID, JiraSubscriptionsKey)
 	return p.client.KV.SetAtomicWithRetries(subKey, func(initialBytes []byte) (interface{}, error) {
 		subs, err := SubscriptionsFromJSON(initialBytes, instanceID)
@@ -504,7 +799,7 @@ func (p *Plugin) addChannelSubscription(instanceID types.ID, newSubscription *Ch
 			return nil, err
 		}
 
-		err = p.validateSubscription(instanceID, newSubscription, client)
+		err = p.validateSubscription(instanceID, scope, newSubscription, client)
 		if err != nil {
 			return nil, err
 		}
@@ -661,7 +956,7 @@ func (p *Plugin) validateSubscriptionTemplate(subscriptionTemplate *Subscription
 	return nil
 }
 
-func (p *Plugin) validateSubscription(instanceID types.ID, subscription *ChannelSubscription, client Client) error {
+func (p *Plugin) validateSubscription(instanceID, scope types.ID, subscription *ChannelSubscription, client Client) error {
 	if len(subscription.Name) == 0 {
 		return errors.New("please provide a name for the subscription")
 	}
@@ -674,12 +969,29 @@ func (p *Plugin) validateSubscription(instanceID types.ID, subscription *Channel
 		return errors.New("please provide at least one event type")
 	}
 
-	if len(subscription.Filters.IssueTypes) == 0 {
-		return errors.New("please provide at least one issue type")
+	if subscription.Filters.SavedFilter != nil {
+		if err := validateSavedFilterSource(subscription.Filters.SavedFilter, client); err != nil {
+			return err
+		}
+	} else {
+		if len(subscription.Filters.IssueTypes) == 0 {
+			return errors.New("please provide at least one issue type")
+		}
+
+		if (len(subscription.Filters.Projects)) == 0 {
+			return errors.New("please provide a project identifier")
+		}
 	}
 
-	if (len(subscription.Filters.Projects)) == 0 {
-		return errors.New("please provide a project identifier")
+	if len(subscription.Filters.Projects) == 0 {
+		// Only saved filter subscriptions get here, the project specific filters need a project
+		if len(subscription.Filters.Fields) > 0 {
+			return errors.New("please provide a project identifier to filter by fields")
+		}
+		if err := p.validateSubscriptionMentions(client, "", subscription.Mentions); err != nil {
+			return err
+		}
+		return p.validateSubscriptionName(instanceID, subscription)
 	}
 
 	projectKey := subscription.Filters.Projects.Elems()[0]
@@ -700,7 +1012,7 @@ func (p *Plugin) validateSubscription(instanceID types.ID, subscription *Channel
 		}
 
 		if securityLevels == nil {
-			securityLevelsArray, err := p.getSecurityLevelsForProject(client, projectKey)
+			securityLevelsArray, err := p.getSecurityLevelsForProject(instanceID, scope, client, projectKey)
 			if err != nil {
 				return errors.Wrap(err, "failed to get security levels for project")
 			}
@@ -713,8 +1025,28 @@ func (p *Plugin) validateSubscription(instanceID types.ID, subscription *Channel
 		}
 	}
 
-	channelID := subscription.ChannelID
-	subs, err := p.getSubscriptionsForChannel(instanceID, channelID)
+	if err := p.validateTypedFieldFilters(client, projectKey, subscription.Filters.Fields); err != nil {
+		return err
+	}
+
+	if err := p.validateSubscriptionMentions(client, projectKey, subscription.Mentions); err != nil {
+		return err
+	}
+
+	if err := p.validateSubscriptionName(instanceID, subscription); err != nil {
+		return err
+	}
+
+	_, err := client.GetProject(projectKey)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to get project %q", projectKey)
+	}
+
+	return nil
+}
+
+func (p *Plugin) validateSubscriptionName(instanceID types.ID, subscription *ChannelSubscription) error {
+	subs, err := p.getSubscriptionsForChannel(instanceID, subscription.ChannelID)
 	if err != nil {
 		return err
 	}
@@ -724,16 +1056,21 @@ func (p *Plugin) validateSubscription(instanceID types.ID, subscription *Channel
 			return errors.Errorf("Subscription name, '%s', already exists. Please choose another name.", subs[subID].Name)
 		}
 	}
+	return nil
+}
 
-	_, err = client.GetProject(projectKey)
+func (p *Plugin) getSecurityLevelsForProject(instanceID, scope types.ID, client Client, projectKey string) ([]string, error) {
+	key := metadataCacheKey{instanceID, scope, metadataKindSecurityLevels, projectKey}
+	levels, err := p.metadataCache.get(key, metadataCacheTTL, func() (interface{}, error) {
+		return p.fetchSecurityLevelsForProject(client, projectKey)
+	})
 	if err != nil {
-		return errors.WithMessagef(err, "failed to get project %q", projectKey)
+		return nil, err
 	}
-
-	return nil
+	return levels.([]string), nil
 }
 
-func (p *Plugin) getSecurityLevelsForProject(client Client, projectKey string) ([]string, error) {
+func (p *Plugin) fetchSecurityLevelsForProject(client Client, projectKey string) ([]string, error) {
 	createMeta, err := client.GetCreateMetaInfo(p.API, &jira.GetQueryOptions{
 		Expand:      "projects.issuetypes.fields",
 		ProjectKeys: projectKey,
@@ -774,7 +1111,174 @@ func (p *Plugin) getSecurityLevelsForProject(client Client, projectKey string) (
 	return out, nil
 }
 
-func (p *Plugin) editChannelSubscription(instanceID types.ID, modifiedSubscription *ChannelSubscription, client Client) error {
+// validateTypedFieldFilters checks that range and date operators are only used on fields
+// whose createmeta schema is ordered, and that their values parse. The schema type is
+// stored on each filter so that webhook events can be evaluated without fetching createmeta.
+func (p *Plugin) validateTypedFieldFilters(client Client, projectKey string, fields []FieldFilter) error {
+	var schemas map[string]fieldSchema
+	for i, field := range fields {
+		if !isRangeInclusion(field.Inclusion) {
+			continue
+		}
+
+		if schemas == nil {
+			var err error
+			schemas, err = p.getFieldSchemasForProject(client, projectKey)
+			if err != nil {
+				return errors.Wrap(err, "failed to get field schemas for project")
+			}
+		}
+
+		schema, ok := schemas[field.Key]
+		if !ok {
+			return errors.Errorf("field %q was not found in project %q", field.Key, projectKey)
+		}
+
+		if err := validateRangeFilterValues(field.Inclusion, schema.Type, field.Values); err != nil {
+			return errors.WithMessagef(err, "invalid filter for field %q", field.Key)
+		}
+
+		fields[i].Schema = schema.Type
+	}
+
+	return nil
+}
+
+func (p *Plugin) validateSubscriptionMentions(client Client, projectKey string, mentions SubscriptionMentions) error {
+	var schemas map[string]fieldSchema
+	for _, key := range mentions.Fields.Elems() {
+		if key == assigneeField || key == reporterField {
+			continue
+		}
+
+		if projectKey == "" {
+			return errors.Errorf("please provide a project identifier to mention the users of field %q", key)
+		}
+
+		if schemas == nil {
+			var err error
+			schemas, err = p.getFieldSchemasForProject(client, projectKey)
+			if err != nil {
+				return errors.Wrap(err, "failed to get field schemas for project")
+			}
+		}
+
+		if schema, ok := schemas[key]; !ok || !schema.isUserPicker() {
+			return errors.Errorf("field %q is not a user picker field of project %q", key, projectKey)
+		}
+	}
+
+	if mentions.Group == "" {
+		return nil
+	}
+
+	group, err := p.client.Group.GetByName(strings.TrimPrefix(mentions.Group, "@"))
+	if err != nil {
+		return errors.Wrapf(err, "failed to find group %q", mentions.Group)
+	}
+
+	if !group.AllowReference {
+		return errors.Errorf("group %q does not allow mentions", mentions.Group)
+	}
+
+	return nil
+}
+
+// getSubscriptionMentions resolves the mentions configured for a subscription against the
+// issue of the webhook event. Jira users who are not connected to Mattermost are skipped.
+func (p *Plugin) getSubscriptionMentions(wh *webhook, instanceID types.ID, mentions SubscriptionMentions) []string {
+	if wh.Issue.Fields == nil || p.userStore == nil {
+		return nil
+	}
+
+	jiraUsers := NewStringSet()
+	for _, key := range mentions.Fields.Elems() {
+		switch key {
+		case assigneeField:
+			jiraUsers = jiraUsers.Add(getJiraUserIdentifier(wh.Issue.Fields.Assignee)...)
+		case reporterField:
+			jiraUsers = jiraUsers.Add(getJiraUserIdentifier(wh.Issue.Fields.Reporter)...)
+		default:
+			jiraUsers = jiraUsers.Add(getIssueCustomFieldValue(&wh.Issue, key).Elems()...)
+		}
+	}
+
+	usernames := NewStringSet()
+	for _, jiraUser := range jiraUsers.Elems() {
+		mattermostUserID, err := p.userStore.LoadMattermostUserID(instanceID, jiraUser)
+		if err != nil {
+			continue
+		}
+
+		user, err := p.client.User.Get(string(mattermostUserID))
+		if err != nil || user.DeleteAt > 0 {
+			continue
+		}
+
+		usernames = usernames.Add("@" + user.Username)
+	}
+
+	result := usernames.Elems()
+	sort.Strings(result)
+
+	if mentions.Group != "" {
+		result = append(result, "@"+strings.TrimPrefix(mentions.Group, "@"))
+	}
+
+	return result
+}
+
+func getJiraUserIdentifier(user *jira.User) []string {
+	switch {
+	case user == nil:
+		return nil
+	case user.AccountID != "":
+		return []string{user.AccountID}
+	case user.Name != "":
+		return []string{user.Name}
+	}
+	return nil
+}
+
+type fieldSchema struct {
+	Type  string
+	Items string
+}
+
+func (s fieldSchema) isUserPicker() bool {
+	return s.Type == FieldSchemaUser || (s.Type == "array" && s.Items == FieldSchemaUser)
+}
+
+func (p *Plugin) getFieldSchemasForProject(client Client, projectKey string) (map[string]fieldSchema, error) {
+	createMeta, err := client.GetCreateMetaInfo(p.API, &jira.GetQueryOptions{
+		Expand:      "projects.issuetypes.fields",
+		ProjectKeys: projectKey,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error fetching create metadata")
+	}
+
+	schemas := map[string]fieldSchema{}
+	for _, project := range createMeta.Projects {
+		for _, issueType := range project.IssueTypes {
+			for key := range issueType.Fields {
+				schemaType, err := issueType.Fields.String(key + "/schema/type")
+				if err != nil {
+					continue
+				}
+				items, _ := issueType.Fields.String(key + "/schema/items")
+				schemas[key] = fieldSchema{
+					Type:  schemaType,
+					Items: items,
+				}
+			}
+		}
+	}
+
+	return schemas, nil
+}
+
+func (p *Plugin) editChannelSubscription(instanceID, scope types.ID, modifiedSubscription *ChannelSubscription, client Client) error {
 	subKey := keyWithInstanceID(instanceID, JiraSubscriptionsKey)
 	return p.client.KV.SetAtomicWithRetries(subKey, func(initialBytes []byte) (interface{}, error) {
 		subs, err := SubscriptionsFromJSON(initialBytes, instanceID)
@@ -787,7 +1291,7 @@ func (p *Plugin) editChannelSubscription(instanceID types.ID, modifiedSubscripti
 			return nil, errors.New("existing subscription does not exist")
 		}
 
-		err = p.validateSubscription(instanceID, modifiedSubscription, client)
+		err = p.validateSubscription(instanceID, scope, modifiedSubscription, client)
 		if err != nil {
 			return nil, err
 		}
@@ -874,7 +1378,11 @@ func (p *Plugin) listChannelSubscriptions(instanceID types.ID, teamID string) (s
 				})
 
 				for _, channelSubscription := range channelSubscriptions {
-					rows = append(rows, fmt.Sprintf("\t\t* %s - %s", channelSubscription.Filters.Projects.Elems()[0], channelSubscription.Name))
+					source := strings.Join(channelSubscription.Filters.Projects.Elems(), ", ")
+					if savedFilter := channelSubscription.Filters.SavedFilter; savedFilter != nil {
+						source = strings.TrimPrefix(source+", filter "+savedFilter.Name, ", ")
+					}
+					rows = append(rows, fmt.Sprintf("\t\t* %s - %s", source, channelSubscription.Name))
 				}
 			}
 		}
@@ -1131,6 +1639,7 @@ func (p *Plugin) httpSubscribeWebhook(w http.ResponseWriter, r *http.Request, in
 	if conf.EnableWebhookEventLogging {
 		p.client.Log.Debug("Webhook Event Log", "event", string(bb))
 	}
+	p.recordWebhookReceived(instanceID)
 
 	// If there is space in the queue, immediately return a 200; we will process the webhook event async.
 	// If the queue is full, return a 503; we will not process that webhook event.
@@ -1187,7 +1696,10 @@ func (p *Plugin) httpChannelCreateSubscription(w http.ResponseWriter, r *http.Re
 		return respondErr(w, http.StatusInternalServerError, err)
 	}
 
-	err = p.addChannelSubscription(subscription.InstanceID, &subscription, client)
+	if subscription.Filters.SavedFilter != nil {
+		subscription.Filters.SavedFilter.OwnerID = types.ID(mattermostUserID)
+	}
+	err = p.addChannelSubscription(subscription.InstanceID, connection.JiraAccountID(), &subscription, client)
 	if err != nil {
 		return respondErr(w, http.StatusInternalServerError, err)
 	}
@@ -1220,8 +1732,13 @@ func (p *Plugin) httpChannelCreateSubscription(w http.ResponseWriter, r *http.Re
 
 func (p *Plugin) httpChannelEditSubscription(w http.ResponseWriter, r *http.Request) (int, error) {
 	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return respondErr(w, http.StatusBadRequest,
+			errors.WithMessage(err, "failed to read incoming request"))
+	}
 	subscription := ChannelSubscription{}
-	err := json.NewDecoder(r.Body).Decode(&subscription)
+	err = json.Unmarshal(body, &subscription)
 	if err != nil {
 		return respondErr(w, http.StatusBadRequest,
 			errors.WithMessage(err, "failed to decode incoming request"))
@@ -1239,6 +1756,12 @@ func (p *Plugin) httpChannelEditSubscription(w http.ResponseWriter, r *http.Requ
 			errors.Wrap(err, "failed to find existing subscription"))
 	}
 
+	err = keepUnsentSubscriptionFields(body, &subscription, existingSub)
+	if err != nil {
+		return respondErr(w, http.StatusBadRequest,
+			errors.WithMessage(err, "failed to decode incoming request"))
+	}
+
 	err = p.hasPermissionToManageSubscription(subscription.InstanceID, mattermostUserID, existingSub.ChannelID)
 	if err != nil {
 		return respondErr(w, http.StatusForbidden,
@@ -1279,7 +1802,10 @@ func (p *Plugin) httpChannelEditSubscription(w http.ResponseWriter, r *http.Requ
 	if err != nil {
 		return respondErr(w, http.StatusInternalServerError, err)
 	}
-	err = p.editChannelSubscription(subscription.InstanceID, &subscription, client)
+	if subscription.Filters.SavedFilter != nil {
+		subscription.Filters.SavedFilter.OwnerID = types.ID(mattermostUserID)
+	}
+	err = p.editChannelSubscription(subscription.InstanceID, connection.JiraAccountID(), &subscription, client)
 	if err != nil {
 		return respondErr(w, http.StatusInternalServerError, err)
 	}
@@ -1310,6 +1836,35 @@ func (p *Plugin) httpChannelEditSubscription(w http.ResponseWriter, r *http.Requ
 	return http.StatusOK, nil
 }
 
+// keepUnsentSubscriptionFields copies the settings an edit request did not send from the
+// existing subscription, so that clients unaware of them don't reset them.
+func keepUnsentSubscriptionFields(body []byte, subscription, existing *ChannelSubscription) error {
+	sent := map[string]json.RawMessage{}
+	err := json.Unmarshal(body, &sent)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := sent["mentions"]; !ok {
+		subscription.Mentions = existing.Mentions
+	}
+	if _, ok := sent["post_attachments"]; !ok {
+		subscription.PostAttachments = existing.PostAttachments
+	}
+
+	sentFilters := map[string]json.RawMessage{}
+	if raw, ok := sent["filters"]; ok {
+		err = json.Unmarshal(raw, &sentFilters)
+		if err != nil {
+			return err
+		}
+	}
+	if _, ok := sentFilters["saved_filter"]; !ok {
+		subscription.Filters.SavedFilter = existing.Filters.SavedFilter
+	}
+	return nil
+}
+
 func (p *Plugin) httpChannelDeleteSubscription(w http.ResponseWriter, r *http.Request) (int, error) {
 	mattermostUserID := r.Header.Get("Mattermost-User-Id")
 	params := mux.Vars(r)
@@ -0,0 +1,468 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+const (
+	personalSubscribersKey = "personal_subscribers"
+
+	PostTypePersonalSubscription = "custom_jira_personal_subscription"
+
+	MaxPersonalSubscriptionsPerUser = 25
+
+	// projectVisibilityTTL is how long a user who could see an issue of a project is assumed
+	// to still be able to see the issues of the project without a security level.
+	projectVisibilityTTL        = 5 * time.Minute
+	projectVisibilityMaxEntries = 10000
+)
+
+// PersonalSubscription is a subscription owned by a single user, delivered to them by DM.
+// Personal subscriptions are stored on the user's Connection.
+type PersonalSubscription struct {
+	ID      string              `json:"id"`
+	Name    string              `json:"name"`
+	Filters SubscriptionFilters `json:"filters"`
+}
+
+func (sub PersonalSubscription) String() string {
+	events := sub.Filters.Events.Elems()
+	sort.Strings(events)
+	return fmt.Sprintf("**%s** - project %s, events %s", sub.Name, strings.Join(sub.Filters.Projects.Elems(), ", "), strings.Join(events, ", "))
+}
+
+// getPersonalSubscribers returns the IDs of the Mattermost users who have personal
+// subscriptions on the instance. The index avoids loading every connection on each webhook.
+func (p *Plugin) getPersonalSubscribers(instanceID types.ID) (StringSet, error) {
+	var data []byte
+	err := p.client.KV.Get(keyWithInstanceID(instanceID, personalSubscribersKey), &data)
+	if err != nil {
+		return nil, err
+	}
+
+	subscribers := NewStringSet()
+	if len(data) == 0 {
+		return subscribers, nil
+	}
+
+	if err = json.Unmarshal(data, &subscribers); err != nil {
+		return nil, err
+	}
+
+	return subscribers, nil
+}
+
+func (p *Plugin) updatePersonalSubscribers(instanceID, mattermostUserID types.ID, hasSubscriptions bool) error {
+	key := keyWithInstanceID(instanceID, personalSubscribersKey)
+	return p.client.KV.SetAtomicWithRetries(key, func(initialBytes []byte) (interface{}, error) {
+		subscribers := NewStringSet()
+		if len(initialBytes) != 0 {
+			if err := json.Unmarshal(initialBytes, &subscribers); err != nil {
+				return nil, err
+			}
+		}
+
+		if hasSubscriptions {
+			subscribers = subscribers.Add(mattermostUserID.String())
+		} else {
+			subscribers = subscribers.Subtract(mattermostUserID.String())
+		}
+
+		return json.Marshal(subscribers)
+	})
+}
+
+func (p *Plugin) validatePersonalSubscription(connection *Connection, sub *PersonalSubscription, client Client) error {
+	if len(sub.Name) == 0 {
+		return errors.New("please provide a name for the subscription")
+	}
+
+	if len(sub.Name) > MaxSubscriptionNameLength {
+		return errors.Errorf("please provide a name less than %d characters", MaxSubscriptionNameLength)
+	}
+
+	if len(connection.Subscriptions) >= MaxPersonalSubscriptionsPerUser {
+		return errors.Errorf("you can't have more than %d personal subscriptions", MaxPersonalSubscriptionsPerUser)
+	}
+
+	for _, existing := range connection.Subscriptions {
+		if existing.Name == sub.Name {
+			return errors.Errorf("Subscription name, '%s', already exists. Please choose another name.", sub.Name)
+		}
+	}
+
+	if sub.Filters.Events.Len() == 0 {
+		return errors.New("please provide at least one event type")
+	}
+
+	for _, event := range sub.Filters.Events.Elems() {
+		if !allEvents.ContainsAny(event) {
+			return errors.Errorf("unknown event type %q", event)
+		}
+	}
+
+	if sub.Filters.Projects.Len() != 1 {
+		return errors.New("please provide a project identifier")
+	}
+
+	for _, field := range sub.Filters.Fields {
+		if err := validateFieldFilterInclusion(field); err != nil {
+			return errors.WithMessagef(err, "invalid filter for field %q", field.Key)
+		}
+	}
+
+	projectKey := sub.Filters.Projects.Elems()[0]
+	if _, err := client.GetProject(projectKey); err != nil {
+		return errors.WithMessagef(err, "failed to get project %q", projectKey)
+	}
+
+	return p.validateTypedFieldFilters(client, projectKey, sub.Filters.Fields)
+}
+
+func (p *Plugin) addPersonalSubscription(instanceID, mattermostUserID types.ID, connection *Connection, sub *PersonalSubscription, client Client) error {
+	if err := p.validatePersonalSubscription(connection, sub, client); err != nil {
+		return err
+	}
+
+	sub.ID = model.NewId()
+	connection.Subscriptions = append(connection.Subscriptions, *sub)
+	if err := p.userStore.StoreConnection(instanceID, mattermostUserID, connection); err != nil {
+		return err
+	}
+
+	return p.updatePersonalSubscribers(instanceID, mattermostUserID, true)
+}
+
+func (p *Plugin) removePersonalSubscription(instanceID, mattermostUserID types.ID, connection *Connection, nameOrID string) (*PersonalSubscription, error) {
+	var removed *PersonalSubscription
+	remaining := []PersonalSubscription{}
+	for i, sub := range connection.Subscriptions {
+		if removed == nil && (sub.Name == nameOrID || sub.ID == nameOrID) {
+			removed = &connection.Subscriptions[i]
+			continue
+		}
+		remaining = append(remaining, sub)
+	}
+
+	if removed == nil {
+		return nil, errors.Errorf("could not find subscription %q", nameOrID)
+	}
+
+	connection.Subscriptions = remaining
+	if err := p.userStore.StoreConnection(instanceID, mattermostUserID, connection); err != nil {
+		return nil, err
+	}
+
+	return removed, p.updatePersonalSubscribers(instanceID, mattermostUserID, len(remaining) > 0)
+}
+
+// postPersonalSubscriptions sends a DM to every user with a personal subscription matching
+// the webhook event, provided they can see the issue with their own Jira connection.
+func (p *Plugin) postPersonalSubscriptions(wh *webhook, instanceID types.ID) []*model.Post {
+	if wh.headline == "" {
+		return nil
+	}
+
+	subscribers, err := p.getPersonalSubscribers(instanceID)
+	if err != nil {
+		p.errorf("postPersonalSubscriptions: failed to load personal subscribers, err: %v", err)
+		return nil
+	}
+
+	if subscribers.Len() == 0 {
+		return nil
+	}
+
+	instance, err := p.instanceStore.LoadInstance(instanceID)
+	if err != nil {
+		return nil
+	}
+
+	// Issues without a security level are visible to everyone who can browse the project, so
+	// whether the user can see them is cached per project for a little while
+	restricted := getIssueFieldValue(&wh.JiraWebhook.Issue, securityLevelField).Len() > 0
+	projectKey := ""
+	if wh.Issue.Fields != nil {
+		projectKey = wh.Issue.Fields.Project.Key
+	}
+
+	posts := []*model.Post{}
+	for _, mattermostUserID := range subscribers.Elems() {
+		connection, err := p.userStore.LoadConnection(instanceID, types.ID(mattermostUserID))
+		if err != nil {
+			continue
+		}
+
+		if isEventAuthor(wh, connection) {
+			continue
+		}
+
+		var matched *PersonalSubscription
+		for i := range connection.Subscriptions {
+			if p.matchesSubscriptionFilters(wh, instanceID, connection.Subscriptions[i].Filters) {
+				matched = &connection.Subscriptions[i]
+				break
+			}
+		}
+		if matched == nil {
+			continue
+		}
+
+		visibilityKey := projectVisibilityKey{instanceID, types.ID(mattermostUserID), projectKey}
+		if restricted || !p.projectVisibility.isVisible(visibilityKey, time.Now()) {
+			if !p.canSeeIssue(wh, instance, connection) {
+				continue
+			}
+			if !restricted {
+				p.projectVisibility.setVisible(visibilityKey, time.Now())
+			}
+		}
+
+		message := fmt.Sprintf("%s\nSubscription: **%s**", wh.headline, matched.Name)
+		post, err := p.CreateBotDMPost(instanceID, types.ID(mattermostUserID), message, PostTypePersonalSubscription)
+		if err != nil {
+			p.errorf("postPersonalSubscriptions: failed to create notification post, err: %v", err)
+			continue
+		}
+		if post != nil {
+			posts = append(posts, post)
+		}
+	}
+
+	return posts
+}
+
+// canSeeIssue checks with the user's own connection that they can see the issue of the event.
+// Only the issue ID is requested, the event already carries the rest.
+func (p *Plugin) canSeeIssue(wh *webhook, instance Instance, connection *Connection) bool {
+	client, err := instance.GetClient(connection)
+	if err != nil {
+		p.errorf("postPersonalSubscriptions: error while getting jiraClient, err: %v", err)
+		return false
+	}
+
+	_, err = client.GetIssue(wh.Issue.ID, &jira.GetQueryOptions{Fields: "id"})
+	return err == nil
+}
+
+type projectVisibilityKey struct {
+	instanceID       types.ID
+	mattermostUserID types.ID
+	projectKey       string
+}
+
+// projectVisibilityCache remembers, on each server of the cluster, the users who could
+// recently see an issue of a project. Failed checks are not cached, so they are retried on
+// the next event. The zero value is ready to use.
+type projectVisibilityCache struct {
+	lock      sync.Mutex
+	checkedAt map[projectVisibilityKey]time.Time
+}
+
+func (c *projectVisibilityCache) isVisible(key projectVisibilityKey, now time.Time) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	checkedAt, ok := c.checkedAt[key]
+	return ok && now.Sub(checkedAt) < projectVisibilityTTL
+}
+
+func (c *projectVisibilityCache) setVisible(key projectVisibilityKey, now time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.checkedAt == nil || len(c.checkedAt) >= projectVisibilityMaxEntries {
+		c.checkedAt = map[projectVisibilityKey]time.Time{}
+	}
+	c.checkedAt[key] = now
+}
+
+func isEventAuthor(wh *webhook, connection *Connection) bool {
+	author := wh.User
+	if wh.Comment.Author.AccountID != "" || wh.Comment.Author.Name != "" {
+		author = wh.Comment.Author
+	}
+
+	if author.AccountID != "" {
+		return author.AccountID == connection.AccountID
+	}
+
+	return author.Name != "" && author.Name == connection.Name
+}
+
+// parsePersonalSubscriptionArgs parses the arguments of
+// `/jira settings subscriptions add <name> project=<key> [events=<e1,e2>] [types=<t1,t2>] [field=<key>:<inclusion>:<v1,v2>]`.
+func parsePersonalSubscriptionArgs(args []string, client Client) (*PersonalSubscription, error) {
+	if len(args) == 0 {
+		return nil, errors.New("please provide a name for the subscription")
+	}
+
+	sub := &PersonalSubscription{
+		Name: args[0],
+		Filters: SubscriptionFilters{
+			Events:     NewStringSet(eventCreated),
+			Projects:   NewStringSet(),
+			IssueTypes: NewStringSet(),
+		},
+	}
+
+	var issueTypes []string
+	for _, arg := range args[1:] {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || value == "" {
+			return nil, errors.Errorf("invalid argument %q, expected key=value", arg)
+		}
+
+		switch key {
+		case "project":
+			sub.Filters.Projects = NewStringSet(strings.ToUpper(value))
+		case "events":
+			events := NewStringSet()
+			for _, event := range strings.Split(value, ",") {
+				if !strings.HasPrefix(event, "event_") {
+					event = "event_" + event
+				}
+				events = events.Add(event)
+			}
+			sub.Filters.Events = events
+		case "types":
+			issueTypes = strings.Split(value, ",")
+		case "field":
+			// The values can be left out for the empty inclusion
+			parts := strings.SplitN(value, ":", 3)
+			if len(parts) < 2 {
+				return nil, errors.Errorf("invalid field filter %q, expected <field>:<inclusion>:<values>", value)
+			}
+			values := NewStringSet()
+			if len(parts) == 3 && parts[2] != "" {
+				values = NewStringSet(strings.Split(parts[2], ",")...)
+			}
+			sub.Filters.Fields = append(sub.Filters.Fields, FieldFilter{
+				Key:       parts[0],
+				Inclusion: parts[1],
+				Values:    values,
+			})
+		default:
+			return nil, errors.Errorf("unknown argument %q", key)
+		}
+	}
+
+	if sub.Filters.Projects.Len() == 0 {
+		return nil, errors.New("please provide a project identifier with `project=<key>`")
+	}
+
+	if len(issueTypes) > 0 {
+		ids, err := resolveIssueTypeIDs(client, sub.Filters.Projects.Elems()[0], issueTypes)
+		if err != nil {
+			return nil, err
+		}
+		sub.Filters.IssueTypes = NewStringSet(ids...)
+	}
+
+	return sub, nil
+}
+
+func resolveIssueTypeIDs(client Client, projectKey string, namesOrIDs []string) ([]string, error) {
+	project, err := client.GetProject(projectKey)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to get project %q", projectKey)
+	}
+
+	issueTypes := project.IssueTypes
+	if len(issueTypes) == 0 {
+		issueTypes, err = client.GetIssueTypes(project.ID)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to get issue types for project %q", projectKey)
+		}
+	}
+
+	ids := []string{}
+	for _, nameOrID := range namesOrIDs {
+		issueType := findIssueType(issueTypes, nameOrID)
+		if issueType == nil {
+			return nil, errors.Errorf("issue type %q was not found in project %q", nameOrID, projectKey)
+		}
+		ids = append(ids, issueType.ID)
+	}
+
+	return ids, nil
+}
+
+func findIssueType(issueTypes []jira.IssueType, nameOrID string) *jira.IssueType {
+	for i, issueType := range issueTypes {
+		if issueType.ID == nameOrID || strings.EqualFold(issueType.Name, nameOrID) {
+			return &issueTypes[i]
+		}
+	}
+	return nil
+}
+
+func (p *Plugin) settingsSubscriptions(header *model.CommandArgs, instance Instance, mattermostUserID types.ID, connection *Connection, args []string) *model.CommandResponse {
+	const helpText = "`/jira settings subscriptions [list|add|remove]`\n" +
+		"* `list` - List your personal subscriptions\n" +
+		"* `add <name> project=<key> [events=<created,updated_status,...>] [types=<Bug,Task,...>] [field=<field>:<inclusion>:<values>]` - Receive a DM for issues matching the filters\n" +
+		"* `remove <name>` - Remove a personal subscription"
+
+	if len(args) < 2 {
+		return p.response(header, helpText)
+	}
+
+	instanceID := instance.GetID()
+	switch args[1] {
+	case "list":
+		if len(connection.Subscriptions) == 0 {
+			return p.response(header, "You don't have any personal subscriptions. "+helpText)
+		}
+
+		rows := []string{"Your personal subscriptions:"}
+		for _, sub := range connection.Subscriptions {
+			rows = append(rows, "* "+sub.String())
+		}
+		return p.response(header, strings.Join(rows, "\n"))
+
+	case "add":
+		client, err := instance.GetClient(connection)
+		if err != nil {
+			return p.responsef(header, "Failed to get a Jira client. Error: %v.", err)
+		}
+
+		sub, err := parsePersonalSubscriptionArgs(args[2:], client)
+		if err != nil {
+			return p.responsef(header, "%v\n%s", err, helpText)
+		}
+
+		if err = p.addPersonalSubscription(instanceID, mattermostUserID, connection, sub, client); err != nil {
+			return p.responsef(header, "Failed to add the subscription. Error: %v.", err)
+		}
+		return p.responsef(header, "Personal subscription %s was added.", sub.String())
+
+	case "remove":
+		if len(args) != 3 {
+			return p.response(header, helpText)
+		}
+
+		removed, err := p.removePersonalSubscription(instanceID, mattermostUserID, connection, args[2])
+		if err != nil {
+			return p.responsef(header, "Failed to remove the subscription. Error: %v.", err)
+		}
+		return p.responsef(header, "Personal subscription **%s** was removed.", removed.Name)
+
+	default:
+		return p.response(header, helpText)
+	}
+}
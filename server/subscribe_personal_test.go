@@ -0,0 +1,207 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+)
+
+func TestParsePersonalSubscriptionArgs(t *testing.T) {
+	for name, tc := range map[string]struct {
+		args         []string
+		expected     *PersonalSubscription
+		errorMessage string
+	}{
+		"defaults to issue created events": {
+			args: []string{"pay-bugs", "project=pay"},
+			expected: &PersonalSubscription{
+				Name: "pay-bugs",
+				Filters: SubscriptionFilters{
+					Events:     NewStringSet(eventCreated),
+					Projects:   NewStringSet("PAY"),
+					IssueTypes: NewStringSet(),
+				},
+			},
+		},
+		"events and field filters": {
+			args: []string{"checkout", "project=PAY", "events=created,updated_status", "field=components:include_any:10001,10002"},
+			expected: &PersonalSubscription{
+				Name: "checkout",
+				Filters: SubscriptionFilters{
+					Events:     NewStringSet(eventCreated, eventUpdatedStatus),
+					Projects:   NewStringSet("PAY"),
+					IssueTypes: NewStringSet(),
+					Fields: []FieldFilter{
+						{
+							Key:       "components",
+							Inclusion: FilterIncludeAny,
+							Values:    NewStringSet("10001", "10002"),
+						},
+					},
+				},
+			},
+		},
+		"empty field filter without values": {
+			args: []string{"unassigned", "project=PAY", "field=assignee:empty"},
+			expected: &PersonalSubscription{
+				Name: "unassigned",
+				Filters: SubscriptionFilters{
+					Events:     NewStringSet(eventCreated),
+					Projects:   NewStringSet("PAY"),
+					IssueTypes: NewStringSet(),
+					Fields: []FieldFilter{
+						{
+							Key:       "assignee",
+							Inclusion: FilterEmpty,
+							Values:    NewStringSet(),
+						},
+					},
+				},
+			},
+		},
+		"missing name": {
+			args:         []string{},
+			errorMessage: "please provide a name for the subscription",
+		},
+		"missing project": {
+			args:         []string{"name", "events=created"},
+			errorMessage: "please provide a project identifier with `project=<key>`",
+		},
+		"invalid argument": {
+			args:         []string{"name", "project"},
+			errorMessage: "invalid argument \"project\", expected key=value",
+		},
+		"invalid field filter": {
+			args:         []string{"name", "project=PAY", "field=components"},
+			errorMessage: "invalid field filter \"components\", expected <field>:<inclusion>:<values>",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			sub, err := parsePersonalSubscriptionArgs(tc.args, testClient{})
+			if tc.errorMessage != "" {
+				require.EqualError(t, err, tc.errorMessage)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, sub)
+		})
+	}
+}
+
+func TestValidatePersonalSubscription(t *testing.T) {
+	p := &Plugin{}
+	p.SetAPI(&plugintest.API{})
+
+	validFilters := SubscriptionFilters{
+		Events:     NewStringSet(eventCreated),
+		Projects:   NewStringSet("PAY"),
+		IssueTypes: NewStringSet(),
+	}
+
+	for name, tc := range map[string]struct {
+		connection   *Connection
+		sub          *PersonalSubscription
+		errorMessage string
+	}{
+		"valid subscription": {
+			connection: &Connection{},
+			sub:        &PersonalSubscription{Name: "pay", Filters: validFilters},
+		},
+		"duplicate name": {
+			connection:   &Connection{Subscriptions: []PersonalSubscription{{Name: "pay"}}},
+			sub:          &PersonalSubscription{Name: "pay", Filters: validFilters},
+			errorMessage: "Subscription name, 'pay', already exists. Please choose another name.",
+		},
+		"unknown event": {
+			connection: &Connection{},
+			sub: &PersonalSubscription{Name: "pay", Filters: SubscriptionFilters{
+				Events:   NewStringSet("event_unknown"),
+				Projects: NewStringSet("PAY"),
+			}},
+			errorMessage: "unknown event type \"event_unknown\"",
+		},
+		"unknown field filter operator": {
+			connection: &Connection{},
+			sub: &PersonalSubscription{Name: "pay", Filters: SubscriptionFilters{
+				Events:   NewStringSet(eventCreated),
+				Projects: NewStringSet("PAY"),
+				Fields:   []FieldFilter{{Key: "priority", Inclusion: "include", Values: NewStringSet("1")}},
+			}},
+			errorMessage: "invalid filter for field \"priority\": unknown operator \"include\"",
+		},
+		"field filter without values": {
+			connection: &Connection{},
+			sub: &PersonalSubscription{Name: "pay", Filters: SubscriptionFilters{
+				Events:   NewStringSet(eventCreated),
+				Projects: NewStringSet("PAY"),
+				Fields:   []FieldFilter{{Key: "priority", Inclusion: FilterExcludeAny, Values: NewStringSet()}},
+			}},
+			errorMessage: "invalid filter for field \"priority\": at least one value must be provided",
+		},
+		"project not found": {
+			connection: &Connection{},
+			sub: &PersonalSubscription{Name: "pay", Filters: SubscriptionFilters{
+				Events:   NewStringSet(eventCreated),
+				Projects: NewStringSet(nonExistantProjectKey),
+			}},
+			errorMessage: "failed to get project \"FP\": Project FP not found",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := p.validatePersonalSubscription(tc.connection, tc.sub, testClient{})
+			if tc.errorMessage == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.errorMessage)
+			}
+		})
+	}
+}
+
+func TestIsEventAuthor(t *testing.T) {
+	connection := &Connection{User: jira.User{AccountID: "account-1"}}
+
+	assert.True(t, isEventAuthor(&webhook{JiraWebhook: &JiraWebhook{User: jira.User{AccountID: "account-1"}}}, connection))
+	assert.False(t, isEventAuthor(&webhook{JiraWebhook: &JiraWebhook{User: jira.User{AccountID: "account-2"}}}, connection))
+	assert.True(t, isEventAuthor(&webhook{JiraWebhook: &JiraWebhook{
+		User:    jira.User{AccountID: "account-2"},
+		Comment: jira.Comment{Author: jira.User{AccountID: "account-1"}},
+	}}, connection))
+	assert.False(t, isEventAuthor(&webhook{JiraWebhook: &JiraWebhook{}}, connection))
+}
+
+func TestCanSeeIssue(t *testing.T) {
+	api := &plugintest.API{}
+	p := &Plugin{}
+	p.SetAPI(api)
+
+	wh := &webhook{JiraWebhook: &JiraWebhook{Issue: jira.Issue{ID: "10001", Fields: &jira.IssueFields{}}}}
+	assert.True(t, p.canSeeIssue(wh, testInstance1, &Connection{}))
+
+	wh.Issue.ID = nonExistantIssueKey
+	assert.False(t, p.canSeeIssue(wh, testInstance1, &Connection{}))
+}
+
+func TestProjectVisibilityCache(t *testing.T) {
+	var cache projectVisibilityCache
+	key := projectVisibilityKey{testInstance1.GetID(), "user_id", "PAY"}
+	now := time.Now()
+
+	assert.False(t, cache.isVisible(key, now))
+
+	cache.setVisible(key, now)
+	assert.True(t, cache.isVisible(key, now.Add(projectVisibilityTTL-time.Second)))
+	assert.False(t, cache.isVisible(projectVisibilityKey{testInstance1.GetID(), "user_id", "WEB"}, now))
+
+	// The check is made again once the entry expires
+	assert.False(t, cache.isVisible(key, now.Add(projectVisibilityTTL)))
+}
@@ -10,7 +10,9 @@ import (
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
+	jira "github.com/andygrunwald/go-jira"
 	"github.com/stretchr/testify/require"
 
 	"github.com/mattermost/mattermost/server/public/model"
@@ -174,6 +176,202 @@ func TestValidateSubscription(t *testing.T) {
 			},
 			errorMessage: "invalid access to security level",
 		},
+		"valid range filter on a number field": {
+			subscription: &ChannelSubscription{
+				ID:         "id",
+				Name:       "name",
+				ChannelID:  "channelid",
+				InstanceID: "instance_id",
+				Filters: SubscriptionFilters{
+					Events:     NewStringSet("issue_created"),
+					Projects:   NewStringSet("TEST"),
+					IssueTypes: NewStringSet("10001"),
+					Fields: []FieldFilter{
+						{
+							Key:       "customfield_10020",
+							Inclusion: FilterGreaterThanOrEqual,
+							Values:    NewStringSet("8"),
+						},
+					},
+				},
+			},
+			errorMessage: "",
+		},
+		"valid date filter on the due date": {
+			subscription: &ChannelSubscription{
+				ID:         "id",
+				Name:       "name",
+				ChannelID:  "channelid",
+				InstanceID: "instance_id",
+				Filters: SubscriptionFilters{
+					Events:     NewStringSet("issue_created"),
+					Projects:   NewStringSet("TEST"),
+					IssueTypes: NewStringSet("10001"),
+					Fields: []FieldFilter{
+						{
+							Key:       "duedate",
+							Inclusion: FilterWithinNextDays,
+							Values:    NewStringSet("3"),
+						},
+					},
+				},
+			},
+			errorMessage: "",
+		},
+		"invalid number in range filter": {
+			subscription: &ChannelSubscription{
+				ID:         "id",
+				Name:       "name",
+				ChannelID:  "channelid",
+				InstanceID: "instance_id",
+				Filters: SubscriptionFilters{
+					Events:     NewStringSet("issue_created"),
+					Projects:   NewStringSet("TEST"),
+					IssueTypes: NewStringSet("10001"),
+					Fields: []FieldFilter{
+						{
+							Key:       "customfield_10020",
+							Inclusion: FilterLessThan,
+							Values:    NewStringSet("eight"),
+						},
+					},
+				},
+			},
+			errorMessage: "invalid filter for field \"customfield_10020\": \"eight\" is not a valid number",
+		},
+		"between filter requires two values": {
+			subscription: &ChannelSubscription{
+				ID:         "id",
+				Name:       "name",
+				ChannelID:  "channelid",
+				InstanceID: "instance_id",
+				Filters: SubscriptionFilters{
+					Events:     NewStringSet("issue_created"),
+					Projects:   NewStringSet("TEST"),
+					IssueTypes: NewStringSet("10001"),
+					Fields: []FieldFilter{
+						{
+							Key:       "customfield_10020",
+							Inclusion: FilterBetween,
+							Values:    NewStringSet("3"),
+						},
+					},
+				},
+			},
+			errorMessage: "invalid filter for field \"customfield_10020\": exactly two values must be provided",
+		},
+		"range filter on an unordered field": {
+			subscription: &ChannelSubscription{
+				ID:         "id",
+				Name:       "name",
+				ChannelID:  "channelid",
+				InstanceID: "instance_id",
+				Filters: SubscriptionFilters{
+					Events:     NewStringSet("issue_created"),
+					Projects:   NewStringSet("TEST"),
+					IssueTypes: NewStringSet("10001"),
+					Fields: []FieldFilter{
+						{
+							Key:       "customfield_10030",
+							Inclusion: FilterGreaterThan,
+							Values:    NewStringSet("3"),
+						},
+					},
+				},
+			},
+			errorMessage: "invalid filter for field \"customfield_10030\": operator \"greater_than\" is not supported for fields of type \"user\"",
+		},
+		"range filter on an unknown field": {
+			subscription: &ChannelSubscription{
+				ID:         "id",
+				Name:       "name",
+				ChannelID:  "channelid",
+				InstanceID: "instance_id",
+				Filters: SubscriptionFilters{
+					Events:     NewStringSet("issue_created"),
+					Projects:   NewStringSet("TEST"),
+					IssueTypes: NewStringSet("10001"),
+					Fields: []FieldFilter{
+						{
+							Key:       "customfield_99999",
+							Inclusion: FilterGreaterThan,
+							Values:    NewStringSet("3"),
+						},
+					},
+				},
+			},
+			errorMessage: "field \"customfield_99999\" was not found in project \"TEST\"",
+		},
+		"valid saved filter subscription without project": {
+			subscription: &ChannelSubscription{
+				ID:         "id",
+				Name:       "name",
+				ChannelID:  "channelid",
+				InstanceID: "instance_id",
+				Filters: SubscriptionFilters{
+					Events:      NewStringSet("issue_created"),
+					SavedFilter: &SavedFilterSource{ID: testFilterID, OwnerID: "user"},
+				},
+			},
+			errorMessage: "",
+		},
+		"saved filter subscription filtering by field without project": {
+			subscription: &ChannelSubscription{
+				ID:         "id",
+				Name:       "name",
+				ChannelID:  "channelid",
+				InstanceID: "instance_id",
+				Filters: SubscriptionFilters{
+					Events:      NewStringSet("issue_created"),
+					SavedFilter: &SavedFilterSource{ID: testFilterID, OwnerID: "user"},
+					Fields: []FieldFilter{
+						{Key: "priority", Inclusion: FilterIncludeAny, Values: NewStringSet("1")},
+					},
+				},
+			},
+			errorMessage: "please provide a project identifier to filter by fields",
+		},
+		"saved filter subscription mentioning the assignee without project": {
+			subscription: &ChannelSubscription{
+				ID:         "id",
+				Name:       "name",
+				ChannelID:  "channelid",
+				InstanceID: "instance_id",
+				Filters: SubscriptionFilters{
+					Events:      NewStringSet("issue_created"),
+					SavedFilter: &SavedFilterSource{ID: testFilterID, OwnerID: "user"},
+				},
+				Mentions: SubscriptionMentions{Fields: NewStringSet(assigneeField)},
+			},
+			errorMessage: "",
+		},
+		"saved filter subscription mentioning a custom field without project": {
+			subscription: &ChannelSubscription{
+				ID:         "id",
+				Name:       "name",
+				ChannelID:  "channelid",
+				InstanceID: "instance_id",
+				Filters: SubscriptionFilters{
+					Events:      NewStringSet("issue_created"),
+					SavedFilter: &SavedFilterSource{ID: testFilterID, OwnerID: "user"},
+				},
+				Mentions: SubscriptionMentions{Fields: NewStringSet("customfield_10030")},
+			},
+			errorMessage: "please provide a project identifier to mention the users of field \"customfield_10030\"",
+		},
+		"saved filter not visible to the user": {
+			subscription: &ChannelSubscription{
+				ID:         "id",
+				Name:       "name",
+				ChannelID:  "channelid",
+				InstanceID: "instance_id",
+				Filters: SubscriptionFilters{
+					Events:      NewStringSet("issue_created"),
+					SavedFilter: &SavedFilterSource{ID: "999", OwnerID: "user"},
+				},
+			},
+			errorMessage: "failed to get saved filter \"999\": filter 999 not found",
+		},
 		"user does not have read access to the project": {
 			subscription: &ChannelSubscription{
 				ID:         "id",
@@ -201,7 +399,7 @@ func TestValidateSubscription(t *testing.T) {
 			})
 
 			client := testClient{}
-			err := p.validateSubscription(testInstance1.InstanceID, tc.subscription, client)
+			err := p.validateSubscription(testInstance1.InstanceID, "", tc.subscription, client)
 
 			if tc.errorMessage == "" {
 				require.NoError(t, err)
@@ -213,6 +411,232 @@ func TestValidateSubscription(t *testing.T) {
 	}
 }
 
+func TestIsValidRangeInclusion(t *testing.T) {
+	now := time.Date(2024, time.May, 10, 15, 30, 0, 0, time.UTC)
+
+	for name, tc := range map[string]struct {
+		field    FieldFilter
+		value    StringSet
+		expected bool
+	}{
+		"number greater than or equal, matching": {
+			field:    FieldFilter{Inclusion: FilterGreaterThanOrEqual, Values: NewStringSet("8"), Schema: FieldSchemaNumber},
+			value:    NewStringSet("8"),
+			expected: true,
+		},
+		"number greater than, not matching": {
+			field:    FieldFilter{Inclusion: FilterGreaterThan, Values: NewStringSet("8"), Schema: FieldSchemaNumber},
+			value:    NewStringSet("5.5"),
+			expected: false,
+		},
+		"number between, matching": {
+			field:    FieldFilter{Inclusion: FilterBetween, Values: NewStringSet("13", "3"), Schema: FieldSchemaNumber},
+			value:    NewStringSet("5"),
+			expected: true,
+		},
+		"number between, not matching": {
+			field:    FieldFilter{Inclusion: FilterBetween, Values: NewStringSet("3", "13"), Schema: FieldSchemaNumber},
+			value:    NewStringSet("21"),
+			expected: false,
+		},
+		"number filter without issue value": {
+			field:    FieldFilter{Inclusion: FilterLessThan, Values: NewStringSet("8"), Schema: FieldSchemaNumber},
+			value:    NewStringSet(),
+			expected: false,
+		},
+		"date less than, matching": {
+			field:    FieldFilter{Inclusion: FilterLessThan, Values: NewStringSet("2024-06-01"), Schema: FieldSchemaDate},
+			value:    NewStringSet("2024-05-31"),
+			expected: true,
+		},
+		"date within next days, due today": {
+			field:    FieldFilter{Inclusion: FilterWithinNextDays, Values: NewStringSet("3"), Schema: FieldSchemaDate},
+			value:    NewStringSet("2024-05-10"),
+			expected: true,
+		},
+		"date within next days, due later": {
+			field:    FieldFilter{Inclusion: FilterWithinNextDays, Values: NewStringSet("3"), Schema: FieldSchemaDate},
+			value:    NewStringSet("2024-05-14"),
+			expected: false,
+		},
+		"datetime within last days, matching": {
+			field:    FieldFilter{Inclusion: FilterWithinLastDays, Values: NewStringSet("1"), Schema: FieldSchemaDateTime},
+			value:    NewStringSet("2024-05-10T01:00:00.000+0000"),
+			expected: true,
+		},
+		"datetime within last days, too old": {
+			field:    FieldFilter{Inclusion: FilterWithinLastDays, Values: NewStringSet("1"), Schema: FieldSchemaDateTime},
+			value:    NewStringSet("2024-05-08T01:00:00.000+0000"),
+			expected: false,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			actual := isValidRangeInclusion(tc.field, tc.value, tc.field.Inclusion, now)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestGetSubscriptionMentions(t *testing.T) {
+	wh := &webhook{
+		JiraWebhook: &JiraWebhook{
+			Issue: jira.Issue{
+				Fields: &jira.IssueFields{
+					Assignee: &jira.User{AccountID: "assignee-account-id"},
+					Reporter: &jira.User{AccountID: "reporter-account-id"},
+				},
+			},
+		},
+	}
+
+	for name, tc := range map[string]struct {
+		mentions SubscriptionMentions
+		expected []string
+	}{
+		"no mentions configured": {
+			mentions: SubscriptionMentions{},
+			expected: []string{},
+		},
+		"assignee and reporter mapped to the same user": {
+			mentions: SubscriptionMentions{Fields: NewStringSet(assigneeField, reporterField)},
+			expected: []string{"@connected-user"},
+		},
+		"group only": {
+			mentions: SubscriptionMentions{Group: "@oncall-db"},
+			expected: []string{"@oncall-db"},
+		},
+		"assignee and group": {
+			mentions: SubscriptionMentions{Fields: NewStringSet(assigneeField), Group: "oncall-db"},
+			expected: []string{"@connected-user", "@oncall-db"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			api := &plugintest.API{}
+			p := &Plugin{userStore: mockUserStore{}}
+			p.SetAPI(api)
+			p.client = pluginapi.NewClient(p.API, p.Driver)
+
+			api.On("GetUser", string(mockUserIDWithNotifications)).Return(&model.User{Username: "connected-user"}, nil)
+
+			actual := p.getSubscriptionMentions(wh, testInstance1.InstanceID, tc.mentions)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestValidateSubscriptionMentions(t *testing.T) {
+	for name, tc := range map[string]struct {
+		mentions     SubscriptionMentions
+		group        *model.Group
+		errorMessage string
+	}{
+		"user picker field": {
+			mentions: SubscriptionMentions{Fields: NewStringSet(assigneeField, "customfield_10030")},
+		},
+		"field is not a user picker": {
+			mentions:     SubscriptionMentions{Fields: NewStringSet("customfield_10020")},
+			errorMessage: "field \"customfield_10020\" is not a user picker field of project \"TEST\"",
+		},
+		"group allows mentions": {
+			mentions: SubscriptionMentions{Group: "oncall-db"},
+			group:    &model.Group{AllowReference: true},
+		},
+		"group does not allow mentions": {
+			mentions:     SubscriptionMentions{Group: "oncall-db"},
+			group:        &model.Group{AllowReference: false},
+			errorMessage: "group \"oncall-db\" does not allow mentions",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			api := &plugintest.API{}
+			p := &Plugin{}
+			p.SetAPI(api)
+			p.client = pluginapi.NewClient(p.API, p.Driver)
+
+			if tc.group != nil {
+				api.On("GetGroupByName", "oncall-db").Return(tc.group, nil)
+			}
+
+			err := p.validateSubscriptionMentions(testClient{}, "TEST", tc.mentions)
+			if tc.errorMessage == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.errorMessage)
+			}
+		})
+	}
+}
+
+func TestKeepUnsentSubscriptionFields(t *testing.T) {
+	existing := &ChannelSubscription{
+		ID:       "subscriptionid",
+		Mentions: SubscriptionMentions{Group: "oncall-db", Fields: NewStringSet(assigneeField)},
+	}
+
+	for name, tc := range map[string]struct {
+		body     string
+		expected SubscriptionMentions
+	}{
+		"mentions not sent": {
+			body:     `{"id":"subscriptionid","name":"renamed"}`,
+			expected: existing.Mentions,
+		},
+		"mentions cleared": {
+			body:     `{"id":"subscriptionid","mentions":{}}`,
+			expected: SubscriptionMentions{},
+		},
+		"mentions changed": {
+			body:     `{"id":"subscriptionid","mentions":{"group":"oncall-web"}}`,
+			expected: SubscriptionMentions{Group: "oncall-web"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			subscription := ChannelSubscription{}
+			require.NoError(t, json.Unmarshal([]byte(tc.body), &subscription))
+
+			err := keepUnsentSubscriptionFields([]byte(tc.body), &subscription, existing)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, subscription.Mentions)
+		})
+	}
+}
+
+func TestKeepUnsentPostAttachments(t *testing.T) {
+	existing := &ChannelSubscription{ID: "subscriptionid", PostAttachments: true}
+
+	subscription := ChannelSubscription{}
+	body := []byte(`{"id":"subscriptionid","name":"renamed"}`)
+	require.NoError(t, json.Unmarshal(body, &subscription))
+	require.NoError(t, keepUnsentSubscriptionFields(body, &subscription, existing))
+	assert.True(t, subscription.PostAttachments)
+
+	subscription = ChannelSubscription{}
+	body = []byte(`{"id":"subscriptionid","post_attachments":false}`)
+	require.NoError(t, json.Unmarshal(body, &subscription))
+	require.NoError(t, keepUnsentSubscriptionFields(body, &subscription, existing))
+	assert.False(t, subscription.PostAttachments)
+}
+
+func TestKeepUnsentSavedFilter(t *testing.T) {
+	existing := &ChannelSubscription{
+		ID:      "subscriptionid",
+		Filters: SubscriptionFilters{SavedFilter: &SavedFilterSource{ID: testFilterID, OwnerID: "owner"}},
+	}
+
+	subscription := ChannelSubscription{}
+	body := []byte(`{"id":"subscriptionid","filters":{"events":["event_created"]}}`)
+	require.NoError(t, json.Unmarshal(body, &subscription))
+	require.NoError(t, keepUnsentSubscriptionFields(body, &subscription, existing))
+	require.NotNil(t, subscription.Filters.SavedFilter)
+	assert.Equal(t, testFilterID, subscription.Filters.SavedFilter.ID)
+
+	subscription = ChannelSubscription{}
+	body = []byte(`{"id":"subscriptionid","filters":{"events":["event_created"],"saved_filter":null}}`)
+	require.NoError(t, json.Unmarshal(body, &subscription))
+	require.NoError(t, keepUnsentSubscriptionFields(body, &subscription, existing))
+	assert.Nil(t, subscription.Filters.SavedFilter)
+}
+
 func TestListChannelSubscriptions(t *testing.T) {
 	p := &Plugin{}
 	p.updateConfig(func(conf *config) {
@@ -66,7 +66,7 @@ func (p *Plugin) instanceCount() (server int, cloud int, err error) {
 
 	for _, id := range instances.IDs() {
 		switch instances.Get(id).Type {
-		case ServerInstanceType:
+		case ServerInstanceType, ServerOAuthInstanceType:
 			server++
 		case CloudInstanceType:
 			cloud++
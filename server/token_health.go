@@ -0,0 +1,413 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+const (
+	tokenHealthKey           = "token_health"
+	tokenHealthJobKey        = "token_health_check"
+	tokenHealthJobInterval   = 6 * time.Hour
+	tokenHealthRefreshWindow = tokenHealthJobInterval
+	tokenHealthWarnInterval  = 7 * 24 * time.Hour
+
+	// tokenExpiryWarnWindow is how long before their tokens expire the users are warned
+	tokenExpiryWarnWindow = 14 * 24 * time.Hour
+)
+
+const (
+	// tokenStatusRejected means Jira rejected the stored credentials, the user needs to reconnect
+	tokenStatusRejected = "rejected"
+	// tokenStatusFailing means the connection could not be validated for another reason
+	tokenStatusFailing = "failing"
+	// tokenStatusExpired means the OAuth 2.0 refresh token expired, the user is disconnected on
+	// their next use of Jira
+	tokenStatusExpired = "expired"
+	// tokenStatusExpiring means the token works, but expires within tokenExpiryWarnWindow
+	tokenStatusExpiring = "expiring"
+)
+
+const (
+	connectionTypeOAuth2  = "OAuth 2.0"
+	connectionTypeOAuth1  = "OAuth 1.0a"
+	connectionTypePAT     = "Personal Access Token"
+	connectionTypeConnect = "Atlassian Connect"
+)
+
+// brokenConnection is a connection that failed the last token health check.
+type brokenConnection struct {
+	MattermostUserID types.ID
+	JiraDisplayName  string
+	ConnectionType   string
+	Status           string
+	Error            string
+	FailingSince     int64
+	WarnedAt         int64 `json:",omitempty"`
+}
+
+// expiringConnection is a healthy connection whose token expires soon.
+type expiringConnection struct {
+	MattermostUserID types.ID
+	ConnectionType   string
+	ExpiresAt        int64
+	WarnedAt         int64 `json:",omitempty"`
+}
+
+// tokenHealthReport is the result of the last token health check of an instance.
+type tokenHealthReport struct {
+	CheckedAt int64
+	Healthy   int
+	Refreshed int
+	Broken    map[types.ID]*brokenConnection
+	Expiring  map[types.ID]*expiringConnection `json:",omitempty"`
+}
+
+func connectionType(instance Instance, connection *Connection) string {
+	switch {
+	case connection.OAuth2Token != nil:
+		return connectionTypeOAuth2
+	case connection.PersonalAccessToken != "":
+		return connectionTypePAT
+	case connection.Oauth1AccessToken != "":
+		return connectionTypeOAuth1
+	case instance.Common().IsCloudInstance():
+		return connectionTypeConnect
+	}
+	return ""
+}
+
+func (p *Plugin) getTokenHealthReport(instanceID types.ID) (*tokenHealthReport, error) {
+	report := &tokenHealthReport{}
+	if err := p.client.KV.Get(keyWithInstanceID(instanceID, tokenHealthKey), report); err != nil {
+		return nil, errors.Wrap(err, "failed to load the token health report")
+	}
+	return report, nil
+}
+
+func (p *Plugin) storeTokenHealthReport(instanceID types.ID, report *tokenHealthReport) error {
+	if _, err := p.client.KV.Set(keyWithInstanceID(instanceID, tokenHealthKey), report); err != nil {
+		return errors.Wrap(err, "failed to store the token health report")
+	}
+	return nil
+}
+
+// refreshOAuth2TokenAhead refreshes the connection's OAuth 2.0 token when it expires before
+// the next check. Refreshing also rotates the refresh token, so that it does not expire
+// while the user is inactive.
+func (p *Plugin) refreshOAuth2TokenAhead(instance Instance, connection *Connection, now time.Time) (bool, error) {
+	oauthInstance, ok := instance.(oauth2Instance)
+	if !ok || connection.OAuth2Token == nil || connection.OAuth2Token.Expiry.IsZero() {
+		return false, nil
+	}
+	if connection.OAuth2Token.Expiry.After(now.Add(tokenHealthRefreshWindow)) {
+		return false, nil
+	}
+
+	expiring := *connection.OAuth2Token
+	expiring.Expiry = now.Add(-time.Second)
+	connection.OAuth2Token = &expiring
+
+	p.oauth2Clients.invalidate(instance.GetID(), connection.MattermostUserID)
+	if _, err := p.getOAuth2HTTPClient(instance, oauthInstance.GetOAuthConfig(), connection); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// personalAccessTokenExpiry returns when the Personal Access Token of the connection expires,
+// or zero if it does not or is unknown. Jira Data Center lists the tokens of the user, the
+// connection's is found by the ID encoded in the token.
+func (p *Plugin) personalAccessTokenExpiry(client Client, connection *Connection) time.Time {
+	pat, err := p.decryptPersonalAccessToken(connection.PersonalAccessToken)
+	if err != nil {
+		return time.Time{}
+	}
+	id := personalAccessTokenID(pat)
+	if id == "" {
+		return time.Time{}
+	}
+
+	tokens := []struct {
+		ID         json.Number `json:"id"`
+		ExpiringAt string      `json:"expiringAt"`
+	}{}
+	if err = client.RESTGetRaw("/rest/pat/latest/tokens", nil, &tokens); err != nil {
+		p.client.Log.Debug("Failed to list the Personal Access Tokens of the user",
+			"mattermostUserID", connection.MattermostUserID.String(), "error", err.Error())
+		return time.Time{}
+	}
+	for _, token := range tokens {
+		if token.ID.String() != id || token.ExpiringAt == "" {
+			continue
+		}
+		for _, layout := range []string{"2006-01-02T15:04:05.000-0700", time.RFC3339} {
+			if expiresAt, err := time.Parse(layout, token.ExpiringAt); err == nil {
+				return expiresAt
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// personalAccessTokenID returns the ID of a Jira Data Center Personal Access Token, encoded
+// in the token as "<id>:<secret>".
+func personalAccessTokenID(pat string) string {
+	decoded, err := base64.StdEncoding.DecodeString(pat)
+	if err != nil {
+		return ""
+	}
+	id, _, found := strings.Cut(string(decoded), ":")
+	if !found {
+		return ""
+	}
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return ""
+	}
+	return id
+}
+
+// tokenExpiry returns when the credentials of a healthy connection expire, or zero if they
+// do not or it is unknown.
+func (p *Plugin) tokenExpiry(client Client, connection *Connection) time.Time {
+	switch {
+	case connection.OAuth2Token != nil && connection.OAuth2RefreshTokenExpiry != 0:
+		return time.UnixMilli(connection.OAuth2RefreshTokenExpiry)
+	case connection.PersonalAccessToken != "":
+		return p.personalAccessTokenExpiry(client, connection)
+	}
+	return time.Time{}
+}
+
+// checkConnectionHealth validates a single connection with Jira, and returns a nil broken
+// connection if it is healthy. A healthy connection whose token expires soon is returned as
+// expiring. It does not disconnect the users whose tokens expired.
+func (p *Plugin) checkConnectionHealth(instance Instance, user *User, now time.Time) (refreshed bool, broken *brokenConnection, expiring *expiringConnection) {
+	connection, err := p.userStore.LoadConnection(instance.GetID(), user.MattermostUserID)
+	if err != nil {
+		p.client.Log.Warn("Failed to load the connection to check the token health",
+			"mattermostUserID", user.MattermostUserID.String(), "instanceID", instance.GetID().String(), "error", err.Error())
+		return false, nil, nil
+	}
+	connection.MattermostUserID = user.MattermostUserID
+
+	broken = &brokenConnection{
+		MattermostUserID: user.MattermostUserID,
+		JiraDisplayName:  connection.DisplayName,
+		ConnectionType:   connectionType(instance, connection),
+		Status:           tokenStatusFailing,
+	}
+
+	var client Client
+	refreshed, err = p.refreshOAuth2TokenAhead(instance, connection, now)
+	if err == nil {
+		client, err = instance.GetClient(connection)
+		if err == nil {
+			_, err = client.GetSelf()
+		}
+	}
+	if err == nil {
+		expiresAt := p.tokenExpiry(client, connection)
+		if expiresAt.IsZero() || expiresAt.After(now.Add(tokenExpiryWarnWindow)) {
+			return refreshed, nil, nil
+		}
+		return refreshed, nil, &expiringConnection{
+			MattermostUserID: user.MattermostUserID,
+			ConnectionType:   broken.ConnectionType,
+			ExpiresAt:        expiresAt.UnixMilli(),
+		}
+	}
+
+	broken.Error = err.Error()
+	switch {
+	case strings.Contains(err.Error(), "invalid_grant") || errors.Is(err, errTokenExpired):
+		broken.Status = tokenStatusExpired
+	case StatusCode(err) == http.StatusUnauthorized || StatusCode(err) == http.StatusForbidden:
+		broken.Status = tokenStatusRejected
+	}
+	return refreshed, broken, nil
+}
+
+// checkTokenHealth periodically validates all the stored connections. It refreshes OAuth 2.0
+// tokens ahead of their expiry, warns the users whose credentials Jira rejects or whose tokens
+// expire soon, and stores a report per instance for the system administrators.
+func (p *Plugin) checkTokenHealth() {
+	instances, err := p.instanceStore.LoadInstances()
+	if err != nil {
+		p.client.Log.Warn("Failed to load instances to check the token health", "error", err.Error())
+		return
+	}
+
+	loaded := map[types.ID]Instance{}
+	reports := map[types.ID]*tokenHealthReport{}
+	previous := map[types.ID]*tokenHealthReport{}
+	for _, instanceID := range instances.IDs() {
+		instance, err := p.instanceStore.LoadInstance(instanceID)
+		if err != nil {
+			p.client.Log.Warn("Failed to load instance to check the token health", "instanceID", instanceID.String(), "error", err.Error())
+			continue
+		}
+		instance.Common().backgroundJob = true
+		loaded[instanceID] = instance
+		reports[instanceID] = &tokenHealthReport{
+			Broken:   map[types.ID]*brokenConnection{},
+			Expiring: map[types.ID]*expiringConnection{},
+		}
+		if previous[instanceID], err = p.getTokenHealthReport(instanceID); err != nil {
+			previous[instanceID] = &tokenHealthReport{}
+		}
+	}
+
+	now := time.Now()
+	err = p.userStore.MapUsers(func(user *User) error {
+		if user.ConnectedInstances == nil {
+			return nil
+		}
+		for _, instanceID := range user.ConnectedInstances.IDs() {
+			instance, ok := loaded[instanceID]
+			if !ok {
+				continue
+			}
+			report := reports[instanceID]
+
+			refreshed, broken, expiring := p.checkConnectionHealth(instance, user, now)
+			if refreshed {
+				report.Refreshed++
+			}
+			if broken == nil {
+				report.Healthy++
+				if expiring != nil {
+					if last := previous[instanceID].Expiring[user.MattermostUserID]; last != nil && last.ExpiresAt == expiring.ExpiresAt {
+						expiring.WarnedAt = last.WarnedAt
+					}
+					if now.Sub(time.UnixMilli(expiring.WarnedAt)) > tokenHealthWarnInterval {
+						p.warnUserConnectionExpiring(instance, expiring)
+						expiring.WarnedAt = now.UnixMilli()
+					}
+					report.Expiring[user.MattermostUserID] = expiring
+				}
+				continue
+			}
+
+			broken.FailingSince = now.UnixMilli()
+			if last := previous[instanceID].Broken[user.MattermostUserID]; last != nil {
+				broken.FailingSince = last.FailingSince
+				broken.WarnedAt = last.WarnedAt
+			}
+			if broken.Status == tokenStatusRejected && now.Sub(time.UnixMilli(broken.WarnedAt)) > tokenHealthWarnInterval {
+				p.warnUserConnectionRejected(instance, broken)
+				broken.WarnedAt = now.UnixMilli()
+			}
+			report.Broken[user.MattermostUserID] = broken
+		}
+		return nil
+	})
+	if err != nil {
+		p.client.Log.Warn("Failed to check the token health of all the users", "error", err.Error())
+	}
+
+	for instanceID, report := range reports {
+		report.CheckedAt = model.GetMillis()
+		if err := p.storeTokenHealthReport(instanceID, report); err != nil {
+			p.client.Log.Warn("Failed to store the token health report", "instanceID", instanceID.String(), "error", err.Error())
+		}
+	}
+}
+
+func (p *Plugin) warnUserConnectionExpiring(instance Instance, expiring *expiringConnection) {
+	renew := "reconnect your account using `/jira connect " + instance.GetID().String() + "`"
+	if expiring.ConnectionType == connectionTypePAT {
+		renew = "create a new Personal Access Token in Jira, and connect with it using `/jira connect " + instance.GetID().String() + "`"
+	}
+	_, err := p.CreateBotDMtoMMUserID(expiring.MattermostUserID.String(),
+		":warning: Your Jira %s credentials (%s) expire on %s. To keep receiving notifications and using Jira from Mattermost, please %s.",
+		instance.GetJiraBaseURL(), expiring.ConnectionType, time.UnixMilli(expiring.ExpiresAt).UTC().Format("January 2, 2006"), renew)
+	if err != nil {
+		p.client.Log.Warn("Failed to warn the user about the expiring Jira credentials",
+			"mattermostUserID", expiring.MattermostUserID.String(), "error", err.Error())
+	}
+}
+
+func (p *Plugin) warnUserConnectionRejected(instance Instance, broken *brokenConnection) {
+	_, err := p.CreateBotDMtoMMUserID(broken.MattermostUserID.String(),
+		":warning: Jira %s rejected your stored credentials (%s), so you may stop receiving notifications and "+
+			"Jira actions from Mattermost will fail.\nPlease reconnect your account using `/jira connect %s`.",
+		instance.GetJiraBaseURL(), broken.ConnectionType, instance.GetID())
+	if err != nil {
+		p.client.Log.Warn("Failed to warn the user about the rejected Jira credentials",
+			"mattermostUserID", broken.MattermostUserID.String(), "error", err.Error())
+	}
+}
+
+func formatTokenHealthReport(instanceID types.ID, report *tokenHealthReport) string {
+	text := fmt.Sprintf("#### Connections to %s\n", instanceID)
+	if report.CheckedAt == 0 {
+		return text + "The connections have not been checked yet.\n"
+	}
+
+	return text + fmt.Sprintf("Last checked at %s: %v healthy, %v broken, %v expiring within %v days, %v tokens refreshed ahead of expiry.\n",
+		formatMillis(report.CheckedAt), report.Healthy, len(report.Broken), len(report.Expiring), int(tokenExpiryWarnWindow.Hours()/24), report.Refreshed)
+}
+
+func executeInstanceConnections(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira instance connections` can only be run by a system administrator.")
+	}
+	if len(args) > 1 {
+		return p.responsef(header, "Please use `/jira instance connections [jiraURL]`.")
+	}
+
+	instances, err := p.instanceStore.LoadInstances()
+	if err != nil {
+		return p.responsef(header, "Failed to load instances. Error: %v.", err)
+	}
+
+	instanceIDs := instances.IDs()
+	if len(args) == 1 {
+		instanceID, err := p.resolveInstanceArg(args[0])
+		if err != nil {
+			return p.response(header, err.Error())
+		}
+		instanceIDs = []types.ID{instanceID}
+	}
+
+	text := ""
+	for _, instanceID := range instanceIDs {
+		instance, err := p.instanceStore.LoadInstance(instanceID)
+		if err != nil {
+			return p.responsef(header, "Failed to load the Jira instance %s. Error: %v.", instanceID, err)
+		}
+		report, err := p.getTokenHealthReport(instanceID)
+		if err != nil {
+			return p.response(header, err.Error())
+		}
+		users, err := p.listConnectedUsers(instance)
+		if err != nil {
+			return p.response(header, err.Error())
+		}
+		text += formatTokenHealthReport(instanceID, report) + p.formatConnectedUsers(instanceID, users) + "\n"
+	}
+	if text == "" {
+		return p.responsef(header, "No Jira instances installed.")
+	}
+
+	return p.response(header, text)
+}
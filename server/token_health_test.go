@@ -0,0 +1,204 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+func TestCheckConnectionHealth(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer valid-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"jdoe","displayName":"John Doe"}`))
+	}))
+	defer ts.Close()
+
+	api := &plugintest.API{}
+	api.On("LogDebug", mockAnythingOfTypeBatch("string", 11)...).Return()
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+	p.userStore = NewStore(p)
+	makeTestKVStore(api, nil)
+	p.updateConfig(func(conf *config) {
+		conf.EncryptionKey = "0123456789abcdef0123456789abcdef"
+		conf.maxAttachmentSize = defaultMaxAttachmentSize
+	})
+
+	si := &serverInstance{
+		InstanceCommon: &InstanceCommon{Plugin: p, InstanceID: types.ID(ts.URL), Type: ServerInstanceType},
+		AuthMode:       ServerAuthModePAT,
+	}
+
+	for name, tc := range map[string]struct {
+		token          string
+		expectedStatus string
+	}{
+		"healthy":  {token: "valid-token"},
+		"rejected": {token: "revoked-token", expectedStatus: tokenStatusRejected},
+	} {
+		t.Run(name, func(t *testing.T) {
+			encrypted, err := p.encryptPersonalAccessToken(tc.token)
+			require.NoError(t, err)
+			user := &User{MattermostUserID: "user1"}
+			err = p.userStore.StoreConnection(si.GetID(), user.MattermostUserID, &Connection{
+				User:                jira.User{Name: "jdoe", DisplayName: "John Doe"},
+				PersonalAccessToken: encrypted,
+			})
+			require.NoError(t, err)
+
+			refreshed, broken, expiring := p.checkConnectionHealth(si, user, time.Now())
+			assert.False(t, refreshed)
+			assert.Nil(t, expiring)
+			if tc.expectedStatus == "" {
+				assert.Nil(t, broken)
+				return
+			}
+			require.NotNil(t, broken)
+			assert.Equal(t, tc.expectedStatus, broken.Status)
+			assert.Equal(t, connectionTypePAT, broken.ConnectionType)
+			assert.Equal(t, "John Doe", broken.JiraDisplayName)
+		})
+	}
+}
+
+func TestRefreshOAuth2TokenAhead(t *testing.T) {
+	p := &Plugin{}
+	now := time.Now()
+	ci := &cloudOAuthInstance{
+		InstanceCommon: &InstanceCommon{Plugin: p, InstanceID: "https://example.atlassian.net", Type: CloudOAuthInstanceType},
+	}
+
+	refreshed, err := p.refreshOAuth2TokenAhead(ci, &Connection{
+		OAuth2Token: &oauth2.Token{AccessToken: "token", Expiry: now.Add(2 * tokenHealthRefreshWindow)},
+	}, now)
+	require.NoError(t, err)
+	assert.False(t, refreshed)
+
+	refreshed, err = p.refreshOAuth2TokenAhead(&testInstance{}, &Connection{
+		OAuth2Token: &oauth2.Token{AccessToken: "token", Expiry: now},
+	}, now)
+	require.NoError(t, err)
+	assert.False(t, refreshed)
+}
+
+func TestCheckConnectionHealthExpiringPAT(t *testing.T) {
+	pat := base64.StdEncoding.EncodeToString([]byte("1234:secret"))
+	expiresAt := time.Now().Add(3 * 24 * time.Hour).UTC().Truncate(time.Millisecond)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+pat {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/rest/pat/latest/tokens":
+			_, _ = fmt.Fprintf(w, `[{"id":99,"expiringAt":"2020-01-01T00:00:00.000+0000"},{"id":1234,"expiringAt":%q}]`,
+				expiresAt.Format("2006-01-02T15:04:05.000-0700"))
+		default:
+			_, _ = w.Write([]byte(`{"name":"jdoe","displayName":"John Doe"}`))
+		}
+	}))
+	defer ts.Close()
+
+	api := &plugintest.API{}
+	api.On("LogDebug", mockAnythingOfTypeBatch("string", 11)...).Return()
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+	p.userStore = NewStore(p)
+	makeTestKVStore(api, nil)
+	p.updateConfig(func(conf *config) {
+		conf.EncryptionKey = "0123456789abcdef0123456789abcdef"
+		conf.maxAttachmentSize = defaultMaxAttachmentSize
+	})
+
+	si := &serverInstance{
+		InstanceCommon: &InstanceCommon{Plugin: p, InstanceID: types.ID(ts.URL), Type: ServerInstanceType},
+		AuthMode:       ServerAuthModePAT,
+	}
+	encrypted, err := p.encryptPersonalAccessToken(pat)
+	require.NoError(t, err)
+	user := &User{MattermostUserID: "user1"}
+	require.NoError(t, p.userStore.StoreConnection(si.GetID(), user.MattermostUserID, &Connection{
+		User:                jira.User{Name: "jdoe", DisplayName: "John Doe"},
+		PersonalAccessToken: encrypted,
+	}))
+
+	_, broken, expiring := p.checkConnectionHealth(si, user, time.Now())
+	assert.Nil(t, broken)
+	require.NotNil(t, expiring)
+	assert.Equal(t, expiresAt.UnixMilli(), expiring.ExpiresAt)
+	assert.Equal(t, connectionTypePAT, expiring.ConnectionType)
+
+	// Tokens expiring later are not reported
+	_, broken, expiring = p.checkConnectionHealth(si, user, time.Now().Add(-tokenExpiryWarnWindow))
+	assert.Nil(t, broken)
+	assert.Nil(t, expiring)
+}
+
+func TestPersonalAccessTokenID(t *testing.T) {
+	assert.Equal(t, "1234", personalAccessTokenID(base64.StdEncoding.EncodeToString([]byte("1234:secret"))))
+	assert.Empty(t, personalAccessTokenID(base64.StdEncoding.EncodeToString([]byte("abc:secret"))))
+	assert.Empty(t, personalAccessTokenID("not base64!"))
+}
+
+func TestConnectionSetOAuth2Token(t *testing.T) {
+	now := time.Now()
+	connection := &Connection{OAuth2RefreshTokenExpiry: 1}
+
+	connection.setOAuth2Token((&oauth2.Token{AccessToken: "token"}).WithExtra(map[string]interface{}{
+		"refresh_token_expires_in": float64(3600),
+	}), now)
+	assert.Equal(t, "token", connection.OAuth2Token.AccessToken)
+	assert.Equal(t, now.Add(time.Hour).UnixMilli(), connection.OAuth2RefreshTokenExpiry)
+
+	connection.setOAuth2Token(&oauth2.Token{AccessToken: "token"}, now)
+	assert.Zero(t, connection.OAuth2RefreshTokenExpiry)
+}
+
+func TestGetOAuth2HTTPClientBackgroundJobKeepsExpiredConnection(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+	}))
+	defer ts.Close()
+
+	// No API call is mocked, disconnecting the user would fail the test
+	api := &plugintest.API{}
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+
+	ci := &cloudOAuthInstance{
+		InstanceCommon: &InstanceCommon{Plugin: p, InstanceID: "https://example.atlassian.net", Type: CloudOAuthInstanceType, backgroundJob: true},
+	}
+	conf := &oauth2.Config{Endpoint: oauth2.Endpoint{TokenURL: ts.URL}}
+	connection := &Connection{
+		MattermostUserID: "user1",
+		OAuth2Token:      &oauth2.Token{AccessToken: "token", RefreshToken: "refresh", Expiry: time.Now().Add(-time.Minute)},
+	}
+
+	_, err := p.getOAuth2HTTPClient(ci, conf, connection)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid_grant")
+	api.AssertExpectations(t)
+}
@@ -0,0 +1,283 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+const (
+	transitionDialogCallbackID = "transition_issue"
+	transitionCommentField     = "comment"
+	transitionDateLayout       = "2006-01-02"
+
+	dialogTitleMaxLength       = 24
+	dialogDisplayNameMaxLength = 24
+)
+
+// transitionDialogState is kept in the dialog between opening and submitting it.
+type transitionDialogState struct {
+	InstanceID   types.ID `json:"instance_id"`
+	IssueKey     string   `json:"issue_key"`
+	TransitionID string   `json:"transition_id"`
+	ToState      string   `json:"to_state"`
+	ChannelID    string   `json:"channel_id"`
+}
+
+func transitionHasRequiredFields(transition jira.Transition) bool {
+	for _, field := range transition.Fields {
+		if field.Required {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionDialogFieldKeys returns the sorted keys of the fields that must be filled in to
+// transition an issue, the required ones Jira has no default value for.
+func transitionDialogFieldKeys(fields map[string]TransitionFieldMeta) []string {
+	keys := []string{}
+	for key, field := range fields {
+		if key == transitionCommentField || !field.Required || field.HasDefaultValue {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func transitionFieldNames(fields map[string]TransitionFieldMeta) []string {
+	names := []string{}
+	for _, key := range transitionDialogFieldKeys(fields) {
+		names = append(names, fields[key].Name)
+	}
+	return names
+}
+
+func makeTransitionDialogElement(key string, field TransitionFieldMeta) (model.DialogElement, error) {
+	element := model.DialogElement{
+		DisplayName: truncate(field.Name, dialogDisplayNameMaxLength),
+		Name:        key,
+		Type:        "text",
+	}
+
+	if len(field.AllowedValues) > 0 {
+		element.Type = "select"
+		for _, value := range field.AllowedValues {
+			text := value.Name
+			if text == "" {
+				text = value.Value
+			}
+			element.Options = append(element.Options, &model.PostActionOptions{Text: text, Value: value.ID})
+		}
+		return element, nil
+	}
+
+	switch field.Schema.Type {
+	case "string":
+		if field.Schema.System == "description" || field.Schema.System == "environment" ||
+			strings.HasSuffix(field.Schema.Custom, ":textarea") {
+			element.Type = "textarea"
+		}
+	case "number":
+		element.SubType = "number"
+	case "date":
+		element.Placeholder = "YYYY-MM-DD"
+	case "array":
+		if field.Schema.Items != "string" {
+			return element, errors.Errorf("field %q is not supported", field.Name)
+		}
+		element.HelpText = "Separate the values with spaces."
+	default:
+		return element, errors.Errorf("field %q is not supported", field.Name)
+	}
+	return element, nil
+}
+
+func makeTransitionDialog(in *InTransitionIssue, instance Instance, transition jira.Transition, fields map[string]TransitionFieldMeta) (model.Dialog, error) {
+	permalink := fmt.Sprintf("%v/browse/%v", instance.GetJiraBaseURL(), in.IssueKey)
+
+	elements := []model.DialogElement{}
+	for _, key := range transitionDialogFieldKeys(fields) {
+		element, err := makeTransitionDialogElement(key, fields[key])
+		if err != nil {
+			return model.Dialog{}, errors.WithMessagef(err, "the transition to %q can't be done from Mattermost, please transition [%s](%s) in Jira", transition.To.Name, in.IssueKey, permalink)
+		}
+		elements = append(elements, element)
+	}
+	if _, ok := fields[transitionCommentField]; ok {
+		elements = append(elements, model.DialogElement{
+			DisplayName: "Comment",
+			Name:        transitionCommentField,
+			Type:        "textarea",
+			Optional:    true,
+		})
+	}
+
+	state, err := json.Marshal(transitionDialogState{
+		InstanceID:   instance.GetID(),
+		IssueKey:     in.IssueKey,
+		TransitionID: transition.ID,
+		ToState:      transition.To.Name,
+		ChannelID:    in.PostToChannelID,
+	})
+	if err != nil {
+		return model.Dialog{}, err
+	}
+
+	return model.Dialog{
+		CallbackId:       transitionDialogCallbackID,
+		Title:            truncate(transition.Name, dialogTitleMaxLength),
+		IntroductionText: fmt.Sprintf("Jira requires these fields to transition [%s](%s) to `%s`.", in.IssueKey, permalink, transition.To.Name),
+		Elements:         elements,
+		SubmitLabel:      "Transition",
+		State:            string(state),
+	}, nil
+}
+
+func (p *Plugin) openTransitionDialog(in *InTransitionIssue, instance Instance, transition jira.Transition, fields map[string]TransitionFieldMeta) error {
+	dialog, err := makeTransitionDialog(in, instance, transition, fields)
+	if err != nil {
+		return err
+	}
+
+	err = p.client.Frontend.OpenInteractiveDialog(model.OpenDialogRequest{
+		TriggerId: in.TriggerID,
+		URL:       fmt.Sprintf("/plugins/%s%s%s", manifest.Id, routeAPI, routeIssueTransitionDialog),
+		Dialog:    dialog,
+	})
+	if err != nil {
+		return errors.WithMessage(err, "failed to open the transition dialog")
+	}
+	return nil
+}
+
+// transitionFieldValue converts the value of a dialog element into the value Jira expects
+// for the field.
+func transitionFieldValue(field TransitionFieldMeta, value interface{}) (interface{}, error) {
+	if len(field.AllowedValues) > 0 {
+		id, _ := value.(string)
+		if field.Schema.Type == "array" {
+			return []map[string]string{{"id": id}}, nil
+		}
+		return map[string]string{"id": id}, nil
+	}
+
+	switch field.Schema.Type {
+	case "number":
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case string:
+			number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, errors.New("please enter a number")
+			}
+			return number, nil
+		}
+		return nil, errors.New("please enter a number")
+	case "date":
+		date := strings.TrimSpace(fmt.Sprint(value))
+		if _, err := time.Parse(transitionDateLayout, date); err != nil {
+			return nil, errors.New("please enter a date in the form YYYY-MM-DD")
+		}
+		return date, nil
+	case "array":
+		return strings.Fields(fmt.Sprint(value)), nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// transitionSubmissionFields converts a dialog submission into the fields and the comment of
+// the transition, with the errors of the invalid values by element.
+func transitionSubmissionFields(fields map[string]TransitionFieldMeta, submission map[string]interface{}) (map[string]interface{}, string, map[string]string) {
+	values := map[string]interface{}{}
+	errs := map[string]string{}
+	for _, key := range transitionDialogFieldKeys(fields) {
+		value, ok := submission[key]
+		if !ok || value == nil || value == "" {
+			errs[key] = "This field is required."
+			continue
+		}
+		converted, err := transitionFieldValue(fields[key], value)
+		if err != nil {
+			errs[key] = err.Error()
+			continue
+		}
+		values[key] = converted
+	}
+
+	comment, _ := submission[transitionCommentField].(string)
+	return values, strings.TrimSpace(comment), errs
+}
+
+func (p *Plugin) httpSubmitTransitionDialog(w http.ResponseWriter, r *http.Request) (int, error) {
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+
+	var request model.SubmitDialogRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return respondErr(w, http.StatusBadRequest, errors.WithMessage(err, "failed to decode the dialog submission"))
+	}
+	if request.UserId != mattermostUserID {
+		return respondErr(w, http.StatusUnauthorized, errors.New("not authorized"))
+	}
+	if request.Cancelled {
+		return respondJSON(w, model.SubmitDialogResponse{})
+	}
+
+	var state transitionDialogState
+	if err := json.Unmarshal([]byte(request.State), &state); err != nil {
+		return respondErr(w, http.StatusBadRequest, errors.WithMessage(err, "failed to decode the dialog state"))
+	}
+
+	client, instance, connection, err := p.getClient(state.InstanceID, types.ID(mattermostUserID))
+	if err != nil {
+		return respondJSON(w, model.SubmitDialogResponse{Error: "Your username is not connected to Jira. Please type `/jira connect`."})
+	}
+
+	fields, err := client.GetTransitionFields(state.IssueKey, state.TransitionID)
+	if err != nil {
+		return respondJSON(w, model.SubmitDialogResponse{Error: "Failed to get the fields of the transition. Error: " + err.Error()})
+	}
+	values, comment, errs := transitionSubmissionFields(fields, request.Submission)
+	if len(errs) > 0 {
+		return respondJSON(w, model.SubmitDialogResponse{Errors: errs})
+	}
+
+	err = client.DoTransitionWithFields(state.IssueKey, state.TransitionID, values, comment)
+	if err != nil {
+		return respondJSON(w, model.SubmitDialogResponse{Error: "Failed to transition the issue. Error: " + err.Error()})
+	}
+
+	channelID := state.ChannelID
+	if channelID == "" {
+		channelID = request.ChannelId
+	}
+	in := &InTransitionIssue{
+		mattermostUserID: types.ID(mattermostUserID),
+		InstanceID:       state.InstanceID,
+		PostToChannelID:  channelID,
+		IssueKey:         state.IssueKey,
+		ToState:          state.ToState,
+	}
+	if _, err = p.sendTransitionedIssue(in, client, instance, connection, state.ToState); err != nil {
+		p.client.Post.SendEphemeralPost(mattermostUserID, makePost(p.getUserID(), channelID,
+			fmt.Sprintf("%s was transitioned to `%s`, but it could not be shown. Error: %v.", state.IssueKey, state.ToState, err)))
+	}
+	return respondJSON(w, model.SubmitDialogResponse{})
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTransitionFields(t *testing.T) map[string]TransitionFieldMeta {
+	data := `{
+		"resolution": {"required": true, "name": "Resolution", "schema": {"type": "resolution", "system": "resolution"},
+			"allowedValues": [{"id": "1", "name": "Fixed"}, {"id": "2", "name": "Won't Fix"}]},
+		"fixVersions": {"required": true, "name": "Fix versions", "schema": {"type": "array", "items": "version", "system": "fixVersions"},
+			"allowedValues": [{"id": "10000", "name": "1.0"}]},
+		"customfield_10050": {"required": true, "name": "Time spent in the root cause analysis", "schema": {"type": "number", "custom": "com.atlassian.jira.plugin.system.customfieldtypes:float"}},
+		"customfield_10051": {"required": true, "name": "Verified on", "schema": {"type": "date", "custom": "com.atlassian.jira.plugin.system.customfieldtypes:datepicker"}},
+		"customfield_10052": {"required": true, "name": "Root cause", "schema": {"type": "string", "custom": "com.atlassian.jira.plugin.system.customfieldtypes:textarea"}},
+		"assignee": {"required": true, "hasDefaultValue": true, "name": "Assignee", "schema": {"type": "user", "system": "assignee"}},
+		"labels": {"required": false, "name": "Labels", "schema": {"type": "array", "items": "string", "system": "labels"}},
+		"comment": {"required": false, "name": "Comment", "schema": {"type": "comment", "system": "comment"}}
+	}`
+	fields := map[string]TransitionFieldMeta{}
+	require.NoError(t, json.Unmarshal([]byte(data), &fields))
+	return fields
+}
+
+func TestMakeTransitionDialog(t *testing.T) {
+	in := &InTransitionIssue{IssueKey: "TEST-1", PostToChannelID: "channel-id"}
+	transition := jira.Transition{ID: "31", Name: "Resolve the issue for good", To: jira.Status{Name: "Resolved"}}
+
+	dialog, err := makeTransitionDialog(in, testInstance1, transition, testTransitionFields(t))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Resolve the issue for...", dialog.Title)
+	require.Len(t, dialog.Elements, 6)
+
+	byName := map[string]int{}
+	for i, element := range dialog.Elements {
+		byName[element.Name] = i
+		assert.LessOrEqual(t, len(element.DisplayName), dialogDisplayNameMaxLength)
+	}
+	assert.NotContains(t, byName, "assignee")
+	assert.NotContains(t, byName, "labels")
+
+	resolution := dialog.Elements[byName["resolution"]]
+	assert.Equal(t, "select", resolution.Type)
+	require.Len(t, resolution.Options, 2)
+	assert.Equal(t, "Won't Fix", resolution.Options[1].Text)
+	assert.Equal(t, "2", resolution.Options[1].Value)
+
+	assert.Equal(t, "number", dialog.Elements[byName["customfield_10050"]].SubType)
+	assert.Equal(t, "YYYY-MM-DD", dialog.Elements[byName["customfield_10051"]].Placeholder)
+	assert.Equal(t, "textarea", dialog.Elements[byName["customfield_10052"]].Type)
+
+	comment := dialog.Elements[byName[transitionCommentField]]
+	assert.Equal(t, "textarea", comment.Type)
+	assert.True(t, comment.Optional)
+
+	var state transitionDialogState
+	require.NoError(t, json.Unmarshal([]byte(dialog.State), &state))
+	assert.Equal(t, transitionDialogState{
+		InstanceID:   testInstance1.GetID(),
+		IssueKey:     "TEST-1",
+		TransitionID: "31",
+		ToState:      "Resolved",
+		ChannelID:    "channel-id",
+	}, state)
+
+	t.Run("unsupported field", func(t *testing.T) {
+		fields := map[string]TransitionFieldMeta{
+			"customfield_10060": {Required: true, Name: "Reviewer"},
+		}
+		_, err := makeTransitionDialog(in, testInstance1, transition, fields)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `field "Reviewer" is not supported`)
+	})
+}
+
+func TestTransitionSubmissionFields(t *testing.T) {
+	fields := testTransitionFields(t)
+
+	values, comment, errs := transitionSubmissionFields(fields, map[string]interface{}{
+		"resolution":        "2",
+		"fixVersions":       "10000",
+		"customfield_10050": "1.5",
+		"customfield_10051": "2026-10-18",
+		"customfield_10052": "Race condition",
+		"comment":           " Fixed in the nightly build ",
+	})
+	require.Empty(t, errs)
+	assert.Equal(t, map[string]interface{}{
+		"resolution":        map[string]string{"id": "2"},
+		"fixVersions":       []map[string]string{{"id": "10000"}},
+		"customfield_10050": 1.5,
+		"customfield_10051": "2026-10-18",
+		"customfield_10052": "Race condition",
+	}, values)
+	assert.Equal(t, "Fixed in the nightly build", comment)
+
+	_, _, errs = transitionSubmissionFields(fields, map[string]interface{}{
+		"resolution":        "1",
+		"fixVersions":       "10000",
+		"customfield_10050": "a lot",
+		"customfield_10051": "18/10/2026",
+	})
+	assert.Equal(t, map[string]string{
+		"customfield_10050": "please enter a number",
+		"customfield_10051": "please enter a date in the form YYYY-MM-DD",
+		"customfield_10052": "This field is required.",
+	}, errs)
+}
@@ -8,7 +8,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	jira "github.com/andygrunwald/go-jira"
 	"github.com/pkg/errors"
@@ -33,9 +35,15 @@ type Connection struct {
 	Oauth1AccessToken  string        `json:",omitempty"`
 	Oauth1AccessSecret string        `json:",omitempty"`
 	OAuth2Token        *oauth2.Token `json:",omitempty"`
-	Settings           *ConnectionSettings
-	SavedFieldValues   *SavedFieldValues `json:"saved_field_values,omitempty"`
-	MattermostUserID   types.ID          `json:"mattermost_user_id"`
+	// OAuth2RefreshTokenExpiry is when the refresh token expires, in milliseconds, if Jira
+	// reports it
+	OAuth2RefreshTokenExpiry int64 `json:",omitempty"`
+	// PersonalAccessToken is encrypted with the plugin's encryption key
+	PersonalAccessToken string `json:",omitempty"`
+	Settings            *ConnectionSettings
+	SavedFieldValues    *SavedFieldValues      `json:"saved_field_values,omitempty"`
+	MattermostUserID    types.ID               `json:"mattermost_user_id"`
+	Subscriptions       []PersonalSubscription `json:"subscriptions,omitempty"`
 }
 
 type SavedFieldValues struct {
@@ -43,6 +51,24 @@ type SavedFieldValues struct {
 	IssueType  string `json:"issue_type,omitempty"`
 }
 
+// setOAuth2Token stores a new OAuth 2.0 token, with the expiry of its refresh token when
+// Jira reports it in the token response.
+func (connection *Connection) setOAuth2Token(token *oauth2.Token, now time.Time) {
+	connection.OAuth2Token = token
+	connection.OAuth2RefreshTokenExpiry = 0
+
+	var seconds float64
+	switch value := token.Extra("refresh_token_expires_in").(type) {
+	case float64:
+		seconds = value
+	case string:
+		seconds, _ = strconv.ParseFloat(value, 64)
+	}
+	if seconds > 0 {
+		connection.OAuth2RefreshTokenExpiry = now.Add(time.Duration(seconds) * time.Second).UnixMilli()
+	}
+}
+
 func (connection *Connection) JiraAccountID() types.ID {
 	if connection.AccountID != "" {
 		return types.ID(connection.AccountID)
@@ -308,6 +334,8 @@ func (p *Plugin) disconnectUser(instance Instance, user *User) (*Connection, err
 	if err != nil && errors.Cause(err) != kvstore.ErrNotFound {
 		return nil, err
 	}
+	p.oauth2Clients.invalidate(instance.GetID(), user.MattermostUserID)
+	p.deleteConnectionLastUsed(instance.GetID(), user.MattermostUserID)
 	err = p.userStore.StoreUser(user)
 	if err != nil {
 		return nil, err
@@ -315,6 +343,15 @@ func (p *Plugin) disconnectUser(instance Instance, user *User) (*Connection, err
 
 	p.cleanupDMSubscriptionsOnDisconnect(instance.GetID(), user.MattermostUserID.String())
 
+	if len(conn.Subscriptions) > 0 {
+		if err = p.updatePersonalSubscribers(instance.GetID(), user.MattermostUserID, false); err != nil {
+			p.client.Log.Warn("Failed to clean up personal subscriptions on disconnect",
+				"mattermostUserID", user.MattermostUserID.String(),
+				"instanceID", instance.GetID().String(),
+				"error", err.Error())
+		}
+	}
+
 	info, err := p.GetUserInfo(user.MattermostUserID, user)
 	if err != nil {
 		return nil, err
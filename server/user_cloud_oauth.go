@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/pkg/errors"
@@ -19,6 +20,64 @@ import (
 
 const TokenExpiryTimeBufferInMinutes = 5
 
+// oauth2Instance is implemented by the instances that users connect to with OAuth 2.0.
+type oauth2Instance interface {
+	Instance
+	GetOAuthConfig() *oauth2.Config
+	GetCodeVerifier() string
+}
+
+var _ oauth2Instance = (*cloudOAuthInstance)(nil)
+
+// getOAuth2HTTPClient returns an HTTP client authenticated with the connection's OAuth 2.0
+// token. The token is refreshed when it has expired, and the connection stored with the new
+// token. Clients are cached per instance and user until shortly before the token expires.
+func (p *Plugin) getOAuth2HTTPClient(instance Instance, oauth2Conf *oauth2.Config, connection *Connection) (*http.Client, error) {
+	instanceID := instance.GetID()
+	if client := p.oauth2Clients.get(instanceID, connection, time.Now()); client != nil {
+		return client, nil
+	}
+
+	ctx, err := instance.Common().httpContext(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	tokenSource := oauth2Conf.TokenSource(ctx, connection.OAuth2Token)
+	client := oauth2.NewClient(ctx, tokenSource)
+	client.Timeout = instance.Common().HTTP.timeout()
+
+	// Get a new token, if Access Token has expired
+	currentToken := connection.OAuth2Token
+	updatedToken, err := tokenSource.Token()
+	if err != nil {
+		p.oauth2Clients.invalidate(instanceID, connection.MattermostUserID)
+
+		// Disconnect the user and notify them to reconnect their account. The background
+		// checks only report it, the user is disconnected on their next use of Jira.
+		if strings.Contains(err.Error(), "invalid_grant") {
+			if !instance.Common().backgroundJob {
+				p.disconnectUserDueToExpiredToken(connection.MattermostUserID, instanceID)
+			}
+			return nil, errors.Wrap(err, "your Jira token has expired, please use `/jira connect` to reconnect your account")
+		}
+		return nil, errors.Wrap(err, "failed to get a new refreshed token for the user")
+	}
+
+	// The cached client is keyed by the access token, so store refreshed access tokens as well
+	if updatedToken.AccessToken != currentToken.AccessToken || updatedToken.RefreshToken != currentToken.RefreshToken {
+		connection.setOAuth2Token(updatedToken, time.Now())
+
+		// Store this new access token & refresh token to get a new access token in future when it has expired
+		if err = p.userStore.StoreConnection(instanceID, connection.MattermostUserID, connection); err != nil {
+			return nil, err
+		}
+	}
+
+	p.oauth2Clients.set(instanceID, connection, client, time.Now())
+
+	return client, nil
+}
+
 func (p *Plugin) httpOAuth2Complete(w http.ResponseWriter, r *http.Request, instanceID types.ID) (int, error) {
 	code := r.URL.Query().Get("code")
 	if code == "" {
@@ -95,14 +154,18 @@ func (p *Plugin) GenerateInitialOAuthToken(mattermostUserID, code string, instan
 	if err != nil {
 		return nil, err
 	}
-	oAuthInstance, ok := instance.(*cloudOAuthInstance)
+	oAuthInstance, ok := instance.(oauth2Instance)
 	if !ok {
 		return nil, errors.Errorf("Not supported for instance type %s", instance.Common().Type)
 	}
 
 	oAuthConf := oAuthInstance.GetOAuthConfig()
 
-	token, err := oAuthConf.Exchange(context.Background(), code, oauth2.SetAuthURLParam("code_verifier", oAuthInstance.CodeVerifier))
+	ctx, err := instance.Common().httpContext(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	token, err := oAuthConf.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", oAuthInstance.GetCodeVerifier()))
 	if err != nil {
 		p.client.Log.Error("error while exchanging authorization code for access token", "error", err)
 		return nil, errors.WithMessage(err, "error while exchanging authorization code for access token")
@@ -113,6 +176,6 @@ func (p *Plugin) GenerateInitialOAuthToken(mattermostUserID, code string, instan
 		return nil, err
 	}
 
-	connection.OAuth2Token = token
+	connection.setOAuth2Token(token, time.Now())
 	return connection, nil
 }
@@ -4,8 +4,8 @@
 package main
 
 import (
-	"crypto/x509"
-	"encoding/pem"
+	"encoding/base64"
+	"encoding/json"
 	"net/http"
 	"strings"
 
@@ -146,15 +146,152 @@ func (p *Plugin) httpOAuth1aDisconnect(w http.ResponseWriter, r *http.Request, i
 		})
 }
 
-func (p *Plugin) publicKeyString() (string, error) {
-	rsaKey := p.getConfig().rsaKey
-	b, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+func (p *Plugin) encryptPersonalAccessToken(token string) (string, error) {
+	encryptionKey := p.getConfig().EncryptionKey
+	if encryptionKey == "" {
+		return "", errors.New("encryption key is not configured")
+	}
+
+	encrypted, err := encrypt([]byte(token), []byte(encryptionKey))
 	if err != nil {
-		return "", errors.WithMessage(err, "failed to encode public key")
+		return "", errors.Wrap(err, "failed to encrypt the personal access token")
 	}
-	pkey := pem.EncodeToMemory(&pem.Block{
-		Type:  "PUBLIC KEY",
-		Bytes: b,
+
+	return encode(encrypted), nil
+}
+
+func (p *Plugin) decryptPersonalAccessToken(encoded string) (string, error) {
+	encrypted, err := base64.URLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decode the personal access token")
+	}
+
+	plain, err := p.decryptWithEncryptionKey(encrypted)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decrypt the personal access token, please use /jira connect again")
+	}
+
+	return string(plain), nil
+}
+
+func (p *Plugin) loadPATServerInstance(instanceID types.ID) (*serverInstance, error) {
+	instance, err := p.instanceStore.LoadInstance(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	si, ok := instance.(*serverInstance)
+	if !ok || si.GetAuthMode() != ServerAuthModePAT {
+		return nil, errors.Errorf("Personal Access Tokens are not enabled for Jira instance %s", instanceID)
+	}
+
+	return si, nil
+}
+
+func (p *Plugin) httpPATConnectForm(w http.ResponseWriter, r *http.Request, instanceID types.ID) (int, error) {
+	si, err := p.loadPATServerInstance(instanceID)
+	if err != nil {
+		return respondErr(w, http.StatusBadRequest, err)
+	}
+
+	return p.respondTemplate(w, r, ContentTypeHTML, struct {
+		SubmitURL string
+		TokensURL string
+	}{
+		SubmitURL: p.CreateFullURLPath(instancePath(routeUserConnectPAT, instanceID)),
+		TokensURL: si.GetURL() + "/secure/ViewProfile.jspa?selectedTab=com.atlassian.pats.pats-plugin:jira-user-personal-access-tokens",
 	})
-	return strings.TrimSpace(string(pkey)), nil
+}
+
+// httpPATConnect validates the submitted Personal Access Token with Jira, and stores it
+// encrypted in the user's connection.
+func (p *Plugin) httpPATConnect(w http.ResponseWriter, r *http.Request, instanceID types.ID) (status int, err error) {
+	// The form replaces its content with the response, so errors are rendered too
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		errtext := err.Error()
+		if len(errtext) > 0 {
+			errtext = strings.ToUpper(errtext[:1]) + errtext[1:]
+		}
+		status, err = p.respondSpecialTemplate(w, "/other/message.html", status, ContentTypeHTML, struct {
+			Header  string
+			Message string
+		}{
+			Header:  "Failed to connect to Jira.",
+			Message: errtext,
+		})
+	}()
+
+	si, err := p.loadPATServerInstance(instanceID)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	mattermostUserID := r.Header.Get("Mattermost-User-Id")
+	mmuser, err := p.client.User.Get(mattermostUserID)
+	if err != nil {
+		return http.StatusInternalServerError,
+			errors.WithMessage(err, "failed to load user "+mattermostUserID)
+	}
+
+	request := struct {
+		Token string `json:"token"`
+	}{}
+	if err = json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return http.StatusBadRequest, errors.WithMessage(err, "failed to decode the request")
+	}
+	token := strings.TrimSpace(request.Token)
+	if token == "" {
+		return http.StatusBadRequest, errors.New("please provide a Personal Access Token")
+	}
+
+	encrypted, err := p.encryptPersonalAccessToken(token)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	connection := &Connection{
+		PluginVersion:       manifest.Version,
+		PersonalAccessToken: encrypted,
+	}
+
+	client, err := si.GetClient(connection)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	juser, err := client.GetSelf()
+	if err != nil {
+		return http.StatusUnauthorized, errors.WithMessage(err, "the Personal Access Token was rejected by Jira")
+	}
+	connection.User = *juser
+
+	// Set default settings the first time a user connects
+	connection.Settings = &ConnectionSettings{
+		Notifications: true,
+		RolesForDMNotification: map[string]bool{
+			mentionRole:  true,
+			assigneeRole: true,
+			reporterRole: true,
+			watchingRole: true,
+		},
+	}
+
+	if err = p.connectUser(si, types.ID(mattermostUserID), connection); err != nil {
+		return http.StatusInternalServerError, err
+	}
+
+	return p.respondSpecialTemplate(w, "/oauth1/complete.html", http.StatusOK, ContentTypeHTML, struct {
+		MattermostDisplayName string
+		JiraDisplayName       string
+		RevokeURL             string
+	}{
+		JiraDisplayName:       juser.DisplayName + " (" + juser.Name + ")",
+		MattermostDisplayName: mmuser.GetDisplayName(model.ShowNicknameFullName),
+		RevokeURL:             p.CreateFullURLPath(instancePath(routeUserDisconnect, instanceID)),
+	})
+}
+
+func (p *Plugin) publicKeyString() (string, error) {
+	return publicKeyPEM(p.getRSAKey())
 }
@@ -14,6 +14,9 @@ import (
 	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
 )
 
 func TestUserSettings_String(t *testing.T) {
@@ -145,3 +148,47 @@ func TestGetJiraUserFromMentions(t *testing.T) {
 		})
 	}
 }
+
+func TestServerInstancePersonalAccessToken(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer pat-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"jdoe","displayName":"John Doe"}`))
+	}))
+	defer ts.Close()
+
+	p := &Plugin{}
+	p.SetAPI(&plugintest.API{})
+	p.updateConfig(func(conf *config) {
+		conf.EncryptionKey = "0123456789abcdef0123456789abcdef"
+		conf.maxAttachmentSize = defaultMaxAttachmentSize
+	})
+
+	si := &serverInstance{
+		InstanceCommon: &InstanceCommon{Plugin: p, InstanceID: types.ID(ts.URL), Type: ServerInstanceType},
+		AuthMode:       ServerAuthModePAT,
+	}
+
+	encrypted, err := p.encryptPersonalAccessToken("pat-token")
+	require.NoError(t, err)
+	assert.NotContains(t, encrypted, "pat-token")
+
+	decrypted, err := p.decryptPersonalAccessToken(encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, "pat-token", decrypted)
+
+	client, err := si.GetClient(&Connection{PersonalAccessToken: encrypted})
+	require.NoError(t, err)
+	user, err := client.GetSelf()
+	require.NoError(t, err)
+	assert.Equal(t, "jdoe", user.Name)
+
+	connectURL, _, err := si.GetUserConnectURL("user-id")
+	require.NoError(t, err)
+	assert.Contains(t, connectURL, routeUserConnectPAT)
+
+	_, err = si.GetClient(&Connection{})
+	require.Error(t, err)
+}
@@ -282,7 +282,7 @@ func (p *Plugin) SetAdminAPITokenRequestHeader(req *http.Request) error {
 		return errors.New("admin API token is empty in plugin config")
 	}
 
-	jsonBytes, err := decrypt([]byte(cfg.AdminAPIToken), []byte(cfg.EncryptionKey))
+	jsonBytes, err := p.decryptWithEncryptionKey([]byte(cfg.AdminAPIToken))
 	if err != nil {
 		p.client.Log.Warn("Error decrypting admin API token; re-save the Admin API Token in System Console", "error", err.Error())
 		return err
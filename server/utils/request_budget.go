@@ -0,0 +1,203 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package utils
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrRequestBudgetTimeout is returned when a request waited too long for the budget.
+var ErrRequestBudgetTimeout = errors.New("timed out waiting to send the request, too many requests to the server")
+
+type backgroundPriorityKey struct{}
+
+// WithBackgroundPriority marks the requests sent with the context as background requests,
+// such as the periodic jobs, which use a smaller share of the budget than the requests of
+// the users.
+func WithBackgroundPriority(ctx context.Context) context.Context {
+	return context.WithValue(ctx, backgroundPriorityKey{}, true)
+}
+
+// IsBackgroundPriority returns true if the context is marked by WithBackgroundPriority.
+func IsBackgroundPriority(ctx context.Context) bool {
+	background, _ := ctx.Value(backgroundPriorityKey{}).(bool)
+	return background
+}
+
+// RequestBudget limits the number of concurrent requests to a server, and the rate at which
+// they are sent. Requests over the budget wait instead of failing, up to a deadline. The
+// background requests also take from a budget half the size, so that they always leave
+// room for the requests of the users. A nil budget is unlimited.
+type RequestBudget struct {
+	slots      chan struct{}
+	maxWait    time.Duration
+	background *RequestBudget
+
+	lock      sync.Mutex
+	perSecond float64
+	burst     float64
+	tokens    float64
+	updatedAt time.Time
+}
+
+// NewRequestBudget returns a budget of maxConcurrent requests in flight, and perSecond
+// requests per second with bursts of up to perSecond requests. Requests waiting longer than
+// maxWait fail with ErrRequestBudgetTimeout. Zero disables a limit.
+func NewRequestBudget(maxConcurrent, perSecond int, maxWait time.Duration) *RequestBudget {
+	b := newRequestBudget(maxConcurrent, perSecond, maxWait)
+	b.background = newRequestBudget(halfLimit(maxConcurrent), halfLimit(perSecond), maxWait)
+	return b
+}
+
+func newRequestBudget(maxConcurrent, perSecond int, maxWait time.Duration) *RequestBudget {
+	b := &RequestBudget{
+		maxWait:   maxWait,
+		perSecond: float64(perSecond),
+		burst:     float64(perSecond),
+		tokens:    float64(perSecond),
+		updatedAt: time.Now(),
+	}
+	if maxConcurrent > 0 {
+		b.slots = make(chan struct{}, maxConcurrent)
+	}
+	return b
+}
+
+func halfLimit(limit int) int {
+	if limit <= 0 {
+		return 0
+	}
+	if limit < 2 {
+		return 1
+	}
+	return limit / 2
+}
+
+// Acquire waits until a request can be sent, or the context is done. release must be called
+// once the response is received.
+func (b *RequestBudget) Acquire(ctx context.Context) (release func(), err error) {
+	if b == nil {
+		return func() {}, nil
+	}
+
+	var deadline time.Time
+	if b.maxWait > 0 {
+		deadline = time.Now().Add(b.maxWait)
+	}
+
+	if b.background == nil || !IsBackgroundPriority(ctx) {
+		return b.acquire(ctx, deadline)
+	}
+
+	releaseBackground, err := b.background.acquire(ctx, deadline)
+	if err != nil {
+		return nil, err
+	}
+	releaseShared, err := b.acquire(ctx, deadline)
+	if err != nil {
+		releaseBackground()
+		return nil, err
+	}
+	return func() {
+		releaseShared()
+		releaseBackground()
+	}, nil
+}
+
+func (b *RequestBudget) acquire(ctx context.Context, deadline time.Time) (release func(), err error) {
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	release = func() {}
+	if b.slots != nil {
+		select {
+		case b.slots <- struct{}{}:
+			release = func() { <-b.slots }
+		case <-expired:
+			return nil, ErrRequestBudgetTimeout
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	now := time.Now()
+	wait := b.reserve(now)
+	if wait <= 0 {
+		return release, nil
+	}
+	if !deadline.IsZero() && now.Add(wait).After(deadline) {
+		b.cancelReservation()
+		release()
+		return nil, ErrRequestBudgetTimeout
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return release, nil
+	case <-ctx.Done():
+		b.cancelReservation()
+		release()
+		return nil, ctx.Err()
+	}
+}
+
+// reserve takes a token from the bucket, and returns how long to wait for it to be available.
+func (b *RequestBudget) reserve(now time.Time) time.Duration {
+	if b.perSecond <= 0 {
+		return 0
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.tokens += now.Sub(b.updatedAt).Seconds() * b.perSecond
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.updatedAt = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.perSecond * float64(time.Second))
+}
+
+func (b *RequestBudget) cancelReservation() {
+	if b.perSecond <= 0 {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.tokens++
+}
+
+type backgroundPriorityTransport struct {
+	http.RoundTripper
+}
+
+// NewBackgroundPriorityTransport wraps a transport to send all its requests as background
+// requests.
+func NewBackgroundPriorityTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &backgroundPriorityTransport{RoundTripper: base}
+}
+
+func (t *backgroundPriorityTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.RoundTripper.RoundTrip(req.WithContext(WithBackgroundPriority(req.Context())))
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package utils
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestBudgetConcurrency(t *testing.T) {
+	budget := NewRequestBudget(1, 0, 0)
+
+	release, err := budget.Acquire(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = budget.Acquire(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	release()
+	release, err = budget.Acquire(context.Background())
+	require.NoError(t, err)
+	release()
+}
+
+func TestRequestBudgetRate(t *testing.T) {
+	budget := NewRequestBudget(0, 2, 0)
+	now := time.Now()
+	budget.updatedAt = now
+
+	// Bursts up to the rate are sent right away
+	assert.Equal(t, time.Duration(0), budget.reserve(now))
+	assert.Equal(t, time.Duration(0), budget.reserve(now))
+	assert.Equal(t, 500*time.Millisecond, budget.reserve(now))
+	assert.Equal(t, time.Second, budget.reserve(now))
+
+	// Tokens are refilled over time
+	assert.Equal(t, time.Duration(0), budget.reserve(now.Add(2*time.Second)))
+}
+
+func TestRequestBudgetNil(t *testing.T) {
+	var budget *RequestBudget
+	release, err := budget.Acquire(context.Background())
+	require.NoError(t, err)
+	release()
+}
+
+func TestRequestBudgetMaxWait(t *testing.T) {
+	budget := NewRequestBudget(1, 0, 20*time.Millisecond)
+
+	release, err := budget.Acquire(context.Background())
+	require.NoError(t, err)
+
+	_, err = budget.Acquire(context.Background())
+	require.ErrorIs(t, err, ErrRequestBudgetTimeout)
+	release()
+
+	// Waiting for the rate longer than the deadline fails right away
+	budget = NewRequestBudget(0, 1, 20*time.Millisecond)
+	release, err = budget.Acquire(context.Background())
+	require.NoError(t, err)
+	release()
+	_, err = budget.Acquire(context.Background())
+	require.ErrorIs(t, err, ErrRequestBudgetTimeout)
+}
+
+func TestRequestBudgetBackgroundPriority(t *testing.T) {
+	budget := NewRequestBudget(4, 0, 20*time.Millisecond)
+	background := WithBackgroundPriority(context.Background())
+
+	// The background requests take up to half of the slots
+	releases := []func(){}
+	for i := 0; i < 2; i++ {
+		release, err := budget.Acquire(background)
+		require.NoError(t, err)
+		releases = append(releases, release)
+	}
+	_, err := budget.Acquire(background)
+	require.ErrorIs(t, err, ErrRequestBudgetTimeout)
+
+	// and leave the others to the requests of the users
+	for i := 0; i < 2; i++ {
+		release, err := budget.Acquire(context.Background())
+		require.NoError(t, err)
+		releases = append(releases, release)
+	}
+	_, err = budget.Acquire(context.Background())
+	require.ErrorIs(t, err, ErrRequestBudgetTimeout)
+
+	for _, release := range releases {
+		release()
+	}
+	release, err := budget.Acquire(background)
+	require.NoError(t, err)
+	release()
+}
+
+func TestBackgroundPriorityTransport(t *testing.T) {
+	var background bool
+	base := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		background = IsBackgroundPriority(req.Context())
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "http://jira.example.com", nil)
+	require.NoError(t, err)
+	_, err = NewBackgroundPriorityTransport(base).RoundTrip(req)
+	require.NoError(t, err)
+	assert.True(t, background)
+
+	_, err = base.RoundTrip(req)
+	require.NoError(t, err)
+	assert.False(t, background)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
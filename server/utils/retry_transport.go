@@ -0,0 +1,147 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package utils
+
+import (
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures which failed requests are retried, and how long to wait in between.
+// Only idempotent requests are retried.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries of the requests that fail with a network error, or
+	// a 502 or 504 response.
+	MaxRetries int
+
+	// MaxRateLimitRetries is the number of retries of the requests that are rate limited,
+	// with a 429 or 503 response.
+	MaxRateLimitRetries int
+
+	// BaseDelay is the delay of the first retry, doubled on every following retry, when the
+	// server does not send a Retry-After header.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between retries. Responses asking to retry later than MaxDelay
+	// are returned as they are.
+	MaxDelay time.Duration
+}
+
+type retryTransport struct {
+	http.RoundTripper
+	policy RetryPolicy
+	budget *RequestBudget
+}
+
+// NewRetryTransport wraps a transport to retry the failed and rate limited requests, honoring
+// the Retry-After header, and to send the requests within budget. budget may be nil.
+func NewRetryTransport(base http.RoundTripper, policy RetryPolicy, budget *RequestBudget) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &retryTransport{
+		RoundTripper: base,
+		policy:       policy,
+		budget:       budget,
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	idempotent := IsIdempotentRequest(req)
+	for attempt := 0; ; attempt++ {
+		release, err := t.budget.Acquire(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		resp, err := t.RoundTripper.RoundTrip(req)
+		release()
+
+		if !idempotent {
+			return resp, err
+		}
+		delay, retry := t.policy.retryDelay(attempt, resp, err, time.Now())
+		if !retry {
+			return resp, err
+		}
+		if resp != nil && resp.Body != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// IsIdempotentRequest returns true for the requests that can safely be sent again.
+func IsIdempotentRequest(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	}
+	return false
+}
+
+func (p RetryPolicy) retryDelay(attempt int, resp *http.Response, err error, now time.Time) (time.Duration, bool) {
+	switch {
+	case err != nil:
+		return p.backoff(attempt), attempt < p.MaxRetries
+
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		if attempt >= p.MaxRateLimitRetries && attempt >= p.MaxRetries {
+			return 0, false
+		}
+		if retryAfter, ok := ParseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+			return retryAfter, retryAfter <= p.MaxDelay
+		}
+		return p.backoff(attempt), true
+
+	case resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusGatewayTimeout:
+		return p.backoff(attempt), attempt < p.MaxRetries
+	}
+	return 0, false
+}
+
+// backoff returns an exponential delay with jitter, so that the clients rate limited at the
+// same time do not retry at the same time.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.MaxDelay
+	if attempt < 16 && p.BaseDelay<<attempt < p.MaxDelay {
+		delay = p.BaseDelay << attempt
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1)) //nolint:gosec
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, either a number of seconds or a
+// date.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if at.Before(now) {
+		return 0, true
+	}
+	return at.Sub(now), true
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryTransport(t *testing.T) {
+	policy := RetryPolicy{
+		MaxRateLimitRetries: 3,
+		BaseDelay:           time.Millisecond,
+		MaxDelay:            time.Second,
+	}
+
+	for name, tc := range map[string]struct {
+		method           string
+		policy           RetryPolicy
+		failures         int
+		status           int
+		retryAfter       string
+		expectedStatus   int
+		expectedAttempts int
+	}{
+		"rate limited, then succeeds": {
+			method: http.MethodGet, policy: policy, failures: 2, status: http.StatusTooManyRequests, retryAfter: "0",
+			expectedStatus: http.StatusOK, expectedAttempts: 3,
+		},
+		"rate limited too many times": {
+			method: http.MethodGet, policy: policy, failures: 10, status: http.StatusTooManyRequests,
+			expectedStatus: http.StatusTooManyRequests, expectedAttempts: 4,
+		},
+		"retry after exceeds the maximum delay": {
+			method: http.MethodGet, policy: policy, failures: 1, status: http.StatusServiceUnavailable, retryAfter: "120",
+			expectedStatus: http.StatusServiceUnavailable, expectedAttempts: 1,
+		},
+		"not idempotent": {
+			method: http.MethodPost, policy: policy, failures: 1, status: http.StatusTooManyRequests,
+			expectedStatus: http.StatusTooManyRequests, expectedAttempts: 1,
+		},
+		"gateway error is not retried by default": {
+			method: http.MethodGet, policy: policy, failures: 1, status: http.StatusBadGateway,
+			expectedStatus: http.StatusBadGateway, expectedAttempts: 1,
+		},
+		"gateway error with retries": {
+			method: http.MethodGet, policy: RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Second}, failures: 1, status: http.StatusBadGateway,
+			expectedStatus: http.StatusOK, expectedAttempts: 2,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			attempts := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if attempts <= tc.failures {
+					if tc.retryAfter != "" {
+						w.Header().Set("Retry-After", tc.retryAfter)
+					}
+					w.WriteHeader(tc.status)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer ts.Close()
+
+			client := &http.Client{Transport: NewRetryTransport(nil, tc.policy, NewRequestBudget(1, 0, 0))}
+			req, err := http.NewRequest(tc.method, ts.URL, nil)
+			require.NoError(t, err)
+			if tc.method == http.MethodPost {
+				req, err = http.NewRequest(tc.method, ts.URL, strings.NewReader("{}"))
+				require.NoError(t, err)
+			}
+
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+			assert.Equal(t, tc.expectedAttempts, attempts)
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	d, ok := ParseRetryAfter("30", now)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, d)
+
+	d, ok = ParseRetryAfter(now.Add(time.Minute).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, d)
+
+	d, ok = ParseRetryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), d)
+
+	_, ok = ParseRetryAfter("", now)
+	assert.False(t, ok)
+	_, ok = ParseRetryAfter("soon", now)
+	assert.False(t, ok)
+	_, ok = ParseRetryAfter("-1", now)
+	assert.False(t, ok)
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 0; attempt < 10; attempt++ {
+		delay := policy.backoff(attempt)
+		expected := policy.BaseDelay << attempt
+		if expected > policy.MaxDelay {
+			expected = policy.MaxDelay
+		}
+		assert.GreaterOrEqual(t, delay, expected/2)
+		assert.LessOrEqual(t, delay, expected)
+	}
+}
@@ -143,6 +143,9 @@ func TestDisconnectUserDueToExpiredToken(t *testing.T) {
 				// KVGet for subscription cleanup
 				api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
 
+				// Deletes the last use of the connection
+				api.On("KVSetWithOptions", mock.AnythingOfType("string"), []byte(nil), mock.Anything).Return(true, (*model.AppError)(nil)).Once()
+
 				api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
 					return post.UserId == testBotUserID &&
 						post.ChannelId == testChannelID &&
@@ -271,6 +274,7 @@ func TestDisconnectUserDueToExpiredTokenDeduplicatesConcurrentCalls(t *testing.T
 	})).Return().Once()
 	api.On("GetDirectChannel", testMattermostUserID.String(), testBotUserID).Return(&model.Channel{Id: testChannelID}, nil)
 	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), []byte(nil), mock.Anything).Return(true, (*model.AppError)(nil)).Once()
 
 	api.On("CreatePost", mock.MatchedBy(func(post *model.Post) bool {
 		return post.UserId == testBotUserID && post.ChannelId == testChannelID
@@ -470,6 +474,9 @@ func (m *mockJiraClient) UpdateComment(_ string, _ *jira.Comment) (*jira.Comment
 func (m *mockJiraClient) SearchIssues(_ string, _ *jira.SearchOptions) ([]jira.Issue, error) {
 	return nil, nil
 }
+func (m *mockJiraClient) SearchIssuesPage(_, _ string, _ *jira.SearchOptions) ([]jira.Issue, string, error) {
+	return nil, "", nil
+}
 func (m *mockJiraClient) SearchUsersAssignableToIssue(_, _ string, _ int) ([]jira.User, error) {
 	return nil, nil
 }
@@ -482,9 +489,47 @@ func (m *mockJiraClient) SearchAutoCompleteFields(_ map[string]string) (*AutoCom
 func (m *mockJiraClient) GetWatchers(_, _ string, _ *Connection) (*jira.Watches, error) {
 	return nil, nil
 }
+func (m *mockJiraClient) AddWatcher(_ string, _ *jira.User) error {
+	return nil
+}
+func (m *mockJiraClient) AddRemoteLink(_ string, _ *jira.RemoteLink) error {
+	return nil
+}
+func (m *mockJiraClient) GetAttachment(_ string) (*jira.Attachment, error) {
+	return nil, nil
+}
+func (m *mockJiraClient) DownloadAttachment(_ *jira.Attachment, _ types.ByteSize) ([]byte, error) {
+	return nil, nil
+}
+func (m *mockJiraClient) GetTransitionFields(_, _ string) (map[string]TransitionFieldMeta, error) {
+	return nil, nil
+}
+func (m *mockJiraClient) DoTransitionWithFields(_, _ string, _ map[string]interface{}, _ string) error {
+	return nil
+}
+func (m *mockJiraClient) GetIssueLinkTypes() ([]jira.IssueLinkType, error) { return nil, nil }
+func (m *mockJiraClient) LinkIssues(_ *jira.IssueLink) error               { return nil }
+func (m *mockJiraClient) GetFavouriteFilters() ([]*jira.Filter, error)     { return nil, nil }
+func (m *mockJiraClient) GetFilter(_ string) (*jira.Filter, error)         { return nil, nil }
+func (m *mockJiraClient) SearchFilters(_ string, _ int) ([]*jira.Filter, error) {
+	return nil, nil
+}
 func (m *mockJiraClient) GetUserVisibilityGroups(_ map[string]string) (*CommentVisibilityResult, error) {
 	return nil, nil
 }
+func (m *mockJiraClient) GetBoards(_, _ string, _ int) ([]Board, error) { return nil, nil }
+func (m *mockJiraClient) GetBoard(_ int) (*Board, error)                { return nil, nil }
+func (m *mockJiraClient) GetBoardSprints(_ int, _ string) ([]Sprint, error) {
+	return nil, nil
+}
+func (m *mockJiraClient) GetBoardBacklog(_ int, _ []string, _ int) ([]jira.Issue, error) {
+	return nil, nil
+}
+func (m *mockJiraClient) GetSprint(_ int) (*Sprint, error) { return nil, nil }
+func (m *mockJiraClient) GetSprintIssues(_ int, _ []string) ([]jira.Issue, error) {
+	return nil, nil
+}
+func (m *mockJiraClient) MoveIssuesToSprint(_ int, _ []string) error { return nil }
 func (m *mockJiraClient) GetProject(_ string) (*jira.Project, error) { return nil, nil }
 func (m *mockJiraClient) ListProjects(_ string, _ int, _ bool) (jira.ProjectList, error) {
 	return nil, nil
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -32,7 +33,7 @@ const (
 
 type Webhook interface {
 	Events() StringSet
-	PostToChannel(p *Plugin, instanceID types.ID, channelID, fromUserID, subscriptionName string) (*model.Post, int, error)
+	PostToChannel(p *Plugin, instanceID types.ID, channelID, fromUserID string, subscription *ChannelSubscription) (*model.Post, int, error)
 	PostNotifications(p *Plugin, instanceID types.ID) ([]*model.Post, int, error)
 }
 
@@ -51,6 +52,13 @@ type webhook struct {
 	fields        []*model.SlackAttachmentField
 	notifications []webhookUserNotification
 	fieldInfo     webhookField
+
+	// attachmentIDs are the IDs of the attachments added to the issue.
+	attachmentIDs []string
+
+	// savedFilterMatches are the results of the saved filters run for the event, by filter
+	// ID and owner.
+	savedFilterMatches map[string]bool
 }
 
 type webhookUserNotification struct {
@@ -66,13 +74,15 @@ func (wh *webhook) Events() StringSet {
 	return wh.eventTypes
 }
 
-func (wh webhook) PostToChannel(p *Plugin, instanceID types.ID, channelID, fromUserID, subscriptionName string) (*model.Post, int, error) {
+// PostToChannel posts the webhook event to a channel. subscription is nil for events
+// received through the legacy per-channel webhook URL.
+func (wh webhook) PostToChannel(p *Plugin, instanceID types.ID, channelID, fromUserID string, subscription *ChannelSubscription) (*model.Post, int, error) {
 	pluginConfig := p.getConfig()
 
 	if wh.headline == "" {
 		return nil, http.StatusBadRequest, errors.Errorf("unsupported webhook")
-	} else if pluginConfig.DisplaySubscriptionNameInNotifications && subscriptionName != "" {
-		wh.headline = fmt.Sprintf("%s\nSubscription: **%s**", wh.headline, subscriptionName)
+	} else if pluginConfig.DisplaySubscriptionNameInNotifications && subscription != nil && subscription.Name != "" {
+		wh.headline = fmt.Sprintf("%s\nSubscription: **%s**", wh.headline, subscription.Name)
 	}
 
 	post := &model.Post{
@@ -122,6 +132,18 @@ func (wh webhook) PostToChannel(p *Plugin, instanceID types.ID, channelID, fromU
 		post.Message = wh.headline
 	}
 
+	if subscription != nil {
+		// Mentions are only notified when they are part of the message, not the attachment.
+		mentions := p.getSubscriptionMentions(&wh, instanceID, subscription.Mentions)
+		if len(mentions) > 0 {
+			post.Message = strings.TrimSpace(post.Message + "\n" + strings.Join(mentions, " "))
+		}
+	}
+
+	if wh.Issue.Key != "" {
+		p.addIssuePostProps(post, instanceID, wh.Issue.Key)
+	}
+
 	if err := p.client.Post.CreatePost(post); err != nil {
 		return nil, http.StatusInternalServerError, err
 	}
@@ -0,0 +1,140 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+// maxFilesPerPost is the number of files Mattermost accepts in a post.
+const maxFilesPerPost = 10
+
+// postedAttachmentMIMETypes are the types of the Jira attachments uploaded to Mattermost,
+// matched by prefix.
+var postedAttachmentMIMETypes = []string{
+	"image/",
+	"video/",
+	"audio/",
+	"text/plain",
+	"application/pdf",
+}
+
+func isPostedAttachmentMIME(mime string) bool {
+	mime = strings.ToLower(mime)
+	for _, prefix := range postedAttachmentMIMETypes {
+		if strings.HasPrefix(mime, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// attachmentThread is a thread of an issue in a channel, where its attachments are posted.
+type attachmentThread struct {
+	ChannelID string
+	RootID    string
+}
+
+// issueAttachmentFile is a Jira attachment downloaded to be uploaded to Mattermost.
+type issueAttachmentFile struct {
+	Filename string
+	Content  []byte
+}
+
+// issueThreadRootID returns the root of the thread of an issue in the channel of a post just
+// made about it: the first post about the issue if it is known, or else the post itself.
+func (p *Plugin) issueThreadRootID(issueID string, post *model.Post) string {
+	if post.RootId != "" {
+		return post.RootId
+	}
+
+	var rootID string
+	err := p.client.KV.Get(fmt.Sprintf(ticketRootPostIDKey, issueID, post.ChannelId), &rootID)
+	if err == nil && rootID != "" {
+		return rootID
+	}
+	return post.Id
+}
+
+// postIssueAttachments uploads the attachments added to an issue as files in its threads. They
+// are downloaded once, with the Jira connection of a user involved in the issue, and skipped
+// if none is connected. It is run in the background, not to hold up the webhook workers.
+func (p *Plugin) postIssueAttachments(wh *webhook, instanceID types.ID, fromUserID string, threads []attachmentThread) {
+	client, _, err := wh.fetchConnectedUser(p, instanceID)
+	if err != nil || client == nil {
+		p.client.Log.Debug("Skipping the Jira attachments, no user of the issue is connected", "issue", wh.Issue.Key)
+		return
+	}
+
+	p.postAttachments(client, wh.Issue.Key, wh.attachmentIDs, fromUserID, threads)
+}
+
+// postAttachments downloads the attachments of an issue, and posts them in each of the threads.
+func (p *Plugin) postAttachments(client Client, issueKey string, attachmentIDs []string, fromUserID string, threads []attachmentThread) {
+	files := p.downloadIssueAttachments(client, issueKey, attachmentIDs)
+	if len(files) == 0 {
+		return
+	}
+	for _, thread := range threads {
+		p.uploadIssueAttachments(issueKey, files, fromUserID, thread)
+	}
+}
+
+func (p *Plugin) downloadIssueAttachments(client Client, issueKey string, attachmentIDs []string) []issueAttachmentFile {
+	maxSize := p.getConfig().maxAttachmentSize
+	files := []issueAttachmentFile{}
+	for _, attachmentID := range attachmentIDs {
+		attachment, err := client.GetAttachment(attachmentID)
+		if err != nil {
+			p.client.Log.Warn("Failed to get the Jira attachment", "issue", issueKey, "attachment_id", attachmentID, "error", err.Error())
+			continue
+		}
+		if !isPostedAttachmentMIME(attachment.MimeType) || types.ByteSize(attachment.Size) > maxSize {
+			continue
+		}
+
+		content, err := client.DownloadAttachment(attachment, maxSize)
+		if err != nil {
+			p.client.Log.Warn("Failed to download the Jira attachment", "issue", issueKey, "attachment_id", attachmentID, "error", err.Error())
+			continue
+		}
+		files = append(files, issueAttachmentFile{Filename: attachment.Filename, Content: content})
+	}
+	return files
+}
+
+func (p *Plugin) uploadIssueAttachments(issueKey string, files []issueAttachmentFile, fromUserID string, thread attachmentThread) {
+	fileIDs := []string{}
+	for _, file := range files {
+		fileInfo, err := p.client.File.Upload(bytes.NewReader(file.Content), file.Filename, thread.ChannelID)
+		if err != nil {
+			p.client.Log.Warn("Failed to upload the Jira attachment", "issue", issueKey, "filename", file.Filename, "error", err.Error())
+			continue
+		}
+		fileIDs = append(fileIDs, fileInfo.Id)
+	}
+
+	for len(fileIDs) > 0 {
+		n := len(fileIDs)
+		if n > maxFilesPerPost {
+			n = maxFilesPerPost
+		}
+		err := p.client.Post.CreatePost(&model.Post{
+			ChannelId: thread.ChannelID,
+			UserId:    fromUserID,
+			RootId:    thread.RootID,
+			FileIds:   fileIDs[:n],
+		})
+		if err != nil {
+			p.client.Log.Warn("Failed to post the Jira attachments", "issue", issueKey, "error", err.Error())
+		}
+		fileIDs = fileIDs[n:]
+	}
+}
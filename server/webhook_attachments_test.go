@@ -0,0 +1,135 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	jira "github.com/andygrunwald/go-jira"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+func TestIsPostedAttachmentMIME(t *testing.T) {
+	assert.True(t, isPostedAttachmentMIME("image/png"))
+	assert.True(t, isPostedAttachmentMIME("Application/PDF"))
+	assert.True(t, isPostedAttachmentMIME("text/plain; charset=utf-8"))
+	assert.False(t, isPostedAttachmentMIME("application/zip"))
+	assert.False(t, isPostedAttachmentMIME("text/html"))
+}
+
+func TestParseWebhookAttachmentIDs(t *testing.T) {
+	jwh := &JiraWebhook{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"webhookEvent": "jira:issue_updated",
+		"issue": {"key": "TEST-1", "fields": {"summary": "Login fails"}},
+		"changelog": {"items": [
+			{"field": "Attachment", "fieldtype": "jira", "from": null, "to": "10010", "toString": "screenshot.png"},
+			{"field": "Attachment", "fieldtype": "jira", "from": "10001", "fromString": "old.log", "to": null},
+			{"field": "Attachment", "fieldtype": "jira", "from": null, "to": "10011", "toString": "trace.txt"}
+		]}
+	}`), jwh))
+
+	wh, ok := parseWebhookChangeLog(jwh).(*webhook)
+	require.True(t, ok)
+	assert.Equal(t, []string{"10010", "10011"}, wh.attachmentIDs)
+}
+
+type attachmentsTestClient struct {
+	testClient
+	downloads map[string]int
+}
+
+func (client attachmentsTestClient) GetAttachment(id string) (*jira.Attachment, error) {
+	switch id {
+	case "10010":
+		return &jira.Attachment{ID: id, Filename: "screenshot.png", MimeType: "image/png", Size: 100}, nil
+	case "10011":
+		return &jira.Attachment{ID: id, Filename: "archive.zip", MimeType: "application/zip", Size: 100}, nil
+	}
+	return nil, errors.New("attachment not found")
+}
+
+func (client attachmentsTestClient) DownloadAttachment(attachment *jira.Attachment, _ types.ByteSize) ([]byte, error) {
+	client.downloads[attachment.ID]++
+	return []byte("content of " + attachment.Filename), nil
+}
+
+func TestPostAttachments(t *testing.T) {
+	api := &plugintest.API{}
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+	p.updateConfig(func(conf *config) {
+		conf.maxAttachmentSize = defaultMaxAttachmentSize
+	})
+
+	api.On("LogWarn", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	api.On("UploadFile", []byte("content of screenshot.png"), "channel1", "screenshot.png").Return(&model.FileInfo{Id: "file1"}, nil)
+	api.On("UploadFile", []byte("content of screenshot.png"), "channel2", "screenshot.png").Return(&model.FileInfo{Id: "file2"}, nil)
+	posts := []*model.Post{}
+	api.On("CreatePost", mock.AnythingOfType("*model.Post")).Run(func(args mock.Arguments) {
+		posts = append(posts, args.Get(0).(*model.Post).Clone())
+	}).Return(&model.Post{}, nil)
+
+	client := attachmentsTestClient{downloads: map[string]int{}}
+	p.postAttachments(client, "TEST-1", []string{"10010", "10011", "10012"}, "botUserID", []attachmentThread{
+		{ChannelID: "channel1", RootID: "root1"},
+		{ChannelID: "channel2", RootID: "root2"},
+	})
+
+	assert.Equal(t, map[string]int{"10010": 1}, client.downloads)
+
+	require.Len(t, posts, 2)
+	assert.Equal(t, "root1", posts[0].RootId)
+	assert.Equal(t, []string{"file1"}, []string(posts[0].FileIds))
+	assert.Equal(t, "root2", posts[1].RootId)
+	assert.Equal(t, []string{"file2"}, []string(posts[1].FileIds))
+}
+
+func TestIssueThreadRootID(t *testing.T) {
+	for name, tc := range map[string]struct {
+		post       *model.Post
+		storedRoot string
+		expected   string
+	}{
+		"reply in the thread of the issue": {
+			post:     &model.Post{Id: "post1", ChannelId: "channel1", RootId: "root1"},
+			expected: "root1",
+		},
+		"thread of the issue is known": {
+			post:       &model.Post{Id: "post1", ChannelId: "channel1"},
+			storedRoot: "root1",
+			expected:   "root1",
+		},
+		"first post about the issue": {
+			post:     &model.Post{Id: "post1", ChannelId: "channel1"},
+			expected: "post1",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			api := &plugintest.API{}
+			p := &Plugin{}
+			p.SetAPI(api)
+			p.client = pluginapi.NewClient(p.API, p.Driver)
+
+			var stored []byte
+			if tc.storedRoot != "" {
+				stored, _ = json.Marshal(tc.storedRoot)
+			}
+			api.On("KVGet", "ticket_post_id_10001_channel_id_channel1").Return(stored, nil)
+
+			assert.Equal(t, tc.expected, p.issueThreadRootID("10001", tc.post))
+		})
+	}
+}
@@ -0,0 +1,524 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+const (
+	webhookHealthKey = "webhook_health"
+
+	webhookHealthJobKey      = "webhook_health_check"
+	webhookHealthJobInterval = time.Hour
+
+	// Avoid a KV write for every event received on busy instances
+	webhookLastEventWriteInterval = time.Minute
+
+	defaultWebhookQuietThresholdHours = 24
+
+	jiraWebhookAPIPath = "/rest/webhooks/1.0/webhook"
+	jiraWebhookName    = "Mattermost Jira plugin"
+
+	webhookRegistrationCreated   = "created"
+	webhookRegistrationUpdated   = "updated"
+	webhookRegistrationUnchanged = "unchanged"
+)
+
+// jiraWebhookEvents are the events the subscriptions webhook is registered for.
+var jiraWebhookEvents = []string{
+	"jira:issue_created",
+	"jira:issue_updated",
+	"jira:issue_deleted",
+	"comment_created",
+	"comment_updated",
+	"comment_deleted",
+	"project_updated",
+	"project_deleted",
+}
+
+// webhookHealth is stored per instance and tracks the Jira-side webhook registration and
+// the last time an event was received from it.
+type webhookHealth struct {
+	LastEventAt       int64  `json:"last_event_at,omitempty"`
+	RegisteredAt      int64  `json:"registered_at,omitempty"`
+	JiraWebhookSelf   string `json:"jira_webhook_self,omitempty"`
+	RegistrationError string `json:"registration_error,omitempty"`
+	QuietNotifiedAt   int64  `json:"quiet_notified_at,omitempty"`
+}
+
+// jiraWebhook is the representation of a webhook in the Jira REST webhook API.
+type jiraWebhook struct {
+	Self        string            `json:"self,omitempty"`
+	Name        string            `json:"name"`
+	URL         string            `json:"url"`
+	Events      []string          `json:"events"`
+	Filters     map[string]string `json:"filters,omitempty"`
+	ExcludeBody bool              `json:"excludeBody"`
+	Enabled     bool              `json:"enabled"`
+}
+
+func (p *Plugin) getWebhookQuietThreshold() time.Duration {
+	value := p.getConfig().WebhookQuietThresholdHours
+	if value == "" {
+		return defaultWebhookQuietThresholdHours * time.Hour
+	}
+
+	hours, err := strconv.Atoi(value)
+	if err != nil || hours <= 0 {
+		return 0
+	}
+
+	return time.Duration(hours) * time.Hour
+}
+
+func (p *Plugin) getWebhookHealth(instanceID types.ID) (*webhookHealth, error) {
+	health := &webhookHealth{}
+	if err := p.client.KV.Get(keyWithInstanceID(instanceID, webhookHealthKey), health); err != nil {
+		return nil, errors.Wrap(err, "failed to load webhook health")
+	}
+
+	return health, nil
+}
+
+func (p *Plugin) updateWebhookHealth(instanceID types.ID, update func(health *webhookHealth)) error {
+	err := p.client.KV.SetAtomicWithRetries(keyWithInstanceID(instanceID, webhookHealthKey), func(initialBytes []byte) (interface{}, error) {
+		health := &webhookHealth{}
+		if len(initialBytes) != 0 {
+			if err := json.Unmarshal(initialBytes, health); err != nil {
+				return nil, err
+			}
+		}
+
+		update(health)
+		return health, nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to store webhook health")
+	}
+
+	return nil
+}
+
+// recordWebhookReceived notes the time an event was received for the instance. Writes are
+// throttled per server, so the stored time may lag by up to webhookLastEventWriteInterval.
+func (p *Plugin) recordWebhookReceived(instanceID types.ID) {
+	now := model.GetMillis()
+	if last, ok := p.webhookLastEventWrites.Load(instanceID); ok && now-last.(int64) < webhookLastEventWriteInterval.Milliseconds() {
+		return
+	}
+	p.webhookLastEventWrites.Store(instanceID, now)
+
+	err := p.updateWebhookHealth(instanceID, func(health *webhookHealth) {
+		health.LastEventAt = now
+	})
+	if err != nil {
+		p.client.Log.Warn("Failed to record the last webhook event time", "instance", instanceID, "error", err.Error())
+	}
+}
+
+func (p *Plugin) canRegisterWebhooks() bool {
+	conf := p.getConfig()
+	return conf.AdminAPIToken != "" && conf.AdminEmail != ""
+}
+
+// doAdminAPITokenRequest invokes the Jira REST API with the admin API token configured in the
+// plugin settings. in and out are JSON encoded and decoded when not nil.
+func (p *Plugin) doAdminAPITokenRequest(instance Instance, method, endpoint string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create HTTP request. URL: %s", endpoint)
+	}
+	if err = p.SetAdminAPITokenRequestHeader(req); err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient, err := instance.Common().httpClient(adminAPITokenRequestTimeout)
+	if err != nil {
+		return err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "request to Jira failed. URL: %s", endpoint)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read the response. StatusCode: %d", resp.StatusCode)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return errors.Errorf("the admin API token is not permitted to manage webhooks. StatusCode: %d", resp.StatusCode)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return errors.Errorf("unexpected status code: %d. Response: %s", resp.StatusCode, string(respBody))
+	}
+
+	if out != nil && len(respBody) > 0 {
+		if err = json.Unmarshal(respBody, out); err != nil {
+			return errors.Wrap(err, "failed to unmarshal the response")
+		}
+	}
+
+	return nil
+}
+
+// isSameWebhookEndpoint compares two webhook URLs ignoring the query, which contains the
+// secret and may be outdated.
+func isSameWebhookEndpoint(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(ua.Host, ub.Host) && strings.TrimRight(ua.Path, "/") == strings.TrimRight(ub.Path, "/")
+}
+
+func findRegisteredWebhook(webhooks []jiraWebhook, webhookURL string) *jiraWebhook {
+	for i := range webhooks {
+		if isSameWebhookEndpoint(webhooks[i].URL, webhookURL) {
+			return &webhooks[i]
+		}
+	}
+	return nil
+}
+
+// needsUpdate returns true if the registered webhook doesn't deliver all events to the expected URL.
+func (wh *jiraWebhook) needsUpdate(webhookURL string) bool {
+	if !wh.Enabled || wh.ExcludeBody || wh.URL != webhookURL {
+		return true
+	}
+
+	return !NewStringSet(wh.Events...).ContainsAll(jiraWebhookEvents...)
+}
+
+// registerSubscriptionsWebhook creates the subscriptions webhook in Jira, or updates it if it
+// was deleted, disabled or points to an outdated secret.
+func (p *Plugin) registerSubscriptionsWebhook(instance Instance) (string, error) {
+	if !p.canRegisterWebhooks() {
+		return "", errors.New("automatic webhook registration requires the Admin API Token and Admin Email plugin settings")
+	}
+
+	instanceID := instance.GetID()
+	action, self, err := p.ensureJiraWebhook(instance)
+
+	updateErr := p.updateWebhookHealth(instanceID, func(health *webhookHealth) {
+		if err != nil {
+			health.RegistrationError = err.Error()
+			return
+		}
+		health.RegistrationError = ""
+		health.JiraWebhookSelf = self
+		if action != webhookRegistrationUnchanged || health.RegisteredAt == 0 {
+			health.RegisteredAt = model.GetMillis()
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	if updateErr != nil {
+		return "", updateErr
+	}
+
+	return action, nil
+}
+
+func (p *Plugin) ensureJiraWebhook(instance Instance) (action, self string, err error) {
+	endpoint := strings.TrimRight(instance.GetURL(), "/") + jiraWebhookAPIPath
+	webhookURL := p.getSubscriptionsWebhookURL(instance.GetID())
+
+	webhooks := []jiraWebhook{}
+	if err = p.doAdminAPITokenRequest(instance, http.MethodGet, endpoint, nil, &webhooks); err != nil {
+		return "", "", errors.WithMessage(err, "failed to list Jira webhooks")
+	}
+
+	desired := &jiraWebhook{
+		Name:    jiraWebhookName,
+		URL:     webhookURL,
+		Events:  jiraWebhookEvents,
+		Filters: map[string]string{"issue-related-events-section": ""},
+		Enabled: true,
+	}
+
+	existing := findRegisteredWebhook(webhooks, webhookURL)
+	if existing == nil {
+		created := &jiraWebhook{}
+		if err = p.doAdminAPITokenRequest(instance, http.MethodPost, endpoint, desired, created); err != nil {
+			return "", "", errors.WithMessage(err, "failed to create the Jira webhook")
+		}
+		return webhookRegistrationCreated, created.Self, nil
+	}
+
+	if !existing.needsUpdate(webhookURL) {
+		return webhookRegistrationUnchanged, existing.Self, nil
+	}
+
+	// Keep the name and any JQL filter the admin may have set up
+	desired.Name = existing.Name
+	if len(existing.Filters) > 0 {
+		desired.Filters = existing.Filters
+	}
+	desired.Events = NewStringSet(existing.Events...).Add(jiraWebhookEvents...).Elems()
+	if err = p.doAdminAPITokenRequest(instance, http.MethodPut, existing.Self, desired, nil); err != nil {
+		return "", "", errors.WithMessage(err, "failed to update the Jira webhook")
+	}
+
+	return webhookRegistrationUpdated, existing.Self, nil
+}
+
+// refreshRegisteredWebhooks re-registers the webhooks that were previously registered by the
+// plugin, e.g. after the webhook secret is regenerated.
+func (p *Plugin) refreshRegisteredWebhooks() {
+	instances, err := p.instanceStore.LoadInstances()
+	if err != nil {
+		p.client.Log.Warn("Failed to load instances to refresh the Jira webhooks", "error", err.Error())
+		return
+	}
+
+	for _, instanceID := range instances.IDs() {
+		health, err := p.getWebhookHealth(instanceID)
+		if err != nil || health.RegisteredAt == 0 {
+			continue
+		}
+
+		instance, err := p.instanceStore.LoadInstance(instanceID)
+		if err != nil {
+			continue
+		}
+
+		action, err := p.registerSubscriptionsWebhook(instance)
+		if err != nil {
+			p.client.Log.Warn("Failed to refresh the Jira webhook", "instance", instanceID, "error", err.Error())
+			continue
+		}
+		if action != webhookRegistrationUnchanged {
+			p.client.Log.Info("Refreshed the Jira webhook", "instance", instanceID, "action", action)
+		}
+	}
+}
+
+// isQuiet returns true if no event was received for longer than the threshold, and the
+// sysadmins have not yet been notified about this period of silence.
+func (health *webhookHealth) isQuiet(now int64, threshold time.Duration) bool {
+	since := health.LastEventAt
+	if since == 0 {
+		// Only watch registered webhooks until the first event is received
+		since = health.RegisteredAt
+	}
+	if since == 0 || threshold == 0 {
+		return false
+	}
+
+	return now-since > threshold.Milliseconds() && health.QuietNotifiedAt < since
+}
+
+// checkWebhookHealth runs periodically on one server in the cluster. It repairs the webhooks
+// registered by the plugin, and DMs the sysadmins when an instance has gone quiet.
+func (p *Plugin) checkWebhookHealth() {
+	if p.canRegisterWebhooks() {
+		p.refreshRegisteredWebhooks()
+	}
+
+	threshold := p.getWebhookQuietThreshold()
+	if threshold == 0 {
+		return
+	}
+
+	instances, err := p.instanceStore.LoadInstances()
+	if err != nil {
+		p.client.Log.Warn("Failed to load instances to check the webhook health", "error", err.Error())
+		return
+	}
+
+	now := model.GetMillis()
+	for _, instanceID := range instances.IDs() {
+		health, err := p.getWebhookHealth(instanceID)
+		if err != nil || !health.isQuiet(now, threshold) {
+			continue
+		}
+
+		p.notifySysAdminsWebhookQuiet(instanceID, health, threshold)
+
+		err = p.updateWebhookHealth(instanceID, func(health *webhookHealth) {
+			health.QuietNotifiedAt = now
+		})
+		if err != nil {
+			p.client.Log.Warn("Failed to store the webhook health", "instance", instanceID, "error", err.Error())
+		}
+	}
+}
+
+func (p *Plugin) notifySysAdminsWebhookQuiet(instanceID types.ID, health *webhookHealth, threshold time.Duration) {
+	last := "No events have been received since the webhook was registered"
+	if health.LastEventAt != 0 {
+		last = fmt.Sprintf("The last event was received at %s", time.UnixMilli(health.LastEventAt).UTC().Format(time.RFC3339))
+	}
+
+	message := fmt.Sprintf("#### :warning: No Jira webhook events received from %s for more than %s\n"+
+		"%s. The Jira webhook may have been deleted, disabled, or configured with an outdated secret.\n"+
+		"Use `/jira instance status %s` to check the webhook health, and `/jira webhook register --instance=%s` to register it again.",
+		instanceID, threshold, last, instanceID, instanceID)
+
+	for page := 0; ; page++ {
+		admins, err := p.client.User.List(&model.UserGetOptions{
+			Role:     model.SystemAdminRoleId,
+			Page:     page,
+			PerPage:  100,
+			Inactive: false,
+		})
+		if err != nil {
+			p.client.Log.Warn("Failed to list the system administrators", "error", err.Error())
+			return
+		}
+
+		for _, admin := range admins {
+			if admin.IsBot || admin.DeleteAt != 0 {
+				continue
+			}
+			if _, err = p.CreateBotDMtoMMUserID(admin.Id, "%s", message); err != nil {
+				p.client.Log.Warn("Failed to notify a system administrator about a quiet Jira webhook", "user_id", admin.Id, "error", err.Error())
+			}
+		}
+
+		if len(admins) < 100 {
+			return
+		}
+	}
+}
+
+func formatWebhookHealth(instanceID types.ID, health *webhookHealth, threshold time.Duration, now int64) string {
+	formatTime := func(millis int64) string {
+		if millis == 0 {
+			return "never"
+		}
+		return fmt.Sprintf("%s (%s ago)", time.UnixMilli(millis).UTC().Format(time.RFC3339),
+			time.Duration(now-millis).Truncate(time.Minute))
+	}
+
+	status := ":white_check_mark: Healthy"
+	switch {
+	case health.LastEventAt == 0 && health.RegisteredAt == 0:
+		status = ":grey_question: Unknown, no events have been received yet"
+	case threshold != 0 && health.LastEventAt == 0 && now-health.RegisteredAt > threshold.Milliseconds(),
+		threshold != 0 && health.LastEventAt != 0 && now-health.LastEventAt > threshold.Milliseconds():
+		status = fmt.Sprintf(":warning: Quiet, no events received for more than %s", threshold)
+	}
+
+	registration := "not registered by the plugin, use `/jira webhook register` to register it"
+	if health.RegisteredAt != 0 {
+		registration = "registered at " + formatTime(health.RegisteredAt)
+	}
+
+	rows := []string{
+		fmt.Sprintf("#### Webhook health for instance %s", instanceID),
+		"* Status: " + status,
+		"* Last event received: " + formatTime(health.LastEventAt),
+		"* Jira webhook: " + registration,
+	}
+	if health.RegistrationError != "" {
+		rows = append(rows, "* Last registration error: "+health.RegistrationError)
+	}
+
+	return strings.Join(rows, "\n")
+}
+
+func executeInstanceStatus(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return p.responsef(header, "`/jira instance status` can only be run by a system administrator.")
+	}
+
+	jiraURL, args, err := p.parseCommandFlagInstanceURL(args)
+	if err != nil {
+		return p.responsef(header, "%v", err)
+	}
+	switch {
+	case len(args) == 1 && jiraURL == "":
+		jiraURL = args[0]
+	case len(args) != 0:
+		return p.responsef(header, "Please use `/jira instance status [jiraURL]`.")
+	}
+
+	instanceID, err := p.ResolveWebhookInstanceURL(jiraURL)
+	if err != nil {
+		return p.response(header, err.Error())
+	}
+	instance, err := p.instanceStore.LoadInstance(instanceID)
+	if err != nil {
+		return p.response(header, err.Error())
+	}
+
+	health, err := p.getWebhookHealth(instanceID)
+	if err != nil {
+		return p.response(header, err.Error())
+	}
+
+	text := fmt.Sprintf("Instance %s, type %s.\n", instanceID, instance.Common().Type) +
+		formatWebhookHealth(instanceID, health, p.getWebhookQuietThreshold(), model.GetMillis())
+	return p.response(header, text)
+}
+
+func executeWebhookRegister(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	instanceID, args, resp := p.resolveWebhookCommandInstance(header, args)
+	if resp != nil {
+		return resp
+	}
+	if len(args) != 0 {
+		return p.responsef(header, "Please use `/jira webhook register [--instance=<jiraURL>]`.")
+	}
+
+	instance, err := p.instanceStore.LoadInstance(instanceID)
+	if err != nil {
+		return p.response(header, err.Error())
+	}
+
+	action, err := p.registerSubscriptionsWebhook(instance)
+	if err != nil {
+		return p.responsef(header, "Failed to register the Jira webhook for instance %s. Error: %v.\nUse `/jira webhook` to set it up manually.", instanceID, err)
+	}
+
+	switch action {
+	case webhookRegistrationCreated:
+		return p.responsef(header, "The subscriptions webhook was created in Jira instance %s.", instanceID)
+	case webhookRegistrationUpdated:
+		return p.responsef(header, "The subscriptions webhook in Jira instance %s was updated.", instanceID)
+	default:
+		return p.responsef(header, "The subscriptions webhook in Jira instance %s is already up to date.", instanceID)
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+func TestJiraWebhookNeedsUpdate(t *testing.T) {
+	const webhookURL = "https://mm.example.com/plugins/jira/api/v2/webhook?secret=new"
+
+	for name, tc := range map[string]struct {
+		webhook  jiraWebhook
+		expected bool
+	}{
+		"up to date": {
+			webhook:  jiraWebhook{URL: webhookURL, Events: jiraWebhookEvents, Enabled: true},
+			expected: false,
+		},
+		"disabled": {
+			webhook:  jiraWebhook{URL: webhookURL, Events: jiraWebhookEvents},
+			expected: true,
+		},
+		"outdated secret": {
+			webhook:  jiraWebhook{URL: "https://mm.example.com/plugins/jira/api/v2/webhook?secret=old", Events: jiraWebhookEvents, Enabled: true},
+			expected: true,
+		},
+		"missing events": {
+			webhook:  jiraWebhook{URL: webhookURL, Events: []string{"jira:issue_created"}, Enabled: true},
+			expected: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.webhook.needsUpdate(webhookURL))
+		})
+	}
+
+	assert.True(t, isSameWebhookEndpoint(webhookURL, "https://MM.example.com/plugins/jira/api/v2/webhook/?secret=old"))
+	assert.False(t, isSameWebhookEndpoint(webhookURL, "https://mm.example.com/plugins/jira/api/v2/other?secret=new"))
+}
+
+func TestWebhookHealthIsQuiet(t *testing.T) {
+	now := time.Now().UnixMilli()
+	hour := time.Hour.Milliseconds()
+
+	for name, tc := range map[string]struct {
+		health   webhookHealth
+		expected bool
+	}{
+		"never received nor registered": {
+			health: webhookHealth{},
+		},
+		"recent event": {
+			health: webhookHealth{LastEventAt: now - hour},
+		},
+		"quiet": {
+			health:   webhookHealth{LastEventAt: now - 25*hour},
+			expected: true,
+		},
+		"already notified": {
+			health: webhookHealth{LastEventAt: now - 25*hour, QuietNotifiedAt: now - hour},
+		},
+		"registered without events": {
+			health:   webhookHealth{RegisteredAt: now - 25*hour},
+			expected: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.health.isQuiet(now, 24*time.Hour))
+		})
+	}
+
+	assert.False(t, (&webhookHealth{LastEventAt: now - 25*hour}).isQuiet(now, 0))
+}
+
+func TestRegisterSubscriptionsWebhook(t *testing.T) {
+	var registered []jiraWebhook
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _, ok := r.BasicAuth()
+		if !ok || user != "admin@example.com" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			_ = json.NewEncoder(w).Encode(registered)
+		case http.MethodPost:
+			wh := jiraWebhook{}
+			_ = json.NewDecoder(r.Body).Decode(&wh)
+			wh.Self = "http://" + r.Host + jiraWebhookAPIPath + "/1"
+			registered = append(registered, wh)
+			_ = json.NewEncoder(w).Encode(wh)
+		case http.MethodPut:
+			wh := jiraWebhook{}
+			_ = json.NewDecoder(r.Body).Decode(&wh)
+			wh.Self = registered[0].Self
+			registered[0] = wh
+		}
+	}))
+	defer ts.Close()
+
+	api := &plugintest.API{}
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+	makeTestKVStore(api, nil)
+
+	encryptionKey := "0123456789abcdef0123456789abcdef"
+	token, err := json.Marshal("admin-token")
+	require.NoError(t, err)
+	encryptedToken, err := encrypt(token, []byte(encryptionKey))
+	require.NoError(t, err)
+
+	p.updateConfig(func(conf *config) {
+		conf.Secret = "secret1"
+		conf.EncryptionKey = encryptionKey
+		conf.AdminAPIToken = string(encryptedToken)
+		conf.AdminEmail = "admin@example.com"
+		conf.mattermostSiteURL = "https://mm.example.com"
+	})
+
+	instance := &testInstance{InstanceCommon: InstanceCommon{InstanceID: types.ID(ts.URL)}}
+
+	action, err := p.registerSubscriptionsWebhook(instance)
+	require.NoError(t, err)
+	assert.Equal(t, webhookRegistrationCreated, action)
+	require.Len(t, registered, 1)
+	assert.Equal(t, p.getSubscriptionsWebhookURL(instance.InstanceID), registered[0].URL)
+
+	action, err = p.registerSubscriptionsWebhook(instance)
+	require.NoError(t, err)
+	assert.Equal(t, webhookRegistrationUnchanged, action)
+
+	p.updateConfig(func(conf *config) {
+		conf.Secret = "secret2"
+	})
+	registered[0].Enabled = false
+
+	action, err = p.registerSubscriptionsWebhook(instance)
+	require.NoError(t, err)
+	assert.Equal(t, webhookRegistrationUpdated, action)
+	require.Len(t, registered, 1)
+	assert.True(t, registered[0].Enabled)
+	assert.Equal(t, p.getSubscriptionsWebhookURL(instance.InstanceID), registered[0].URL)
+
+	health, err := p.getWebhookHealth(instance.InstanceID)
+	require.NoError(t, err)
+	assert.NotZero(t, health.RegisteredAt)
+	assert.Equal(t, registered[0].Self, health.JiraWebhookSelf)
+	assert.Empty(t, health.RegistrationError)
+}
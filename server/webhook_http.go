@@ -104,7 +104,7 @@ func (p *Plugin) httpWebhook(w http.ResponseWriter, r *http.Request, instanceID
 	}
 
 	// Post the event to the channel
-	_, statusCode, err := wh.PostToChannel(p, instanceID, channel.Id, p.getUserID(), "")
+	_, statusCode, err := wh.PostToChannel(p, instanceID, channel.Id, p.getUserID(), nil)
 	if err != nil {
 		return respondErr(w, statusCode, err)
 	}
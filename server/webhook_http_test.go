@@ -42,8 +42,8 @@ func (wh testWebhookWrapper) Events() StringSet {
 	return wh.Webhook.Events()
 }
 
-func (wh *testWebhookWrapper) PostToChannel(p *Plugin, instanceID types.ID, channelID, fromUserID, subscriptionName string) (*model.Post, int, error) {
-	post, status, err := wh.Webhook.PostToChannel(p, "", channelID, fromUserID, subscriptionName)
+func (wh *testWebhookWrapper) PostToChannel(p *Plugin, instanceID types.ID, channelID, fromUserID string, subscription *ChannelSubscription) (*model.Post, int, error) {
+	post, status, err := wh.Webhook.PostToChannel(p, "", channelID, fromUserID, subscription)
 	if post != nil {
 		wh.postedToChannel = post
 	}
@@ -0,0 +1,452 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+
+	"github.com/mattermost/mattermost-plugin-jira/server/utils/types"
+)
+
+const (
+	webhookEventKeyFormat     = "webhook_event_%d"
+	webhookEventBodyKeyFormat = "webhook_event_body_%d"
+
+	maxWebhookEventInspectorSize = 200
+
+	// The bodies of larger events are not kept, they can not be replayed
+	maxWebhookEventBodySize = 256 * 1024
+
+	// webhookEventTTL is how long the recorded events are kept. The events hold the issue data
+	// Jira sends, so the slots left over when the inspector is made smaller or disabled must
+	// not be kept forever.
+	webhookEventTTL = 7 * 24 * time.Hour
+
+	// maxRecordedRejections is the number of rejected subscriptions kept per event, the
+	// others are only counted
+	maxRecordedRejections = 20
+
+	webhookEventOutcomeProcessed   = "processed"
+	webhookEventOutcomeIgnored     = "ignored"
+	webhookEventOutcomeUnsupported = "unsupported"
+	webhookEventOutcomeFailed      = "failed"
+)
+
+// webhookEventRecord describes what happened to a webhook event received from Jira. The
+// raw body is stored separately so that listing events stays cheap.
+type webhookEventRecord struct {
+	Seq                  int64             `json:"seq"`
+	ReceivedAt           int64             `json:"received_at"`
+	ReplayOf             int64             `json:"replay_of,omitempty"`
+	Size                 int               `json:"size"`
+	WebhookEvent         string            `json:"webhook_event,omitempty"`
+	IssueKey             string            `json:"issue_key,omitempty"`
+	Events               []string          `json:"events,omitempty"`
+	Outcome              string            `json:"outcome"`
+	Error                string            `json:"error,omitempty"`
+	MatchedSubscriptions []string          `json:"matched_subscriptions,omitempty"`
+	RejectedCount        int               `json:"rejected_count,omitempty"`
+	RejectedFilters      map[string]string `json:"rejected_filters,omitempty"`
+	PostIDs              []string          `json:"post_ids,omitempty"`
+}
+
+type webhookEventBody struct {
+	Seq  int64  `json:"seq"`
+	Data []byte `json:"data"`
+}
+
+// webhookEventInspector allocates the IDs of the webhook events, and the slots of the
+// per-instance ring buffers where they are stored. Each slot is a separate key written
+// without a compare-and-set, so recording events does not contend on a single key. The ring
+// positions are kept in memory, so in a cluster the nodes may overwrite each other's recent
+// events.
+type webhookEventInspector struct {
+	lock    sync.Mutex
+	lastSeq int64
+	next    map[types.ID]int
+}
+
+func (i *webhookEventInspector) nextSeq(now int64) int64 {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	// Time based, so that the IDs stay unique across restarts and nodes
+	i.lastSeq++
+	if now > i.lastSeq {
+		i.lastSeq = now
+	}
+	return i.lastSeq
+}
+
+// nextSlot returns the slot of the next event of the instance. The first time, it starts
+// from the oldest stored event, using load to read the stored events.
+func (i *webhookEventInspector) nextSlot(instanceID types.ID, size int, load func() []*webhookEventRecord) int {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+
+	slot, ok := i.next[instanceID]
+	if !ok {
+		slot = oldestWebhookEventSlot(load())
+	}
+	if slot >= size {
+		slot = 0
+	}
+	if i.next == nil {
+		i.next = map[types.ID]int{}
+	}
+	i.next[instanceID] = (slot + 1) % size
+	return slot
+}
+
+func oldestWebhookEventSlot(slots []*webhookEventRecord) int {
+	oldest := 0
+	for slot, record := range slots {
+		if record == nil {
+			return slot
+		}
+		if record.Seq < slots[oldest].Seq {
+			oldest = slot
+		}
+	}
+	return oldest
+}
+
+func newWebhookEventRecord(msg *webhookMessage) *webhookEventRecord {
+	record := &webhookEventRecord{
+		ReceivedAt: model.GetMillis(),
+		ReplayOf:   msg.ReplayOf,
+		Size:       len(msg.Data),
+	}
+
+	// Best effort, so that events which fail to parse can still be identified
+	raw := struct {
+		WebhookEvent string `json:"webhookEvent"`
+		Issue        struct {
+			Key string `json:"key"`
+		} `json:"issue"`
+	}{}
+	if err := json.Unmarshal(msg.Data, &raw); err == nil {
+		record.WebhookEvent = raw.WebhookEvent
+		record.IssueKey = raw.Issue.Key
+	}
+
+	return record
+}
+
+func (r *webhookEventRecord) addPosts(posts ...*model.Post) {
+	for _, post := range posts {
+		if post != nil {
+			r.PostIDs = append(r.PostIDs, post.Id)
+		}
+	}
+}
+
+// setRejections counts the subscriptions that rejected the event, and keeps the reasons of
+// the first maxRecordedRejections, sorted by ID, so that the record stays small.
+func (r *webhookEventRecord) setRejections(rejected map[string]string) {
+	r.RejectedCount = len(rejected)
+	if len(rejected) <= maxRecordedRejections {
+		r.RejectedFilters = rejected
+		return
+	}
+
+	ids := make([]string, 0, len(rejected))
+	for id := range rejected {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	r.RejectedFilters = make(map[string]string, maxRecordedRejections)
+	for _, id := range ids[:maxRecordedRejections] {
+		r.RejectedFilters[id] = rejected[id]
+	}
+}
+
+func (r *webhookEventRecord) rejectedCount() int {
+	if r.RejectedCount > len(r.RejectedFilters) {
+		return r.RejectedCount
+	}
+	return len(r.RejectedFilters)
+}
+
+func (r *webhookEventRecord) String() string {
+	summary := fmt.Sprintf("`%d` %s **%s** %s - %s",
+		r.Seq, time.UnixMilli(r.ReceivedAt).UTC().Format(time.RFC3339), r.WebhookEvent, r.IssueKey, r.Outcome)
+	if r.ReplayOf != 0 {
+		summary += fmt.Sprintf(" (replay of `%d`)", r.ReplayOf)
+	}
+	return fmt.Sprintf("%s; matched %d, rejected %d, posts %d",
+		summary, len(r.MatchedSubscriptions), r.rejectedCount(), len(r.PostIDs))
+}
+
+// getWebhookEventInspectorSize returns the number of events kept per instance. The
+// inspector is disabled unless configured.
+func (p *Plugin) getWebhookEventInspectorSize() int {
+	size, err := strconv.Atoi(p.getConfig().WebhookEventInspectorSize)
+	if err != nil || size < 0 {
+		return 0
+	}
+	if size > maxWebhookEventInspectorSize {
+		return maxWebhookEventInspectorSize
+	}
+
+	return size
+}
+
+func webhookEventKey(instanceID types.ID, slot int) string {
+	return keyWithInstanceID(instanceID, types.ID(fmt.Sprintf(webhookEventKeyFormat, slot)))
+}
+
+func webhookEventBodyKey(instanceID types.ID, slot int) string {
+	return keyWithInstanceID(instanceID, types.ID(fmt.Sprintf(webhookEventBodyKeyFormat, slot)))
+}
+
+// recordWebhookEvent stores the record in the next slot of the instance's ring buffer,
+// replacing the oldest event when full, and the raw body in the matching body slot. Both
+// expire after webhookEventTTL.
+func (p *Plugin) recordWebhookEvent(instanceID types.ID, record *webhookEventRecord, data []byte) error {
+	size := p.getWebhookEventInspectorSize()
+	if size == 0 {
+		return nil
+	}
+
+	record.Seq = p.webhookEvents.nextSeq(record.ReceivedAt)
+	slot := p.webhookEvents.nextSlot(instanceID, size, func() []*webhookEventRecord {
+		return p.loadWebhookEventSlots(instanceID, size)
+	})
+
+	// The body is stored first, so that a listed event can be replayed
+	body := &webhookEventBody{Seq: record.Seq}
+	if len(data) <= maxWebhookEventBodySize {
+		body.Data = data
+	}
+	if _, err := p.client.KV.Set(webhookEventBodyKey(instanceID, slot), body, pluginapi.SetExpiry(webhookEventTTL)); err != nil {
+		return errors.Wrap(err, "failed to store webhook event body")
+	}
+
+	if _, err := p.client.KV.Set(webhookEventKey(instanceID, slot), record, pluginapi.SetExpiry(webhookEventTTL)); err != nil {
+		return errors.Wrap(err, "failed to store webhook event record")
+	}
+
+	return nil
+}
+
+// loadWebhookEventSlots returns the event stored in each slot, nil for the empty slots.
+func (p *Plugin) loadWebhookEventSlots(instanceID types.ID, size int) []*webhookEventRecord {
+	slots := make([]*webhookEventRecord, size)
+	for slot := range slots {
+		var record *webhookEventRecord
+		if err := p.client.KV.Get(webhookEventKey(instanceID, slot), &record); err != nil {
+			p.client.Log.Debug("Failed to load a webhook event", "instance", instanceID.String(), "slot", slot, "error", err.Error())
+			continue
+		}
+		if record != nil && record.Seq != 0 {
+			slots[slot] = record
+		}
+	}
+	return slots
+}
+
+// getWebhookEvents returns the stored events of the instance, from oldest to newest.
+func (p *Plugin) getWebhookEvents(instanceID types.ID) []*webhookEventRecord {
+	events := []*webhookEventRecord{}
+	for _, record := range p.loadWebhookEventSlots(instanceID, p.getWebhookEventInspectorSize()) {
+		if record != nil {
+			events = append(events, record)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Seq < events[j].Seq
+	})
+	return events
+}
+
+// findWebhookEvent returns the event and its slot.
+func (p *Plugin) findWebhookEvent(instanceID types.ID, seq int64) (*webhookEventRecord, int, error) {
+	for slot, record := range p.loadWebhookEventSlots(instanceID, p.getWebhookEventInspectorSize()) {
+		if record != nil && record.Seq == seq {
+			return record, slot, nil
+		}
+	}
+	return nil, 0, errors.Errorf("webhook event %d is no longer available", seq)
+}
+
+func (p *Plugin) getWebhookEventRecord(instanceID types.ID, seq int64) (*webhookEventRecord, error) {
+	record, _, err := p.findWebhookEvent(instanceID, seq)
+	return record, err
+}
+
+func (p *Plugin) getWebhookEventBody(instanceID types.ID, seq int64) ([]byte, error) {
+	if p.getWebhookEventInspectorSize() == 0 {
+		return nil, errors.New("the webhook event inspector is disabled")
+	}
+
+	record, slot, err := p.findWebhookEvent(instanceID, seq)
+	if err != nil {
+		return nil, err
+	}
+
+	body := webhookEventBody{}
+	if err := p.client.KV.Get(webhookEventBodyKey(instanceID, slot), &body); err != nil {
+		return nil, err
+	}
+	if body.Seq != seq {
+		return nil, errors.Errorf("the body of webhook event %d is no longer available", seq)
+	}
+	if len(body.Data) == 0 {
+		return nil, errors.Errorf("the body of webhook event %d was not kept, its %d bytes are over the limit of %d", seq, record.Size, maxWebhookEventBodySize)
+	}
+
+	return body.Data, nil
+}
+
+// replayWebhookEvent processes a recorded webhook event again, synchronously, as if it was
+// just received from Jira.
+func (p *Plugin) replayWebhookEvent(instanceID types.ID, seq int64) error {
+	data, err := p.getWebhookEventBody(instanceID, seq)
+	if err != nil {
+		return err
+	}
+
+	return webhookWorker{p: p}.process(&webhookMessage{
+		InstanceID: instanceID,
+		Data:       data,
+		ReplayOf:   seq,
+	})
+}
+
+func (p *Plugin) resolveWebhookCommandInstance(header *model.CommandArgs, args []string) (types.ID, []string, *model.CommandResponse) {
+	authorized, err := authorizedSysAdmin(p, header.UserId)
+	if err != nil {
+		return "", nil, p.responsef(header, "%v", err)
+	}
+	if !authorized {
+		return "", nil, p.responsef(header, "`/jira webhook` can only be run by a system administrator.")
+	}
+
+	jiraURL, args, err := p.parseCommandFlagInstanceURL(args)
+	if err != nil {
+		return "", nil, p.responsef(header, "%v", err)
+	}
+
+	instanceID, err := p.ResolveWebhookInstanceURL(jiraURL)
+	if err != nil {
+		return "", nil, p.response(header, err.Error())
+	}
+
+	return instanceID, args, nil
+}
+
+func executeWebhookEvents(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	instanceID, args, resp := p.resolveWebhookCommandInstance(header, args)
+	if resp != nil {
+		return resp
+	}
+
+	if len(args) > 1 {
+		return p.responsef(header, "Please use `/jira webhook events [event-id]`.")
+	}
+
+	if len(args) == 1 {
+		seq, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return p.responsef(header, "Invalid webhook event ID `%s`.", args[0])
+		}
+
+		record, err := p.getWebhookEventRecord(instanceID, seq)
+		if err != nil {
+			return p.response(header, err.Error())
+		}
+
+		return p.response(header, formatWebhookEventRecord(record))
+	}
+
+	events := p.getWebhookEvents(instanceID)
+	if len(events) == 0 {
+		if p.getWebhookEventInspectorSize() == 0 {
+			return p.response(header, "The webhook event inspector is disabled. Set **Webhook Event Inspector Size** in the plugin settings to enable it.")
+		}
+		return p.responsef(header, "No webhook events have been received for instance %s.", instanceID)
+	}
+
+	rows := []string{fmt.Sprintf("#### Recent webhook events for instance %s", instanceID)}
+	for i := len(events) - 1; i >= 0; i-- {
+		rows = append(rows, "* "+events[i].String())
+	}
+	rows = append(rows, "", "Use `/jira webhook events <id>` for details and `/jira webhook replay <id>` to process an event again.")
+
+	return p.response(header, strings.Join(rows, "\n"))
+}
+
+func formatWebhookEventRecord(record *webhookEventRecord) string {
+	rows := []string{
+		fmt.Sprintf("#### Webhook event `%d`", record.Seq),
+		"* " + record.String(),
+		fmt.Sprintf("* Size: %d bytes", record.Size),
+	}
+
+	if len(record.Events) > 0 {
+		rows = append(rows, "* Parsed events: "+strings.Join(record.Events, ", "))
+	}
+	if record.Error != "" {
+		rows = append(rows, "* Error: "+record.Error)
+	}
+	if len(record.MatchedSubscriptions) > 0 {
+		rows = append(rows, "* Matched subscriptions: "+strings.Join(record.MatchedSubscriptions, ", "))
+	}
+	if len(record.RejectedFilters) > 0 {
+		if record.rejectedCount() > len(record.RejectedFilters) {
+			rows = append(rows, fmt.Sprintf("* Rejected subscriptions, first %d of %d:", len(record.RejectedFilters), record.rejectedCount()))
+		} else {
+			rows = append(rows, "* Rejected subscriptions:")
+		}
+		ids := make([]string, 0, len(record.RejectedFilters))
+		for id := range record.RejectedFilters {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			rows = append(rows, fmt.Sprintf("  * %s: %s", id, record.RejectedFilters[id]))
+		}
+	}
+	if len(record.PostIDs) > 0 {
+		rows = append(rows, "* Posts: "+strings.Join(record.PostIDs, ", "))
+	}
+
+	return strings.Join(rows, "\n")
+}
+
+func executeWebhookReplay(p *Plugin, c *plugin.Context, header *model.CommandArgs, args ...string) *model.CommandResponse {
+	instanceID, args, resp := p.resolveWebhookCommandInstance(header, args)
+	if resp != nil {
+		return resp
+	}
+
+	if len(args) != 1 {
+		return p.responsef(header, "Please use `/jira webhook replay <event-id>`.")
+	}
+
+	seq, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return p.responsef(header, "Invalid webhook event ID `%s`.", args[0])
+	}
+
+	if err = p.replayWebhookEvent(instanceID, seq); err != nil {
+		return p.responsef(header, "Failed to replay webhook event `%d`. Error: %v.", seq, err)
+	}
+
+	return p.responsef(header, "Webhook event `%d` was replayed. Use `/jira webhook events` to view the outcome.", seq)
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest/mock"
+	"github.com/mattermost/mattermost/server/public/pluginapi"
+)
+
+func TestNewWebhookEventRecord(t *testing.T) {
+	record := newWebhookEventRecord(&webhookMessage{
+		Data:     []byte(`{"webhookEvent":"jira:issue_created","issue":{"key":"TEST-10"}}`),
+		ReplayOf: 4,
+	})
+
+	assert.Equal(t, "jira:issue_created", record.WebhookEvent)
+	assert.Equal(t, "TEST-10", record.IssueKey)
+	assert.Equal(t, int64(4), record.ReplayOf)
+	assert.NotZero(t, record.ReceivedAt)
+
+	record = newWebhookEventRecord(&webhookMessage{Data: []byte("not json")})
+	assert.Empty(t, record.WebhookEvent)
+	assert.Equal(t, 8, record.Size)
+}
+
+func TestWebhookEventInspectorSize(t *testing.T) {
+	for value, expected := range map[string]int{
+		"":      0,
+		"0":     0,
+		"5":     5,
+		"-1":    0,
+		"many":  0,
+		"10000": maxWebhookEventInspectorSize,
+	} {
+		p := &Plugin{}
+		p.updateConfig(func(conf *config) {
+			conf.WebhookEventInspectorSize = value
+		})
+		assert.Equal(t, expected, p.getWebhookEventInspectorSize(), value)
+	}
+}
+
+func TestRecordWebhookEventExpiry(t *testing.T) {
+	api := &plugintest.API{}
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+	p.updateConfig(func(conf *config) {
+		conf.WebhookEventInspectorSize = "2"
+	})
+	api.On("KVGet", mock.AnythingOfType("string")).Return(nil, nil)
+	api.On("KVSetWithOptions", mock.AnythingOfType("string"), mock.Anything, mock.MatchedBy(func(opts model.PluginKVSetOptions) bool {
+		return opts.ExpireInSeconds == int64(webhookEventTTL/time.Second)
+	})).Return(true, nil).Twice()
+
+	err := p.recordWebhookEvent(testInstance1.InstanceID, &webhookEventRecord{ReceivedAt: 1000}, []byte("body"))
+	require.NoError(t, err)
+	api.AssertExpectations(t)
+}
+
+func TestRecordWebhookEvent(t *testing.T) {
+	api := &plugintest.API{}
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+	kv := makeTestKVStore(api, nil)
+	p.updateConfig(func(conf *config) {
+		conf.WebhookEventInspectorSize = "2"
+	})
+
+	for i := 0; i < 3; i++ {
+		record := &webhookEventRecord{ReceivedAt: 1000, Outcome: webhookEventOutcomeProcessed}
+		err := p.recordWebhookEvent(testInstance1.InstanceID, record, []byte(fmt.Sprintf("body %d", i)))
+		require.NoError(t, err)
+		assert.Equal(t, int64(1000+i), record.Seq)
+	}
+
+	events := p.getWebhookEvents(testInstance1.InstanceID)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(1001), events[0].Seq)
+	assert.Equal(t, int64(1002), events[1].Seq)
+
+	body, err := p.getWebhookEventBody(testInstance1.InstanceID, 1002)
+	require.NoError(t, err)
+	assert.Equal(t, "body 2", string(body))
+
+	_, err = p.getWebhookEventBody(testInstance1.InstanceID, 1000)
+	require.EqualError(t, err, "webhook event 1000 is no longer available")
+
+	_, err = p.getWebhookEventRecord(testInstance1.InstanceID, 1000)
+	require.EqualError(t, err, "webhook event 1000 is no longer available")
+
+	// After a restart, the oldest event is replaced first
+	restarted := &Plugin{}
+	restarted.SetAPI(api)
+	restarted.client = p.client
+	restarted.updateConfig(func(conf *config) {
+		conf.WebhookEventInspectorSize = "2"
+	})
+	record := &webhookEventRecord{ReceivedAt: 2000, Outcome: webhookEventOutcomeProcessed}
+	require.NoError(t, restarted.recordWebhookEvent(testInstance1.InstanceID, record, make([]byte, maxWebhookEventBodySize+1)))
+
+	events = restarted.getWebhookEvents(testInstance1.InstanceID)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(1002), events[0].Seq)
+	assert.Equal(t, int64(2000), events[1].Seq)
+
+	// The bodies over the limit are not kept
+	_, err = restarted.getWebhookEventBody(testInstance1.InstanceID, 2000)
+	require.Error(t, err)
+	for _, data := range kv {
+		assert.Less(t, len(data), maxWebhookEventBodySize)
+	}
+}
+
+func TestRecordWebhookEventDisabled(t *testing.T) {
+	api := &plugintest.API{}
+	p := &Plugin{}
+	p.SetAPI(api)
+	p.client = pluginapi.NewClient(p.API, p.Driver)
+
+	// No KV call is mocked, nothing is stored by default
+	require.NoError(t, p.recordWebhookEvent(testInstance1.InstanceID, &webhookEventRecord{}, []byte("body")))
+}
+
+func TestWebhookEventRecordSetRejections(t *testing.T) {
+	rejected := map[string]string{}
+	for i := 0; i < maxRecordedRejections+5; i++ {
+		rejected[fmt.Sprintf("sub%02d", i)] = "filtered"
+	}
+
+	record := &webhookEventRecord{}
+	record.setRejections(rejected)
+	assert.Equal(t, maxRecordedRejections+5, record.RejectedCount)
+	assert.Len(t, record.RejectedFilters, maxRecordedRejections)
+	assert.Contains(t, record.RejectedFilters, "sub00")
+	assert.NotContains(t, record.RejectedFilters, fmt.Sprintf("sub%02d", maxRecordedRejections))
+	assert.Contains(t, record.String(), fmt.Sprintf("rejected %d", maxRecordedRejections+5))
+	assert.Contains(t, formatWebhookEventRecord(record), fmt.Sprintf("first %d of %d", maxRecordedRejections, maxRecordedRejections+5))
+}
@@ -153,6 +153,10 @@ func parseWebhookChangeLog(jwh *JiraWebhook) Webhook {
 			event = parseWebhookUpdatedField(jwh, eventUpdatedRank, field, fieldID, strings.ToLower(fromWithDefault), strings.ToLower(toWithDefault))
 		case field == "Attachment":
 			event = parseWebhookUpdatedAttachments(jwh, from, to, fromWithDefault, toWithDefault)
+			if item.To != "" {
+				// The changelog holds the ID of the added attachment
+				event.attachmentIDs = []string{item.To}
+			}
 		case field == labelsField:
 			event = parseWebhookUpdatedLabels(jwh, from, to, fromWithDefault, toWithDefault)
 		case field == "assignee":
@@ -596,6 +600,7 @@ func mergeWebhookEvents(events []*webhook) Webhook {
 
 	for _, event := range events {
 		merged.eventTypes = merged.eventTypes.Union(event.eventTypes)
+		merged.attachmentIDs = append(merged.attachmentIDs, event.attachmentIDs...)
 		strike := "~~"
 		if event.fieldInfo.name == descriptionField || strings.HasPrefix(event.fieldInfo.from, strike) {
 			strike = ""
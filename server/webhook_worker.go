@@ -18,6 +18,9 @@ type webhookWorker struct {
 type webhookMessage struct {
 	InstanceID types.ID
 	Data       []byte
+
+	// ReplayOf is the ID of the inspected webhook event being replayed, if any.
+	ReplayOf int64
 }
 
 func (ww webhookWorker) work() {
@@ -34,19 +37,37 @@ func (ww webhookWorker) work() {
 }
 
 func (ww webhookWorker) process(msg *webhookMessage) (err error) {
+	record := newWebhookEventRecord(msg)
 	defer func() {
-		if errors.Is(err, ErrWebhookIgnored) {
+		switch {
+		case err == nil:
+			record.Outcome = webhookEventOutcomeProcessed
+		case errors.Is(err, ErrWebhookIgnored):
 			// ignore ErrWebhookIgnored - from here up it's a success
+			record.Outcome = webhookEventOutcomeIgnored
 			err = nil
+		case errors.Is(err, errWebhookeventUnsupported):
+			record.Outcome = webhookEventOutcomeUnsupported
+			record.Error = err.Error()
+		default:
+			record.Outcome = webhookEventOutcomeFailed
+			record.Error = err.Error()
+		}
+
+		if recordErr := ww.p.recordWebhookEvent(msg.InstanceID, record, msg.Data); recordErr != nil {
+			ww.p.debugf("WebhookWorker id: %d, failed to record webhook event, err: %v", ww.id, recordErr)
 		}
 	}()
 
+	ww.p.invalidateMetadataForWebhookEvent(msg.InstanceID, record.WebhookEvent)
+
 	wh, err := ParseWebhook(msg.Data)
 	if err != nil {
 		return err
 	}
 
 	v := wh.(*webhook)
+	record.Events = v.Events().Elems()
 
 	if isStandaloneCommentEvent(v.JiraWebhook) {
 		instance, loadErr := ww.p.instanceStore.LoadInstance(msg.InstanceID)
@@ -65,17 +86,25 @@ func (ww webhookWorker) process(msg *webhookMessage) (err error) {
 	ww.p.checkIssueWatchers(v, msg.InstanceID)
 	ww.p.applyReporterNotification(v, msg.InstanceID, v.Issue.Fields.Reporter)
 
-	if _, _, err = wh.PostNotifications(ww.p, msg.InstanceID); err != nil {
+	notificationPosts, _, err := wh.PostNotifications(ww.p, msg.InstanceID)
+	if err != nil {
 		ww.p.errorf("WebhookWorker id: %d, error posting notifications, err: %v", ww.id, err)
 	}
+	record.addPosts(notificationPosts...)
+
+	record.addPosts(ww.p.postPersonalSubscriptions(v, msg.InstanceID)...)
 
-	channelsSubscribed, err := ww.p.getChannelsSubscribed(v, msg.InstanceID)
+	channelsSubscribed, rejected, err := ww.p.getChannelsSubscribedWithRejections(v, msg.InstanceID)
 	if err != nil {
 		return err
 	}
+	record.setRejections(rejected)
 
 	botUserID := ww.p.getUserID()
+	attachmentThreads := []attachmentThread{}
 	for _, channelSubscribed := range channelsSubscribed {
+		record.MatchedSubscriptions = append(record.MatchedSubscriptions, channelSubscribed.ID)
+
 		channel, err := ww.p.client.Channel.Get(channelSubscribed.ChannelID)
 		if err != nil {
 			ww.p.client.Log.Warn("Error occurred while getting the channel details while posting the webhook event", "ChannelID", channelSubscribed.ChannelID, "Error", err.Error())
@@ -86,10 +115,24 @@ func (ww webhookWorker) process(msg *webhookMessage) (err error) {
 			continue
 		}
 
-		if _, _, err1 := wh.PostToChannel(ww.p, msg.InstanceID, channelSubscribed.ChannelID, botUserID, channelSubscribed.Name); err1 != nil {
+		post, _, err1 := wh.PostToChannel(ww.p, msg.InstanceID, channelSubscribed.ChannelID, botUserID, &channelSubscribed)
+		if err1 != nil {
 			ww.p.errorf("WebhookWorker id: %d, error posting to channel, err: %v", ww.id, err1)
+			continue
+		}
+		record.addPosts(post)
+
+		if channelSubscribed.PostAttachments && len(v.attachmentIDs) > 0 {
+			attachmentThreads = append(attachmentThreads, attachmentThread{
+				ChannelID: post.ChannelId,
+				RootID:    ww.p.issueThreadRootID(v.Issue.ID, post),
+			})
 		}
 	}
 
+	if len(attachmentThreads) > 0 {
+		go ww.p.postIssueAttachments(v, msg.InstanceID, botUserID, attachmentThreads)
+	}
+
 	return nil
 }
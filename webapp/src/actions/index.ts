@@ -50,11 +50,12 @@ export const closeDisconnectModal = () => {
     };
 };
 
-export const openCreateModal = (postId: string) => {
+export const openCreateModal = (postId: string, includeThread = false) => {
     return {
         type: ActionTypes.OPEN_CREATE_ISSUE_MODAL,
         data: {
             postId,
+            includeThread,
         },
     };
 };
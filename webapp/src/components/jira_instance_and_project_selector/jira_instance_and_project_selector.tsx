@@ -33,6 +33,9 @@ export type Props = {
     fetchJiraProjectMetadata: (instanceID: string) => Promise<APIResponse<ProjectMetadata>>;
     getConnected: () => Promise<GetConnectedResponse>;
     hideProjectSelector?: boolean;
+
+    // projectOptional lets the project be left empty, without selecting the last used project.
+    projectOptional?: boolean;
 };
 
 type State = {
@@ -105,7 +108,7 @@ export default class JiraInstanceAndProjectSelector extends React.PureComponent<
             fetchingProjectMetadata: false,
         });
 
-        if (projectMetadata.saved_field_values && projectMetadata.saved_field_values.project_key && !this.props.selectedProjectID) {
+        if (projectMetadata.saved_field_values && projectMetadata.saved_field_values.project_key && !this.props.selectedProjectID && !this.props.projectOptional) {
             this.props.onProjectChange(projectMetadata.saved_field_values);
         }
     };
@@ -158,7 +161,7 @@ export default class JiraInstanceAndProjectSelector extends React.PureComponent<
                     name={'projects'}
                     label={'Project'}
                     limitOptions={true}
-                    required={true}
+                    required={!this.props.projectOptional}
                     onChange={this.handleProjectChange}
                     options={projectOptions}
                     isMulti={false}
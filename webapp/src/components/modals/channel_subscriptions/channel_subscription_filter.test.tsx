@@ -201,4 +201,60 @@ describe('components/ChannelSubscriptionFilter', () => {
         const error = ref.current?.checkInclusionError();
         expect(error).toEqual('Security level inclusion cannot be "Exclude Any". Note that the default value is now "Empty".');
     });
+
+    test('checkInclusionError should validate the values of range inclusions', async () => {
+        const field = {
+            key: 'customfield_10069',
+            name: 'Due Date',
+            schema: {type: 'date'},
+            userDefined: true,
+            issueTypes: [],
+        } as FilterField;
+        const props: Props = {
+            ...baseProps,
+            fields: [...baseProps.fields, field],
+            field,
+            value: {
+                inclusion: FilterFieldInclusion.BETWEEN,
+                key: 'customfield_10069',
+                values: ['2024-01-01'],
+            },
+        };
+        const ref = React.createRef<ChannelSubscriptionFilter>();
+        const {rerender} = renderWithRedux(
+            <ChannelSubscriptionFilter
+                {...props}
+                ref={ref}
+            />,
+        );
+
+        expect(ref.current?.checkInclusionError()).toEqual('Due Date requires exactly two values.');
+
+        rerender(
+            <ChannelSubscriptionFilter
+                {...props}
+                value={{...props.value, values: ['2024-01-01', 'tomorrow']}}
+                ref={ref}
+            />,
+        );
+        expect(ref.current?.checkInclusionError()).toEqual('"tomorrow" is not a valid date (YYYY-MM-DD) for Due Date.');
+
+        rerender(
+            <ChannelSubscriptionFilter
+                {...props}
+                value={{...props.value, inclusion: FilterFieldInclusion.WITHIN_LAST_DAYS, values: ['0']}}
+                ref={ref}
+            />,
+        );
+        expect(ref.current?.checkInclusionError()).toEqual('Due Date requires a positive number of days.');
+
+        rerender(
+            <ChannelSubscriptionFilter
+                {...props}
+                value={{...props.value, values: ['2024-01-01', '2024-02-01']}}
+                ref={ref}
+            />,
+        );
+        expect(ref.current?.checkInclusionError()).toBeNull();
+    });
 });
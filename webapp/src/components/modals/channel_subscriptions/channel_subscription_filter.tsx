@@ -11,10 +11,14 @@ import JiraSprintSelector from 'components/data_selectors/jira_sprint_selector';
 
 import {
     FIELD_KEY_STATUS,
+    getDefaultFilterInclusion,
     isCommentVisibilityField,
+    isDateField,
     isEpicLinkField,
     isLabelField,
     isMultiSelectField,
+    isRangeField,
+    isRangeInclusion,
     isSecurityLevelField,
     isSprintField,
     isTeamField,
@@ -72,7 +76,10 @@ export default class ChannelSubscriptionFilter extends React.PureComponent<Props
     handleInclusionChange = (name: string, choice: FilterFieldInclusion): void => {
         const {onChange, value} = this.props;
 
-        const newValues = choice === FilterFieldInclusion.EMPTY ? [] : value.values;
+        let newValues = value.values;
+        if (choice === FilterFieldInclusion.EMPTY || isWithinDaysInclusion(choice) !== isWithinDaysInclusion(value.inclusion)) {
+            newValues = [];
+        }
 
         onChange(value, {...value, inclusion: choice, values: newValues});
     };
@@ -80,7 +87,8 @@ export default class ChannelSubscriptionFilter extends React.PureComponent<Props
     handleFieldTypeChange = (name: string, choice: string): void => {
         const {onChange, value} = this.props;
 
-        onChange(value, {...value, values: [], key: choice, inclusion: FilterFieldInclusion.INCLUDE_ANY});
+        const field = this.props.fields.find((f) => f.key === choice);
+        onChange(value, {...value, values: [], key: choice, inclusion: getDefaultFilterInclusion(field)});
     };
 
     handleFieldValuesChange = (name: string, values: string[]): void => {
@@ -164,6 +172,35 @@ export default class ChannelSubscriptionFilter extends React.PureComponent<Props
             return 'Security level inclusion cannot be "Exclude Any". Note that the default value is now "Empty".';
         }
 
+        if (isRangeInclusion(inclusion)) {
+            return this.checkRangeValuesError();
+        }
+
+        return null;
+    };
+
+    checkRangeValuesError = (): string | null => {
+        const {field, value} = this.props;
+        const values = value.values;
+
+        if (isWithinDaysInclusion(value.inclusion)) {
+            if (values.length !== 1 || !(/^[1-9][0-9]*$/).test(values[0])) {
+                return `${field.name} requires a positive number of days.`;
+            }
+            return null;
+        }
+
+        const expected = value.inclusion === FilterFieldInclusion.BETWEEN ? 2 : 1;
+        if (values.length !== expected) {
+            return `${field.name} requires exactly ${expected === 2 ? 'two values' : 'one value'}.`;
+        }
+
+        const isValidValue = isDateField(field) ? (v: string) => (/^\d{4}-\d{2}-\d{2}/).test(v) && !isNaN(Date.parse(v)) : (v: string) => v.trim() !== '' && !isNaN(Number(v));
+        const invalid = values.find((v) => !isValidValue(v));
+        if (invalid) {
+            return `"${invalid}" is not a valid ${isDateField(field) ? 'date (YYYY-MM-DD)' : 'number'} for ${field.name}.`;
+        }
+
         return null;
     };
 
@@ -202,6 +239,21 @@ export default class ChannelSubscriptionFilter extends React.PureComponent<Props
         case FilterFieldInclusion.INCLUDE_OR_EMPTY:
             subtext = 'Includes the specified values or when the value is empty';
             break;
+        case FilterFieldInclusion.GREATER_THAN:
+        case FilterFieldInclusion.GREATER_THAN_OR_EQUAL:
+        case FilterFieldInclusion.LESS_THAN:
+        case FilterFieldInclusion.LESS_THAN_OR_EQUAL:
+            subtext = 'Compares the value with the specified value';
+            break;
+        case FilterFieldInclusion.BETWEEN:
+            subtext = 'Includes values between the two specified values, inclusive';
+            break;
+        case FilterFieldInclusion.WITHIN_NEXT_DAYS:
+            subtext = 'Includes dates from now up to the specified number of days';
+            break;
+        case FilterFieldInclusion.WITHIN_LAST_DAYS:
+            subtext = 'Includes dates from the specified number of days ago up to now';
+            break;
         }
 
         return (
@@ -248,7 +300,11 @@ export default class ChannelSubscriptionFilter extends React.PureComponent<Props
             ];
         }
 
-        if (!isMultiSelectField(field)) {
+        if (isRangeField(field)) {
+            inclusionSelectOptions = getRangeInclusionOptions(isDateField(field));
+        }
+
+        if (!isMultiSelectField(field) && inclusionSelectOptions.some((opt) => opt.value === FilterFieldInclusion.INCLUDE_ALL)) {
             const includeAllIndex = inclusionSelectOptions.findIndex((opt) => opt.value === FilterFieldInclusion.INCLUDE_ALL);
             inclusionSelectOptions.splice(includeAllIndex, 1);
         }
@@ -301,6 +357,10 @@ export default class ChannelSubscriptionFilter extends React.PureComponent<Props
         if (value.inclusion === FilterFieldInclusion.EMPTY) {
             lastSelectPlaceholder = '';
             disableLastSelect = true;
+        } else if (isWithinDaysInclusion(value.inclusion)) {
+            lastSelectPlaceholder = 'Number of days';
+        } else if (isRangeInclusion(value.inclusion) && isDateField(field)) {
+            lastSelectPlaceholder = 'YYYY-MM-DD';
         }
 
         const selectProps = {
@@ -457,6 +517,34 @@ export default class ChannelSubscriptionFilter extends React.PureComponent<Props
     }
 }
 
+function isWithinDaysInclusion(inclusion: FilterFieldInclusion): boolean {
+    return inclusion === FilterFieldInclusion.WITHIN_NEXT_DAYS || inclusion === FilterFieldInclusion.WITHIN_LAST_DAYS;
+}
+
+function getRangeInclusionOptions(isDate: boolean): ReactSelectOption[] {
+    if (!isDate) {
+        return [
+            {label: 'Greater Than', value: FilterFieldInclusion.GREATER_THAN},
+            {label: 'Greater Than or Equal', value: FilterFieldInclusion.GREATER_THAN_OR_EQUAL},
+            {label: 'Less Than', value: FilterFieldInclusion.LESS_THAN},
+            {label: 'Less Than or Equal', value: FilterFieldInclusion.LESS_THAN_OR_EQUAL},
+            {label: 'Between', value: FilterFieldInclusion.BETWEEN},
+            {label: 'Empty', value: FilterFieldInclusion.EMPTY},
+        ];
+    }
+
+    return [
+        {label: 'After', value: FilterFieldInclusion.GREATER_THAN},
+        {label: 'On or After', value: FilterFieldInclusion.GREATER_THAN_OR_EQUAL},
+        {label: 'Before', value: FilterFieldInclusion.LESS_THAN},
+        {label: 'On or Before', value: FilterFieldInclusion.LESS_THAN_OR_EQUAL},
+        {label: 'Between', value: FilterFieldInclusion.BETWEEN},
+        {label: 'Within the Next Days', value: FilterFieldInclusion.WITHIN_NEXT_DAYS},
+        {label: 'Within the Last Days', value: FilterFieldInclusion.WITHIN_LAST_DAYS},
+        {label: 'Empty', value: FilterFieldInclusion.EMPTY},
+    ];
+}
+
 type EmptyChannelSubscriptionFilterProps = {
     fields: FilterField[];
     theme: Theme;
@@ -5,12 +5,11 @@ import React from 'react';
 
 import {
     FilterField,
-    FilterFieldInclusion,
     FilterValue,
     IssueMetadata,
 } from 'types/model';
 
-import {getConflictingFields} from 'utils/jira_issue_metadata';
+import {getConflictingFields, getDefaultFilterInclusion} from 'utils/jira_issue_metadata';
 
 import ChannelSubscriptionFilter, {EmptyChannelSubscriptionFilter} from './channel_subscription_filter';
 
@@ -46,7 +45,8 @@ export default class ChannelSubscriptionFilters extends React.PureComponent<Prop
         const index = newValues.findIndex((f) => f === oldValue);
 
         if (index === -1) {
-            newValues.push({...newValue, inclusion: FilterFieldInclusion.INCLUDE_ANY, values: []});
+            const field = this.props.fields.find((f) => f.key === newValue.key);
+            newValues.push({...newValue, inclusion: getDefaultFilterInclusion(field), values: []});
             this.setState({showCreateRow: false});
         } else {
             newValues.splice(index, 1, newValue);
@@ -384,6 +384,8 @@ describe('components/EditChannelSubscription', () => {
                 filters: channelSubscriptionForCloud.filters,
                 name: channelSubscriptionForCloud.name,
                 instance_id: 'https://something.atlassian.net',
+                mentions: {fields: [], group: undefined},
+                post_attachments: false,
             },
         );
         expect(editChannelSubscription).not.toHaveBeenCalled();
@@ -483,6 +485,8 @@ describe('components/EditChannelSubscription', () => {
                 filters: channelSubscriptionForServer.filters,
                 name: MockSubscriptionName,
                 instance_id: 'https://something.atlassian.net',
+                mentions: {fields: [], group: undefined},
+                post_attachments: false,
             },
         );
         expect(editChannelSubscription).not.toHaveBeenCalled();
@@ -600,10 +604,99 @@ describe('components/EditChannelSubscription', () => {
                 },
                 name: 'SubTestName',
                 instance_id: 'https://something.atlassian.net',
+                mentions: {fields: [], group: undefined},
+                post_attachments: false,
             },
         );
     });
 
+    test('should keep the mentions of a subscription when editing it', async () => {
+        const editChannelSubscription = jest.fn().mockResolvedValue({});
+        const subscription = {
+            ...channelSubscriptionForCloud,
+            mentions: {fields: ['assignee', 'customfield_99999'], group: 'oncall-db'},
+        };
+        const props = {
+            ...baseProps,
+            editChannelSubscription,
+            selectedSubscription: subscription,
+        };
+        const ref = React.createRef<EditChannelSubscription>();
+        await act(async () => {
+            renderWithRedux(
+                <EditChannelSubscription
+                    {...props}
+                    ref={ref}
+                />,
+            );
+        });
+        await act(async () => {
+            ref.current?.setState(baseState);
+        });
+
+        if (ref.current) {
+            ref.current.validator = {validate: () => true, addComponent: jest.fn(), removeComponent: jest.fn()};
+        }
+
+        await act(async () => {
+            ref.current?.handleCreate({preventDefault: jest.fn()});
+        });
+        expect(editChannelSubscription).toHaveBeenCalledWith(expect.objectContaining({
+            mentions: {fields: ['assignee'], group: 'oncall-db'},
+        }));
+    });
+
+    test('should edit a saved filter subscription without project', async () => {
+        const editChannelSubscription = jest.fn().mockResolvedValue({});
+        const fetchJiraIssueMetadataForProjects = jest.fn().mockResolvedValue({data: cloudIssueMetadata});
+        const subscription = {
+            id: 'asxtifxe8jyi9y81htww6ixkiy',
+            channel_id: testChannel.id,
+            filters: {
+                events: ['event_created'],
+                projects: [],
+                issue_types: [],
+                fields: [],
+                saved_filter: {id: '10100', name: 'Open bugs'},
+            },
+            name: 'Open bugs',
+            instance_id: 'https://something.atlassian.net',
+        };
+        const props = {
+            ...baseProps,
+            editChannelSubscription,
+            fetchJiraIssueMetadataForProjects,
+            channelSubscriptions: [subscription],
+            selectedSubscription: subscription,
+        };
+        const ref = React.createRef<EditChannelSubscription>();
+        await act(async () => {
+            renderWithRedux(
+                <EditChannelSubscription
+                    {...props}
+                    ref={ref}
+                />,
+            );
+        });
+        expect(fetchJiraIssueMetadataForProjects).not.toHaveBeenCalled();
+
+        if (ref.current) {
+            ref.current.validator = {validate: () => true, addComponent: jest.fn(), removeComponent: jest.fn()};
+        }
+
+        await act(async () => {
+            ref.current?.handleCreate({preventDefault: jest.fn()});
+        });
+        expect(ref.current?.state.error).toBe(null);
+        expect(editChannelSubscription).toHaveBeenCalledWith(expect.objectContaining({
+            id: subscription.id,
+            filters: expect.objectContaining({
+                events: ['event_created'],
+                saved_filter: {id: '10100', name: 'Open bugs'},
+            }),
+        }));
+    });
+
     test('should edit a subscription', async () => {
         const createChannelSubscription = jest.fn().mockResolvedValue({});
         const editChannelSubscription = jest.fn().mockResolvedValue({});
@@ -648,6 +741,8 @@ describe('components/EditChannelSubscription', () => {
                 filters: channelSubscriptionForCloud.filters,
                 name: channelSubscriptionForCloud.name,
                 instance_id: 'https://something.atlassian.net',
+                mentions: {fields: [], group: undefined},
+                post_attachments: false,
             },
         );
         expect(createChannelSubscription).not.toHaveBeenCalled();
@@ -20,6 +20,7 @@ import {
     getCustomFieldFiltersForProjects,
     getCustomFieldValuesForEvents,
     getIssueTypes,
+    getMentionFieldOptions,
 } from 'utils/jira_issue_metadata';
 
 import {
@@ -29,6 +30,7 @@ import {
     IssueMetadata,
     ReactSelectOption,
     SavedFieldValues,
+    SubscriptionMentions,
 } from 'types/model';
 
 import ChannelSubscriptionFilters from './channel_subscription_filters';
@@ -70,6 +72,8 @@ export type Props = SharedProps & {
 
 export type State = {
     filters: ChannelSubscriptionFiltersModel;
+    mentions: SubscriptionMentions;
+    postAttachments: boolean;
     instanceID: string;
     fetchingIssueMetadata: boolean;
     jiraIssueMetadata: IssueMetadata | null;
@@ -99,14 +103,20 @@ export default class EditChannelSubscription extends PureComponent<Props, State>
         };
 
         let subscriptionName = null;
+        let mentions: SubscriptionMentions = {};
+        let postAttachments = false;
         if (props.selectedSubscription) {
             filters = Object.assign({}, filters, props.selectedSubscription.filters);
             subscriptionName = props.selectedSubscription.name;
+            mentions = props.selectedSubscription.mentions || {};
+            postAttachments = Boolean(props.selectedSubscription.post_attachments);
         }
 
         if (props.selectedSubscriptionTemplate) {
             filters = Object.assign({}, filters, props.selectedSubscriptionTemplate.filters);
             subscriptionName = props.selectedSubscriptionTemplate.name;
+            mentions = props.selectedSubscriptionTemplate.mentions || {};
+            postAttachments = Boolean(props.selectedSubscriptionTemplate.post_attachments);
         }
 
         filters.fields = filters.fields || [];
@@ -132,6 +142,8 @@ export default class EditChannelSubscription extends PureComponent<Props, State>
             submitting: false,
             submittingTemplate: false,
             filters,
+            mentions,
+            postAttachments,
             fetchingIssueMetadata,
             jiraIssueMetadata: null,
             subscriptionName,
@@ -231,6 +243,18 @@ export default class EditChannelSubscription extends PureComponent<Props, State>
         this.clearConflictingErrorMessage();
     };
 
+    handleMentionFieldsChange = (id: string, value: string[] | null) => {
+        this.setState({mentions: {...this.state.mentions, fields: value || []}});
+    };
+
+    handleMentionGroupChange = (id: string, value: string) => {
+        this.setState({mentions: {...this.state.mentions, group: value}});
+    };
+
+    handlePostAttachmentsChange = (e: React.ChangeEvent<HTMLInputElement>) => {
+        this.setState({postAttachments: e.target.checked});
+    };
+
     clearConflictingErrorMessage = () => {
         this.setState({conflictingError: null});
     };
@@ -349,6 +373,7 @@ export default class EditChannelSubscription extends PureComponent<Props, State>
             issue_types: [],
             events: [],
             fields: [],
+            saved_filter: this.state.filters.saved_filter,
         };
 
         let fetchingIssueMetadata = false;
@@ -402,11 +427,19 @@ export default class EditChannelSubscription extends PureComponent<Props, State>
             fields: configuredFields,
         };
 
+        const mentionFieldOptions = getMentionFieldOptions(this.state.jiraIssueMetadata, this.state.filters.projects);
+        const mentions = {
+            fields: (this.state.mentions.fields || []).filter((key) => mentionFieldOptions.find((option) => option.value === key)),
+            group: this.state.mentions.group?.trim(),
+        };
+
         const subscription = {
             channel_id: this.props.channel.id,
             filters,
             name: this.state.subscriptionName?.trim(),
             instance_id: this.state.instanceID,
+            mentions,
+            post_attachments: this.state.postAttachments,
         } as ChannelSubscription;
 
         if (this.props.selectedSubscriptionTemplate) {
@@ -461,6 +494,47 @@ export default class EditChannelSubscription extends PureComponent<Props, State>
         });
     };
 
+    renderNotificationSettings(mentionFieldOptions: ReactSelectOption[]): JSX.Element {
+        const mentionFields = this.state.mentions.fields || [];
+        return (
+            <React.Fragment>
+                <ReactSelectSetting
+                    name='mention_fields'
+                    label='Mention Users In'
+                    required={false}
+                    onChange={this.handleMentionFieldsChange}
+                    options={mentionFieldOptions}
+                    isMulti={true}
+                    theme={this.props.theme}
+                    value={mentionFieldOptions.filter((option) => mentionFields.includes(option.value))}
+                />
+                <Input
+                    id='mention_group'
+                    label='Mention Group'
+                    placeholder='Group name'
+                    type={'input'}
+                    maxLength={64}
+                    required={false}
+                    onChange={this.handleMentionGroupChange}
+                    value={this.state.mentions.group || ''}
+                    readOnly={false}
+                    addValidate={this.validator.addComponent}
+                    removeValidate={this.validator.removeComponent}
+                />
+                <div className='checkbox margin-bottom'>
+                    <label>
+                        <input
+                            type='checkbox'
+                            onChange={this.handlePostAttachmentsChange}
+                            checked={this.state.postAttachments}
+                        />
+                        {'Post the images and files attached to the issues in their threads'}
+                    </label>
+                </div>
+            </React.Fragment>
+        );
+    }
+
     render(): JSX.Element {
         const style = getModalStyles(this.props.theme);
 
@@ -471,6 +545,9 @@ export default class EditChannelSubscription extends PureComponent<Props, State>
         const filterFields = getCustomFieldFiltersForProjects(this.state.jiraIssueMetadata, this.state.filters.projects, this.state.filters.issue_types);
 
         const eventOptions = JiraEventOptions.concat(customFields);
+        const mentionFieldOptions = getMentionFieldOptions(this.state.jiraIssueMetadata, this.state.filters.projects);
+        const notificationSettings = this.renderNotificationSettings(mentionFieldOptions);
+        const savedFilter = this.state.filters.saved_filter;
 
         let conflictingErrorComponent = null;
         if (this.state.conflictingError) {
@@ -486,6 +563,28 @@ export default class EditChannelSubscription extends PureComponent<Props, State>
             let innerComponent = null;
             if (this.state.fetchingIssueMetadata) {
                 innerComponent = <Loading/>;
+            } else if (savedFilter && !this.state.filters.projects[0]) {
+                innerComponent = (
+                    <React.Fragment>
+                        <div className='margin-bottom'>
+                            <label className='control-label'>{'Saved Filter'}</label>
+                            <p>{`Notifies the issues matching the Jira saved filter "${savedFilter.name || savedFilter.id}". Select a project to also filter by issue type and fields.`}</p>
+                        </div>
+                        <ReactSelectSetting
+                            name='events'
+                            label='Events'
+                            required={true}
+                            onChange={this.handleSettingChange}
+                            options={JiraEventOptions}
+                            isMulti={true}
+                            theme={this.props.theme}
+                            value={JiraEventOptions.filter((option) => this.state.filters.events.includes(option.value))}
+                            addValidate={this.validator.addComponent}
+                            removeValidate={this.validator.removeComponent}
+                        />
+                        {notificationSettings}
+                    </React.Fragment>
+                );
             } else if (this.state.filters.projects[0] && !this.state.getMetaDataErr && this.state.jiraIssueMetadata) {
                 innerComponent = (
                     <React.Fragment>
@@ -538,6 +637,7 @@ export default class EditChannelSubscription extends PureComponent<Props, State>
                             searchTeamFields={this.props.searchTeamFields}
                             projectKey={this.state.filters.projects[0] || ''}
                         />
+                        {notificationSettings}
                         <div>
                             <label className='control-label margin-bottom'>
                                 {'Approximate JQL Output'}
@@ -587,6 +687,7 @@ export default class EditChannelSubscription extends PureComponent<Props, State>
                             selectedProjectID={this.state.filters.projects[0]}
                             onInstanceChange={this.handleJiraInstanceChange}
                             onProjectChange={this.handleProjectChange}
+                            projectOptional={Boolean(this.state.filters.saved_filter)}
                             onError={(error: string) => this.setState({error})}
 
                             theme={this.props.theme}
@@ -637,7 +738,7 @@ export default class EditChannelSubscription extends PureComponent<Props, State>
             );
         }
 
-        const enableSubmitButton = Boolean(this.state.filters.projects[0]);
+        const enableSubmitButton = Boolean(this.state.filters.projects[0] || this.state.filters.saved_filter);
         const enableDeleteButton = Boolean(this.props.selectedSubscription || this.props.selectedSubscriptionTemplate);
         let saveSubscriptionButtonText = '';
         let headerText = '';
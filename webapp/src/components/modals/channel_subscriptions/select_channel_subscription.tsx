@@ -70,7 +70,12 @@ export default class SelectChannelSubscriptionInternal extends React.PureCompone
     };
 
     getProjectName = (sub: ChannelSubscription): string => {
-        const projectKey = sub.filters.projects[0];
+        const projectKey = sub.filters.projects?.[0];
+        if (!projectKey) {
+            const savedFilter = sub.filters.saved_filter;
+            return savedFilter ? `Filter: ${savedFilter.name || savedFilter.id}` : '';
+        }
+
         if (!this.props.allProjectMetadata) {
             return projectKey;
         }
@@ -35,6 +35,7 @@ type Props = {
     channelId?: string;
     currentTeam: Team;
     post?: Post;
+    includeThread?: boolean;
     theme: Theme;
     visible: boolean;
     fetchJiraIssueMetadataForProjects: (projectKeys: string[], instanceID: string) => Promise<APIResponse<IssueMetadata>>;
@@ -57,7 +58,7 @@ export default class CreateIssueForm extends React.PureComponent<Props, State> {
         super(props);
 
         let description = this.props.description || '';
-        if (props.post) {
+        if (props.post && !props.includeThread) {
             description = props.post.message;
         }
 
@@ -213,6 +214,7 @@ export default class CreateIssueForm extends React.PureComponent<Props, State> {
         const requiredFieldsNotCovered = this.getFieldsNotCovered();
         const issue = {
             post_id: postId,
+            include_thread: Boolean(this.props.includeThread),
             current_team: this.props.currentTeam.name,
             fields: this.state.fields,
             channel_id: channelId as string,
@@ -8,6 +8,7 @@ import CreateIssueForm from './create_issue_form';
 
 type Props = {
     visible: boolean;
+    includeThread?: boolean;
     close: () => void;
 }
 
@@ -26,7 +27,7 @@ export default function CreateIssueModal(props: Props) {
             backdrop='static'
         >
             <Modal.Header closeButton={true}>
-                <Modal.Title>{'Create Jira Issue'}</Modal.Title>
+                <Modal.Title>{props.includeThread ? 'Create Jira Issue from Thread' : 'Create Jira Issue'}</Modal.Title>
             </Modal.Header>
             <CreateIssueForm {...props}/>
         </Modal>
@@ -20,13 +20,14 @@ import {GlobalState} from 'types/store';
 import CreateIssue from './create_issue_modal';
 
 const mapStateToProps = (state: GlobalState) => {
-    const {postId, description, channelId} = getCreateModal(state);
+    const {postId, includeThread, description, channelId} = getCreateModal(state);
     const post = (postId) ? getPost(state, postId) : null;
     const currentTeam = getCurrentTeam(state);
 
     return {
         visible: isCreateModalVisible(state),
         post,
+        includeThread,
         description,
         channelId,
         currentTeam,
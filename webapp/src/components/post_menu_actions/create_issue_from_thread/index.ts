@@ -0,0 +1,29 @@
+// Copyright (c) 2017-present Mattermost, Inc. All Rights Reserved.
+// See LICENSE.txt for license information.
+
+import {connect} from 'react-redux';
+
+import {GlobalState} from 'types/store';
+
+import {getCurrentUserLocale} from 'selectors';
+
+import CreateIssuePostMenuAction from 'components/post_menu_actions/create_issue/create_issue';
+
+function mapStateToProps(state: GlobalState): {actionText: string} {
+    const locale = getCurrentUserLocale(state);
+
+    let actionText;
+    switch (locale) {
+    case 'es':
+        actionText = 'Crear incidencia en Jira desde el hilo';
+        break;
+    default:
+        actionText = 'Create Jira Issue from Thread';
+    }
+
+    return {
+        actionText,
+    };
+}
+
+export default connect(mapStateToProps)(CreateIssuePostMenuAction);
@@ -123,7 +123,7 @@ export default class ReactSelectSetting extends React.PureComponent<Props, State
                     {...this.props}
                     noOptionsMessage={() => 'Start typing...'}
                     formatCreateLabel={(value) => `Add "${value}"`}
-                    placeholder=''
+                    placeholder={this.props.placeholder || ''}
                     menuPortalTarget={document.body}
                     menuPlacement='auto'
                     onChange={this.handleChange}
@@ -10,6 +10,7 @@ import ConnectModal from 'components/modals/connect_modal';
 import DisconnectModal from 'components/modals/disconnect_modal';
 
 import CreateIssuePostMenuAction from 'components/post_menu_actions/create_issue';
+import CreateIssueFromThreadPostMenuAction from 'components/post_menu_actions/create_issue_from_thread';
 
 import CreateIssueModal from 'components/modals/create_issue';
 
@@ -83,6 +84,26 @@ const setupUILater = (registry: any, store: Store<object, Action<object>>): () =
                     return true;
                 },
             });
+            registry.registerPostDropdownMenuAction({
+                text: CreateIssueFromThreadPostMenuAction,
+                action: (postId: string) => {
+                    const state = store.getState() as GlobalState;
+                    if (!isUserConnected(state)) {
+                        return;
+                    }
+
+                    store.dispatch<any>(openCreateModal(postId, true));
+                },
+                filter: (postId: string): boolean => {
+                    const state = store.getState() as GlobalState;
+                    const post = getPost(state, postId);
+                    const oldSystemMessageOrNull = post ? isSystemMessage(post) : true;
+                    const systemMessage = isCombinedUserActivityPost(post) || oldSystemMessageOrNull;
+                    const userConnected = isUserConnected(state);
+
+                    return !systemMessage && userConnected;
+                },
+            });
             registry.registerRootComponent(AttachCommentToIssueModal);
             registry.registerPostDropdownMenuAction({
                 text: AttachCommentToIssuePostMenuAction,
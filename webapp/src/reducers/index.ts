@@ -121,6 +121,7 @@ const createModal = (state = '', action = {} as AnyAction) => {
         return {
             state,
             postId: action.data.postId,
+            includeThread: action.data.includeThread,
             description: action.data.description,
             channelId: action.data.channelId,
         };
@@ -156,6 +156,13 @@ export enum FilterFieldInclusion {
     EXCLUDE_ANY = 'exclude_any',
     EMPTY = 'empty',
     INCLUDE_OR_EMPTY = 'include_or_empty',
+    GREATER_THAN = 'greater_than',
+    GREATER_THAN_OR_EQUAL = 'greater_than_or_equal',
+    LESS_THAN = 'less_than',
+    LESS_THAN_OR_EQUAL = 'less_than_or_equal',
+    BETWEEN = 'between',
+    WITHIN_NEXT_DAYS = 'within_next_days',
+    WITHIN_LAST_DAYS = 'within_last_days',
 }
 
 export type FilterValue = {
@@ -164,11 +171,23 @@ export type FilterValue = {
     inclusion: FilterFieldInclusion;
 }
 
+export type SavedFilterSource = {
+    id: string;
+    name: string;
+    owner_id?: string;
+};
+
 export type ChannelSubscriptionFilters = {
     projects: string[];
     events: string[];
     issue_types: string[];
     fields: FilterValue[];
+    saved_filter?: SavedFilterSource;
+};
+
+export type SubscriptionMentions = {
+    fields?: string[];
+    group?: string;
 };
 
 export type ChannelSubscription = {
@@ -177,6 +196,8 @@ export type ChannelSubscription = {
     filters: ChannelSubscriptionFilters;
     name: string;
     instance_id: string;
+    mentions?: SubscriptionMentions;
+    post_attachments?: boolean;
 }
 
 export type SubscriptionTemplate = ChannelSubscription
@@ -213,6 +234,7 @@ export type CreateIssueRequest = {
     instance_id: string;
     required_fields_not_covered: string[][];
     post_id: string;
+    include_thread?: boolean;
     current_team: string;
     channel_id: string;
     fields: {};
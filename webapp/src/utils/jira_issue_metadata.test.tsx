@@ -18,8 +18,10 @@ import {
     generateJQLStringFromSubscriptionFilters,
     getConflictingFields,
     getCustomFieldFiltersForProjects,
+    getDefaultFilterInclusion,
     getJiraTicketDetails,
     getStatusField,
+    isRangeField,
     isSprintField,
 } from './jira_issue_metadata';
 
@@ -69,6 +71,25 @@ describe('utils/jira_issue_metadata', () => {
         expect(actual[3].name).toBe('Team');
     });
 
+    test('should return number and date fields for the range operators', () => {
+        const field = {
+            hasDefaultValue: false,
+            key: 'customfield_10069',
+            name: 'Due Date',
+            operations: ['set'],
+            required: false,
+            schema: {custom: 'com.atlassian.jira.plugin.system.customfieldtypes:datepicker', customId: 10069, type: 'date'},
+        };
+        const metadata = useFieldForIssueMetadata(field, 'customfield_10069');
+        const actual = getCustomFieldFiltersForProjects(metadata, [metadata.projects[0].key], []);
+        expect(actual.length).toBe(4);
+        expect(actual[1].key).toBe('customfield_10069');
+        expect(actual[1].userDefined).toBe(true);
+        expect(isRangeField(actual[1])).toBe(true);
+        expect(getDefaultFilterInclusion(actual[1])).toBe(FilterFieldInclusion.GREATER_THAN_OR_EQUAL);
+        expect(getDefaultFilterInclusion(actual[0])).toBe(FilterFieldInclusion.INCLUDE_ANY);
+    });
+
     test('should return options for multi-select options', () => {
         const field = {
             allowedValues: [
@@ -677,6 +698,36 @@ describe('utils/jira_issue_metadata', () => {
             const actual = generateJQLStringFromSubscriptionFilters(issueMetadata, fields, filters);
             expect(actual).toEqual('Project = KT AND IssueType IN (Bug) AND Priority IS EMPTY');
         });
+
+        it('range inclusions chosen', () => {
+            const dueDateField = {
+                key: 'duedate',
+                name: 'Due Date',
+                schema: {type: 'date'},
+                userDefined: true,
+                issueTypes: [],
+            } as FilterField;
+            const storyPointsField = {
+                key: 'customfield_10072',
+                name: 'Story Points',
+                schema: {type: 'number'},
+                userDefined: true,
+                issueTypes: [],
+            } as FilterField;
+
+            const filters: ChannelSubscriptionFilters = {
+                projects: ['KT'],
+                issue_types: ['10001'],
+                events: [],
+                fields: [
+                    {key: 'customfield_10072', values: ['3', '8'], inclusion: FilterFieldInclusion.BETWEEN},
+                    {key: 'duedate', values: ['7'], inclusion: FilterFieldInclusion.WITHIN_NEXT_DAYS},
+                ],
+            };
+
+            const actual = generateJQLStringFromSubscriptionFilters(issueMetadata, [dueDateField, storyPointsField], filters);
+            expect(actual).toEqual('Project = KT AND IssueType IN (Bug) AND ("Story Points" >= 3 AND "Story Points" <= 8) AND ("Due Date" >= now() AND "Due Date" <= 7d)');
+        });
     });
 
     describe('getJiraTicketDetails', () => {
@@ -167,6 +167,23 @@ export function getCustomFieldsForProjects(metadata: IssueMetadata | null, proje
     return sortByName(Object.values(customFieldHash));
 }
 
+// getMentionFieldOptions returns the user picker fields whose users can be mentioned in the posts of a subscription.
+export function getMentionFieldOptions(metadata: IssueMetadata | null, projectKeys: string[]): ReactSelectOption[] {
+    const options: ReactSelectOption[] = [
+        {value: 'assignee', label: 'Assignee'},
+        {value: 'reporter', label: 'Reporter'},
+    ];
+
+    for (const field of getCustomFieldsForProjects(metadata, projectKeys)) {
+        const {type, items} = field.schema;
+        const isUserPicker = type === 'user' || (type === 'array' && items === 'user');
+        if (isUserPicker && field.key !== 'assignee' && field.key !== 'reporter') {
+            options.push({value: field.key, label: field.name});
+        }
+    }
+    return options;
+}
+
 const allowedTypes = [
     'priority',
     'securitylevel',
@@ -186,6 +203,23 @@ const allowedFieldTypes = [
     'option',
 ];
 
+// Fields of these types are filtered with the range operators
+const rangeFieldTypes = [
+    'number',
+    'date',
+    'datetime',
+];
+
+const rangeInclusions = [
+    FilterFieldInclusion.GREATER_THAN,
+    FilterFieldInclusion.GREATER_THAN_OR_EQUAL,
+    FilterFieldInclusion.LESS_THAN,
+    FilterFieldInclusion.LESS_THAN_OR_EQUAL,
+    FilterFieldInclusion.BETWEEN,
+    FilterFieldInclusion.WITHIN_NEXT_DAYS,
+    FilterFieldInclusion.WITHIN_LAST_DAYS,
+];
+
 const jiraSystemCustomFieldTypesKey = 'com.atlassian.jira.plugin.system.customfieldtypes';
 
 const avoidedCustomTypesForFilters: string[] = [];
@@ -202,7 +236,7 @@ function isValidFieldForFilter(field: JiraField): boolean {
     }
 
     return allowedTypes.includes(type) || (custom && acceptedCustomTypesForFilters.includes(custom)) ||
-    allowedFieldTypes.includes(type) ||
+    allowedFieldTypes.includes(type) || rangeFieldTypes.includes(type) ||
     (type === 'array' && typeof items !== 'undefined' && allowedArrayTypes.includes(items));
 }
 
@@ -286,7 +320,18 @@ export function getCustomFieldFiltersForProjects(metadata: IssueMetadata | null,
         } as FilterField;
     });
 
-    const result = userResult.concat(userDefinedFields);
+    const rangeFields = fields.filter((field) => rangeFieldTypes.includes(field.schema.type) && !field.allowedValues);
+    const populatedRangeFields = rangeFields.map((field) => {
+        return {
+            key: field.key,
+            name: field.name,
+            schema: field.schema,
+            userDefined: true,
+            issueTypes: field.validIssueTypes,
+        } as FilterField;
+    });
+
+    const result = userResult.concat(userDefinedFields, populatedRangeFields);
     const epicLinkField = fields.find(isEpicLinkField);
     if (epicLinkField) {
         result.unshift({
@@ -418,6 +463,27 @@ export function isTextField(field: JiraField | FilterField): boolean {
     return field.schema.type === 'string';
 }
 
+export function isRangeField(field: JiraField | FilterField): boolean {
+    return Boolean(field.schema && rangeFieldTypes.includes(field.schema.type));
+}
+
+export function isDateField(field: JiraField | FilterField): boolean {
+    return Boolean(field.schema && (field.schema.type === 'date' || field.schema.type === 'datetime'));
+}
+
+export function isRangeInclusion(inclusion: FilterFieldInclusion): boolean {
+    return rangeInclusions.includes(inclusion);
+}
+
+// getDefaultFilterInclusion returns the inclusion a new filter on the field starts with.
+export function getDefaultFilterInclusion(field?: FilterField): FilterFieldInclusion {
+    if (field && isRangeField(field)) {
+        return FilterFieldInclusion.GREATER_THAN_OR_EQUAL;
+    }
+
+    return FilterFieldInclusion.INCLUDE_ANY;
+}
+
 export function isSecurityLevelField(field: JiraField | FilterField): boolean {
     return field.schema.type === 'securitylevel';
 }
@@ -446,6 +512,28 @@ function quoteGuard(s: string) {
     return s;
 }
 
+function getRangeJQL(fieldName: string, inclusion: FilterFieldInclusion, values: string[]): string {
+    const first = values[0] || '?';
+    switch (inclusion) {
+    case FilterFieldInclusion.GREATER_THAN:
+        return `${fieldName} > ${first}`;
+    case FilterFieldInclusion.GREATER_THAN_OR_EQUAL:
+        return `${fieldName} >= ${first}`;
+    case FilterFieldInclusion.LESS_THAN:
+        return `${fieldName} < ${first}`;
+    case FilterFieldInclusion.LESS_THAN_OR_EQUAL:
+        return `${fieldName} <= ${first}`;
+    case FilterFieldInclusion.BETWEEN:
+        return `(${fieldName} >= ${first} AND ${fieldName} <= ${values[1] || '?'})`;
+    case FilterFieldInclusion.WITHIN_NEXT_DAYS:
+        return `(${fieldName} >= now() AND ${fieldName} <= ${first}d)`;
+    case FilterFieldInclusion.WITHIN_LAST_DAYS:
+        return `(${fieldName} >= -${first}d AND ${fieldName} <= now())`;
+    default:
+        return '';
+    }
+}
+
 export function generateJQLStringFromSubscriptionFilters(issueMetadata: IssueMetadata, fields: FilterField[], filters: ChannelSubscriptionFilters, securityLevelEmptyForJiraSubscriptions?: boolean) {
     const projectJQL = `Project = ${quoteGuard(filters.projects[0]) || '?'}`;
 
@@ -476,6 +564,10 @@ export function generateJQLStringFromSubscriptionFilters(issueMetadata: IssueMet
             return `${quoteGuard(fieldName)} IS EMPTY`;
         }
 
+        if (isRangeInclusion(inclusion)) {
+            return getRangeJQL(quoteGuard(fieldName), inclusion, values.map((v) => quoteGuard(v)));
+        }
+
         const inclusionString = inclusion === FilterFieldInclusion.EXCLUDE_ANY ? 'NOT IN' : 'IN';
         if (!values.length) {
             return `${quoteGuard(fieldName)} ${inclusionString} ?`;